@@ -0,0 +1,248 @@
+// Package v1 defines the JSON payloads of version 1 of the upload HTTP API.
+// Field names are part of the contract: new fields may be added, but existing
+// ones are never renamed or repurposed within v1.
+package v1
+
+import (
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/s3"
+)
+
+// MediaType is sent as Content-Type of every v1 response.
+const MediaType = "application/vnd.file-uploader.v1+json"
+
+type (
+	Video struct {
+		DurationSeconds float64 `json:"durationSeconds"`
+		Width           int     `json:"width"`
+		Height          int     `json:"height"`
+		Codec           string  `json:"codec"`
+		ThumbnailKey    string  `json:"thumbnailKey,omitempty"`
+	}
+
+	UploadResponse struct {
+		Key           string            `json:"key"`
+		Location      string            `json:"location"`
+		Metadata      map[string]string `json:"metadata,omitempty"`
+		Text          string            `json:"text,omitempty"`
+		Video         *Video            `json:"video,omitempty"`
+		CorrelationID string            `json:"correlationId,omitempty"`
+		RequestID     string            `json:"requestId,omitempty"`
+	}
+
+	MultipartUploadResponse struct {
+		Files  []UploadResponse  `json:"files"`
+		Fields map[string]string `json:"fields,omitempty"`
+	}
+
+	DeleteRequest struct {
+		Bucket string   `json:"bucket"`
+		Keys   []string `json:"keys"`
+		DryRun bool     `json:"dryRun,omitempty"`
+		Force  bool     `json:"force,omitempty"`
+	}
+
+	KeyResult struct {
+		Key    string `json:"key"`
+		Status string `json:"status"`
+		Error  *Error `json:"error,omitempty"`
+	}
+
+	DeleteResponse struct {
+		Results       []KeyResult `json:"results"`
+		CorrelationID string      `json:"correlationId,omitempty"`
+	}
+
+	PostPolicyRequest struct {
+		Bucket         string `json:"bucket"`
+		Key            string `json:"key,omitempty"`
+		KeyPrefix      string `json:"keyPrefix,omitempty"`
+		ContentType    string `json:"contentType,omitempty"`
+		MinSize        int64  `json:"minSize,omitempty"`
+		MaxSize        int64  `json:"maxSize,omitempty"`
+		ExpiresSeconds int64  `json:"expiresSeconds,omitempty"`
+	}
+
+	PostPolicyResponse struct {
+		URL       string            `json:"url"`
+		Fields    map[string]string `json:"fields"`
+		ExpiresAt time.Time         `json:"expiresAt"`
+	}
+
+	PresignedMultipartRequest struct {
+		Bucket         string            `json:"bucket"`
+		Key            string            `json:"key"`
+		ContentType    string            `json:"contentType,omitempty"`
+		Size           int64             `json:"size"`
+		PartSize       int64             `json:"partSize,omitempty"`
+		ExpiresSeconds int64             `json:"expiresSeconds,omitempty"`
+		Tags           map[string]string `json:"tags,omitempty"`
+	}
+
+	PresignedPart struct {
+		Number int32  `json:"number"`
+		URL    string `json:"url"`
+		Offset int64  `json:"offset"`
+		Size   int64  `json:"size"`
+	}
+
+	PresignedMultipartResponse struct {
+		UploadID  string          `json:"uploadId"`
+		Key       string          `json:"key"`
+		PartSize  int64           `json:"partSize"`
+		Parts     []PresignedPart `json:"parts"`
+		ExpiresAt time.Time       `json:"expiresAt"`
+	}
+
+	CompletedPart struct {
+		Number int32  `json:"number"`
+		ETag   string `json:"etag"`
+	}
+
+	CompleteMultipartRequest struct {
+		Bucket   string          `json:"bucket"`
+		Key      string          `json:"key"`
+		UploadID string          `json:"uploadId"`
+		Parts    []CompletedPart `json:"parts"`
+		Size     int64           `json:"size"`
+	}
+
+	ConfirmUploadRequest struct {
+		Bucket      string `json:"bucket"`
+		Key         string `json:"key"`
+		Size        int64  `json:"size,omitempty"`
+		MaxSize     int64  `json:"maxSize,omitempty"`
+		ContentType string `json:"contentType,omitempty"`
+		SHA256      string `json:"sha256,omitempty"`
+		ETag        string `json:"etag,omitempty"`
+	}
+
+	FileResponse struct {
+		Key          string            `json:"key"`
+		Size         int64             `json:"size"`
+		ContentType  string            `json:"contentType"`
+		ETag         string            `json:"etag"`
+		LastModified time.Time         `json:"lastModified"`
+		StorageClass string            `json:"storageClass,omitempty"`
+		VersionID    string            `json:"versionId,omitempty"`
+		Metadata     map[string]string `json:"metadata,omitempty"`
+		Tags         map[string]string `json:"tags,omitempty"`
+	}
+)
+
+func NewUploadResponse(result s3.UploadFileResult) UploadResponse {
+	response := UploadResponse{
+		Key:           result.Filename,
+		Location:      result.Location,
+		Metadata:      result.Metadata,
+		Text:          result.Text,
+		CorrelationID: result.CorrelationID,
+		RequestID:     result.RequestID,
+	}
+	if video := result.Video; video != nil {
+		response.Video = &Video{
+			DurationSeconds: video.Duration.Seconds(),
+			Width:           video.Width,
+			Height:          video.Height,
+			Codec:           video.Codec,
+			ThumbnailKey:    video.ThumbnailKey,
+		}
+	}
+
+	return response
+}
+
+func NewMultipartUploadResponse(result s3.MultipartUploadResult) MultipartUploadResponse {
+	response := MultipartUploadResponse{Files: make([]UploadResponse, 0, len(result.Files)), Fields: result.Fields}
+	for _, file := range result.Files {
+		response.Files = append(response.Files, NewUploadResponse(file))
+	}
+
+	return response
+}
+
+func (r DeleteRequest) Request() s3.DeleteFileRequest {
+	return s3.DeleteFileRequest{BucketName: r.Bucket, Filename: r.Keys, DryRun: r.DryRun, Force: r.Force}
+}
+
+func NewDeleteResponse(result s3.BatchResult) DeleteResponse {
+	response := DeleteResponse{Results: make([]KeyResult, 0, len(result.Results)), CorrelationID: result.CorrelationID}
+	for _, keyResult := range result.Results {
+		item := KeyResult{Key: keyResult.Key, Status: string(keyResult.Status)}
+		if keyResult.Err != nil {
+			item.Error = NewError(keyResult.Err)
+		}
+		response.Results = append(response.Results, item)
+	}
+
+	return response
+}
+
+func (r PostPolicyRequest) Request() s3.PostPolicyRequest {
+	return s3.PostPolicyRequest{
+		BucketName:  r.Bucket,
+		Filename:    r.Key,
+		KeyPrefix:   r.KeyPrefix,
+		ContentType: r.ContentType,
+		MinSize:     r.MinSize,
+		MaxSize:     r.MaxSize,
+		Expires:     time.Duration(r.ExpiresSeconds) * time.Second,
+	}
+}
+
+func NewPostPolicyResponse(policy s3.PostPolicy) PostPolicyResponse {
+	return PostPolicyResponse{URL: policy.URL, Fields: policy.Fields, ExpiresAt: policy.Expires}
+}
+
+func (r PresignedMultipartRequest) Request() s3.PresignedMultipartRequest {
+	return s3.PresignedMultipartRequest{
+		BucketName:  r.Bucket,
+		Filename:    r.Key,
+		ContentType: r.ContentType,
+		Size:        r.Size,
+		PartSize:    r.PartSize,
+		Expires:     time.Duration(r.ExpiresSeconds) * time.Second,
+		Tags:        r.Tags,
+	}
+}
+
+func NewPresignedMultipartResponse(upload s3.PresignedMultipartUpload) PresignedMultipartResponse {
+	response := PresignedMultipartResponse{
+		UploadID:  upload.UploadID,
+		Key:       upload.Filename,
+		PartSize:  upload.PartSize,
+		Parts:     make([]PresignedPart, 0, len(upload.Parts)),
+		ExpiresAt: upload.Expires,
+	}
+	for _, part := range upload.Parts {
+		response.Parts = append(response.Parts, PresignedPart(part))
+	}
+
+	return response
+}
+
+func (r CompleteMultipartRequest) Request() s3.CompletePresignedMultipartRequest {
+	parts := make([]s3.CompletedPart, 0, len(r.Parts))
+	for _, part := range r.Parts {
+		parts = append(parts, s3.CompletedPart(part))
+	}
+
+	return s3.CompletePresignedMultipartRequest{BucketName: r.Bucket, Filename: r.Key, UploadID: r.UploadID, Parts: parts, Size: r.Size}
+}
+
+func (r ConfirmUploadRequest) Request() s3.ConfirmUploadRequest {
+	return s3.ConfirmUploadRequest{
+		BucketName:  r.Bucket,
+		Filename:    r.Key,
+		Size:        r.Size,
+		MaxSize:     r.MaxSize,
+		ContentType: r.ContentType,
+		SHA256:      r.SHA256,
+		ETag:        r.ETag,
+	}
+}
+
+func NewFileResponse(stat s3.FileStat) FileResponse {
+	return FileResponse(stat)
+}
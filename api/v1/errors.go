@@ -0,0 +1,139 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+	"github.com/KurniawanHendiW/file-uploader/breaker"
+	"github.com/KurniawanHendiW/file-uploader/s3"
+)
+
+// ErrorCode values are stable; clients should branch on them rather than on
+// messages.
+type ErrorCode string
+
+const (
+	CodeValidation       ErrorCode = "validation_failed"
+	CodeUnauthenticated  ErrorCode = "unauthenticated"
+	CodeForbidden        ErrorCode = "forbidden"
+	CodeNotOwner         ErrorCode = "not_owner"
+	CodeNotFound         ErrorCode = "not_found"
+	CodeExists           ErrorCode = "already_exists"
+	CodeProtected        ErrorCode = "protected"
+	CodeTooLarge         ErrorCode = "too_large"
+	CodeQuotaExceeded    ErrorCode = "quota_exceeded"
+	CodeChecksumMismatch ErrorCode = "checksum_mismatch"
+	CodeUploadRejected   ErrorCode = "upload_rejected"
+	CodeContentRejected  ErrorCode = "content_rejected"
+	CodeUnavailable      ErrorCode = "unavailable"
+	CodeInternal         ErrorCode = "internal"
+)
+
+type (
+	FieldError struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	}
+
+	Error struct {
+		Code          ErrorCode    `json:"code"`
+		Message       string       `json:"message"`
+		Fields        []FieldError `json:"fields,omitempty"`
+		CorrelationID string       `json:"correlationId,omitempty"`
+		RequestID     string       `json:"requestId,omitempty"`
+	}
+
+	ErrorResponse struct {
+		Error Error `json:"error"`
+	}
+)
+
+var errorCodes = []struct {
+	err    error
+	code   ErrorCode
+	status int
+}{
+	{authz.ErrUnauthenticated, CodeUnauthenticated, http.StatusUnauthorized},
+	{authz.ErrForbidden, CodeForbidden, http.StatusForbidden},
+	{s3.ErrNotOwner, CodeNotOwner, http.StatusForbidden},
+	{s3.ErrAccessDenied, CodeForbidden, http.StatusForbidden},
+	{s3.ErrKeyOutsideNamespace, CodeForbidden, http.StatusForbidden},
+	{s3.ErrFileNotFound, CodeNotFound, http.StatusNotFound},
+	{s3.ErrBucketNotFound, CodeNotFound, http.StatusNotFound},
+	{s3.ErrFileExists, CodeExists, http.StatusConflict},
+	{s3.ErrProtectedKey, CodeProtected, http.StatusConflict},
+	{s3.ErrDeleteThresholdExceeded, CodeProtected, http.StatusConflict},
+	{s3.ErrRequestTooLarge, CodeTooLarge, http.StatusRequestEntityTooLarge},
+	{s3.ErrSourceTooLarge, CodeTooLarge, http.StatusRequestEntityTooLarge},
+	{s3.ErrQuotaExceeded, CodeQuotaExceeded, http.StatusInsufficientStorage},
+	{s3.ErrChecksumMismatch, CodeChecksumMismatch, http.StatusUnprocessableEntity},
+	{s3.ErrUploadRejected, CodeUploadRejected, http.StatusUnprocessableEntity},
+	{s3.ErrContentRejected, CodeContentRejected, http.StatusUnprocessableEntity},
+	{s3.ErrContentQuarantined, CodeContentRejected, http.StatusUnprocessableEntity},
+	{s3.ErrClientUnavailable, CodeUnavailable, http.StatusServiceUnavailable},
+	{s3.ErrServiceClosed, CodeUnavailable, http.StatusServiceUnavailable},
+	{breaker.ErrOpen, CodeUnavailable, http.StatusServiceUnavailable},
+}
+
+// NewError maps err onto its error code. Validation errors list the offending
+// fields; errors with a correlation ID carry it along.
+func NewError(err error) *Error {
+	apiErr := &Error{Code: CodeInternal, Message: err.Error()}
+
+	var validationErr *s3.ValidationError
+	if errors.As(err, &validationErr) {
+		apiErr.Code = CodeValidation
+		for _, violation := range validationErr.Violations {
+			apiErr.Fields = append(apiErr.Fields, FieldError{Field: violation.Field, Message: violation.Error()})
+		}
+	} else if code, _ := errorCode(err); code != "" {
+		apiErr.Code = code
+	}
+
+	var correlated *s3.CorrelatedError
+	if errors.As(err, &correlated) {
+		apiErr.CorrelationID, apiErr.RequestID = correlated.CorrelationID, correlated.RequestID
+	}
+
+	return apiErr
+}
+
+// StatusCode maps err onto the HTTP status of its error code.
+func StatusCode(err error) int {
+	var validationErr *s3.ValidationError
+	if errors.As(err, &validationErr) {
+		return http.StatusBadRequest
+	}
+
+	if _, status := errorCode(err); status != 0 {
+		return status
+	}
+
+	return http.StatusInternalServerError
+}
+
+func errorCode(err error) (ErrorCode, int) {
+	for _, mapping := range errorCodes {
+		if errors.Is(err, mapping.err) {
+			return mapping.code, mapping.status
+		}
+	}
+
+	return "", 0
+}
+
+// WriteJSON writes v with the v1 media type.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", MediaType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("failed to write response: %v", err)
+	}
+}
+
+func WriteError(w http.ResponseWriter, err error) {
+	WriteJSON(w, StatusCode(err), ErrorResponse{Error: *NewError(err)})
+}
@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+	"github.com/KurniawanHendiW/file-uploader/s3"
+)
+
+func TestNewError(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   ErrorCode
+		wantStatus int
+		wantFields []string
+	}{
+		{name: "validation", err: &s3.ValidationError{Violations: []*s3.Violation{s3.Violationf("BucketName", "bucket name is required"), s3.Violationf("Key", "key is required")}}, wantCode: CodeValidation, wantStatus: http.StatusBadRequest, wantFields: []string{"BucketName", "Key"}},
+		{name: "wrapped sentinel", err: fmt.Errorf("download a.txt: %w", s3.ErrFileNotFound), wantCode: CodeNotFound, wantStatus: http.StatusNotFound},
+		{name: "forbidden", err: authz.ErrForbidden, wantCode: CodeForbidden, wantStatus: http.StatusForbidden},
+		{name: "unavailable", err: &s3.ClientInitError{Err: errors.New("no credentials")}, wantCode: CodeUnavailable, wantStatus: http.StatusServiceUnavailable},
+		{name: "unknown", err: errors.New("boom"), wantCode: CodeInternal, wantStatus: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := NewError(tt.err)
+			if apiErr.Code != tt.wantCode || apiErr.Message != tt.err.Error() {
+				t.Errorf("NewError = %+v, want code %s and the error's message", apiErr, tt.wantCode)
+			}
+			fields := []string{}
+			for _, field := range apiErr.Fields {
+				fields = append(fields, field.Field)
+			}
+			if !slices.Equal(fields, append([]string{}, tt.wantFields...)) {
+				t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+			}
+			if got := StatusCode(tt.err); got != tt.wantStatus {
+				t.Errorf("StatusCode = %d, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	err := &s3.CorrelatedError{CorrelationID: "corr-1", RequestID: "req-1", Err: s3.ErrFileExists}
+	recorder := httptest.NewRecorder()
+
+	WriteError(recorder, err)
+
+	if recorder.Code != http.StatusConflict || recorder.Header().Get("Content-Type") != MediaType {
+		t.Errorf("response = %d with %q, want %d with %q", recorder.Code, recorder.Header().Get("Content-Type"), http.StatusConflict, MediaType)
+	}
+	var response ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got := response.Error; got.Code != CodeExists || got.CorrelationID != "corr-1" || got.RequestID != "req-1" {
+		t.Errorf("error = %+v, want %s carrying the correlation and request IDs", got, CodeExists)
+	}
+}
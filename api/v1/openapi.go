@@ -0,0 +1,177 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Endpoint describes one v1 operation for the OpenAPI document. Request is nil
+// for operations without a JSON body; Form marks multipart/form-data uploads.
+type Endpoint struct {
+	Method      string
+	Path        string
+	OperationID string
+	Summary     string
+	Request     any
+	Form        bool
+	Response    any
+	Status      int
+}
+
+// Endpoints are the operations of the v1 upload API.
+var Endpoints = []Endpoint{
+	{Method: http.MethodPost, Path: "/v1/uploads", OperationID: "uploadMultipart", Summary: "Upload the files of a multipart form", Form: true, Response: MultipartUploadResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/v1/uploads/policy", OperationID: "createPostPolicy", Summary: "Sign a browser POST policy", Request: PostPolicyRequest{}, Response: PostPolicyResponse{}, Status: http.StatusOK},
+	{Method: http.MethodPost, Path: "/v1/uploads/multipart", OperationID: "createPresignedMultipart", Summary: "Start a presigned multipart upload", Request: PresignedMultipartRequest{}, Response: PresignedMultipartResponse{}, Status: http.StatusCreated},
+	{Method: http.MethodPost, Path: "/v1/uploads/multipart/complete", OperationID: "completePresignedMultipart", Summary: "Complete a presigned multipart upload", Request: CompleteMultipartRequest{}, Response: UploadResponse{}, Status: http.StatusOK},
+	{Method: http.MethodPost, Path: "/v1/uploads/confirm", OperationID: "confirmUpload", Summary: "Confirm a direct upload matches what was authorized", Request: ConfirmUploadRequest{}, Response: FileResponse{}, Status: http.StatusOK},
+	{Method: http.MethodPost, Path: "/v1/files/delete", OperationID: "deleteFiles", Summary: "Delete files", Request: DeleteRequest{}, Response: DeleteResponse{}, Status: http.StatusOK},
+}
+
+// OpenAPI renders an OpenAPI 3.0 document for endpoints, deriving the schemas
+// from the Go types and their json tags. Fields without omitempty are required.
+func OpenAPI(title, version string, endpoints []Endpoint) ([]byte, error) {
+	generator := schemaGenerator{components: map[string]any{}}
+	errorSchema := generator.schema(reflect.TypeOf(ErrorResponse{}))
+
+	paths := map[string]map[string]any{}
+	for _, endpoint := range endpoints {
+		operation := map[string]any{
+			"operationId": endpoint.OperationID,
+			"summary":     endpoint.Summary,
+			"responses": map[string]any{
+				statusKey(endpoint.Status): map[string]any{
+					"description": http.StatusText(max(endpoint.Status, http.StatusOK)),
+					"content":     map[string]any{MediaType: map[string]any{"schema": generator.schema(reflect.TypeOf(endpoint.Response))}},
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content":     map[string]any{MediaType: map[string]any{"schema": errorSchema}},
+				},
+			},
+		}
+
+		switch {
+		case endpoint.Form:
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{"multipart/form-data": map[string]any{"schema": map[string]any{
+					"type":                 "object",
+					"additionalProperties": map[string]any{"type": "string", "format": "binary"},
+				}}},
+			}
+		case endpoint.Request != nil:
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": generator.schema(reflect.TypeOf(endpoint.Request))}},
+			}
+		}
+
+		if paths[endpoint.Path] == nil {
+			paths[endpoint.Path] = map[string]any{}
+		}
+		paths[endpoint.Path][strings.ToLower(endpoint.Method)] = operation
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"openapi":    "3.0.3",
+		"info":       map[string]any{"title": title, "version": version},
+		"paths":      paths,
+		"components": map[string]any{"schemas": generator.components},
+	}, "", "  ")
+}
+
+type schemaGenerator struct {
+	components map[string]any
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	errorCodeType = reflect.TypeOf(ErrorCode(""))
+)
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == errorCodeType:
+		codes := []ErrorCode{CodeValidation, CodeInternal}
+		for _, mapping := range errorCodes {
+			if !slices.Contains(codes, mapping.code) {
+				codes = append(codes, mapping.code)
+			}
+		}
+		return map[string]any{"type": "string", "enum": codes}
+	case t.Kind() == reflect.Pointer:
+		return g.schema(t.Elem())
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.component(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+// component registers a named struct once and refers to it, which also keeps
+// recursive types finite.
+func (g *schemaGenerator) component(t reflect.Type) map[string]any {
+	ref := map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	if _, ok := g.components[t.Name()]; ok {
+		return ref
+	}
+	g.components[t.Name()] = nil
+
+	properties := map[string]any{}
+	required := []string{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	g.components[t.Name()] = schema
+
+	return ref
+}
+
+func statusKey(status int) string {
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return strconv.Itoa(status)
+}
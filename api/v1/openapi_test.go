@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	type node struct {
+		Name     string  `json:"name"`
+		Note     string  `json:"note,omitempty"`
+		Children []*node `json:"children"`
+		internal string
+	}
+	endpoints := []Endpoint{
+		{Method: http.MethodPost, Path: "/v1/nodes", OperationID: "createNode", Request: node{}, Response: node{}, Status: http.StatusCreated},
+		{Method: http.MethodPost, Path: "/v1/nodes/upload", OperationID: "uploadNode", Form: true, Response: UploadResponse{}},
+	}
+
+	content, err := OpenAPI("API", "1.0.0", endpoints)
+	if err != nil {
+		t.Fatalf("OpenAPI: %v", err)
+	}
+	var document struct {
+		Paths map[string]map[string]struct {
+			OperationID string         `json:"operationId"`
+			RequestBody map[string]any `json:"requestBody"`
+			Responses   map[string]any `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]any `json:"properties"`
+				Required   []string       `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(content, &document); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+
+	create := document.Paths["/v1/nodes"]["post"]
+	if create.OperationID != "createNode" || create.Responses["201"] == nil || create.Responses["default"] == nil {
+		t.Errorf("createNode = %+v, want a 201 and a default error response", create)
+	}
+	if _, ok := create.RequestBody["content"].(map[string]any)["application/json"]; !ok {
+		t.Errorf("createNode request body = %v, want JSON", create.RequestBody)
+	}
+	upload := document.Paths["/v1/nodes/upload"]["post"]
+	if _, ok := upload.RequestBody["content"].(map[string]any)["multipart/form-data"]; !ok || upload.Responses["200"] == nil {
+		t.Errorf("uploadNode = %+v, want a form body and a 200 response", upload)
+	}
+
+	schema := document.Components.Schemas["node"]
+	if len(schema.Properties) != 3 || !slices.Equal(schema.Required, []string{"name", "children"}) {
+		t.Errorf("node schema = %+v, want name, note and children with note optional", schema)
+	}
+	for _, name := range []string{"UploadResponse", "Video", "ErrorResponse", "Error", "FieldError"} {
+		if _, ok := document.Components.Schemas[name]; !ok {
+			t.Errorf("schema %s missing", name)
+		}
+	}
+}
+
+func TestOpenAPIEndpoints(t *testing.T) {
+	content, err := OpenAPI("file-uploader", "1", Endpoints)
+	if err != nil {
+		t.Fatalf("OpenAPI: %v", err)
+	}
+	var document struct {
+		Paths map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(content, &document); err != nil {
+		t.Fatalf("decode document: %v", err)
+	}
+	for _, endpoint := range Endpoints {
+		if document.Paths[endpoint.Path]["post"] == nil {
+			t.Errorf("%s %s missing from the document", endpoint.Method, endpoint.Path)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package appendlog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+const (
+	segmentPrefix = "seg-"
+	basePrefix    = "base-"
+	seqDigits     = 20
+
+	defaultMinCompactSegments = 16
+)
+
+type (
+	Options struct {
+		// MinCompactSegments is the number of segments Compact waits for before
+		// merging them; it defaults to 16.
+		MinCompactSegments int
+	}
+
+	// Log emulates an append-only object on top of a store without appends.
+	// Every Append writes an immutable, sequence-numbered segment under the
+	// prefix; Compact folds segments into a base object. A log must have a single
+	// writer: concurrent writers in other processes would reuse sequence numbers.
+	Log struct {
+		store  storage.Storage
+		prefix string
+		opts   Options
+
+		mu     sync.Mutex
+		next   uint64
+		loaded bool
+	}
+
+	// layout is a snapshot of the objects making up the log, in read order.
+	layout struct {
+		base     string
+		baseSeq  uint64
+		segments []segment
+		stale    []string
+	}
+
+	segment struct {
+		key string
+		seq uint64
+	}
+)
+
+func New(store storage.Storage, prefix string, opts Options) *Log {
+	if prefix != "" && !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+
+	if opts.MinCompactSegments <= 0 {
+		opts.MinCompactSegments = defaultMinCompactSegments
+	}
+
+	return &Log{store: store, prefix: prefix, opts: opts}
+}
+
+// Append writes data as the next segment and returns its sequence number.
+func (l *Log) Append(ctx context.Context, data []byte) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.loaded {
+		current, err := l.layout(ctx)
+		if err != nil {
+			return 0, err
+		}
+		l.next = current.lastSeq() + 1
+		l.loaded = true
+	}
+
+	seq := l.next
+	_, err := l.store.Put(ctx, l.key(segmentPrefix, seq), bytes.NewReader(data), storage.PutOptions{
+		ContentType: "application/octet-stream",
+		Size:        int64(len(data)),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to append segment %d: %w", seq, err)
+	}
+
+	l.next++
+	return seq, nil
+}
+
+// Reader streams the whole log, base object first and then every later segment
+// in order, as of the moment it is called.
+func (l *Log) Reader(ctx context.Context) (io.ReadCloser, error) {
+	current, err := l.layout(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reader{ctx: ctx, store: l.store, keys: current.keys()}, nil
+}
+
+// Compact merges the base object and all segments into a new base once at least
+// MinCompactSegments segments exist. The new base is written before anything is
+// deleted, and readers ignore segments it already covers, so an interrupted
+// compaction never loses or duplicates data.
+func (l *Log) Compact(ctx context.Context) error {
+	current, err := l.layout(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(current.segments) < l.opts.MinCompactSegments {
+		return l.remove(ctx, current.stale)
+	}
+
+	// Only the snapshot is merged; segments appended meanwhile stay segments and
+	// sort after the new base.
+	r := &reader{ctx: ctx, store: l.store, keys: current.keys()}
+	defer r.Close()
+
+	if _, err := l.store.Put(ctx, l.key(basePrefix, current.lastSeq()), r, storage.PutOptions{
+		ContentType: "application/octet-stream",
+		Size:        -1,
+	}); err != nil {
+		return fmt.Errorf("failed to write compacted log: %w", err)
+	}
+
+	obsolete := append(current.stale, current.keys()...)
+
+	log.Printf("compacted %d segments of log %s", len(current.segments), l.prefix)
+	return l.remove(ctx, obsolete)
+}
+
+func (l *Log) remove(ctx context.Context, keys []string) error {
+	var errs []error
+	for _, key := range keys {
+		if err := l.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (l *Log) key(kind string, seq uint64) string {
+	return fmt.Sprintf("%s%s%0*d", l.prefix, kind, seqDigits, seq)
+}
+
+func (l *Log) layout(ctx context.Context) (layout, error) {
+	current := layout{}
+	var bases []segment
+	err := l.store.List(ctx, l.prefix, func(info storage.ObjectInfo) error {
+		name := strings.TrimPrefix(info.Key, l.prefix)
+		for _, kind := range []string{basePrefix, segmentPrefix} {
+			digits, ok := strings.CutPrefix(name, kind)
+			if !ok || len(digits) != seqDigits {
+				continue
+			}
+
+			seq, err := strconv.ParseUint(digits, 10, 64)
+			if err != nil {
+				continue
+			}
+
+			if kind == basePrefix {
+				bases = append(bases, segment{key: info.Key, seq: seq})
+			} else {
+				current.segments = append(current.segments, segment{key: info.Key, seq: seq})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return layout{}, err
+	}
+
+	// Not every store lists in lexical order, so both are put in sequence order;
+	// the newest base is then the last one.
+	sort.Slice(bases, func(i, j int) bool { return bases[i].seq < bases[j].seq })
+	sort.Slice(current.segments, func(i, j int) bool { return current.segments[i].seq < current.segments[j].seq })
+	if len(bases) > 0 {
+		newest := bases[len(bases)-1]
+		current.base, current.baseSeq = newest.key, newest.seq
+		for _, base := range bases[:len(bases)-1] {
+			current.stale = append(current.stale, base.key)
+		}
+	}
+
+	segments := current.segments[:0]
+	for _, seg := range current.segments {
+		if current.base != "" && seg.seq <= current.baseSeq {
+			current.stale = append(current.stale, seg.key)
+			continue
+		}
+		segments = append(segments, seg)
+	}
+	current.segments = segments
+
+	return current, nil
+}
+
+func (c layout) keys() []string {
+	keys := make([]string, 0, len(c.segments)+1)
+	if c.base != "" {
+		keys = append(keys, c.base)
+	}
+	for _, seg := range c.segments {
+		keys = append(keys, seg.key)
+	}
+
+	return keys
+}
+
+func (c layout) lastSeq() uint64 {
+	if len(c.segments) > 0 {
+		return c.segments[len(c.segments)-1].seq
+	}
+
+	return c.baseSeq
+}
+
+// reader concatenates objects, opening each one only when the previous one is
+// exhausted.
+type reader struct {
+	ctx     context.Context
+	store   storage.Storage
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+
+			body, _, err := r.store.Get(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, fmt.Errorf("failed to read %s: %w", r.keys[0], err)
+			}
+			r.current, r.keys = body, r.keys[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+
+		return n, err
+	}
+}
+
+func (r *reader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+
+	return r.current.Close()
+}
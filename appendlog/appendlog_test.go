@@ -0,0 +1,85 @@
+package appendlog
+
+import (
+	"context"
+	"io"
+	"slices"
+	"testing"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/storage/memory"
+)
+
+// reversedStore lists keys in reverse lexical order.
+type reversedStore struct {
+	storage.Storage
+}
+
+func (s reversedStore) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	var infos []storage.ObjectInfo
+	if err := s.Storage.List(ctx, prefix, func(info storage.ObjectInfo) error {
+		infos = append(infos, info)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	slices.Reverse(infos)
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestLogOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   func() storage.Storage
+		compact bool
+	}{
+		{name: "lexical listing", store: memory.NewStorage},
+		{name: "reversed listing", store: func() storage.Storage { return reversedStore{memory.NewStorage()} }},
+		{name: "reversed listing after compaction", store: func() storage.Storage { return reversedStore{memory.NewStorage()} }, compact: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := tt.store()
+			l := New(store, "log", Options{MinCompactSegments: 2})
+			for _, data := range []string{"a", "b", "c"} {
+				if _, err := l.Append(ctx, []byte(data)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.compact {
+				if err := l.Compact(ctx); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := l.Append(ctx, []byte("d")); err != nil {
+				t.Fatal(err)
+			}
+
+			// A second log over the same store loads the sequence from the listing.
+			reopened := New(store, "log", Options{})
+			if seq, err := reopened.Append(ctx, []byte("e")); err != nil || seq != 5 {
+				t.Fatalf("Append = %d, %v, want 5", seq, err)
+			}
+
+			r, err := reopened.Reader(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "abcde" {
+				t.Errorf("log = %q, want %q", got, "abcde")
+			}
+		})
+	}
+}
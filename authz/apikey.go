@@ -0,0 +1,57 @@
+package authz
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"sync"
+)
+
+// APIKeyAuthenticator authenticates static API keys. Only SHA-256 digests of the
+// keys are kept in memory.
+type APIKeyAuthenticator struct {
+	mu   sync.RWMutex
+	keys map[string]Principal
+}
+
+func NewAPIKeyAuthenticator() *APIKeyAuthenticator {
+	return &APIKeyAuthenticator{keys: map[string]Principal{}}
+}
+
+func (a *APIKeyAuthenticator) Add(apiKey string, principal Principal) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.keys[digest(apiKey)] = principal
+}
+
+func (a *APIKeyAuthenticator) Revoke(apiKey string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.keys, digest(apiKey))
+}
+
+func (a *APIKeyAuthenticator) Authenticate(_ context.Context, credential string) (Principal, error) {
+	if credential == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	sum := digest(credential)
+	for known, principal := range a.keys {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(sum)) == 1 {
+			return principal, nil
+		}
+	}
+
+	return Principal{}, ErrUnauthenticated
+}
+
+func digest(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,101 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+type Permission string
+
+const (
+	PermissionUpload   Permission = "upload"
+	PermissionDownload Permission = "download"
+	PermissionDelete   Permission = "delete"
+)
+
+type (
+	// Principal is the authenticated caller and what it is allowed to do. Empty
+	// Buckets or Prefixes mean no restriction; a zero MaxSize means unlimited.
+	Principal struct {
+		ID          string
+		Permissions []Permission
+		Buckets     []string
+		Prefixes    []string
+		MaxSize     int64
+	}
+
+	Action struct {
+		Permission Permission
+		BucketName string
+		Filename   string
+		Size       int64
+	}
+
+	// Authenticator resolves the credential presented by a caller (an API key or a
+	// bearer token) into a Principal.
+	Authenticator interface {
+		Authenticate(ctx context.Context, credential string) (Principal, error)
+	}
+)
+
+func (p Principal) Authorize(action Action) error {
+	if !slices.Contains(p.Permissions, action.Permission) {
+		return fmt.Errorf("%w: %s may not %s", ErrForbidden, p.ID, action.Permission)
+	}
+
+	if len(p.Buckets) > 0 && !slices.Contains(p.Buckets, action.BucketName) {
+		return fmt.Errorf("%w: %s may not access bucket %s", ErrForbidden, p.ID, action.BucketName)
+	}
+
+	if len(p.Prefixes) > 0 && !slices.ContainsFunc(p.Prefixes, func(prefix string) bool {
+		return underPrefix(action.Filename, prefix)
+	}) {
+		return fmt.Errorf("%w: %s may not access %s", ErrForbidden, p.ID, action.Filename)
+	}
+
+	if p.MaxSize > 0 && action.Size > p.MaxSize {
+		return fmt.Errorf("%w: %s may not upload more than %d bytes", ErrForbidden, p.ID, p.MaxSize)
+	}
+
+	return nil
+}
+
+// underPrefix matches prefixes on "/" boundaries, so a grant on "a/b" covers
+// "a/b" and "a/b/c" but not "a/bc". A prefix ending in "/" covers only keys below it.
+func underPrefix(key, prefix string) bool {
+	if prefix == "" || strings.HasSuffix(prefix, "/") {
+		return strings.HasPrefix(key, prefix)
+	}
+
+	return key == prefix || strings.HasPrefix(key, prefix+"/")
+}
+
+type principalKey struct{}
+
+func NewContext(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// Authorize checks action against the principal stored in ctx, so handlers and
+// interceptors share one code path regardless of transport.
+func Authorize(ctx context.Context, action Action) error {
+	principal, ok := FromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+
+	return principal.Authorize(action)
+}
@@ -0,0 +1,170 @@
+package authz
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPrincipalAuthorize(t *testing.T) {
+	all := []Permission{PermissionUpload, PermissionDownload, PermissionDelete}
+	tests := []struct {
+		name      string
+		principal Principal
+		action    Action
+		wantErr   error
+	}{
+		{
+			name:      "unrestricted",
+			principal: Principal{ID: "p", Permissions: all},
+			action:    Action{Permission: PermissionDelete, BucketName: "bucket", Filename: "a.txt"},
+		},
+		{
+			name:      "missing permission",
+			principal: Principal{ID: "p", Permissions: []Permission{PermissionDownload}},
+			action:    Action{Permission: PermissionUpload, BucketName: "bucket", Filename: "a.txt"},
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "other bucket",
+			principal: Principal{ID: "p", Permissions: all, Buckets: []string{"bucket"}},
+			action:    Action{Permission: PermissionUpload, BucketName: "other", Filename: "a.txt"},
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "prefix itself",
+			principal: Principal{ID: "p", Permissions: all, Prefixes: []string{"a/b"}},
+			action:    Action{Permission: PermissionUpload, BucketName: "bucket", Filename: "a/b"},
+		},
+		{
+			name:      "below prefix",
+			principal: Principal{ID: "p", Permissions: all, Prefixes: []string{"a/b"}},
+			action:    Action{Permission: PermissionUpload, BucketName: "bucket", Filename: "a/b/c.txt"},
+		},
+		{
+			name:      "sibling sharing the prefix",
+			principal: Principal{ID: "p", Permissions: all, Prefixes: []string{"a/b"}},
+			action:    Action{Permission: PermissionUpload, BucketName: "bucket", Filename: "a/bc"},
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "below prefix with trailing slash",
+			principal: Principal{ID: "p", Permissions: all, Prefixes: []string{"a/b/"}},
+			action:    Action{Permission: PermissionUpload, BucketName: "bucket", Filename: "a/b/c.txt"},
+		},
+		{
+			name:      "prefix with trailing slash does not cover its parent",
+			principal: Principal{ID: "p", Permissions: all, Prefixes: []string{"a/b/"}},
+			action:    Action{Permission: PermissionUpload, BucketName: "bucket", Filename: "a/b"},
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "within max size",
+			principal: Principal{ID: "p", Permissions: all, MaxSize: 10},
+			action:    Action{Permission: PermissionUpload, BucketName: "bucket", Filename: "a.txt", Size: 10},
+		},
+		{
+			name:      "over max size",
+			principal: Principal{ID: "p", Permissions: all, MaxSize: 10},
+			action:    Action{Permission: PermissionUpload, BucketName: "bucket", Filename: "a.txt", Size: 11},
+			wantErr:   ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.principal.Authorize(tt.action)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Authorize() = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAuthorizeFromContext(t *testing.T) {
+	action := Action{Permission: PermissionDownload, BucketName: "bucket", Filename: "a.txt"}
+
+	if err := Authorize(context.Background(), action); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Authorize without principal = %v, want %v", err, ErrUnauthenticated)
+	}
+
+	ctx := NewContext(context.Background(), Principal{ID: "p", Permissions: []Permission{PermissionDownload}})
+	if err := Authorize(ctx, action); err != nil {
+		t.Errorf("Authorize with principal = %v", err)
+	}
+}
+
+func TestAPIKeyAuthenticator(t *testing.T) {
+	keys := NewAPIKeyAuthenticator()
+	keys.Add("secret", Principal{ID: "p"})
+
+	principal, err := keys.Authenticate(context.Background(), "secret")
+	if err != nil || principal.ID != "p" {
+		t.Fatalf("Authenticate = %+v, %v", principal, err)
+	}
+
+	for _, credential := range []string{"", "other"} {
+		if _, err := keys.Authenticate(context.Background(), credential); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("Authenticate(%q) = %v, want %v", credential, err, ErrUnauthenticated)
+		}
+	}
+
+	keys.Revoke("secret")
+	if _, err := keys.Authenticate(context.Background(), "secret"); !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("Authenticate after Revoke = %v, want %v", err, ErrUnauthenticated)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	keys := NewAPIKeyAuthenticator()
+	keys.Add("secret", Principal{ID: "p"})
+
+	handler := Middleware(keys, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := FromContext(r.Context())
+		if !ok {
+			t.Error("principal missing from request context")
+		}
+		w.Write([]byte(principal.ID))
+	}))
+
+	tests := []struct {
+		name       string
+		header     http.Header
+		wantStatus int
+	}{
+		{name: "api key", header: http.Header{"X-Api-Key": {"secret"}}, wantStatus: http.StatusOK},
+		{name: "bearer token", header: http.Header{"Authorization": {"Bearer secret"}}, wantStatus: http.StatusOK},
+		{name: "lowercase metadata", header: http.Header{"x-api-key": {"secret"}}, wantStatus: http.StatusOK},
+		{name: "wrong key", header: http.Header{"X-Api-Key": {"other"}}, wantStatus: http.StatusUnauthorized},
+		{name: "no credential", header: http.Header{}, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header = tt.header
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{err: ErrUnauthenticated, want: http.StatusUnauthorized},
+		{err: ErrForbidden, want: http.StatusForbidden},
+		{err: errors.New("boom"), want: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		if got := StatusCode(tt.err); got != tt.want {
+			t.Errorf("StatusCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package authz
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+)
+
+const apiKeyHeader = "X-API-Key"
+
+// Middleware authenticates the request with the API key header or bearer token and
+// stores the principal in the request context for Authorize.
+func Middleware(authenticator Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := authenticator.Authenticate(r.Context(), Credential(r.Header))
+		if err != nil {
+			log.Printf("failed to authenticate request %s %s: %v", r.Method, r.URL.Path, err)
+			http.Error(w, ErrUnauthenticated.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), principal)))
+	})
+}
+
+// Credential extracts the API key or bearer token from request headers or gRPC
+// metadata (which uses the same lowercase keys).
+func Credential(header map[string][]string) string {
+	if apiKey := headerValue(header, apiKeyHeader); apiKey != "" {
+		return apiKey
+	}
+
+	if token, ok := strings.CutPrefix(headerValue(header, "Authorization"), "Bearer "); ok {
+		return token
+	}
+
+	return ""
+}
+
+func headerValue(header map[string][]string, name string) string {
+	for key, values := range header {
+		if len(values) > 0 && strings.EqualFold(key, name) {
+			return values[0]
+		}
+	}
+
+	return ""
+}
+
+// StatusCode maps authorization errors onto HTTP status codes.
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,85 @@
+package authz
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/KurniawanHendiW/file-uploader/signing"
+)
+
+type Claims struct {
+	jwt.RegisteredClaims
+	Permissions []Permission `json:"perms,omitempty"`
+	Buckets     []string     `json:"buckets,omitempty"`
+	Prefixes    []string     `json:"prefixes,omitempty"`
+	MaxSize     int64        `json:"max_size,omitempty"`
+}
+
+type JWTAuthenticator struct {
+	keyFunc jwt.Keyfunc
+	options []jwt.ParserOption
+}
+
+// NewJWTAuthenticator validates bearer tokens with keyFunc. Pass parser options
+// such as jwt.WithIssuer, jwt.WithAudience or jwt.WithValidMethods to pin them.
+func NewJWTAuthenticator(keyFunc jwt.Keyfunc, options ...jwt.ParserOption) *JWTAuthenticator {
+	return &JWTAuthenticator{
+		keyFunc: keyFunc,
+		options: append([]jwt.ParserOption{jwt.WithExpirationRequired()}, options...),
+	}
+}
+
+func (a *JWTAuthenticator) Authenticate(_ context.Context, credential string) (Principal, error) {
+	if credential == "" {
+		return Principal{}, ErrUnauthenticated
+	}
+
+	claims := &Claims{}
+	if _, err := jwt.ParseWithClaims(credential, claims, a.keyFunc, a.options...); err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	if claims.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+
+	return Principal{
+		ID:          claims.Subject,
+		Permissions: claims.Permissions,
+		Buckets:     claims.Buckets,
+		Prefixes:    claims.Prefixes,
+		MaxSize:     claims.MaxSize,
+	}, nil
+}
+
+// KeyringKeyFunc resolves the "kid" header of a token against keys, so tokens
+// signed with HS256 or EdDSA keep validating across key rotations.
+func KeyringKeyFunc(keys *signing.Keyring) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		keyID, _ := token.Header["kid"].(string)
+		key, ok := keys.Key(keyID)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", signing.ErrUnknownKey, keyID)
+		}
+
+		switch key.Algorithm {
+		case signing.HMACSHA256, "":
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("key %s does not sign with %s", keyID, token.Method.Alg())
+			}
+			return key.Secret, nil
+		case signing.Ed25519:
+			if _, ok := token.Method.(*jwt.SigningMethodEd25519); !ok {
+				return nil, fmt.Errorf("key %s does not sign with %s", keyID, token.Method.Alg())
+			}
+			if key.PublicKey != nil {
+				return key.PublicKey, nil
+			}
+			return key.PrivateKey.Public(), nil
+		default:
+			return nil, fmt.Errorf("unsupported algorithm %q", key.Algorithm)
+		}
+	}
+}
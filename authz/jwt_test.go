@@ -0,0 +1,81 @@
+package authz
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/KurniawanHendiW/file-uploader/signing"
+)
+
+func TestJWTAuthenticator(t *testing.T) {
+	hmacKey := signing.Key{ID: "hmac", Secret: bytes.Repeat([]byte("k"), 32)}
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edKey := signing.Key{ID: "ed", Algorithm: signing.Ed25519, PrivateKey: private}
+
+	keys, err := signing.NewKeyring(hmacKey, edKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	authenticator := NewJWTAuthenticator(KeyringKeyFunc(keys))
+
+	claims := func(subject string, expires time.Duration) Claims {
+		return Claims{
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   subject,
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(expires)),
+			},
+			Permissions: []Permission{PermissionUpload},
+			Prefixes:    []string{"a/"},
+		}
+	}
+	sign := func(method jwt.SigningMethod, keyID string, claims Claims, key any) string {
+		token := jwt.NewWithClaims(method, claims)
+		token.Header["kid"] = keyID
+		signed, err := token.SignedString(key)
+		if err != nil {
+			t.Fatalf("SignedString: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "hmac", token: sign(jwt.SigningMethodHS256, "hmac", claims("p", time.Hour), hmacKey.Secret)},
+		{name: "ed25519", token: sign(jwt.SigningMethodEdDSA, "ed", claims("p", time.Hour), private)},
+		{name: "expired", token: sign(jwt.SigningMethodHS256, "hmac", claims("p", -time.Hour), hmacKey.Secret), wantErr: true},
+		{name: "unknown key", token: sign(jwt.SigningMethodHS256, "other", claims("p", time.Hour), hmacKey.Secret), wantErr: true},
+		{name: "wrong secret", token: sign(jwt.SigningMethodHS256, "hmac", claims("p", time.Hour), bytes.Repeat([]byte("x"), 32)), wantErr: true},
+		{name: "algorithm mismatch", token: sign(jwt.SigningMethodEdDSA, "hmac", claims("p", time.Hour), private), wantErr: true},
+		{name: "no subject", token: sign(jwt.SigningMethodHS256, "hmac", claims("", time.Hour), hmacKey.Secret), wantErr: true},
+		{name: "empty", token: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(context.Background(), tt.token)
+			if tt.wantErr {
+				if !errors.Is(err, ErrUnauthenticated) {
+					t.Errorf("Authenticate = %v, want %v", err, ErrUnauthenticated)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if principal.ID != "p" || len(principal.Prefixes) != 1 || principal.Prefixes[0] != "a/" {
+				t.Errorf("principal = %+v", principal)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+// Package bench runs reproducible upload and transfer workloads against an
+// S3-compatible server, usually MinIO or LocalStack started by testharness, and
+// reports throughput and allocations so that changes such as part-size
+// defaults or buffer pooling can be compared against a baseline.
+package bench
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/s3"
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/testharness"
+	"github.com/KurniawanHendiW/file-uploader/transfer"
+)
+
+const (
+	kib = 1024
+	mib = 1024 * kib
+
+	defaultBucket = "bench"
+)
+
+type (
+	// Env is what a scenario runs against.
+	Env struct {
+		Service    s3.S3Service
+		Store      storage.Storage
+		BucketName string
+	}
+
+	// Scenario is one workload. Run returns the bytes and operations it moved;
+	// iteration distinguishes key names between repeated runs.
+	Scenario struct {
+		Name string
+		Run  func(ctx context.Context, env Env, iteration int) (bytes, ops int64, err error)
+	}
+
+	Options struct {
+		// Iterations defaults to 3; the first run is a warm-up and is not reported
+		// unless it is the only one.
+		Iterations int
+	}
+
+	Result struct {
+		Name        string        `json:"name"`
+		Iterations  int           `json:"iterations"`
+		Duration    time.Duration `json:"duration"`
+		Bytes       int64         `json:"bytes"`
+		Ops         int64         `json:"ops"`
+		MBPerSecond float64       `json:"mbPerSecond"`
+		OpsPerSec   float64       `json:"opsPerSecond"`
+		AllocsPerOp float64       `json:"allocsPerOp"`
+		BytesPerOp  float64       `json:"allocatedBytesPerOp"`
+	}
+
+	Report struct {
+		GeneratedAt time.Time `json:"generatedAt"`
+		GoVersion   string    `json:"goVersion"`
+		Results     []Result  `json:"results"`
+	}
+
+	// Regression is a result that got slower or allocates more than the
+	// baseline by more than the tolerance.
+	Regression struct {
+		Name     string
+		Metric   string
+		Baseline float64
+		Current  float64
+	}
+)
+
+// Start runs a disposable server with testharness and returns an environment
+// for it. opts are applied to the service, e.g. s3.WithBufferPool, so variants
+// can be compared. The returned function terminates the server.
+func Start(ctx context.Context, backend testharness.Backend, opts ...s3.Option) (Env, func(context.Context) error, error) {
+	h, err := testharness.Start(ctx, testharness.Options{Backend: backend, Buckets: []string{defaultBucket}})
+	if err != nil {
+		return Env{}, nil, err
+	}
+
+	svc := h.Service(opts...)
+	store, err := s3.NewStorage(svc, defaultBucket)
+	if err != nil {
+		return Env{}, nil, errors.Join(err, h.Terminate(ctx))
+	}
+
+	return Env{Service: svc, Store: store, BucketName: defaultBucket}, h.Terminate, nil
+}
+
+// SmallFiles uploads count files of size bytes with concurrency uploads in
+// flight.
+func SmallFiles(count int, size int64, concurrency int) Scenario {
+	return Scenario{
+		Name: fmt.Sprintf("small-files/%dx%s/c%d", count, formatSize(size), concurrency),
+		Run: func(ctx context.Context, env Env, iteration int) (int64, int64, error) {
+			var errs atomic.Pointer[error]
+			sem := make(chan struct{}, max(concurrency, 1))
+			var wg sync.WaitGroup
+			for i := range count {
+				sem <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer func() {
+						<-sem
+						wg.Done()
+					}()
+
+					_, err := env.Service.UploadFile(s3.UploadFileRequest{
+						BucketName:  env.BucketName,
+						Filename:    fmt.Sprintf("small/%d/%06d", iteration, i),
+						ContentType: "application/octet-stream",
+						Body:        payload(uint64(i), size),
+					})
+					if err != nil {
+						errs.CompareAndSwap(nil, &err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if err := errs.Load(); err != nil {
+				return 0, 0, *err
+			}
+			return int64(count) * size, int64(count), nil
+		},
+	}
+}
+
+// LargeFile uploads one file of size bytes from a stream, so the uploader
+// cannot seek and has to buffer parts.
+func LargeFile(size int64) Scenario {
+	return Scenario{
+		Name: "large-file/" + formatSize(size),
+		Run: func(ctx context.Context, env Env, iteration int) (int64, int64, error) {
+			_, err := env.Service.UploadFile(s3.UploadFileRequest{
+				BucketName:  env.BucketName,
+				Filename:    fmt.Sprintf("large/%d", iteration),
+				ContentType: "application/octet-stream",
+				Body:        payload(uint64(iteration), size),
+			})
+			if err != nil {
+				return 0, 0, err
+			}
+			return size, 1, nil
+		},
+	}
+}
+
+// MixedSync seeds a prefix with files of the given sizes, once, and measures
+// copying it to a fresh prefix with transfer.Copy.
+func MixedSync(sizes []int64, concurrency int) Scenario {
+	var seeded sync.Once
+	var seedErr error
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+
+	return Scenario{
+		Name: fmt.Sprintf("mixed-sync/%dfiles/%s/c%d", len(sizes), formatSize(total), concurrency),
+		Run: func(ctx context.Context, env Env, iteration int) (int64, int64, error) {
+			seeded.Do(func() {
+				for i, size := range sizes {
+					key := fmt.Sprintf("mixed/src/%06d", i)
+					if _, err := env.Store.Put(ctx, key, payload(uint64(i), size), storage.PutOptions{Size: size}); err != nil {
+						seedErr = err
+						return
+					}
+				}
+			})
+			if seedErr != nil {
+				return 0, 0, fmt.Errorf("failed to seed mixed workload: %w", seedErr)
+			}
+
+			result, err := transfer.Copy(ctx, env.Store, env.Store, transfer.Options{
+				Prefix:            "mixed/src/",
+				DestinationPrefix: fmt.Sprintf("mixed/dst/%d/", iteration),
+				Concurrency:       concurrency,
+			})
+			return result.Bytes, result.Transferred, err
+		},
+	}
+}
+
+// DefaultScenarios covers small-file fan-out, a single large upload and a mixed
+// sync.
+func DefaultScenarios() []Scenario {
+	mixed := make([]int64, 0, 64)
+	for i := range 64 {
+		mixed = append(mixed, []int64{4 * kib, 256 * kib, 2 * mib, 24 * mib}[i%4])
+	}
+
+	return []Scenario{
+		SmallFiles(500, 16*kib, 32),
+		LargeFile(512 * mib),
+		MixedSync(mixed, 8),
+	}
+}
+
+// Run runs every scenario opts.Iterations times. Allocation figures cover the
+// whole process, so nothing else should run alongside.
+func Run(ctx context.Context, env Env, scenarios []Scenario, opts Options) (Report, error) {
+	iterations := opts.Iterations
+	if iterations <= 0 {
+		iterations = 3
+	}
+
+	report := Report{GeneratedAt: time.Now().UTC(), GoVersion: runtime.Version()}
+	for _, scenario := range scenarios {
+		result := Result{Name: scenario.Name}
+		for iteration := range iterations {
+			runtime.GC()
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+
+			started := time.Now()
+			bytes, ops, err := scenario.Run(ctx, env, iteration)
+			elapsed := time.Since(started)
+			if err != nil {
+				return report, fmt.Errorf("scenario %s failed: %w", scenario.Name, err)
+			}
+
+			runtime.ReadMemStats(&after)
+			if iteration == 0 && iterations > 1 {
+				continue
+			}
+
+			result.Iterations++
+			result.Duration += elapsed
+			result.Bytes += bytes
+			result.Ops += ops
+			result.AllocsPerOp += float64(after.Mallocs - before.Mallocs)
+			result.BytesPerOp += float64(after.TotalAlloc - before.TotalAlloc)
+		}
+
+		if seconds := result.Duration.Seconds(); seconds > 0 {
+			result.MBPerSecond = float64(result.Bytes) / mib / seconds
+			result.OpsPerSec = float64(result.Ops) / seconds
+		}
+		if result.Ops > 0 {
+			result.AllocsPerOp /= float64(result.Ops)
+			result.BytesPerOp /= float64(result.Ops)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	return report, nil
+}
+
+// Compare reports results of current that are slower than baseline, or
+// allocate more per operation, by more than tolerance (0.1 for 10%).
+func Compare(baseline, current Report, tolerance float64) []Regression {
+	previous := map[string]Result{}
+	for _, result := range baseline.Results {
+		previous[result.Name] = result
+	}
+
+	regressions := []Regression{}
+	for _, result := range current.Results {
+		base, ok := previous[result.Name]
+		if !ok {
+			continue
+		}
+
+		if base.MBPerSecond > 0 && result.MBPerSecond < base.MBPerSecond*(1-tolerance) {
+			regressions = append(regressions, Regression{result.Name, "mbPerSecond", base.MBPerSecond, result.MBPerSecond})
+		}
+		if base.AllocsPerOp > 0 && result.AllocsPerOp > base.AllocsPerOp*(1+tolerance) {
+			regressions = append(regressions, Regression{result.Name, "allocsPerOp", base.AllocsPerOp, result.AllocsPerOp})
+		}
+		if base.BytesPerOp > 0 && result.BytesPerOp > base.BytesPerOp*(1+tolerance) {
+			regressions = append(regressions, Regression{result.Name, "allocatedBytesPerOp", base.BytesPerOp, result.BytesPerOp})
+		}
+	}
+
+	return regressions
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+func ReadReport(r io.Reader) (Report, error) {
+	var report Report
+	err := json.NewDecoder(r).Decode(&report)
+	return report, err
+}
+
+func (r Report) WriteText(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "scenario\tMB/s\tops/s\tallocs/op\tB/op\t")
+	for _, result := range r.Results {
+		fmt.Fprintf(table, "%s\t%.1f\t%.1f\t%.0f\t%.0f\t\n",
+			result.Name, result.MBPerSecond, result.OpsPerSec, result.AllocsPerOp, result.BytesPerOp)
+	}
+	return table.Flush()
+}
+
+// payload streams size pseudo-random bytes that depend only on seed, so every
+// run uploads the same content without holding it in memory.
+func payload(seed uint64, size int64) io.Reader {
+	var key [32]byte
+	for i := range 8 {
+		key[i] = byte(seed >> (8 * i))
+	}
+	return io.LimitReader(rand.NewChaCha8(key), size)
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size >= mib && size%mib == 0:
+		return fmt.Sprintf("%dMiB", size/mib)
+	case size >= kib && size%kib == 0:
+		return fmt.Sprintf("%dKiB", size/kib)
+	default:
+		return fmt.Sprintf("%dB", size)
+	}
+}
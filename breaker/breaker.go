@@ -0,0 +1,182 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+type State int
+
+const (
+	Closed State = iota
+	Open
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	default:
+		return "half-open"
+	}
+}
+
+const (
+	defaultFailureThreshold = 5
+	defaultOpenDuration     = 30 * time.Second
+	defaultHalfOpenProbes   = 1
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type Settings struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit. Defaults to 5.
+	FailureThreshold int
+	// OpenDuration is how long calls fail fast before probing. Defaults to 30s.
+	OpenDuration time.Duration
+	// HalfOpenProbes is how many trial calls may run while half-open; that
+	// many successes close the circuit again. Defaults to 1.
+	HalfOpenProbes int
+	// IsFailure decides which errors count against the service. By default
+	// every error except context cancellation counts.
+	IsFailure func(err error) bool
+	// OnStateChange is called on every transition, e.g. to export a metric.
+	OnStateChange func(from, to State)
+}
+
+type Breaker struct {
+	settings Settings
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	failures   int
+	probes     int
+	successes  int
+	openedAt   time.Time
+}
+
+func New(settings Settings) *Breaker {
+	if settings.FailureThreshold <= 0 {
+		settings.FailureThreshold = defaultFailureThreshold
+	}
+
+	if settings.OpenDuration <= 0 {
+		settings.OpenDuration = defaultOpenDuration
+	}
+
+	if settings.HalfOpenProbes <= 0 {
+		settings.HalfOpenProbes = defaultHalfOpenProbes
+	}
+
+	if settings.IsFailure == nil {
+		settings.IsFailure = func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		}
+	}
+
+	return &Breaker{settings: settings}
+}
+
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	return b.state
+}
+
+// Do runs fn unless the circuit is open, in which case it returns ErrOpen
+// without calling fn.
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	done(err)
+	return err
+}
+
+// Allow reserves a call. The caller must report the call's outcome to done.
+func (b *Breaker) Allow() (done func(err error), err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refresh()
+	switch b.state {
+	case Open:
+		return nil, ErrOpen
+	case HalfOpen:
+		if b.probes >= b.settings.HalfOpenProbes {
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+
+	generation := b.generation
+	return func(err error) { b.record(generation, err) }, nil
+}
+
+func (b *Breaker) record(generation uint64, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Outcomes of calls started before the last transition no longer apply.
+	if generation != b.generation {
+		return
+	}
+
+	failed := err != nil && b.settings.IsFailure(err)
+	switch b.state {
+	case Closed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+
+		b.failures++
+		if b.failures >= b.settings.FailureThreshold {
+			b.transition(Open)
+		}
+	case HalfOpen:
+		if failed {
+			b.transition(Open)
+			return
+		}
+
+		b.successes++
+		if b.successes >= b.settings.HalfOpenProbes {
+			b.transition(Closed)
+		}
+	}
+}
+
+// refresh moves an open circuit to half-open once OpenDuration has passed.
+func (b *Breaker) refresh() {
+	if b.state == Open && time.Since(b.openedAt) >= b.settings.OpenDuration {
+		b.transition(HalfOpen)
+	}
+}
+
+func (b *Breaker) transition(to State) {
+	from := b.state
+	b.state = to
+	b.generation++
+	b.failures, b.probes, b.successes = 0, 0, 0
+
+	if to == Open {
+		b.openedAt = time.Now()
+	}
+
+	if b.settings.OnStateChange != nil {
+		b.settings.OnStateChange(from, to)
+	}
+}
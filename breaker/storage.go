@@ -0,0 +1,153 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+type breakerStorage struct {
+	next    storage.Storage
+	breaker *Breaker
+}
+
+// Storage guards every call to next with b. Missing objects are an expected
+// outcome and never count as failures. The result implements
+// storage.RangeReader and storage.Presigner when next does.
+func Storage(next storage.Storage, b *Breaker) storage.Storage {
+	s := &breakerStorage{next: next, breaker: b}
+
+	_, ranged := next.(storage.RangeReader)
+	_, presigned := next.(storage.Presigner)
+	switch {
+	case ranged && presigned:
+		return rangePresignStorage{s}
+	case ranged:
+		return rangeStorage{s}
+	case presigned:
+		return presignStorage{s}
+	default:
+		return s
+	}
+}
+
+type (
+	rangeStorage        struct{ *breakerStorage }
+	presignStorage      struct{ *breakerStorage }
+	rangePresignStorage struct{ *breakerStorage }
+)
+
+func (s rangeStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return s.getRange(ctx, key, offset, length)
+}
+
+func (s presignStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.presignGet(ctx, key, expires)
+}
+
+func (s rangePresignStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return s.getRange(ctx, key, offset, length)
+}
+
+func (s rangePresignStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.presignGet(ctx, key, expires)
+}
+
+func (s *breakerStorage) do(fn func() error) error {
+	return s.breaker.Do(func() error {
+		if err := fn(); err != nil && !errors.Is(err, storage.ErrNotExist) {
+			return err
+		}
+		return nil
+	})
+}
+
+func (s *breakerStorage) Stat(ctx context.Context, key string) (info storage.ObjectInfo, err error) {
+	if breakerErr := s.do(func() error {
+		info, err = s.next.Stat(ctx, key)
+		return err
+	}); breakerErr != nil {
+		return storage.ObjectInfo{}, breakerErr
+	}
+
+	return info, err
+}
+
+func (s *breakerStorage) Get(ctx context.Context, key string) (body io.ReadCloser, info storage.ObjectInfo, err error) {
+	if breakerErr := s.do(func() error {
+		body, info, err = s.next.Get(ctx, key)
+		return err
+	}); breakerErr != nil {
+		return nil, storage.ObjectInfo{}, breakerErr
+	}
+
+	return body, info, err
+}
+
+func (s *breakerStorage) getRange(ctx context.Context, key string, offset, length int64) (body io.ReadCloser, err error) {
+	if breakerErr := s.do(func() error {
+		body, err = s.next.(storage.RangeReader).GetRange(ctx, key, offset, length)
+		return err
+	}); breakerErr != nil {
+		return nil, breakerErr
+	}
+
+	return body, err
+}
+
+// presignGet only signs locally, but an open circuit means the URL would not
+// work either.
+func (s *breakerStorage) presignGet(ctx context.Context, key string, expires time.Duration) (url string, err error) {
+	if breakerErr := s.do(func() error {
+		url, err = s.next.(storage.Presigner).PresignGet(ctx, key, expires)
+		return err
+	}); breakerErr != nil {
+		return "", breakerErr
+	}
+
+	return url, err
+}
+
+func (s *breakerStorage) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (info storage.ObjectInfo, err error) {
+	if breakerErr := s.do(func() error {
+		info, err = s.next.Put(ctx, key, r, opts)
+		return err
+	}); breakerErr != nil {
+		return storage.ObjectInfo{}, breakerErr
+	}
+
+	return info, err
+}
+
+func (s *breakerStorage) Delete(ctx context.Context, key string) (err error) {
+	if breakerErr := s.do(func() error {
+		err = s.next.Delete(ctx, key)
+		return err
+	}); breakerErr != nil {
+		return breakerErr
+	}
+
+	return err
+}
+
+func (s *breakerStorage) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) (err error) {
+	if breakerErr := s.do(func() error {
+		var fnErr error
+		err = s.next.List(ctx, prefix, func(info storage.ObjectInfo) error {
+			fnErr = fn(info)
+			return fnErr
+		})
+		// An error from the caller's callback says nothing about the backend.
+		if fnErr != nil && errors.Is(err, fnErr) {
+			return nil
+		}
+		return err
+	}); breakerErr != nil {
+		return breakerErr
+	}
+
+	return err
+}
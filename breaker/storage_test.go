@@ -0,0 +1,110 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/storage/memory"
+)
+
+type plainStore struct {
+	storage.Storage
+}
+
+type presigningStore struct {
+	storage.Storage
+}
+
+func (presigningStore) PresignGet(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://example.com/" + key, nil
+}
+
+type rangePresigningStore struct {
+	presigningStore
+	storage.RangeReader
+}
+
+var errUnavailable = errors.New("unavailable")
+
+// failingRanges fails every ranged read.
+type failingRanges struct {
+	storage.Storage
+}
+
+func (failingRanges) GetRange(context.Context, string, int64, int64) (io.ReadCloser, error) {
+	return nil, errUnavailable
+}
+
+func TestStorageForwardsOptionalInterfaces(t *testing.T) {
+	next := memory.NewStorage()
+	tests := []struct {
+		name          string
+		next          storage.Storage
+		wantRange     bool
+		wantPresigner bool
+	}{
+		{name: "plain", next: plainStore{next}},
+		{name: "ranged", next: next, wantRange: true},
+		{name: "presigner", next: presigningStore{plainStore{next}}, wantPresigner: true},
+		{name: "both", next: rangePresigningStore{presigningStore{next}, next.(storage.RangeReader)}, wantRange: true, wantPresigner: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := Storage(tt.next, New(Settings{}))
+			if _, ok := store.(storage.RangeReader); ok != tt.wantRange {
+				t.Errorf("RangeReader = %v, want %v", ok, tt.wantRange)
+			}
+			if _, ok := store.(storage.Presigner); ok != tt.wantPresigner {
+				t.Errorf("Presigner = %v, want %v", ok, tt.wantPresigner)
+			}
+		})
+	}
+}
+
+func TestStorageGuardsRangedReads(t *testing.T) {
+	next := memory.NewStorage()
+	if _, err := next.Put(context.Background(), "f", strings.NewReader("0123456789"), storage.PutOptions{Size: -1}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		next    storage.Storage
+		calls   int
+		want    string
+		wantErr error
+	}{
+		{name: "read", next: next, calls: 1, want: "2345"},
+		{name: "opens after failures", next: failingRanges{next}, calls: 3, wantErr: ErrOpen},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := Storage(tt.next, New(Settings{FailureThreshold: 2})).(storage.RangeReader)
+
+			var body io.ReadCloser
+			var err error
+			for range tt.calls {
+				body, err = store.GetRange(context.Background(), "f", 2, 4)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("GetRange error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer body.Close()
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GetRange = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
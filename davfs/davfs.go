@@ -0,0 +1,388 @@
+package davfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/webdav"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+// keepFile marks directories created through MKCOL, since object stores have no
+// empty directories. It is hidden from listings.
+const keepFile = ".keep"
+
+type fileSystem struct {
+	store    storage.Storage
+	readOnly bool
+}
+
+// NewHandler serves store over WebDAV so desktop clients can mount it. In
+// read-only mode every modifying method fails with 403.
+func NewHandler(store storage.Storage, prefix string, readOnly bool) http.Handler {
+	handler := &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: NewFileSystem(store, readOnly),
+		LockSystem: webdav.NewMemLS(),
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// webdav closes the file of a PUT whose body broke off like any other,
+		// so the file needs the declared length to tell the two apart.
+		if r.Method == http.MethodPut && r.ContentLength >= 0 {
+			r = r.WithContext(context.WithValue(r.Context(), contentLengthKey{}, r.ContentLength))
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+type contentLengthKey struct{}
+
+func NewFileSystem(store storage.Storage, readOnly bool) webdav.FileSystem {
+	return &fileSystem{store: store, readOnly: readOnly}
+}
+
+func objectKey(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func (f *fileSystem) Mkdir(ctx context.Context, name string, _ os.FileMode) error {
+	if f.readOnly {
+		return os.ErrPermission
+	}
+
+	key := objectKey(name)
+	if _, err := f.store.Stat(ctx, key); err == nil {
+		return os.ErrExist
+	}
+
+	_, err := f.store.Put(ctx, path.Join(key, keepFile), strings.NewReader(""), storage.PutOptions{Size: 0})
+	return err
+}
+
+func (f *fileSystem) OpenFile(ctx context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	key := objectKey(name)
+	// webdav opens files O_RDWR for PROPPATCH, which must not replace them, so
+	// only opens that create or overwrite content take the write path.
+	writing := flag&(os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0
+
+	if writing {
+		if f.readOnly {
+			return nil, os.ErrPermission
+		}
+		if key == "" {
+			return nil, os.ErrInvalid
+		}
+		return newWriteFile(ctx, f.store, key), nil
+	}
+
+	info, err := f.stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return &dirFile{ctx: ctx, store: f.store, key: key, info: info}, nil
+	}
+
+	object := info.(fileInfo).object
+	return &readFile{SeekReader: storage.NewSeekReader(ctx, f.store, object), info: info}, nil
+}
+
+func (f *fileSystem) RemoveAll(ctx context.Context, name string) error {
+	if f.readOnly {
+		return os.ErrPermission
+	}
+
+	key := objectKey(name)
+	if key == "" {
+		return os.ErrPermission
+	}
+
+	if err := f.store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotExist) {
+		return err
+	}
+
+	keys := []string{}
+	err := f.store.List(ctx, key+"/", func(info storage.ObjectInfo) error {
+		keys = append(keys, info.Key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, k := range keys {
+		if err := f.store.Delete(ctx, k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Rename copies and deletes every affected object; it is not atomic.
+func (f *fileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	if f.readOnly {
+		return os.ErrPermission
+	}
+
+	oldKey, newKey := objectKey(oldName), objectKey(newName)
+	if oldKey == "" || newKey == "" {
+		return os.ErrPermission
+	}
+
+	if _, err := f.store.Stat(ctx, oldKey); err == nil {
+		return f.move(ctx, oldKey, newKey)
+	}
+
+	keys := []string{}
+	err := f.store.List(ctx, oldKey+"/", func(info storage.ObjectInfo) error {
+		keys = append(keys, info.Key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		return os.ErrNotExist
+	}
+
+	for _, k := range keys {
+		if err := f.move(ctx, k, newKey+strings.TrimPrefix(k, oldKey)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (f *fileSystem) move(ctx context.Context, oldKey, newKey string) error {
+	body, info, err := f.store.Get(ctx, oldKey)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	if _, err := f.store.Put(ctx, newKey, body, storage.PutOptions{
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		Metadata:    info.Metadata,
+	}); err != nil {
+		return err
+	}
+
+	return f.store.Delete(ctx, oldKey)
+}
+
+func (f *fileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	return f.stat(ctx, objectKey(name))
+}
+
+func (f *fileSystem) stat(ctx context.Context, key string) (os.FileInfo, error) {
+	if key == "" {
+		return dirInfo{name: "/"}, nil
+	}
+
+	object, err := f.store.Stat(ctx, key)
+	if err == nil {
+		return fileInfo{object: object}, nil
+	}
+	if !errors.Is(err, storage.ErrNotExist) {
+		return nil, err
+	}
+
+	isDir, err := storage.IsDir(ctx, f.store, key)
+	if err != nil {
+		return nil, err
+	}
+	if !isDir {
+		return nil, os.ErrNotExist
+	}
+
+	return dirInfo{name: path.Base(key)}, nil
+}
+
+type fileInfo struct {
+	object storage.ObjectInfo
+}
+
+func (i fileInfo) Name() string       { return path.Base(i.object.Key) }
+func (i fileInfo) Size() int64        { return i.object.Size }
+func (i fileInfo) Mode() os.FileMode  { return 0o644 }
+func (i fileInfo) ModTime() time.Time { return i.object.LastModified }
+func (i fileInfo) IsDir() bool        { return false }
+func (i fileInfo) Sys() any           { return i.object }
+
+// ContentType lets webdav report the stored content type instead of sniffing.
+func (i fileInfo) ContentType(context.Context) (string, error) {
+	if i.object.ContentType != "" {
+		return i.object.ContentType, nil
+	}
+
+	if ct := mime.TypeByExtension(path.Ext(i.object.Key)); ct != "" {
+		return ct, nil
+	}
+
+	return "", webdav.ErrNotImplemented
+}
+
+// ETag reports the backend ETag so clients can cache and detect changes.
+func (i fileInfo) ETag(context.Context) (string, error) {
+	if i.object.ETag == "" {
+		return "", webdav.ErrNotImplemented
+	}
+
+	return i.object.ETag, nil
+}
+
+type dirInfo struct {
+	name string
+}
+
+func (i dirInfo) Name() string       { return i.name }
+func (i dirInfo) Size() int64        { return 0 }
+func (i dirInfo) Mode() os.FileMode  { return fs.ModeDir | 0o755 }
+func (i dirInfo) ModTime() time.Time { return time.Time{} }
+func (i dirInfo) IsDir() bool        { return true }
+func (i dirInfo) Sys() any           { return nil }
+
+type readFile struct {
+	*storage.SeekReader
+	info os.FileInfo
+}
+
+func (f *readFile) Readdir(int) ([]fs.FileInfo, error) { return nil, os.ErrInvalid }
+func (f *readFile) Stat() (fs.FileInfo, error)         { return f.info, nil }
+func (f *readFile) Write([]byte) (int, error)          { return 0, os.ErrPermission }
+
+type dirFile struct {
+	ctx     context.Context
+	store   storage.Storage
+	key     string
+	info    os.FileInfo
+	entries []fs.FileInfo
+	read    bool
+}
+
+func (d *dirFile) Readdir(count int) ([]fs.FileInfo, error) {
+	if !d.read {
+		entries, err := storage.ReadDir(d.ctx, d.store, d.key)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			switch {
+			case entry.Name == keepFile && !entry.IsDir:
+			case entry.IsDir:
+				d.entries = append(d.entries, dirInfo{name: entry.Name})
+			default:
+				d.entries = append(d.entries, fileInfo{object: entry.Info})
+			}
+		}
+		d.read = true
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n := min(count, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error)     { return d.info, nil }
+func (d *dirFile) Read([]byte) (int, error)       { return 0, os.ErrInvalid }
+func (d *dirFile) Seek(int64, int) (int64, error) { return 0, nil }
+func (d *dirFile) Write([]byte) (int, error)      { return 0, os.ErrInvalid }
+func (d *dirFile) Close() error                   { return nil }
+
+// writeFile streams writes into Put running in the background; Close waits for
+// the upload to finish and reports its error. An upload that failed, was
+// cancelled or fell short of the request's Content-Length is aborted on Close
+// instead of being committed.
+type writeFile struct {
+	ctx  context.Context
+	key  string
+	pw   *io.PipeWriter
+	done chan error
+	size int64
+	// expected is the declared length, or -1 when it is unknown.
+	expected int64
+	err      error
+}
+
+func newWriteFile(ctx context.Context, store storage.Storage, key string) *writeFile {
+	pr, pw := io.Pipe()
+	w := &writeFile{ctx: ctx, key: key, pw: pw, done: make(chan error, 1), expected: -1}
+	if expected, ok := ctx.Value(contentLengthKey{}).(int64); ok {
+		w.expected = expected
+	}
+
+	go func() {
+		_, err := store.Put(ctx, key, pr, storage.PutOptions{
+			ContentType: mime.TypeByExtension(path.Ext(key)),
+			Size:        -1,
+		})
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+
+	return w
+}
+
+func (w *writeFile) Write(p []byte) (int, error) {
+	n, err := w.pw.Write(p)
+	w.size += int64(n)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return n, err
+}
+
+func (w *writeFile) Close() error {
+	err := w.err
+	switch {
+	case err != nil:
+	case w.ctx.Err() != nil:
+		err = w.ctx.Err()
+	case w.expected >= 0 && w.size != w.expected:
+		err = fmt.Errorf("received %d of %d bytes", w.size, w.expected)
+	}
+	if err != nil {
+		w.pw.CloseWithError(err)
+		<-w.done
+		return err
+	}
+
+	w.pw.Close()
+	return <-w.done
+}
+
+func (w *writeFile) Read([]byte) (int, error)           { return 0, os.ErrInvalid }
+func (w *writeFile) Seek(int64, int) (int64, error)     { return w.size, nil }
+func (w *writeFile) Readdir(int) ([]fs.FileInfo, error) { return nil, os.ErrInvalid }
+
+func (w *writeFile) Stat() (fs.FileInfo, error) {
+	return fileInfo{object: storage.ObjectInfo{Key: w.key, Size: w.size, LastModified: time.Now()}}, nil
+}
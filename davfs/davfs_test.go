@@ -0,0 +1,129 @@
+package davfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/storage/memory"
+)
+
+const proppatchBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propertyupdate xmlns:D="DAV:" xmlns:Z="urn:example">
+  <D:set><D:prop><Z:color>blue</Z:color></D:prop></D:set>
+</D:propertyupdate>`
+
+func TestMetadataRequestsKeepContents(t *testing.T) {
+	tests := []struct {
+		method string
+		body   string
+		header http.Header
+	}{
+		{method: "PROPPATCH", body: proppatchBody},
+		{method: "PROPFIND", header: http.Header{"Depth": {"0"}}},
+		{method: http.MethodGet},
+		{method: http.MethodHead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			store := memory.NewStorage()
+			if _, err := store.Put(context.Background(), "docs/a.txt", strings.NewReader("original"), storage.PutOptions{Size: -1}); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			server := httptest.NewServer(NewHandler(store, "", false))
+			defer server.Close()
+
+			req, err := http.NewRequest(tt.method, server.URL+"/docs/a.txt", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.method, err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+
+			body, _, err := store.Get(context.Background(), "docs/a.txt")
+			if err != nil {
+				t.Fatalf("Get after %s: %v", tt.method, err)
+			}
+			defer body.Close()
+			if data, _ := io.ReadAll(body); string(data) != "original" {
+				t.Errorf("contents after %s = %q, want %q", tt.method, data, "original")
+			}
+		})
+	}
+}
+
+func TestPutReplacesContents(t *testing.T) {
+	store := memory.NewStorage()
+	server := httptest.NewServer(NewHandler(store, "", false))
+	defer server.Close()
+
+	for _, content := range []string{"first", "second"} {
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/a.txt", strings.NewReader(content))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("PUT: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			t.Fatalf("PUT status = %d", resp.StatusCode)
+		}
+	}
+
+	body, _, err := store.Get(context.Background(), "a.txt")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "second" {
+		t.Errorf("contents = %q, want %q", data, "second")
+	}
+}
+
+func TestAbortedPutKeepsContents(t *testing.T) {
+	tests := []struct {
+		name string
+		body io.Reader
+	}{
+		{name: "body broke off", body: io.MultiReader(strings.NewReader("trunc"), iotest.ErrReader(errors.New("connection reset")))},
+		{name: "body shorter than declared", body: strings.NewReader("trunc")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memory.NewStorage()
+			if _, err := store.Put(context.Background(), "a.txt", strings.NewReader("original"), storage.PutOptions{Size: -1}); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodPut, "/a.txt", tt.body)
+			req.ContentLength = 10
+			w := httptest.NewRecorder()
+			NewHandler(store, "", false).ServeHTTP(w, req)
+
+			if w.Code < 300 {
+				t.Errorf("PUT status = %d, want an error", w.Code)
+			}
+			body, _, err := store.Get(context.Background(), "a.txt")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			defer body.Close()
+			if data, _ := io.ReadAll(body); string(data) != "original" {
+				t.Errorf("contents = %q, want %q", data, "original")
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package fileserver
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/signing"
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+const (
+	defaultCacheControl   = "public, max-age=3600"
+	defaultRedirectExpiry = 15 * time.Minute
+)
+
+type Options struct {
+	// Prefix is stripped from the request path before it is used as the key.
+	Prefix string
+	// CacheControl defaults to public caching for one hour.
+	CacheControl string
+	// Redirect answers GET and HEAD with a 307 to a presigned URL instead of
+	// proxying the body. It requires a backend implementing storage.Presigner.
+	Redirect       bool
+	RedirectExpiry time.Duration
+	// ShareKeys, when set, restricts the server to share links: every request
+	// needs a "token" query parameter made by ShareToken for the requested key.
+	ShareKeys *signing.Keyring
+}
+
+type shareClaims struct {
+	Key     string `json:"key"`
+	Expires int64  `json:"exp"`
+}
+
+// ShareToken signs a token granting access to key until expires has passed.
+// Tokens stay valid across key rotations while keys still holds the key that
+// signed them.
+func ShareToken(keys *signing.Keyring, key string, expires time.Duration) (string, error) {
+	payload, err := json.Marshal(shareClaims{Key: key, Expires: time.Now().Add(expires).Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	return keys.SignToken(payload)
+}
+
+func (f *fileServer) shared(r *http.Request, key string) bool {
+	payload, err := f.opts.ShareKeys.VerifyToken(r.URL.Query().Get("token"))
+	if err != nil {
+		return false
+	}
+
+	var claims shareClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return false
+	}
+
+	return claims.Key == key && time.Now().Unix() < claims.Expires
+}
+
+type fileServer struct {
+	store storage.Storage
+	opts  Options
+}
+
+// New serves objects from store. Conditional and range requests are handled by
+// http.ServeContent using the object's ETag and last-modified time.
+func New(store storage.Storage, opts Options) (http.Handler, error) {
+	if opts.Redirect {
+		if _, ok := store.(storage.Presigner); !ok {
+			return nil, errors.New("redirect mode requires a storage backend that can presign URLs")
+		}
+	}
+
+	if opts.CacheControl == "" {
+		opts.CacheControl = defaultCacheControl
+	}
+
+	if opts.RedirectExpiry == 0 {
+		opts.RedirectExpiry = defaultRedirectExpiry
+	}
+
+	return &fileServer{store: store, opts: opts}, nil
+}
+
+func (f *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest, ok := strings.CutPrefix(r.URL.Path, f.opts.Prefix)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	key := strings.TrimPrefix(path.Clean("/"+rest), "/")
+	if key == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if f.opts.ShareKeys != nil && !f.shared(r, key) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	if f.opts.Redirect {
+		f.redirect(w, r, key)
+		return
+	}
+
+	info, err := f.store.Stat(r.Context(), key)
+	if err != nil {
+		f.error(w, r, key, err)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Cache-Control", f.opts.CacheControl)
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Disposition", disposition(key, info.ContentType))
+	if info.ContentType != "" {
+		header.Set("Content-Type", info.ContentType)
+	}
+	if info.ETag != "" {
+		etag := info.ETag
+		if !strings.HasPrefix(etag, `"`) {
+			etag = `"` + etag + `"`
+		}
+		header.Set("ETag", etag)
+	}
+
+	body := storage.NewSeekReader(r.Context(), f.store, info)
+	defer body.Close()
+
+	http.ServeContent(w, r, path.Base(key), info.LastModified, body)
+}
+
+// disposition lets browsers render media and plain text inline and downloads
+// everything else, so uploaded HTML or SVG never runs on the server's origin.
+func disposition(key, contentType string) string {
+	kind := "attachment"
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "image/svg+xml":
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "audio/"),
+		mediaType == "text/plain",
+		mediaType == "application/pdf":
+		kind = "inline"
+	}
+
+	if value := mime.FormatMediaType(kind, map[string]string{"filename": path.Base(key)}); value != "" {
+		return value
+	}
+	return kind
+}
+
+func (f *fileServer) redirect(w http.ResponseWriter, r *http.Request, key string) {
+	if _, err := f.store.Stat(r.Context(), key); err != nil {
+		f.error(w, r, key, err)
+		return
+	}
+
+	url, err := f.store.(storage.Presigner).PresignGet(r.Context(), key, f.opts.RedirectExpiry)
+	if err != nil {
+		f.error(w, r, key, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+}
+
+func (f *fileServer) error(w http.ResponseWriter, r *http.Request, key string, err error) {
+	if errors.Is(err, storage.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+
+	log.Printf("failed to serve file %s: %v", key, err)
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
@@ -0,0 +1,140 @@
+package fileserver
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/signing"
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/storage/memory"
+)
+
+func TestFileServer(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorage()
+	for key, contentType := range map[string]string{
+		"docs/a.txt":   "text/plain",
+		"docs/b.html":  "text/html",
+		"docs/c.svg":   "image/svg+xml",
+		"docs/d e.png": "image/png",
+	} {
+		if _, err := store.Put(ctx, key, strings.NewReader("0123456789"), storage.PutOptions{ContentType: contentType, Size: -1}); err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+
+	handler, err := New(store, Options{Prefix: "/files/"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		method          string
+		path            string
+		header          http.Header
+		wantStatus      int
+		wantBody        string
+		wantDisposition string
+	}{
+		{name: "inline text", method: http.MethodGet, path: "/files/docs/a.txt", wantStatus: http.StatusOK, wantBody: "0123456789", wantDisposition: `inline; filename=a.txt`},
+		{name: "html downloads", method: http.MethodGet, path: "/files/docs/b.html", wantStatus: http.StatusOK, wantDisposition: `attachment; filename=b.html`},
+		{name: "svg downloads", method: http.MethodGet, path: "/files/docs/c.svg", wantStatus: http.StatusOK, wantDisposition: `attachment; filename=c.svg`},
+		{name: "quoted filename", method: http.MethodGet, path: "/files/docs/d%20e.png", wantStatus: http.StatusOK, wantDisposition: `inline; filename="d e.png"`},
+		{name: "range", method: http.MethodGet, path: "/files/docs/a.txt", header: http.Header{"Range": {"bytes=2-4"}}, wantStatus: http.StatusPartialContent, wantBody: "234", wantDisposition: `inline; filename=a.txt`},
+		{name: "missing prefix", method: http.MethodGet, path: "/docs/a.txt", wantStatus: http.StatusNotFound},
+		{name: "traversal stays below prefix", method: http.MethodGet, path: "/files/../docs/a.txt", wantStatus: http.StatusOK, wantBody: "0123456789", wantDisposition: `inline; filename=a.txt`},
+		{name: "no key", method: http.MethodGet, path: "/files/", wantStatus: http.StatusNotFound},
+		{name: "not found", method: http.MethodGet, path: "/files/docs/missing.txt", wantStatus: http.StatusNotFound},
+		{name: "method not allowed", method: http.MethodPost, path: "/files/docs/a.txt", wantStatus: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+			if tt.wantDisposition == "" {
+				return
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tt.wantDisposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tt.wantDisposition)
+			}
+			if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("X-Content-Type-Options = %q, want nosniff", got)
+			}
+		})
+	}
+}
+
+func TestFileServerShareTokens(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStorage()
+	for _, key := range []string{"a.txt", "b.txt"} {
+		if _, err := store.Put(ctx, key, strings.NewReader("data"), storage.PutOptions{ContentType: "text/plain", Size: -1}); err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+
+	keys, err := signing.NewKeyring(signing.Key{ID: "share", Secret: bytes.Repeat([]byte("s"), 32)})
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	handler, err := New(store, Options{ShareKeys: keys})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	token := func(key string, expires time.Duration) string {
+		token, err := ShareToken(keys, key, expires)
+		if err != nil {
+			t.Fatalf("ShareToken: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		wantStatus int
+	}{
+		{name: "valid", path: "/a.txt", token: token("a.txt", time.Hour), wantStatus: http.StatusOK},
+		{name: "other key", path: "/b.txt", token: token("a.txt", time.Hour), wantStatus: http.StatusForbidden},
+		{name: "expired", path: "/a.txt", token: token("a.txt", -time.Minute), wantStatus: http.StatusForbidden},
+		{name: "tampered", path: "/a.txt", token: token("a.txt", time.Hour) + "x", wantStatus: http.StatusForbidden},
+		{name: "missing", path: "/a.txt", wantStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tt.path+"?token="+url.QueryEscape(tt.token), nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestNewRedirectRequiresPresigner(t *testing.T) {
+	if _, err := New(memory.NewStorage(), Options{Redirect: true}); err == nil {
+		t.Error("New with Redirect on a backend without presigning succeeded")
+	}
+}
@@ -0,0 +1,94 @@
+// Package idgen generates the IDs used for correlation and job IDs, upload
+// tokens, temp names and generated keys.
+package idgen
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/oklog/ulid/v2"
+	"github.com/segmentio/ksuid"
+)
+
+type Generator interface {
+	NewID() string
+}
+
+type GeneratorFunc func() string
+
+func (f GeneratorFunc) NewID() string {
+	return f()
+}
+
+// Default is used wherever no Generator is configured. Replace it at startup,
+// before any IDs are generated, to change IDs process-wide.
+var Default Generator = UUID()
+
+// UUID generates random version 4 UUIDs.
+func UUID() Generator {
+	return GeneratorFunc(uuid.NewString)
+}
+
+// UUIDv7 generates time-ordered version 7 UUIDs.
+func UUIDv7() Generator {
+	return GeneratorFunc(func() string {
+		return uuid.Must(uuid.NewV7()).String()
+	})
+}
+
+// ULID generates lexicographically sortable IDs that are monotonic within the
+// process, even for IDs generated in the same millisecond.
+func ULID() Generator {
+	return GeneratorFunc(func() string {
+		return ulid.Make().String()
+	})
+}
+
+// KSUID generates IDs sortable by their creation second.
+func KSUID() Generator {
+	return GeneratorFunc(func() string {
+		return ksuid.New().String()
+	})
+}
+
+// Key builds an object key under prefix named by a new ID, keeping the
+// extension of filename, e.g. "uploads/01J9…ZK.jpg". With a sortable
+// generator, keys list in upload order.
+func Key(g Generator, prefix, filename string) string {
+	if g == nil {
+		g = Default
+	}
+
+	return prefix + g.NewID() + strings.ToLower(path.Ext(filename))
+}
+
+// createTempTries matches os.CreateTemp, which gives up after as many names.
+const createTempTries = 10000
+
+// CreateTemp is os.CreateTemp with the random part of the name, the last "*"
+// in pattern, taken from g. The file is created with mode 0600; names that
+// already exist are skipped.
+func CreateTemp(g Generator, dir, pattern string) (*os.File, error) {
+	if g == nil {
+		g = Default
+	}
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	prefix, suffix := pattern, ""
+	if i := strings.LastIndex(pattern, "*"); i >= 0 {
+		prefix, suffix = pattern[:i], pattern[i+1:]
+	}
+
+	for try := 1; ; try++ {
+		file, err := os.OpenFile(filepath.Join(dir, prefix+g.NewID()+suffix), os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600)
+		if os.IsExist(err) && try < createTempTries {
+			continue
+		}
+		return file, err
+	}
+}
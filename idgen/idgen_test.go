@@ -0,0 +1,55 @@
+package idgen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// sequence returns ids in turn, repeating the last one.
+func sequence(ids ...string) Generator {
+	return GeneratorFunc(func() string {
+		id := ids[0]
+		if len(ids) > 1 {
+			ids = ids[1:]
+		}
+		return id
+	})
+}
+
+func TestCreateTemp(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      []string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "free name", ids: []string{"b"}, wantName: "tmp-b.part"},
+		{name: "taken name is skipped", ids: []string{"a", "a", "b"}, wantName: "tmp-b.part"},
+		{name: "every name taken", ids: []string{"a"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "tmp-a.part"), nil, 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := CreateTemp(sequence(tt.ids...), dir, "tmp-*.part")
+			if tt.wantErr {
+				if !os.IsExist(err) {
+					t.Fatalf("CreateTemp error = %v, want an exists error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+
+			if got := filepath.Base(file.Name()); got != tt.wantName {
+				t.Errorf("CreateTemp created %s, want %s", got, tt.wantName)
+			}
+		})
+	}
+}
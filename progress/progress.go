@@ -0,0 +1,421 @@
+// Package progress tracks batch uploads, downloads and syncs file by file and
+// renders them as a live terminal view with speed, ETA, retries and errors.
+package progress
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/s3"
+	"github.com/KurniawanHendiW/file-uploader/transfer"
+)
+
+type State int
+
+const (
+	Pending State = iota
+	Running
+	Done
+	Failed
+	Skipped
+)
+
+func (s State) String() string {
+	switch s {
+	case Running:
+		return "running"
+	case Done:
+		return "done"
+	case Failed:
+		return "failed"
+	case Skipped:
+		return "skipped"
+	default:
+		return "pending"
+	}
+}
+
+// File is a snapshot of one tracked file. Size is zero when unknown.
+type File struct {
+	Key      string
+	State    State
+	Size     int64
+	Bytes    int64
+	Retries  int
+	Err      error
+	Started  time.Time
+	Finished time.Time
+}
+
+// Speed is the average rate in bytes per second since the file started.
+func (f File) Speed(now time.Time) float64 {
+	end := f.Finished
+	if end.IsZero() {
+		end = now
+	}
+	elapsed := end.Sub(f.Started).Seconds()
+	if f.Started.IsZero() || elapsed <= 0 {
+		return 0
+	}
+	return float64(f.Bytes) / elapsed
+}
+
+// ETA is zero when the size or speed is unknown.
+func (f File) ETA(now time.Time) time.Duration {
+	speed := f.Speed(now)
+	if f.State != Running || f.Size <= 0 || speed <= 0 {
+		return 0
+	}
+	return time.Duration(float64(f.Size-f.Bytes) / speed * float64(time.Second))
+}
+
+// Summary totals every file seen by a Tracker.
+type Summary struct {
+	Files   int
+	Done    int
+	Failed  int
+	Skipped int
+	Retries int
+	Bytes   int64
+	Size    int64
+	Started time.Time
+}
+
+// Tracker is safe for concurrent use by transfer workers.
+type Tracker struct {
+	mu      sync.Mutex
+	files   map[string]*File
+	order   []string
+	started time.Time
+	now     func() time.Time
+}
+
+func NewTracker() *Tracker {
+	return &Tracker{files: map[string]*File{}, started: time.Now(), now: time.Now}
+}
+
+func (t *Tracker) file(key string) *File {
+	f, ok := t.files[key]
+	if !ok {
+		f = &File{Key: key}
+		t.files[key] = f
+		t.order = append(t.order, key)
+	}
+	return f
+}
+
+func (t *Tracker) Start(key string, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := t.file(key)
+	f.State, f.Size, f.Started = Running, size, t.now()
+}
+
+func (t *Tracker) Add(key string, n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := t.file(key)
+	if f.Started.IsZero() {
+		f.State, f.Started = Running, t.now()
+	}
+	f.Bytes += n
+}
+
+// Retry records a failed attempt that will be tried again; the bytes of the
+// attempt are discarded.
+func (t *Tracker) Retry(key string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := t.file(key)
+	f.Retries++
+	f.Bytes = 0
+	f.Err = err
+}
+
+// Finish ends a file as done, or failed when err is set.
+func (t *Tracker) Finish(key string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := t.file(key)
+	f.Finished, f.Err = t.now(), err
+	if f.Started.IsZero() {
+		f.Started = f.Finished
+	}
+	if err != nil {
+		f.State = Failed
+		return
+	}
+	f.State = Done
+	if f.Size > f.Bytes {
+		f.Bytes = f.Size
+	}
+}
+
+func (t *Tracker) Skip(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := t.file(key)
+	f.State, f.Finished = Skipped, t.now()
+}
+
+// Reader counts the bytes read from r towards key.
+func (t *Tracker) Reader(key string, r io.Reader) io.Reader {
+	return &countingReader{r: r, key: key, tracker: t}
+}
+
+type countingReader struct {
+	r       io.Reader
+	key     string
+	tracker *Tracker
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.tracker.Add(c.key, int64(n))
+	}
+	return n, err
+}
+
+// Snapshot returns the files in the order they were first seen.
+func (t *Tracker) Snapshot() ([]File, Summary) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	files := make([]File, 0, len(t.order))
+	summary := Summary{Files: len(t.order), Started: t.started}
+	for _, key := range t.order {
+		f := *t.files[key]
+		files = append(files, f)
+
+		summary.Bytes += f.Bytes
+		summary.Size += f.Size
+		summary.Retries += f.Retries
+		switch f.State {
+		case Done:
+			summary.Done++
+		case Failed:
+			summary.Failed++
+		case Skipped:
+			summary.Skipped++
+		}
+	}
+
+	return files, summary
+}
+
+// Transfer is a transfer.Options.OnProgress callback. Copy only reports
+// finished objects, so their bytes are counted as a whole.
+func (t *Tracker) Transfer() func(transfer.Progress) {
+	var last transfer.Progress
+	var mu sync.Mutex
+	return func(p transfer.Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case p.Failed > last.Failed:
+			t.Finish(p.Key, fmt.Errorf("transfer of %s failed", p.Key))
+		case p.Skipped > last.Skipped:
+			t.Skip(p.Key)
+		default:
+			t.Add(p.Key, p.Bytes-last.Bytes)
+			t.Finish(p.Key, nil)
+		}
+		last = p
+	}
+}
+
+// Migrate is an s3.MigrateRequest.OnProgress callback. Migrate reports from
+// parallel workers, so bytes may be attributed to the wrong file when two
+// objects finish at the same time; totals stay exact.
+func (t *Tracker) Migrate() func(s3.MigrateProgress) {
+	var last s3.MigrateProgress
+	var mu sync.Mutex
+	return func(p s3.MigrateProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if p.Failed > last.Failed {
+			t.Finish(p.Filename, fmt.Errorf("migration of %s failed", p.Filename))
+		} else {
+			t.Add(p.Filename, max(p.Bytes-last.Bytes, 0))
+			t.Finish(p.Filename, nil)
+		}
+		last.Failed, last.Bytes = max(last.Failed, p.Failed), max(last.Bytes, p.Bytes)
+	}
+}
+
+// Export is an s3.ExportOptions.OnProgress callback, with the same caveat as
+// Migrate.
+func (t *Tracker) Export() func(s3.ExportProgress) {
+	var last s3.ExportProgress
+	var mu sync.Mutex
+	return func(p s3.ExportProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case p.Failed > last.Failed:
+			t.Finish(p.Filename, fmt.Errorf("export of %s failed", p.Filename))
+		case p.Skipped > last.Skipped:
+			t.Skip(p.Filename)
+		default:
+			t.Add(p.Filename, max(p.Bytes-last.Bytes, 0))
+			t.Finish(p.Filename, nil)
+		}
+		last.Failed, last.Skipped = max(last.Failed, p.Failed), max(last.Skipped, p.Skipped)
+		last.Bytes = max(last.Bytes, p.Bytes)
+	}
+}
+
+// View renders a Tracker to a terminal, redrawing in place.
+type View struct {
+	Tracker *Tracker
+	// MaxRows limits the files shown; running and failed files are shown
+	// first. Defaults to 20.
+	MaxRows int
+	// Width of the progress bars, defaults to 24.
+	Width int
+
+	lines int
+}
+
+// Run redraws the view every interval until ctx is done, then draws it a last
+// time so the final state stays on screen.
+func (v *View) Run(ctx context.Context, w io.Writer, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := v.Draw(w); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return v.Draw(w)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Draw replaces the previously drawn frame with the current state.
+func (v *View) Draw(w io.Writer) error {
+	files, summary := v.Tracker.Snapshot()
+	now := v.Tracker.now()
+
+	buf := bufio.NewWriter(w)
+	if v.lines > 0 {
+		fmt.Fprintf(buf, "\x1b[%dA", v.lines)
+	}
+
+	lines := v.frame(files, summary, now)
+	for _, line := range lines {
+		fmt.Fprintf(buf, "\x1b[2K%s\n", line)
+	}
+	// Clear what is left of a longer previous frame.
+	for i := len(lines); i < v.lines; i++ {
+		fmt.Fprint(buf, "\x1b[2K\n")
+	}
+	if extra := v.lines - len(lines); extra > 0 {
+		fmt.Fprintf(buf, "\x1b[%dA", extra)
+	}
+	v.lines = len(lines)
+
+	return buf.Flush()
+}
+
+func (v *View) frame(files []File, summary Summary, now time.Time) []string {
+	maxRows, width := v.MaxRows, v.Width
+	if maxRows <= 0 {
+		maxRows = 20
+	}
+	if width <= 0 {
+		width = 24
+	}
+
+	elapsed := now.Sub(summary.Started)
+	speed := 0.0
+	if elapsed > 0 {
+		speed = float64(summary.Bytes) / elapsed.Seconds()
+	}
+	lines := []string{fmt.Sprintf("%d/%d files  %d failed  %d skipped  %d retries  %s  %s/s  %s elapsed",
+		summary.Done, summary.Files, summary.Failed, summary.Skipped, summary.Retries,
+		formatBytes(float64(summary.Bytes)), formatBytes(speed), elapsed.Round(time.Second))}
+
+	slices.SortStableFunc(files, func(a, b File) int { return rank(a.State) - rank(b.State) })
+	for i, f := range files {
+		if i == maxRows {
+			lines = append(lines, fmt.Sprintf("… %d more", len(files)-maxRows))
+			break
+		}
+		lines = append(lines, fileLine(f, now, width))
+	}
+
+	return lines
+}
+
+// rank orders running files first, then failed ones, then the rest.
+func rank(state State) int {
+	switch state {
+	case Running:
+		return 0
+	case Failed:
+		return 1
+	case Pending:
+		return 2
+	default:
+		return 3
+	}
+}
+
+func fileLine(f File, now time.Time, width int) string {
+	var fraction float64
+	switch {
+	case f.State == Done || f.State == Skipped:
+		fraction = 1
+	case f.Size > 0:
+		fraction = min(float64(f.Bytes)/float64(f.Size), 1)
+	}
+	filled := int(fraction * float64(width))
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", width-filled)
+
+	line := fmt.Sprintf("%-8s %s %3.0f%%  %9s/s", f.State, bar, fraction*100, formatBytes(f.Speed(now)))
+	if eta := f.ETA(now); eta > 0 {
+		line += fmt.Sprintf("  eta %s", eta.Round(time.Second))
+	}
+	if f.Retries > 0 {
+		line += fmt.Sprintf("  %d retries", f.Retries)
+	}
+	line += "  " + f.Key
+	if f.Err != nil {
+		line += ": " + f.Err.Error()
+	}
+
+	return line
+}
+
+func formatBytes(n float64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%.0f B", n)
+	}
+	exp := 0
+	for n >= unit*unit && exp < 4 {
+		n /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", n/unit, "KMGTP"[exp])
+}
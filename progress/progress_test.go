@@ -0,0 +1,158 @@
+package progress
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/s3"
+	"github.com/KurniawanHendiW/file-uploader/transfer"
+)
+
+// clock advances by one second on every reading.
+func clock(tracker *Tracker) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker.started = now
+	tracker.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func TestTracker(t *testing.T) {
+	tracker := NewTracker()
+	clock(tracker)
+	errRetry, errFailed := errors.New("reset"), errors.New("denied")
+
+	tracker.Start("a.bin", 100)
+	io.Copy(io.Discard, tracker.Reader("a.bin", strings.NewReader(strings.Repeat("x", 30))))
+	tracker.Retry("a.bin", errRetry)
+	tracker.Add("a.bin", 40)
+	tracker.Finish("b.bin", errFailed)
+	tracker.Skip("c.bin")
+
+	files, summary := tracker.Snapshot()
+	if len(files) != 3 || files[0].Key != "a.bin" || files[1].Key != "b.bin" || files[2].Key != "c.bin" {
+		t.Fatalf("files = %+v, want them in the order first seen", files)
+	}
+	a := files[0]
+	if a.State != Running || a.Bytes != 40 || a.Retries != 1 || !errors.Is(a.Err, errRetry) {
+		t.Errorf("a.bin = %+v, want running with the retried bytes discarded", a)
+	}
+	now := a.Started.Add(4 * time.Second)
+	if speed, eta := a.Speed(now), a.ETA(now); speed != 10 || eta != 6*time.Second {
+		t.Errorf("a.bin speed and ETA = %v, %v, want 10 B/s and 6s", speed, eta)
+	}
+	if b := files[1]; b.State != Failed || !errors.Is(b.Err, errFailed) || b.ETA(now) != 0 {
+		t.Errorf("b.bin = %+v, want failed", b)
+	}
+	if files[2].State != Skipped {
+		t.Errorf("c.bin = %+v, want skipped", files[2])
+	}
+	if summary.Files != 3 || summary.Failed != 1 || summary.Skipped != 1 || summary.Done != 0 || summary.Retries != 1 || summary.Bytes != 40 || summary.Size != 100 {
+		t.Errorf("summary = %+v", summary)
+	}
+
+	tracker.Finish("a.bin", nil)
+	if files, summary := tracker.Snapshot(); files[0].State != Done || files[0].Bytes != 100 || summary.Done != 1 {
+		t.Errorf("finished a.bin = %+v, want done with its full size", files[0])
+	}
+}
+
+func TestTrackerCallbacks(t *testing.T) {
+	tests := []struct {
+		name   string
+		report func(*Tracker)
+	}{
+		{name: "transfer", report: func(tracker *Tracker) {
+			report := tracker.Transfer()
+			report(transfer.Progress{Key: "done.txt", Transferred: 1, Bytes: 10})
+			report(transfer.Progress{Key: "failed.txt", Transferred: 1, Failed: 1, Bytes: 10})
+			report(transfer.Progress{Key: "skipped.txt", Transferred: 1, Failed: 1, Skipped: 1, Bytes: 10})
+		}},
+		{name: "migrate", report: func(tracker *Tracker) {
+			report := tracker.Migrate()
+			report(s3.MigrateProgress{Filename: "done.txt", Copied: 1, Bytes: 10})
+			report(s3.MigrateProgress{Filename: "failed.txt", Copied: 1, Failed: 1, Bytes: 10})
+		}},
+		{name: "export", report: func(tracker *Tracker) {
+			report := tracker.Export()
+			report(s3.ExportProgress{Filename: "done.txt", Downloaded: 1, Bytes: 10})
+			report(s3.ExportProgress{Filename: "failed.txt", Downloaded: 1, Failed: 1, Bytes: 10})
+			report(s3.ExportProgress{Filename: "skipped.txt", Downloaded: 1, Failed: 1, Skipped: 1, Bytes: 10})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker()
+			tt.report(tracker)
+
+			files, summary := tracker.Snapshot()
+			want := map[string]State{"done.txt": Done, "failed.txt": Failed, "skipped.txt": Skipped}
+			for _, f := range files {
+				if f.State != want[f.Key] {
+					t.Errorf("%s = %v, want %v", f.Key, f.State, want[f.Key])
+				}
+			}
+			if summary.Done != 1 || summary.Failed != 1 || summary.Bytes != 10 {
+				t.Errorf("summary = %+v, want one done, one failed and 10 bytes", summary)
+			}
+		})
+	}
+}
+
+func TestViewDraw(t *testing.T) {
+	tracker := NewTracker()
+	clock(tracker)
+	tracker.Start("a.bin", 100)
+	tracker.Add("a.bin", 50)
+	tracker.Finish("b.bin", errors.New("denied"))
+	tracker.Finish("c.bin", nil)
+	view := &View{Tracker: tracker, MaxRows: 2, Width: 10}
+
+	var out bytes.Buffer
+	if err := view.Draw(&out); err != nil {
+		t.Fatalf("Draw: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("frame = %q, want a summary, two files and a remainder", lines)
+	}
+	if !strings.Contains(lines[0], "1/3 files  1 failed") {
+		t.Errorf("summary line = %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "running  █████░░░░░  50%") || !strings.HasSuffix(lines[1], "a.bin") {
+		t.Errorf("first file line = %q, want a.bin half done", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], "b.bin: denied") || !strings.HasSuffix(lines[3], "… 1 more") {
+		t.Errorf("file lines = %q, want the failure and then the remainder", lines[2:])
+	}
+
+	out.Reset()
+	view.MaxRows = 1
+	if err := view.Draw(&out); err != nil {
+		t.Fatalf("Draw: %v", err)
+	}
+	if frame := out.String(); !strings.HasPrefix(frame, "\x1b[4A") || !strings.HasSuffix(frame, "\x1b[2K\n\x1b[1A") {
+		t.Errorf("redraw = %q, want it to move up over the previous frame and clear its last line", frame)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    float64
+		want string
+	}{
+		{n: 512, want: "512 B"},
+		{n: 1536, want: "1.5 KiB"},
+		{n: 3 << 20, want: "3.0 MiB"},
+	}
+	for _, tt := range tests {
+		if got := formatBytes(tt.n); got != tt.want {
+			t.Errorf("formatBytes(%v) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/smithy-go/middleware"
+)
+
+const (
+	accessPointAliasSuffix = "-s3alias"
+	mrapAliasSuffix        = ".mrap"
+)
+
+// isAccessPoint reports whether bucketName names an access point or a
+// Multi-Region Access Point rather than a bucket: an access point ARN, an
+// access point alias or an MRAP alias. Such names cannot be created, checked
+// with HeadBucket or accelerated.
+func isAccessPoint(bucketName string) bool {
+	return arn.IsARN(bucketName) ||
+		strings.HasSuffix(bucketName, accessPointAliasSuffix) ||
+		strings.HasSuffix(bucketName, mrapAliasSuffix)
+}
+
+// resolveBucket maps logical names set with WithBucketNames onto bucket names
+// and turns an MRAP alias into the MRAP ARN S3 expects, using the account ID
+// set with WithAccountID. Other names are returned unchanged.
+func (s *s3Service) resolveBucket(bucketName string) (string, error) {
+	bucketName = s.bucketNames.Name(bucketName)
+	if !strings.HasSuffix(bucketName, mrapAliasSuffix) || arn.IsARN(bucketName) {
+		return bucketName, nil
+	}
+
+	if s.accountID == "" {
+		return "", fmt.Errorf("account id is required to use multi-region access point %s", bucketName)
+	}
+
+	return arn.ARN{
+		Partition: s.partition(),
+		Service:   "s3",
+		AccountID: s.accountID,
+		Resource:  "accesspoint/" + bucketName,
+	}.String(), nil
+}
+
+// regionPartitions maps region name prefixes onto their partitions; other
+// regions are in "aws".
+var regionPartitions = []struct{ prefix, partition string }{
+	{"cn-", "aws-cn"},
+	{"us-gov-", "aws-us-gov"},
+	{"us-iso-", "aws-iso"},
+	{"us-isob-", "aws-iso-b"},
+	{"eu-isoe-", "aws-iso-e"},
+	{"us-isof-", "aws-iso-f"},
+}
+
+// partition returns the ARN partition of the resolved region, or of the
+// endpoint set with WithEndpoint when it is a China endpoint.
+func (s *s3Service) partition() string {
+	if strings.Contains(s.endpoint, ".amazonaws.com.cn") {
+		return "aws-cn"
+	}
+
+	for _, p := range regionPartitions {
+		if strings.HasPrefix(s.awsCfg.Region, p.prefix) {
+			return p.partition
+		}
+	}
+
+	return "aws"
+}
+
+// bucketArn returns the ARN of bucketName in the service's partition. Access
+// point ARNs are returned unchanged.
+func (s *s3Service) bucketArn(bucketName string) string {
+	if arn.IsARN(bucketName) {
+		return bucketName
+	}
+	return arn.ARN{Partition: s.partition(), Service: "s3", Resource: bucketName}.String()
+}
+
+// accessPointMiddleware rewrites logical names and MRAP aliases in the Bucket
+// and CopySource of every operation input, so callers can use them wherever a
+// bucket goes.
+func (s *s3Service) accessPointMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("FileUploaderAccessPoint", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		input := reflect.ValueOf(in.Parameters)
+		if input.Kind() != reflect.Pointer || input.Elem().Kind() != reflect.Struct {
+			return next.HandleInitialize(ctx, in)
+		}
+
+		if field := input.Elem().FieldByName("Bucket"); field.IsValid() && field.Type() == reflect.TypeOf((*string)(nil)) && !field.IsNil() {
+			bucketName, err := s.resolveBucket(aws.ToString(field.Interface().(*string)))
+			if err != nil {
+				return middleware.InitializeOutput{}, middleware.Metadata{}, err
+			}
+			field.Set(reflect.ValueOf(aws.String(bucketName)))
+		}
+
+		if field := input.Elem().FieldByName("CopySource"); field.IsValid() && field.Type() == reflect.TypeOf((*string)(nil)) && !field.IsNil() {
+			source, err := url.PathUnescape(aws.ToString(field.Interface().(*string)))
+			if alias, key, ok := strings.Cut(source, "/"); err == nil && ok && (strings.HasSuffix(alias, mrapAliasSuffix) || s.bucketNames[alias] != "") {
+				bucketName, err := s.resolveBucket(alias)
+				if err != nil {
+					return middleware.InitializeOutput{}, middleware.Metadata{}, err
+				}
+				field.Set(reflect.ValueOf(aws.String(copySource(bucketName, key))))
+			}
+		}
+
+		return next.HandleInitialize(ctx, in)
+	}), middleware.Before)
+}
+
+// accessPointHost returns the virtual host serving objects of an access point
+// ARN or MRAP alias, or "" for anything else.
+func accessPointHost(bucketName string) string {
+	if strings.HasSuffix(bucketName, mrapAliasSuffix) && !arn.IsARN(bucketName) {
+		return bucketName + ".accesspoint.s3-global.amazonaws.com"
+	}
+
+	parsed, err := arn.Parse(bucketName)
+	if err != nil {
+		return ""
+	}
+
+	name, ok := strings.CutPrefix(parsed.Resource, "accesspoint/")
+	if !ok {
+		return ""
+	}
+
+	if parsed.Region == "" {
+		return name + ".accesspoint.s3-global.amazonaws.com"
+	}
+
+	return fmt.Sprintf("%s-%s.s3-accesspoint.%s.amazonaws.com", name, parsed.AccountID, parsed.Region)
+}
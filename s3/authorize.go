@@ -0,0 +1,113 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+// authorize checks action against principal, or the principal stored in ctx
+// when the request carries none.
+func (s *s3Service) authorize(ctx context.Context, principal *authz.Principal, action authz.Action) error {
+	if !s.authorization {
+		return nil
+	}
+
+	if principal != nil {
+		return principal.Authorize(action)
+	}
+
+	return authz.Authorize(ctx, action)
+}
+
+// authorizeUpload authorizes data and, when its size is not known up front,
+// limits the body to the principal's MaxSize.
+func (s *s3Service) authorizeUpload(ctx context.Context, data UploadFileRequest) (UploadFileRequest, error) {
+	if !s.authorization {
+		return data, nil
+	}
+
+	principal, ok := authz.FromContext(ctx)
+	if data.Principal != nil {
+		principal, ok = *data.Principal, true
+	}
+	if !ok {
+		return data, authz.ErrUnauthenticated
+	}
+
+	size := knownSize(data)
+	action := authz.Action{Permission: authz.PermissionUpload, BucketName: data.BucketName, Filename: data.Filename, Size: max(size, 0)}
+	if err := principal.Authorize(action); err != nil {
+		return data, err
+	}
+
+	if size < 0 && principal.MaxSize > 0 {
+		if data.Body != nil {
+			data.Body = &authorizedBody{r: data.Body, remaining: principal.MaxSize, principal: principal}
+		} else {
+			// Base64 encodes every 3 bytes as 4 characters.
+			data.Base64Body = &authorizedBody{r: data.Base64Body, remaining: (principal.MaxSize + 2) / 3 * 4, principal: principal}
+		}
+	}
+
+	return data, nil
+}
+
+// authorizedBody fails once more than the principal may upload has been read.
+type authorizedBody struct {
+	r         io.Reader
+	remaining int64
+	principal authz.Principal
+}
+
+func (b *authorizedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.r.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, fmt.Errorf("%w: %s may not upload more than %d bytes", authz.ErrForbidden, b.principal.ID, b.principal.MaxSize)
+	}
+
+	return n, err
+}
+
+// authorizeKeys checks permission on every key before the operation touches
+// any of them, using the principal stored in ctx.
+func (s *s3Service) authorizeKeys(ctx context.Context, permission authz.Permission, bucketName string, keys ...string) error {
+	for _, key := range keys {
+		if err := s.authorize(ctx, nil, authz.Action{Permission: permission, BucketName: bucketName, Filename: key}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// authorizedItems keeps the items the principal in ctx may access with
+// permission. A grant on the prefix "a/b" does not cover listed keys like
+// "a/bc", so listings check every key even after checking their prefix.
+func authorizedItems[T any](ctx context.Context, s *s3Service, permission authz.Permission, items []T, object func(T) (bucketName, key string)) ([]T, error) {
+	if !s.authorization {
+		return items, nil
+	}
+
+	allowed := make([]T, 0, len(items))
+	for _, item := range items {
+		bucketName, key := object(item)
+		err := s.authorizeKeys(ctx, permission, bucketName, key)
+		if errors.Is(err, authz.ErrUnauthenticated) {
+			return nil, err
+		}
+		if err == nil {
+			allowed = append(allowed, item)
+		}
+	}
+
+	return allowed, nil
+}
@@ -0,0 +1,286 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+func TestAuthorization(t *testing.T) {
+	all := []authz.Permission{authz.PermissionUpload, authz.PermissionDownload, authz.PermissionDelete}
+	tests := []struct {
+		name      string
+		principal *authz.Principal
+		run       func(svc S3Service, principal *authz.Principal) error
+		wantErr   error
+	}{
+		{
+			name:      "upload allowed",
+			principal: &authz.Principal{ID: "p", Permissions: all},
+			run:       authorizedUpload("hello"),
+		},
+		{
+			name:    "upload without principal",
+			run:     authorizedUpload("hello"),
+			wantErr: authz.ErrUnauthenticated,
+		},
+		{
+			name:      "upload to another bucket",
+			principal: &authz.Principal{ID: "p", Permissions: all, Buckets: []string{"other"}},
+			run:       authorizedUpload("hello"),
+			wantErr:   authz.ErrForbidden,
+		},
+		{
+			name:      "streamed upload over max size",
+			principal: &authz.Principal{ID: "p", Permissions: all, MaxSize: 3},
+			run:       authorizedUpload("hello"),
+			wantErr:   authz.ErrForbidden,
+		},
+		{
+			name:      "download without permission",
+			principal: &authz.Principal{ID: "p", Permissions: []authz.Permission{authz.PermissionUpload}},
+			run: func(svc S3Service, principal *authz.Principal) error {
+				_, err := svc.DownloadFile(DownloadFileRequest{BucketName: "bucket", Filename: "existing.txt", Principal: principal})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "download allowed",
+			principal: &authz.Principal{ID: "p", Permissions: all},
+			run: func(svc S3Service, principal *authz.Principal) error {
+				_, err := svc.DownloadFile(DownloadFileRequest{BucketName: "bucket", Filename: "existing.txt", Principal: principal})
+				return err
+			},
+		},
+		{
+			name:      "delete outside prefixes",
+			principal: &authz.Principal{ID: "p", Permissions: all, Prefixes: []string{"mine/"}},
+			run: func(svc S3Service, principal *authz.Principal) error {
+				_, err := svc.DeleteFile(DeleteFileRequest{BucketName: "bucket", Filename: []string{"existing.txt"}, Principal: principal})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "existing.txt", "text/plain", []byte("existing"), nil)
+			svc := fake.service(WithAuthorization())
+
+			err := tt.run(svc, tt.principal)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if _, ok := fake.object("bucket", "existing.txt"); !ok {
+				t.Errorf("existing.txt was deleted")
+			}
+		})
+	}
+}
+
+func TestAuthorizationFromContext(t *testing.T) {
+	readOnly := authz.Principal{ID: "p", Permissions: []authz.Permission{authz.PermissionDownload}}
+	scoped := authz.Principal{ID: "p", Permissions: []authz.Permission{authz.PermissionUpload, authz.PermissionDownload, authz.PermissionDelete}, Prefixes: []string{"existing"}}
+	tests := []struct {
+		name      string
+		principal authz.Principal
+		run       func(ctx context.Context, svc S3Service) error
+		wantErr   error
+	}{
+		{
+			name:      "download",
+			principal: readOnly,
+			run: func(ctx context.Context, svc S3Service) error {
+				_, err := svc.DownloadFileContext(ctx, DownloadFileRequest{BucketName: "bucket", Filename: "existing.txt"})
+				return err
+			},
+		},
+		{
+			name:      "delete without permission",
+			principal: readOnly,
+			run: func(ctx context.Context, svc S3Service) error {
+				_, err := svc.DeleteFileContext(ctx, DeleteFileRequest{BucketName: "bucket", Filename: []string{"existing.txt"}})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "rename without delete permission",
+			principal: readOnly,
+			run: func(ctx context.Context, svc S3Service) error {
+				_, err := svc.RenameFile(ctx, "bucket", "existing.txt", "moved.txt", RenameOptions{})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "zip of a key sharing the granted prefix",
+			principal: scoped,
+			run: func(ctx context.Context, svc S3Service) error {
+				return svc.StreamZip(ctx, "bucket", []string{"existing.txt"}, io.Discard)
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "query without permission",
+			principal: authz.Principal{ID: "p", Permissions: []authz.Permission{authz.PermissionUpload}},
+			run: func(ctx context.Context, svc S3Service) error {
+				_, err := svc.QueryObject(ctx, QueryObjectRequest{BucketName: "bucket", Filename: "existing.txt", Expression: "SELECT * FROM s3object", InputFormat: QueryFormatCSV, OutputFormat: QueryFormatCSV})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "post policy outside prefixes",
+			principal: scoped,
+			run: func(ctx context.Context, svc S3Service) error {
+				_, err := svc.CreatePostPolicy(ctx, PostPolicyRequest{BucketName: "bucket", KeyPrefix: "existing"})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "listing outside prefixes",
+			principal: scoped,
+			run: func(ctx context.Context, svc S3Service) error {
+				files := svc.ListFiles(ctx, ListFilesRequest{BucketName: "bucket"})
+				for range files.All() {
+				}
+				return files.Err()
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "usage report without permission",
+			principal: authz.Principal{ID: "p", Permissions: []authz.Permission{authz.PermissionUpload}},
+			run: func(ctx context.Context, svc S3Service) error {
+				_, err := svc.GenerateUsageReport(ctx, UsageReportRequest{BucketName: "bucket"})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "stale upload cleanup without delete permission",
+			principal: readOnly,
+			run: func(ctx context.Context, svc S3Service) error {
+				_, err := svc.AbortStaleUploads(ctx, AbortStaleUploadsRequest{BucketName: "bucket", OlderThan: time.Hour})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "batch job without permission",
+			principal: readOnly,
+			run: func(ctx context.Context, svc S3Service) error {
+				_, err := svc.SubmitBatchJob(ctx, BatchJobRequest{
+					AccountID:   "123456789012",
+					RoleArn:     "arn:aws:iam::123456789012:role/batch",
+					BucketName:  "bucket",
+					Filenames:   []string{"existing.txt"},
+					ManifestKey: "manifest.csv",
+					Operation:   BatchTag,
+					Tags:        map[string]string{"a": "b"},
+				})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+		{
+			name:      "presigned multipart without permission",
+			principal: readOnly,
+			run: func(ctx context.Context, svc S3Service) error {
+				_, err := svc.CreatePresignedMultipart(ctx, PresignedMultipartRequest{BucketName: "bucket", Filename: "big.bin", Size: 1})
+				return err
+			},
+			wantErr: authz.ErrForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "existing.txt", "text/plain", []byte("existing"), nil)
+			svc := fake.service(WithAuthorization())
+
+			err := tt.run(authz.NewContext(context.Background(), tt.principal), svc)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if _, ok := fake.object("bucket", "existing.txt"); !ok {
+				t.Errorf("existing.txt was removed")
+			}
+		})
+	}
+}
+
+func TestAuthorizationFiltersListedKeys(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	catalog := newFakeCatalog()
+	svc := fake.service(WithAuthorization(), WithCatalog(catalog))
+	for _, key := range []string{"mine/a.txt", "mineral.txt"} {
+		fake.put("bucket", key, "text/plain", []byte("data"), nil)
+		catalog.Record(context.Background(), CatalogEntry{BucketName: "bucket", Key: key})
+	}
+
+	principal := authz.Principal{ID: "p", Permissions: []authz.Permission{authz.PermissionDownload}, Prefixes: []string{"mine"}}
+	ctx := authz.NewContext(context.Background(), principal)
+
+	files := svc.ListFiles(ctx, ListFilesRequest{BucketName: "bucket", Prefix: "mine"})
+	var listed []string
+	for file := range files.All() {
+		listed = append(listed, file.Key)
+	}
+	if err := files.Err(); err != nil {
+		t.Fatalf("ListFiles: %v", err)
+	}
+	if len(listed) != 1 || listed[0] != "mine/a.txt" {
+		t.Errorf("ListFiles = %v, want only mine/a.txt", listed)
+	}
+
+	entries, err := svc.QueryCatalog(ctx, CatalogQuery{BucketName: "bucket", Prefix: "mine"})
+	if err != nil {
+		t.Fatalf("QueryCatalog: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Key != "mine/a.txt" {
+		t.Errorf("QueryCatalog = %+v, want only mine/a.txt", entries)
+	}
+
+	report, err := svc.GenerateUsageReport(ctx, UsageReportRequest{BucketName: "bucket", Prefix: "mine"})
+	if err != nil {
+		t.Fatalf("GenerateUsageReport: %v", err)
+	}
+	if report.Total.ObjectCount != 1 {
+		t.Errorf("report counts %d objects, want 1", report.Total.ObjectCount)
+	}
+}
+
+func TestTenantDownloadKeepsPrincipal(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	fake.put("bucket", "acme/a.txt", "text/plain", []byte("data"), nil)
+	tenant := newFakeTenant(t, fake, Tenant{ID: "acme", BucketName: "bucket", Prefix: "acme"}, WithAuthorization())
+
+	principal := &authz.Principal{ID: "p", Permissions: []authz.Permission{authz.PermissionUpload}}
+	_, err := tenant.DownloadFile(DownloadFileRequest{Filename: "a.txt", Principal: principal})
+	if !errors.Is(err, authz.ErrForbidden) {
+		t.Errorf("DownloadFile = %v, want %v", err, authz.ErrForbidden)
+	}
+}
+
+func authorizedUpload(body string) func(svc S3Service, principal *authz.Principal) error {
+	return func(svc S3Service, principal *authz.Principal) error {
+		_, err := svc.UploadFile(UploadFileRequest{
+			BucketName:  "bucket",
+			Filename:    "a.txt",
+			ContentType: "text/plain",
+			Body:        io.NopCloser(strings.NewReader(body)),
+			Principal:   principal,
+		})
+		return err
+	}
+}
@@ -0,0 +1,306 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	controlTypes "github.com/aws/aws-sdk-go-v2/service/s3control/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+type BatchOperation string
+
+const (
+	BatchCopy       BatchOperation = "copy"
+	BatchTag        BatchOperation = "tag"
+	BatchDeleteTags BatchOperation = "delete-tags"
+	BatchRestore    BatchOperation = "restore"
+	// BatchInvoke runs a Lambda function per object. S3 Batch Operations has no
+	// native delete, so bulk deletes are done through a Lambda function.
+	BatchInvoke BatchOperation = "invoke"
+)
+
+const defaultBatchJobPollInterval = 30 * time.Second
+
+// SubmitBatchJob writes a CSV manifest of the given keys and submits an S3 Batch
+// Operations job over it, returning the job ID.
+func (s *s3Service) SubmitBatchJob(ctx context.Context, data BatchJobRequest) (string, error) {
+	if err := s.acquire(); err != nil {
+		return "", err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateBatchJob(data); err != nil {
+		return "", err
+	}
+
+	if err := s.authorizeBatchJob(ctx, data); err != nil {
+		return "", err
+	}
+
+	data, err := s.resolveBatchBuckets(data)
+	if err != nil {
+		return "", err
+	}
+
+	operation, err := s.batchOperation(data)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := s.putBatchManifest(ctx, data)
+	if err != nil {
+		return "", err
+	}
+
+	token := s.newID()
+	input := &s3control.CreateJobInput{
+		AccountId:            aws.String(data.AccountID),
+		RoleArn:              aws.String(data.RoleArn),
+		ClientRequestToken:   aws.String(token),
+		ConfirmationRequired: aws.Bool(false),
+		Description:          aws.String(data.Description),
+		Priority:             aws.Int32(data.Priority),
+		Operation:            operation,
+		Manifest:             manifest,
+		Report:               &controlTypes.JobReport{Enabled: false},
+	}
+	if data.ReportBucket != "" {
+		input.Report = &controlTypes.JobReport{
+			Enabled:     true,
+			Bucket:      aws.String(s.bucketArn(data.ReportBucket)),
+			Prefix:      aws.String(data.ReportPrefix),
+			Format:      controlTypes.JobReportFormatReportCsv20180820,
+			ReportScope: controlTypes.JobReportScopeFailedTasksOnly,
+		}
+	}
+
+	output, err := s.controlCli.CreateJob(ctx, input)
+	if err != nil {
+		log.Printf("failed to create batch job on bucket %s: %v", data.BucketName, err)
+		return "", fmt.Errorf("failed to create batch job: %w", err)
+	}
+
+	return aws.ToString(output.JobId), nil
+}
+
+func (s *s3Service) GetBatchJobStatus(ctx context.Context, data BatchJobStatusRequest) (BatchJobStatus, error) {
+	if err := s.acquire(); err != nil {
+		return BatchJobStatus{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	return s.batchJobStatus(ctx, data)
+}
+
+func (s *s3Service) batchJobStatus(ctx context.Context, data BatchJobStatusRequest) (BatchJobStatus, error) {
+	if data.AccountID == "" || data.JobID == "" {
+		return BatchJobStatus{}, errors.New("account id and job id are required")
+	}
+
+	output, err := s.controlCli.DescribeJob(ctx, &s3control.DescribeJobInput{
+		AccountId: aws.String(data.AccountID),
+		JobId:     aws.String(data.JobID),
+	})
+	if err != nil {
+		log.Printf("failed to describe batch job %s: %v", data.JobID, err)
+		return BatchJobStatus{}, fmt.Errorf("failed to describe batch job: %w", err)
+	}
+
+	job := output.Job
+	status := BatchJobStatus{
+		JobID:  aws.ToString(job.JobId),
+		Status: string(job.Status),
+		Reason: aws.ToString(job.StatusUpdateReason),
+	}
+	if job.ProgressSummary != nil {
+		status.TotalTasks = aws.ToInt64(job.ProgressSummary.TotalNumberOfTasks)
+		status.SucceededTasks = aws.ToInt64(job.ProgressSummary.NumberOfTasksSucceeded)
+		status.FailedTasks = aws.ToInt64(job.ProgressSummary.NumberOfTasksFailed)
+	}
+	for _, failure := range job.FailureReasons {
+		status.Failures = append(status.Failures, aws.ToString(failure.FailureReason))
+	}
+
+	return status, nil
+}
+
+// WaitForBatchJob polls until the job reaches a terminal state or ctx is done.
+func (s *s3Service) WaitForBatchJob(ctx context.Context, data BatchJobStatusRequest, interval time.Duration) (BatchJobStatus, error) {
+	if err := s.acquire(); err != nil {
+		return BatchJobStatus{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if interval <= 0 {
+		interval = defaultBatchJobPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status, err := s.batchJobStatus(ctx, data)
+		if err != nil {
+			return status, err
+		}
+
+		if status.Done() {
+			return status, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (b BatchJobStatus) Done() bool {
+	switch controlTypes.JobStatus(b.Status) {
+	case controlTypes.JobStatusComplete, controlTypes.JobStatusFailed, controlTypes.JobStatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// authorizeBatchJob checks every key the job touches, and the manifest and
+// report it writes, before anything is written. Lambda functions usually
+// delete, so invoking one needs delete permission.
+func (s *s3Service) authorizeBatchJob(ctx context.Context, data BatchJobRequest) error {
+	manifestBucket := data.ManifestBucket
+	if manifestBucket == "" {
+		manifestBucket = data.BucketName
+	}
+	if err := s.authorizeKeys(ctx, authz.PermissionUpload, manifestBucket, data.ManifestKey); err != nil {
+		return err
+	}
+
+	if data.ReportBucket != "" {
+		if err := s.authorizeKeys(ctx, authz.PermissionUpload, data.ReportBucket, data.ReportPrefix); err != nil {
+			return err
+		}
+	}
+
+	switch data.Operation {
+	case BatchCopy:
+		if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.BucketName, data.Filenames...); err != nil {
+			return err
+		}
+		for _, filename := range data.Filenames {
+			if err := s.authorizeKeys(ctx, authz.PermissionUpload, data.DestinationBucket, data.DestinationPrefix+filename); err != nil {
+				return err
+			}
+		}
+		return nil
+	case BatchInvoke:
+		return s.authorizeKeys(ctx, authz.PermissionDelete, data.BucketName, data.Filenames...)
+	default:
+		return s.authorizeKeys(ctx, authz.PermissionUpload, data.BucketName, data.Filenames...)
+	}
+}
+
+// resolveBatchBuckets resolves every bucket of data up front: S3 Control takes
+// bucket ARNs and manifests list buckets, neither of which goes through the
+// middleware that resolves logical names and MRAP aliases.
+func (s *s3Service) resolveBatchBuckets(data BatchJobRequest) (BatchJobRequest, error) {
+	for _, bucketName := range []*string{&data.BucketName, &data.ManifestBucket, &data.ReportBucket, &data.DestinationBucket} {
+		resolved, err := s.resolveBucket(*bucketName)
+		if err != nil {
+			return BatchJobRequest{}, err
+		}
+		*bucketName = resolved
+	}
+
+	return data, nil
+}
+
+func (s *s3Service) putBatchManifest(ctx context.Context, data BatchJobRequest) (*controlTypes.JobManifest, error) {
+	manifestBucket := data.ManifestBucket
+	if manifestBucket == "" {
+		manifestBucket = data.BucketName
+	}
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	for _, filename := range data.Filenames {
+		// Keys in batch manifests must be URL-encoded.
+		if err := writer.Write([]string{data.BucketName, url.QueryEscape(filename)}); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+
+	output, err := s.s3Cli.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(manifestBucket),
+		Key:         aws.String(data.ManifestKey),
+		ContentType: aws.String("text/csv"),
+		Body:        bytes.NewReader(buffer.Bytes()),
+	})
+	if err != nil {
+		log.Printf("failed to write batch manifest %s: %v", data.ManifestKey, err)
+		return nil, fmt.Errorf("failed to write batch manifest: %w", err)
+	}
+
+	return &controlTypes.JobManifest{
+		Spec: &controlTypes.JobManifestSpec{
+			Format: controlTypes.JobManifestFormatS3BatchOperationsCsv20180820,
+			Fields: []controlTypes.JobManifestFieldName{controlTypes.JobManifestFieldNameBucket, controlTypes.JobManifestFieldNameKey},
+		},
+		Location: &controlTypes.JobManifestLocation{
+			ObjectArn: aws.String(s.bucketArn(manifestBucket) + "/" + data.ManifestKey),
+			ETag:      output.ETag,
+		},
+	}, nil
+}
+
+func (s *s3Service) batchOperation(data BatchJobRequest) (*controlTypes.JobOperation, error) {
+	switch data.Operation {
+	case BatchCopy:
+		copyOperation := &controlTypes.S3CopyObjectOperation{
+			TargetResource:    aws.String(s.bucketArn(data.DestinationBucket)),
+			MetadataDirective: controlTypes.S3MetadataDirectiveCopy,
+		}
+		if data.DestinationPrefix != "" {
+			copyOperation.TargetKeyPrefix = aws.String(data.DestinationPrefix)
+		}
+		return &controlTypes.JobOperation{S3PutObjectCopy: copyOperation}, nil
+	case BatchTag:
+		tagSet := make([]controlTypes.S3Tag, 0, len(data.Tags))
+		for key, value := range data.Tags {
+			tagSet = append(tagSet, controlTypes.S3Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+		return &controlTypes.JobOperation{S3PutObjectTagging: &controlTypes.S3SetObjectTaggingOperation{TagSet: tagSet}}, nil
+	case BatchDeleteTags:
+		return &controlTypes.JobOperation{S3DeleteObjectTagging: &controlTypes.S3DeleteObjectTaggingOperation{}}, nil
+	case BatchRestore:
+		return &controlTypes.JobOperation{S3InitiateRestoreObject: &controlTypes.S3InitiateRestoreObjectOperation{
+			ExpirationInDays: aws.Int32(data.RestoreDays),
+			GlacierJobTier:   controlTypes.S3GlacierJobTierBulk,
+		}}, nil
+	case BatchInvoke:
+		return &controlTypes.JobOperation{LambdaInvoke: &controlTypes.LambdaInvokeOperation{
+			FunctionArn: aws.String(data.LambdaArn),
+		}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported batch operation %q", data.Operation)
+	}
+}
@@ -0,0 +1,109 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func TestBucketArn(t *testing.T) {
+	tests := []struct {
+		name     string
+		region   string
+		endpoint string
+		want     string
+	}{
+		{name: "commercial", region: "eu-west-1", want: "arn:aws:s3:::bucket"},
+		{name: "china", region: "cn-north-1", want: "arn:aws-cn:s3:::bucket"},
+		{name: "govcloud", region: "us-gov-west-1", want: "arn:aws-us-gov:s3:::bucket"},
+		{name: "iso-b", region: "us-isob-east-1", want: "arn:aws-iso-b:s3:::bucket"},
+		{name: "china endpoint", endpoint: "https://s3.cn-northwest-1.amazonaws.com.cn", want: "arn:aws-cn:s3:::bucket"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &s3Service{awsCfg: aws.Config{Region: tt.region}, endpoint: tt.endpoint}
+			if got := s.bucketArn("bucket"); got != tt.want {
+				t.Errorf("bucketArn = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveBatchBuckets(t *testing.T) {
+	s := &s3Service{
+		awsCfg:      aws.Config{Region: "eu-west-1"},
+		accountID:   "123456789012",
+		bucketNames: BucketNames{"uploads": "uploads-prod", "reports": "reports-prod"},
+	}
+
+	data, err := s.resolveBatchBuckets(BatchJobRequest{
+		BucketName:        "uploads",
+		ReportBucket:      "reports",
+		DestinationBucket: "mfzwi23gnjvgw.mrap",
+	})
+	if err != nil {
+		t.Fatalf("resolveBatchBuckets: %v", err)
+	}
+
+	if data.BucketName != "uploads-prod" || data.ManifestBucket != "" || data.ReportBucket != "reports-prod" {
+		t.Errorf("buckets = %q, %q, %q, want logical names resolved", data.BucketName, data.ManifestBucket, data.ReportBucket)
+	}
+	if got, want := s.bucketArn(data.DestinationBucket), "arn:aws:s3::123456789012:accesspoint/mfzwi23gnjvgw.mrap"; got != want {
+		t.Errorf("destination arn = %s, want %s", got, want)
+	}
+}
+
+type controlTransport struct {
+	requests []*http.Request
+}
+
+func (c *controlTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, r)
+	body := `<DescribeJobResult><Job><JobId>job</JobId><Status>Complete</Status></Job></DescribeJobResult>`
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/xml"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}, nil
+}
+
+func TestBatchJobUsesServiceClientOptions(t *testing.T) {
+	// A CA bundle cannot be applied to a plain *http.Client.
+	t.Setenv("AWS_CA_BUNDLE", "")
+	t.Setenv("AWS_REGION", "us-east-1")
+	transport := &controlTransport{}
+	svc := NewS3Service("us-east-1",
+		WithCredentials(credentials.NewStaticCredentialsProvider("test", "test", "")),
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithHeaders(http.Header{"X-Trace": {"trace"}}),
+		WithDualStack(),
+	)
+
+	for range 2 {
+		status, err := svc.GetBatchJobStatus(context.Background(), BatchJobStatusRequest{AccountID: "123456789012", JobID: "job"})
+		if err != nil {
+			t.Fatalf("GetBatchJobStatus: %v", err)
+		}
+		if status.JobID != "job" {
+			t.Errorf("job id = %q, want job", status.JobID)
+		}
+	}
+
+	if len(transport.requests) != 2 {
+		t.Fatalf("sent %d requests, want 2", len(transport.requests))
+	}
+	for _, r := range transport.requests {
+		if got := r.Header.Get("X-Trace"); got != "trace" {
+			t.Errorf("X-Trace = %q, want the service headers on control requests", got)
+		}
+		if !strings.Contains(r.URL.Host, "dualstack") {
+			t.Errorf("host = %s, want a dual-stack endpoint", r.URL.Host)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type DeleteStatus string
+
+const (
+	Deleted            DeleteStatus = "deleted"
+	Trashed            DeleteStatus = "trashed"
+	Restored           DeleteStatus = "restored"
+	DeleteNotFound     DeleteStatus = "not_found"
+	DeleteAccessDenied DeleteStatus = "access_denied"
+	DeleteFailed       DeleteStatus = "failed"
+	DeleteProtected    DeleteStatus = "protected"
+	WouldDelete        DeleteStatus = "would_delete"
+)
+
+// Succeeded returns the keys the operation was applied to.
+func (r BatchResult) Succeeded() []string {
+	keys := []string{}
+	for _, result := range r.Results {
+		if result.Err == nil && result.Status != WouldDelete {
+			keys = append(keys, result.Key)
+		}
+	}
+
+	return keys
+}
+
+// Err joins the errors of keys that could not be deleted. Keys that did not
+// exist are left out, as deleting them is already a no-op.
+func (r BatchResult) Err() error {
+	var errs []error
+	for _, result := range r.Results {
+		if result.Err != nil && result.Status != DeleteNotFound {
+			errs = append(errs, result.Err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func keyFailure(key string, err error) KeyResult {
+	var respErr *awsHttp.ResponseError
+	if errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusForbidden {
+		return KeyResult{Key: key, Status: DeleteAccessDenied, Err: fmt.Errorf("%s: %w", key, ErrAccessDenied)}
+	}
+	if isNotFound(err) {
+		return KeyResult{Key: key, Status: DeleteNotFound, Err: ErrFileNotFound}
+	}
+
+	return KeyResult{Key: key, Status: DeleteFailed, Err: fmt.Errorf("%s: %w", key, err)}
+}
+
+func deleteObjectsFailure(deleteErr types.Error) KeyResult {
+	key := aws.ToString(deleteErr.Key)
+	switch aws.ToString(deleteErr.Code) {
+	case "AccessDenied":
+		return KeyResult{Key: key, Status: DeleteAccessDenied, Err: fmt.Errorf("%s: %w", key, ErrAccessDenied)}
+	case "NoSuchKey":
+		return KeyResult{Key: key, Status: DeleteNotFound, Err: ErrFileNotFound}
+	default:
+		return KeyResult{
+			Key:    key,
+			Status: DeleteFailed,
+			Err:    fmt.Errorf("%s: %s: %s", key, aws.ToString(deleteErr.Code), aws.ToString(deleteErr.Message)),
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// breakerMiddleware wraps each operation, after retries, in the circuit
+// breaker so a degraded region fails fast with breaker.ErrOpen. Only server
+// errors, throttling and transport failures count; client errors such as 404
+// say nothing about the service's health.
+func (s *s3Service) breakerMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("FileUploaderCircuitBreaker", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		done, err := s.breaker.Allow()
+		if err != nil {
+			return middleware.InitializeOutput{}, middleware.Metadata{}, err
+		}
+
+		out, metadata, err := next.HandleInitialize(ctx, in)
+		if isServiceFailure(err) {
+			done(err)
+		} else {
+			done(nil)
+		}
+
+		return out, metadata, err
+	}), middleware.Before)
+}
+
+func isServiceFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	// 503 SlowDown is a ResponseError too.
+	var respErr *awsHttp.ResponseError
+	if errors.As(err, &respErr) {
+		status := respErr.HTTPStatusCode()
+		return status >= http.StatusInternalServerError || status == http.StatusTooManyRequests
+	}
+
+	// Anything else only counts if it came from the transport; validation,
+	// serialization and credential errors are raised before a request is sent.
+	var sendErr *smithyhttp.RequestSendError
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &sendErr) || errors.As(err, &netErr)
+}
@@ -0,0 +1,53 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+func responseError(status int) error {
+	return &awsHttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+		Err:      fmt.Errorf("status %d", status),
+	}}
+}
+
+func TestIsServiceFailure(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "success", err: nil},
+		{name: "cancelled", err: fmt.Errorf("operation error: %w", context.Canceled)},
+		{name: "timeout", err: fmt.Errorf("operation error: %w", context.DeadlineExceeded), want: true},
+		{name: "server error", err: responseError(http.StatusInternalServerError), want: true},
+		{name: "slow down", err: responseError(http.StatusServiceUnavailable), want: true},
+		{name: "too many requests", err: responseError(http.StatusTooManyRequests), want: true},
+		{name: "retries exhausted", err: &retry.MaxAttemptsError{Attempt: 3, Err: responseError(http.StatusServiceUnavailable)}, want: true},
+		{name: "not found", err: responseError(http.StatusNotFound)},
+		{name: "forbidden", err: responseError(http.StatusForbidden)},
+		{name: "connection refused", err: &smithyhttp.RequestSendError{Err: dialErr}, want: true},
+		{name: "net error", err: dialErr, want: true},
+		{name: "invalid params", err: &smithy.InvalidParamsError{Context: "PutObjectInput"}},
+		{name: "credentials", err: errors.New("failed to refresh cached credentials")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isServiceFailure(tt.err); got != tt.want {
+				t.Errorf("isServiceFailure(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
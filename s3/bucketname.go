@@ -0,0 +1,145 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+type (
+	// BucketTemplate names the buckets of an application per environment, so
+	// the same code runs against dev, stage and prod buckets.
+	BucketTemplate struct {
+		// Vars fill the {name} placeholders of the templates, e.g.
+		// {"app": "billing", "env": "prod"}.
+		Vars map[string]string
+		// Prefix is prepended to every resolved name.
+		Prefix string
+		// Buckets maps the names callers use onto templates, e.g.
+		// "uploads": "{app}-{env}-uploads".
+		Buckets map[string]string
+	}
+
+	// BucketNames maps logical bucket names onto resolved S3 bucket names.
+	BucketNames map[string]string
+)
+
+var (
+	reservedBucketPrefixes = []string{"xn--", "sthree-", "amzn-s3-demo-"}
+	reservedBucketSuffixes = []string{"-s3alias", "--ol-s3", ".mrap", "--x-s3", "--table-s3"}
+)
+
+// ResolveBucketNames expands every template of t and checks the results
+// against the S3 bucket naming rules. All problems are reported together.
+func ResolveBucketNames(t BucketTemplate) (BucketNames, error) {
+	names := make(BucketNames, len(t.Buckets))
+	var errs []error
+	for _, alias := range slices.Sorted(maps.Keys(t.Buckets)) {
+		template := t.Buckets[alias]
+		name, err := expandBucketTemplate(template, t.Vars)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("bucket %s: %w", alias, err))
+			continue
+		}
+
+		name = t.Prefix + name
+		if err := ValidateBucketName(name); err != nil {
+			errs = append(errs, fmt.Errorf("bucket %s: %w", alias, err))
+			continue
+		}
+		names[alias] = name
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return names, nil
+}
+
+// Name returns the resolved name of alias, or alias itself when it is not a
+// logical name.
+func (n BucketNames) Name(alias string) string {
+	if name, ok := n[alias]; ok {
+		return name
+	}
+	return alias
+}
+
+func expandBucketTemplate(template string, vars map[string]string) (string, error) {
+	var name strings.Builder
+	for rest := template; rest != ""; {
+		start := strings.IndexAny(rest, "{}")
+		if start < 0 {
+			name.WriteString(rest)
+			break
+		}
+		if rest[start] == '}' {
+			return "", fmt.Errorf("unexpected } in template %q", template)
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed { in template %q", template)
+		}
+
+		key := rest[start+1 : start+end]
+		value, ok := vars[key]
+		if !ok {
+			return "", fmt.Errorf("template %q uses undefined variable %s", template, key)
+		}
+
+		name.WriteString(rest[:start])
+		name.WriteString(value)
+		rest = rest[start+end+1:]
+	}
+
+	return name.String(), nil
+}
+
+// ValidateBucketName checks name against the naming rules of general purpose
+// buckets.
+func ValidateBucketName(name string) error {
+	if len(name) < 3 || len(name) > 63 {
+		return fmt.Errorf("bucket name %q must be between 3 and 63 characters", name)
+	}
+
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+			return fmt.Errorf("bucket name %q may only contain lowercase letters, digits, dots and hyphens", name)
+		}
+	}
+
+	if !isAlphanumeric(name[0]) || !isAlphanumeric(name[len(name)-1]) {
+		return fmt.Errorf("bucket name %q must begin and end with a letter or digit", name)
+	}
+
+	if strings.Contains(name, "..") {
+		return fmt.Errorf("bucket name %q must not contain adjacent dots", name)
+	}
+
+	if _, err := netip.ParseAddr(name); err == nil {
+		return fmt.Errorf("bucket name %q must not be formatted as an IP address", name)
+	}
+
+	for _, prefix := range reservedBucketPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return fmt.Errorf("bucket name %q must not start with the reserved prefix %s", name, prefix)
+		}
+	}
+
+	for _, suffix := range reservedBucketSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return fmt.Errorf("bucket name %q must not end with the reserved suffix %s", name, suffix)
+		}
+	}
+
+	return nil
+}
+
+func isAlphanumeric(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,214 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// BufferPool hands out multipart part buffers under a global memory cap. A pool
+// can be shared by several services, so the cap holds for the whole process no
+// matter how many uploads run at once; uploads wait for a free buffer instead of
+// allocating PartSize×Concurrency bytes each.
+type BufferPool struct {
+	partSize int64
+	maxParts int32
+	slots    chan struct{}
+	buffers  sync.Pool
+}
+
+// NewBufferPool caps part buffers at maxMemory bytes in total. partSize is
+// raised to the 5 MiB S3 minimum when smaller, and at least one buffer is always
+// available.
+func NewBufferPool(partSize, maxMemory int64) *BufferPool {
+	partSize = max(partSize, manager.MinUploadPartSize)
+
+	p := &BufferPool{
+		partSize: partSize,
+		maxParts: manager.MaxUploadParts,
+		slots:    make(chan struct{}, max(maxMemory/partSize, 1)),
+	}
+	p.buffers.New = func() any {
+		buf := make([]byte, partSize)
+		return &buf
+	}
+
+	return p
+}
+
+// Get blocks until the memory cap allows another buffer or ctx is done.
+func (p *BufferPool) Get(ctx context.Context) (*[]byte, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	return p.buffers.Get().(*[]byte), nil
+}
+
+func (p *BufferPool) Put(buf *[]byte) {
+	p.buffers.Put(buf)
+	<-p.slots
+}
+
+// fill reads the next part of r into a pooled buffer. last is set once r is
+// exhausted, in which case n may be smaller than the part size.
+func (p *BufferPool) fill(ctx context.Context, r io.Reader) (buf *[]byte, n int, last bool, err error) {
+	if buf, err = p.Get(ctx); err != nil {
+		return nil, 0, false, err
+	}
+
+	n, err = io.ReadFull(r, *buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return buf, n, true, nil
+	}
+	if err != nil {
+		p.Put(buf)
+		return nil, 0, false, err
+	}
+
+	return buf, n, false, nil
+}
+
+func isReaderAtSeeker(r io.Reader) bool {
+	_, readerAt := r.(io.ReaderAt)
+	_, seeker := r.(io.Seeker)
+	return readerAt && seeker
+}
+
+// pooledUpload uploads a non-seekable body with part buffers taken from the
+// service's BufferPool, and returns the object location.
+func (s *s3Service) pooledUpload(ctx context.Context, input *s3.PutObjectInput, optFns ...func(*s3.Options)) (string, error) {
+	pool := s.bufferPool
+	first, n, last, err := pool.fill(ctx, input.Body)
+	if err != nil {
+		return "", err
+	}
+
+	if last {
+		defer pool.Put(first)
+
+		single := *input
+		single.Body = bytes.NewReader((*first)[:n])
+		output, err := manager.NewUploader(s.s3Cli, manager.WithUploaderRequestOptions(optFns...)).Upload(ctx, &single)
+		if err != nil {
+			return "", err
+		}
+		return output.Location, nil
+	}
+
+	upload, err := s.s3Cli.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      input.Bucket,
+		Key:         input.Key,
+		ContentType: input.ContentType,
+		Metadata:    input.Metadata,
+		Tagging:     input.Tagging,
+	}, optFns...)
+	if err != nil {
+		pool.Put(first)
+		return "", err
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		parts     []types.CompletedPart
+		uploadErr error
+	)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if uploadErr == nil {
+			uploadErr = err
+			cancel()
+		}
+	}
+
+	sem := make(chan struct{}, manager.DefaultUploadConcurrency)
+	buf := first
+	for number := int32(1); ; number++ {
+		// A body of exactly maxParts parts ends with an empty read.
+		if number > pool.maxParts && n > 0 {
+			pool.Put(buf)
+			fail(fmt.Errorf("upload exceeds %d parts", pool.maxParts))
+			break
+		}
+
+		if n > 0 {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(number int32, buf *[]byte, n int) {
+				defer func() {
+					pool.Put(buf)
+					<-sem
+					wg.Done()
+				}()
+
+				part, err := s.s3Cli.UploadPart(ctx, &s3.UploadPartInput{
+					Bucket:        input.Bucket,
+					Key:           input.Key,
+					UploadId:      upload.UploadId,
+					PartNumber:    aws.Int32(number),
+					Body:          bytes.NewReader((*buf)[:n]),
+					ContentLength: aws.Int64(int64(n)),
+				}, optFns...)
+				if err != nil {
+					fail(err)
+					return
+				}
+
+				mu.Lock()
+				parts = append(parts, types.CompletedPart{ETag: part.ETag, PartNumber: aws.Int32(number)})
+				mu.Unlock()
+			}(number, buf, n)
+		} else {
+			pool.Put(buf)
+		}
+
+		if last || ctx.Err() != nil {
+			break
+		}
+
+		if buf, n, last, err = pool.fill(ctx, input.Body); err != nil {
+			fail(err)
+			break
+		}
+	}
+	wg.Wait()
+
+	if uploadErr != nil {
+		log.Printf("failed to upload parts of %s: %v", aws.ToString(input.Key), uploadErr)
+		s.abortMultipart(aws.ToString(input.Bucket), aws.ToString(input.Key), upload.UploadId)
+		return "", uploadErr
+	}
+
+	slices.SortFunc(parts, func(a, b types.CompletedPart) int {
+		return int(aws.ToInt32(a.PartNumber) - aws.ToInt32(b.PartNumber))
+	})
+	output, err := s.s3Cli.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          input.Bucket,
+		Key:             input.Key,
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	}, optFns...)
+	if err != nil {
+		s.abortMultipart(aws.ToString(input.Bucket), aws.ToString(input.Key), upload.UploadId)
+		return "", err
+	}
+
+	return aws.ToString(output.Location), nil
+}
@@ -0,0 +1,117 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestBufferPoolGetBlocksAtCap(t *testing.T) {
+	pool := NewBufferPool(manager.MinUploadPartSize, manager.MinUploadPartSize)
+	buf, err := pool.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Get(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get past the cap = %v, want it to block until the deadline", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := pool.Get(context.Background())
+		done <- err
+	}()
+	pool.Put(buf)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Get after Put: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Get still blocked after a buffer was returned")
+	}
+}
+
+func TestPooledUpload(t *testing.T) {
+	const partSize = manager.MinUploadPartSize
+	tests := []struct {
+		name          string
+		size          int64
+		maxParts      int32
+		failPart      string
+		wantErr       bool
+		wantMultipart bool
+	}{
+		{name: "single part", size: 1024},
+		{name: "multipart", size: 2*partSize + 10, maxParts: 3, wantMultipart: true},
+		{name: "exactly max parts", size: 2 * partSize, maxParts: 2, wantMultipart: true},
+		{name: "too many parts", size: 2*partSize + 10, maxParts: 2, wantErr: true, wantMultipart: true},
+		{name: "failed part", size: 3 * partSize, maxParts: 3, failPart: "2", wantErr: true, wantMultipart: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			if tt.failPart != "" {
+				fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+					if r.Method != http.MethodPut || r.URL.Query().Get("partNumber") != tt.failPart {
+						return false
+					}
+					fakeError(w, http.StatusInternalServerError, "InternalError")
+					return true
+				}
+			}
+			pool := NewBufferPool(partSize, 2*partSize)
+			if tt.maxParts > 0 {
+				pool.maxParts = tt.maxParts
+			}
+			svc := fake.service(WithBufferPool(pool))
+
+			content := randomBytes(7, int(tt.size))
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "big.bin",
+				ContentType: "application/octet-stream",
+				Body:        io.NopCloser(bytes.NewReader(content)),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UploadFile error = %v, want error: %v", err, tt.wantErr)
+			}
+
+			object, stored := fake.object("bucket", "big.bin")
+			switch {
+			case tt.wantErr && stored:
+				t.Error("object stored despite a failed upload")
+			case !tt.wantErr && (!stored || !bytes.Equal(object.body, content)):
+				t.Errorf("stored object = %v, want the %d uploaded bytes", stored, len(content))
+			}
+
+			fake.mu.Lock()
+			multipart, aborted := false, false
+			for _, request := range fake.requests {
+				multipart = multipart || strings.HasPrefix(request, "POST ") && strings.Contains(request, "?uploads")
+				aborted = aborted || strings.HasPrefix(request, "DELETE ") && strings.Contains(request, "uploadId=")
+			}
+			pending := len(fake.uploads)
+			fake.mu.Unlock()
+			if multipart != tt.wantMultipart {
+				t.Errorf("multipart upload created = %v, want %v", multipart, tt.wantMultipart)
+			}
+			if aborted != tt.wantErr || pending != 0 {
+				t.Errorf("aborted = %v with %d uploads pending, want aborted = %v and none pending", aborted, pending, tt.wantErr)
+			}
+			if held := len(pool.slots); held != 0 {
+				t.Errorf("%d buffers still held, want all returned to the pool", held)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// BulkDeleteOptions tune how deletes of many keys are split into DeleteObjects
+// calls. Batches that S3 throttles are retried with exponential backoff.
+type BulkDeleteOptions struct {
+	// BatchSize is capped at the DeleteObjects limit of 1000 keys.
+	BatchSize   int
+	Concurrency int
+	// MaxRetries defaults to 5; a negative value disables retries.
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+func (o BulkDeleteOptions) withDefaults() BulkDeleteOptions {
+	if o.BatchSize <= 0 || o.BatchSize > maxDeleteObjects {
+		o.BatchSize = maxDeleteObjects
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = 4
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	} else if o.MaxRetries == 0 {
+		o.MaxRetries = 5
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = 200 * time.Millisecond
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = 10 * time.Second
+	}
+
+	return o
+}
+
+// backoff returns the delay before retry attempt (0-based), with full jitter
+// so parallel batches do not retry in lockstep.
+func (o BulkDeleteOptions) backoff(attempt int) time.Duration {
+	delay := min(o.MinBackoff<<attempt, o.MaxBackoff)
+	return time.Duration(rand.Int64N(int64(delay)) + 1)
+}
+
+// throttledCodes are DeleteObjects errors, for the whole call or a single key,
+// that are worth retrying after a pause.
+var throttledCodes = map[string]bool{
+	"SlowDown":             true,
+	"Throttling":           true,
+	"ThrottlingException":  true,
+	"RequestLimitExceeded": true,
+	"ServiceUnavailable":   true,
+	"InternalError":        true,
+}
+
+func isThrottled(err error) bool {
+	var apiError smithy.APIError
+	if errors.As(err, &apiError) && throttledCodes[apiError.ErrorCode()] {
+		return true
+	}
+
+	var respErr *awsHttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusServiceUnavailable
+}
+
+// deleteKeys deletes keys in parallel batches, recording the outcome of every
+// key in result in the order of keys, and returns the keys that were removed.
+func (s *s3Service) deleteKeys(ctx context.Context, bucketName string, keys []string, result *BatchResult) []string {
+	objects := make([]types.ObjectIdentifier, 0, len(keys))
+	for _, key := range keys {
+		objects = append(objects, types.ObjectIdentifier{Key: aws.String(key)})
+	}
+
+	return s.deleteObjects(ctx, bucketName, objects, result)
+}
+
+// deleteObjects is deleteKeys for objects that may name a version.
+func (s *s3Service) deleteObjects(ctx context.Context, bucketName string, objects []types.ObjectIdentifier, result *BatchResult) []string {
+	opts := s.bulkDelete.withDefaults()
+
+	var batches [][]types.ObjectIdentifier
+	for start := 0; start < len(objects); start += opts.BatchSize {
+		batches = append(batches, objects[start:min(start+opts.BatchSize, len(objects))])
+	}
+
+	results := make([][]KeyResult, len(batches))
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			results[i] = s.deleteBatch(ctx, bucketName, batch, opts)
+		}()
+	}
+	wg.Wait()
+
+	deleted := make([]string, 0, len(objects))
+	for _, batch := range results {
+		for _, keyResult := range batch {
+			if keyResult.Status == Deleted {
+				deleted = append(deleted, keyResult.Key)
+			}
+		}
+		result.Results = append(result.Results, batch...)
+	}
+
+	return deleted
+}
+
+// existingKeys checks the keys of a delete request in parallel and returns
+// those that exist and may be deleted by the requester; the others are
+// recorded in result. Only dry runs and ownership checks need to look at the
+// keys up front; otherwise every key is returned and DeleteObjects reports
+// each one's outcome.
+func (s *s3Service) existingKeys(ctx context.Context, data DeleteFileRequest, keys []string, result *BatchResult) []string {
+	ownership := data.RequesterID != "" && !data.IgnoreOwnership
+	if !data.DryRun && !ownership {
+		return keys
+	}
+
+	opts := s.bulkDelete.withDefaults()
+
+	outcomes := make([]*KeyResult, len(keys))
+	sem := make(chan struct{}, opts.Concurrency)
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(data.BucketName),
+				Key:    aws.String(key),
+			})
+			switch {
+			case err != nil:
+				failure := keyFailure(key, err)
+				outcomes[i] = &failure
+			case ownership:
+				if denied, ok := authorizeDelete(ctx, data, key, head.Metadata); !ok {
+					outcomes[i] = &denied
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	existing := make([]string, 0, len(keys))
+	for i, key := range keys {
+		if outcomes[i] != nil {
+			result.Results = append(result.Results, *outcomes[i])
+			continue
+		}
+		existing = append(existing, key)
+	}
+
+	return existing
+}
+
+// deleteBatch deletes up to 1000 objects, retrying throttled calls and keys.
+func (s *s3Service) deleteBatch(ctx context.Context, bucketName string, batch []types.ObjectIdentifier, opts BulkDeleteOptions) []KeyResult {
+	outcomes := make(map[string]KeyResult, len(batch))
+	pending := batch
+	for attempt := 0; len(pending) > 0; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(opts.backoff(attempt - 1)):
+			case <-ctx.Done():
+				for _, object := range pending {
+					outcomes[objectID(object.Key, object.VersionId)] = keyFailure(aws.ToString(object.Key), ctx.Err())
+				}
+				pending = nil
+				continue
+			}
+		}
+		retry := attempt < opts.MaxRetries
+
+		output, err := s.s3Cli.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &types.Delete{Objects: pending, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			if retry && isThrottled(err) {
+				logf(ctx, "delete of %d objects on bucket %s throttled, retrying: %v", len(pending), bucketName, err)
+				continue
+			}
+			logf(ctx, "failed to delete %d objects on bucket %s: %v", len(pending), bucketName, err)
+			for _, object := range pending {
+				outcomes[objectID(object.Key, object.VersionId)] = keyFailure(aws.ToString(object.Key), err)
+			}
+			break
+		}
+
+		failed := map[string]types.Error{}
+		for _, deleteErr := range output.Errors {
+			failed[objectID(deleteErr.Key, deleteErr.VersionId)] = deleteErr
+		}
+
+		var throttled []types.ObjectIdentifier
+		for _, object := range pending {
+			id := objectID(object.Key, object.VersionId)
+			deleteErr, ok := failed[id]
+			switch {
+			case !ok:
+				outcomes[id] = KeyResult{Key: aws.ToString(object.Key), Status: Deleted}
+			case retry && throttledCodes[aws.ToString(deleteErr.Code)]:
+				throttled = append(throttled, object)
+			default:
+				logf(ctx, "failed to delete file %s: %s", aws.ToString(object.Key), aws.ToString(deleteErr.Message))
+				outcomes[id] = deleteObjectsFailure(deleteErr)
+			}
+		}
+		pending = throttled
+	}
+
+	results := make([]KeyResult, 0, len(batch))
+	for _, object := range batch {
+		results = append(results, outcomes[objectID(object.Key, object.VersionId)])
+	}
+
+	return results
+}
+
+func objectID(key, versionID *string) string {
+	return aws.ToString(key) + "\x00" + aws.ToString(versionID)
+}
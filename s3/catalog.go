@@ -0,0 +1,135 @@
+package s3
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+type (
+	// Catalog records every object managed through the service, so applications
+	// can list, filter and search without ListObjects calls. Objects uploaded
+	// directly by clients are recorded once ConfirmUpload accepts them.
+	// NewSQLCatalog stores it in Postgres or SQLite.
+	Catalog interface {
+		Record(ctx context.Context, entry CatalogEntry) error
+		Rename(ctx context.Context, bucketName, oldKey, newKey string) error
+		Remove(ctx context.Context, bucketName, key string) error
+		Query(ctx context.Context, query CatalogQuery) ([]CatalogEntry, error)
+	}
+
+	CatalogEntry struct {
+		BucketName  string
+		Key         string
+		Size        int64
+		Checksum    string
+		ContentType string
+		Owner       string
+		UploadedBy  string
+		Tags        map[string]string
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+	}
+
+	// CatalogQuery filters on every field that is set. Entries must carry all
+	// of Tags to match.
+	CatalogQuery struct {
+		BucketName     string
+		Prefix         string
+		Owner          string
+		UploadedBy     string
+		ContentType    string
+		MinSize        int64
+		MaxSize        int64
+		Tags           map[string]string
+		ModifiedAfter  time.Time
+		ModifiedBefore time.Time
+		Limit          int
+		Offset         int
+	}
+)
+
+func (s *s3Service) QueryCatalog(ctx context.Context, query CatalogQuery) ([]CatalogEntry, error) {
+	if err := s.acquire(); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if s.catalog == nil {
+		return nil, ErrCatalogNotConfigured
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, query.BucketName, query.Prefix); err != nil {
+		return nil, err
+	}
+
+	entries, err := s.catalog.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return authorizedItems(ctx, s, authz.PermissionDownload, entries, func(entry CatalogEntry) (string, string) {
+		return entry.BucketName, entry.Key
+	})
+}
+
+// catalogObject records an object after a write. entry carries what only the
+// writer knows, such as the owner and tags; size and checksum are read back with
+// a HEAD request, since streamed uploads do not know them up front.
+func (s *s3Service) catalogObject(ctx context.Context, bucketName, key string, entry CatalogEntry) {
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Printf("failed to get head object %s for catalog: %v", key, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	entry.BucketName, entry.Key = bucketName, key
+	entry.Size = aws.ToInt64(head.ContentLength)
+	entry.Checksum = aws.ToString(head.ETag)
+	entry.ContentType = aws.ToString(head.ContentType)
+	if entry.UploadedBy == "" {
+		entry.UploadedBy = head.Metadata[UploadedByMetadata]
+	}
+	entry.CreatedAt, entry.UpdatedAt = now, now
+	if err := s.catalog.Record(ctx, entry); err != nil {
+		log.Printf("failed to catalog file %s on bucket %s: %v", key, bucketName, err)
+	}
+}
+
+// catalogedEntry returns the catalog entry of key, or an empty entry when key
+// is not cataloged.
+func (s *s3Service) catalogedEntry(ctx context.Context, bucketName, key string) CatalogEntry {
+	if s.catalog == nil {
+		return CatalogEntry{}
+	}
+
+	entries, err := s.catalog.Query(ctx, CatalogQuery{BucketName: bucketName, Prefix: key, Limit: 1})
+	if err != nil {
+		log.Printf("failed to query catalog for file %s on bucket %s: %v", key, bucketName, err)
+		return CatalogEntry{}
+	}
+	if len(entries) == 0 || entries[0].Key != key {
+		return CatalogEntry{}
+	}
+
+	return entries[0]
+}
+
+func (s *s3Service) uncatalog(ctx context.Context, bucketName string, keys []string) {
+	for _, key := range keys {
+		if err := s.catalog.Remove(ctx, bucketName, key); err != nil {
+			log.Printf("failed to remove file %s on bucket %s from catalog: %v", key, bucketName, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCatalogWritePaths(t *testing.T) {
+	upload := UploadFileRequest{
+		BucketName:  "bucket",
+		Filename:    "a.txt",
+		ContentType: "text/plain",
+		OwnerID:     "owner-1",
+		UploadedBy:  "uploader-1",
+	}
+
+	tests := []struct {
+		name  string
+		write func(t *testing.T, fake *fakeS3, svc S3Service, primaryDown *atomic.Bool) error
+		// gone are "bucket/key" entries that must not be cataloged afterwards.
+		gone           []string
+		wantOwner      string
+		wantUploadedBy string
+	}{
+		{
+			name: "upload",
+			write: func(t *testing.T, _ *fakeS3, svc S3Service, _ *atomic.Bool) error {
+				data := upload
+				data.Body = io.NopCloser(strings.NewReader("hello"))
+				_, err := svc.UploadFile(data)
+				return err
+			},
+			wantOwner:      "owner-1",
+			wantUploadedBy: "uploader-1",
+		},
+		{
+			name: "upload from path",
+			write: func(t *testing.T, _ *fakeS3, svc S3Service, _ *atomic.Bool) error {
+				path := filepath.Join(t.TempDir(), "a.txt")
+				if err := os.WriteFile(path, []byte("hello"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+				_, err := svc.UploadFromPath(context.Background(), path, "bucket", "a.txt", PathUploadOptions{})
+				return err
+			},
+		},
+		{
+			name: "confirmed client upload",
+			write: func(t *testing.T, fake *fakeS3, svc S3Service, _ *atomic.Bool) error {
+				fake.put("bucket", "a.txt", "text/plain", []byte("hello"), map[string]string{UploadedByMetadata: "uploader-1"})
+				_, err := svc.ConfirmUpload(context.Background(), ConfirmUploadRequest{BucketName: "bucket", Filename: "a.txt", ContentType: "text/plain"})
+				return err
+			},
+			wantUploadedBy: "uploader-1",
+		},
+		{
+			name: "reconciled failover",
+			write: func(t *testing.T, _ *fakeS3, svc S3Service, primaryDown *atomic.Bool) error {
+				primaryDown.Store(true)
+				data := upload
+				data.Body = io.NopCloser(strings.NewReader("hello"))
+				if _, err := svc.UploadFile(data); err != nil {
+					t.Fatal(err)
+				}
+
+				primaryDown.Store(false)
+				_, err := svc.Reconcile(context.Background())
+				return err
+			},
+			gone:           []string{"backup/failover/bucket/a.txt"},
+			wantOwner:      "owner-1",
+			wantUploadedBy: "uploader-1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket", "backup")
+			var primaryDown atomic.Bool
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if !primaryDown.Load() || !strings.HasPrefix(r.URL.Path, "/bucket/") {
+					return false
+				}
+				fakeError(w, http.StatusServiceUnavailable, "ServiceUnavailable")
+				return true
+			}
+			catalog := newFakeCatalog()
+			svc := fake.service(WithCatalog(catalog), WithFailover(FailoverPolicy{BucketName: "backup"}))
+
+			if err := tt.write(t, fake, svc, &primaryDown); err != nil {
+				t.Fatal(err)
+			}
+
+			entry, ok := catalog.entry("bucket", "a.txt")
+			if !ok {
+				t.Fatalf("a.txt is not cataloged")
+			}
+			if entry.Size != 5 || entry.Owner != tt.wantOwner || entry.UploadedBy != tt.wantUploadedBy {
+				t.Errorf("entry = %+v, want size 5, owner %q, uploaded by %q", entry, tt.wantOwner, tt.wantUploadedBy)
+			}
+			for _, name := range tt.gone {
+				bucketName, key, _ := strings.Cut(name, "/")
+				if _, ok := catalog.entry(bucketName, key); ok {
+					t.Errorf("%s is still cataloged", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/bits"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+const (
+	// ManifestContentType marks objects written by UploadChunked.
+	ManifestContentType = "application/vnd.file-uploader.chunks+json"
+
+	defaultChunkPrefix      = "chunks/"
+	defaultChunkMinSize     = 256 * 1024
+	defaultChunkAvgSize     = 1024 * 1024
+	defaultChunkMaxSize     = 4 * 1024 * 1024
+	defaultChunkConcurrency = 4
+)
+
+// ChunkManifest is the object UploadChunked stores at the file's key. Chunks
+// are stored once per bucket under Prefix by their SHA-256 and shared by every
+// manifest that references them.
+type ChunkManifest struct {
+	Version     int             `json:"version"`
+	Algorithm   string          `json:"algorithm"`
+	Prefix      string          `json:"prefix"`
+	Size        int64           `json:"size"`
+	ContentType string          `json:"contentType"`
+	Chunks      []ManifestChunk `json:"chunks"`
+}
+
+type ManifestChunk struct {
+	SHA256 string `json:"sha256"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+func (o ChunkOptions) withDefaults() ChunkOptions {
+	if o.Prefix == "" {
+		o.Prefix = defaultChunkPrefix
+	}
+	if o.MinSize <= 0 {
+		o.MinSize = defaultChunkMinSize
+	}
+	if o.AvgSize <= 0 {
+		o.AvgSize = max(defaultChunkAvgSize, o.MinSize)
+	}
+	if o.MaxSize <= 0 {
+		o.MaxSize = max(defaultChunkMaxSize, o.AvgSize)
+	}
+	if o.Concurrency <= 0 {
+		o.Concurrency = defaultChunkConcurrency
+	}
+
+	return o
+}
+
+// UploadChunked splits the body with FastCDC and uploads only the chunks the
+// bucket does not have yet, then writes a manifest at data.Filename. Because
+// chunk boundaries follow the content, an edit in a large file changes only the
+// chunks around it, so re-uploading the file sends little more than the delta.
+// The manifest replaces any previous one; chunks are never deleted with it.
+func (s *s3Service) UploadChunked(ctx context.Context, data UploadFileRequest, opts ChunkOptions) (ChunkedUploadResult, error) {
+	if err := s.acquire(); err != nil {
+		return ChunkedUploadResult{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	opts = opts.withDefaults()
+	if err := s.validateUploadChunked(data, opts); err != nil {
+		return ChunkedUploadResult{}, err
+	}
+
+	data, err := s.authorizeUpload(ctx, data)
+	if err != nil {
+		return ChunkedUploadResult{}, err
+	}
+
+	// Chunks referenced by the previous manifest are known to exist, which
+	// saves a HEAD request for every unchanged chunk.
+	known := map[string]bool{}
+	if previous, err := s.readManifest(ctx, data.BucketName, data.Filename); err == nil && previous.Prefix == opts.Prefix {
+		for _, chunk := range previous.Chunks {
+			known[chunk.SHA256] = true
+		}
+	}
+
+	manifest := ChunkManifest{Version: 1, Algorithm: "fastcdc", Prefix: opts.Prefix, ContentType: data.ContentType}
+	result := ChunkedUploadResult{Filename: data.Filename}
+
+	// The first failed chunk cancels the ones in flight and stops reading, as
+	// the manifest cannot be written anyway.
+	chunkCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		errs     []error
+		inFlight = map[string]bool{}
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(errs) > 0
+	}
+	sem := make(chan struct{}, opts.Concurrency)
+	chunker := newChunker(uploadBody(data), opts)
+	for !failed() {
+		chunk, err := chunker.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			mu.Lock()
+			errs = append(errs, fmt.Errorf("failed to read file: %w", err))
+			mu.Unlock()
+			break
+		}
+
+		sum := sha256.Sum256(chunk)
+		hash := hex.EncodeToString(sum[:])
+		manifest.Chunks = append(manifest.Chunks, ManifestChunk{SHA256: hash, Offset: manifest.Size, Size: int64(len(chunk))})
+		manifest.Size += int64(len(chunk))
+
+		mu.Lock()
+		duplicate := known[hash] || inFlight[hash]
+		inFlight[hash] = true
+		if duplicate {
+			result.Reused++
+		}
+		mu.Unlock()
+		if duplicate {
+			continue
+		}
+
+		sem <- struct{}{}
+		if failed() {
+			<-sem
+			break
+		}
+		wg.Add(1)
+		go func(hash string, chunk []byte) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			uploaded, err := s.putChunk(chunkCtx, data.BucketName, opts.Prefix+hash, chunk)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				errs = append(errs, fmt.Errorf("failed to upload chunk %s: %w", hash, err))
+				stop()
+			case uploaded:
+				result.Uploaded++
+				result.UploadedBytes += int64(len(chunk))
+			default:
+				result.Reused++
+			}
+		}(hash, chunk)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		log.Printf("failed to upload chunks of %s: %v", data.Filename, errors.Join(errs...))
+		return result, errors.Join(errs...)
+	}
+
+	body, err := json.Marshal(manifest)
+	if err != nil {
+		return result, err
+	}
+	metadata, uploadToken := s.uploadMetadata(data)
+	metadata["original-content-type"] = data.ContentType
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(data.BucketName),
+		Key:         aws.String(data.Filename),
+		ContentType: aws.String(ManifestContentType),
+		Body:        bytes.NewReader(body),
+		Metadata:    metadata,
+	}
+	if len(data.Tags) > 0 {
+		input.Tagging = aws.String(encodeTags(data.Tags))
+	}
+	if _, err = s.s3Cli.PutObject(WithRequestHeaders(ctx, data.Headers), input); err != nil {
+		log.Printf("failed to upload manifest of %s: %v", data.Filename, err)
+		if s.uploadGuarantee {
+			return result, s.abortUpload(ctx, data, uploadToken, err)
+		}
+		return result, fmt.Errorf("failed to upload manifest: %w", err)
+	}
+
+	result.Chunks = len(manifest.Chunks)
+	result.Size = manifest.Size
+
+	// The manifest stands for the file, so it is indexed, cataloged and
+	// tracked like any other upload.
+	err = s.recordUpload(ctx, data, UploadFileResult{Filename: data.Filename, CorrelationID: s.correlationID(ctx)})
+	return result, err
+}
+
+// putChunk uploads a chunk unless the bucket already has it, and reports
+// whether it did.
+func (s *s3Service) putChunk(ctx context.Context, bucketName, key string, chunk []byte) (bool, error) {
+	_, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err == nil {
+		return false, nil
+	}
+	if !isNotFound(err) {
+		return false, err
+	}
+
+	sum := sha256.Sum256(chunk)
+	_, err = s.s3Cli.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:         aws.String(bucketName),
+		Key:            aws.String(key),
+		Body:           bytes.NewReader(chunk),
+		ContentLength:  aws.Int64(int64(len(chunk))),
+		ContentType:    aws.String("application/octet-stream"),
+		ChecksumSHA256: aws.String(base64.StdEncoding.EncodeToString(sum[:])),
+	})
+	return err == nil, err
+}
+
+// DownloadChunked writes the file described by the manifest at key to w,
+// verifying every chunk against its hash.
+func (s *s3Service) DownloadChunked(ctx context.Context, bucketName, key string, w io.Writer) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateStatFile(bucketName, key); err != nil {
+		return err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, bucketName, key); err != nil {
+		return err
+	}
+
+	manifest, err := s.readManifest(ctx, bucketName, key)
+	if err != nil {
+		return err
+	}
+
+	for _, chunk := range manifest.Chunks {
+		object, err := s.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(manifest.Prefix + chunk.SHA256),
+		})
+		if err != nil {
+			log.Printf("failed to download chunk %s of %s: %v", chunk.SHA256, key, err)
+			return fmt.Errorf("failed to download chunk %s: %w", chunk.SHA256, err)
+		}
+
+		hash := sha256.New()
+		n, err := io.Copy(io.MultiWriter(w, hash), object.Body)
+		object.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to download chunk %s: %w", chunk.SHA256, err)
+		}
+		if n != chunk.Size || hex.EncodeToString(hash.Sum(nil)) != chunk.SHA256 {
+			return fmt.Errorf("%w: chunk %s of %s", ErrChecksumMismatch, chunk.SHA256, key)
+		}
+	}
+
+	return nil
+}
+
+func (s *s3Service) readManifest(ctx context.Context, bucketName, key string) (ChunkManifest, error) {
+	object, err := s.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return ChunkManifest{}, ErrFileNotFound
+		}
+		return ChunkManifest{}, fmt.Errorf("failed to download manifest: %w", err)
+	}
+	defer object.Body.Close()
+
+	if aws.ToString(object.ContentType) != ManifestContentType {
+		return ChunkManifest{}, fmt.Errorf("file %s is not a chunk manifest", key)
+	}
+
+	var manifest ChunkManifest
+	if err := json.NewDecoder(object.Body).Decode(&manifest); err != nil {
+		return ChunkManifest{}, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// gear is the FastCDC rolling hash table, derived from a fixed seed so chunk
+// boundaries never change between builds.
+var gear = func() (table [256]uint64) {
+	seed := uint64(0x9e3779b97f4a7c15)
+	for i := range table {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// chunker splits a stream with normalized FastCDC: a stricter mask below the
+// average size and a looser one above it keep chunk sizes close to AvgSize.
+type chunker struct {
+	r        io.Reader
+	buf      []byte
+	eof      bool
+	min, avg int
+	max      int
+	maskS    uint64
+	maskL    uint64
+}
+
+func newChunker(r io.Reader, opts ChunkOptions) *chunker {
+	level := bits.Len(uint(opts.AvgSize)) - 1
+	return &chunker{
+		r:     r,
+		buf:   make([]byte, 0, 2*opts.MaxSize),
+		min:   opts.MinSize,
+		avg:   opts.AvgSize,
+		max:   opts.MaxSize,
+		maskS: topBits(level + 2),
+		maskL: topBits(max(level-2, 1)),
+	}
+}
+
+// topBits uses the high bits of the hash, which depend on the most bytes of
+// the window since gear shifts left.
+func topBits(n int) uint64 {
+	return ^uint64(0) << (64 - min(n, 63))
+}
+
+// next returns the next chunk, or io.EOF once the stream is consumed.
+func (c *chunker) next() ([]byte, error) {
+	for !c.eof && len(c.buf) < c.max {
+		n, err := c.r.Read(c.buf[len(c.buf):cap(c.buf)])
+		c.buf = c.buf[:len(c.buf)+n]
+		if err == io.EOF {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(c.buf) == 0 {
+		return nil, io.EOF
+	}
+
+	n := c.cut(c.buf)
+	chunk := bytes.Clone(c.buf[:n])
+	c.buf = c.buf[:copy(c.buf, c.buf[n:])]
+
+	return chunk, nil
+}
+
+func (c *chunker) cut(data []byte) int {
+	n := min(len(data), c.max)
+	if n <= c.min {
+		return n
+	}
+
+	normal := min(c.avg, n)
+	var fingerprint uint64
+	i := c.min
+	for ; i < normal; i++ {
+		fingerprint = fingerprint<<1 + gear[data[i]]
+		if fingerprint&c.maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fingerprint = fingerprint<<1 + gear[data[i]]
+		if fingerprint&c.maskL == 0 {
+			return i + 1
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,204 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func randomBytes(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func chunkSizes(t *testing.T, data []byte, opts ChunkOptions) []int {
+	t.Helper()
+
+	c := newChunker(bytes.NewReader(data), opts.withDefaults())
+	var sizes []int
+	for {
+		chunk, err := c.next()
+		if err == io.EOF {
+			return sizes
+		}
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+		sizes = append(sizes, len(chunk))
+	}
+}
+
+func TestChunkerBoundaries(t *testing.T) {
+	opts := ChunkOptions{MinSize: 1024, AvgSize: 4096, MaxSize: 16384}
+	data := randomBytes(1, 256*1024)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty", data: nil},
+		{name: "below min", data: data[:1000]},
+		{name: "random", data: data},
+		{name: "constant", data: make([]byte, 100_000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sizes := chunkSizes(t, tt.data, opts)
+			total := 0
+			for i, size := range sizes {
+				last := i == len(sizes)-1
+				if size > opts.MaxSize || size < opts.MinSize && !last {
+					t.Errorf("chunk %d has %d bytes, want %d to %d", i, size, opts.MinSize, opts.MaxSize)
+				}
+				total += size
+			}
+			if total != len(tt.data) {
+				t.Errorf("chunks cover %d bytes, want %d", total, len(tt.data))
+			}
+		})
+	}
+}
+
+func TestChunkerBoundariesFollowContent(t *testing.T) {
+	opts := ChunkOptions{MinSize: 1024, AvgSize: 4096, MaxSize: 16384}
+	data := randomBytes(2, 256*1024)
+	edited := bytes.Clone(data)
+	copy(edited[128*1024:], "edited")
+
+	before, after := chunkSizes(t, data, opts), chunkSizes(t, edited, opts)
+	if len(before) < 8 {
+		t.Fatalf("got %d chunks, want enough to compare", len(before))
+	}
+
+	// Chunks before the edit are unchanged, and boundaries resynchronise
+	// shortly after it.
+	same := 0
+	for i := 0; i < len(before) && i < len(after) && before[i] == after[i]; i++ {
+		same++
+	}
+	sameTail := 0
+	for i := 1; i <= len(before) && i <= len(after) && before[len(before)-i] == after[len(after)-i]; i++ {
+		sameTail++
+	}
+	if changed := len(before) - same - sameTail; changed > 2 {
+		t.Errorf("an edit changed %d of %d chunks, want at most 2", changed, len(before))
+	}
+}
+
+func TestUploadChunked(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	catalog, indexer := newFakeCatalog(), newFakeIndexer()
+	svc := fake.service(WithCatalog(catalog), WithIndexer(indexer), WithUploadGuarantee())
+
+	// Fixed-size chunks with the first block repeated, so one chunk is reused
+	// while its first copy is still in flight.
+	block := randomBytes(3, 1024)
+	content := bytes.Join([][]byte{block, randomBytes(4, 1024), block, randomBytes(5, 1000)}, nil)
+	opts := ChunkOptions{MinSize: 1024, AvgSize: 1024, MaxSize: 1024}
+	upload := func() ChunkedUploadResult {
+		t.Helper()
+
+		result, err := svc.UploadChunked(context.Background(), UploadFileRequest{
+			BucketName:  "bucket",
+			Filename:    "big.bin",
+			ContentType: "application/x-test",
+			OwnerID:     "owner-1",
+			Body:        io.NopCloser(bytes.NewReader(content)),
+		}, opts)
+		if err != nil {
+			t.Fatalf("UploadChunked: %v", err)
+		}
+		return result
+	}
+
+	first := upload()
+	want := ChunkedUploadResult{Filename: "big.bin", Size: int64(len(content)), Chunks: 4, Uploaded: 3, Reused: 1, UploadedBytes: 3048}
+	if first != want {
+		t.Errorf("first upload = %+v, want %+v", first, want)
+	}
+
+	// Every chunk of the previous manifest is known to exist.
+	fake.mu.Lock()
+	fake.requests = nil
+	fake.mu.Unlock()
+	second := upload()
+	if second.Uploaded != 0 || second.Reused != 4 {
+		t.Errorf("second upload = %+v, want every chunk reused", second)
+	}
+	fake.mu.Lock()
+	for _, request := range fake.requests {
+		if strings.Contains(request, "/chunks/") {
+			t.Errorf("second upload sent %s, want no chunk requests", request)
+		}
+	}
+	fake.mu.Unlock()
+
+	manifest, ok := fake.object("bucket", "big.bin")
+	if !ok {
+		t.Fatal("manifest was not stored")
+	}
+	if manifest.contentType != ManifestContentType || manifest.metadata["original-content-type"] != "application/x-test" {
+		t.Errorf("manifest stored as %s with %v, want the manifest type and the original one", manifest.contentType, manifest.metadata)
+	}
+	if manifest.metadata[OwnerIDMetadata] == "" || manifest.metadata[UploadTokenMetadata] == "" {
+		t.Errorf("manifest metadata = %v, want the owner and upload token", manifest.metadata)
+	}
+	if entry, ok := catalog.entry("bucket", "big.bin"); !ok || entry.Owner != "owner-1" {
+		t.Errorf("catalog entry = %+v, %v, want one for owner-1", entry, ok)
+	}
+	if doc, ok := indexer.doc("bucket", "big.bin"); !ok || doc.ContentType != "application/x-test" {
+		t.Errorf("index document = %+v, %v, want the original content type", doc, ok)
+	}
+
+	var got bytes.Buffer
+	if err := svc.DownloadChunked(context.Background(), "bucket", "big.bin", &got); err != nil {
+		t.Fatalf("DownloadChunked: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), content) {
+		t.Errorf("downloaded %d bytes, want the %d uploaded", got.Len(), len(content))
+	}
+}
+
+func TestUploadChunkedStopsOnFailure(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	var (
+		mu        sync.Mutex
+		attempted = map[string]bool{}
+	)
+	fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodPut || !strings.Contains(r.URL.Path, "/chunks/") {
+			return false
+		}
+		mu.Lock()
+		attempted[r.URL.Path] = true
+		mu.Unlock()
+		fakeError(w, http.StatusForbidden, "AccessDenied")
+		return true
+	}
+	svc := fake.service()
+
+	_, err := svc.UploadChunked(context.Background(), UploadFileRequest{
+		BucketName:  "bucket",
+		Filename:    "big.bin",
+		ContentType: "application/octet-stream",
+		Body:        io.NopCloser(bytes.NewReader(randomBytes(6, 8*1024))),
+	}, ChunkOptions{MinSize: 1024, AvgSize: 1024, MaxSize: 1024, Concurrency: 1})
+	if err == nil {
+		t.Fatal("UploadChunked with failing chunks succeeded")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempted) != 1 {
+		t.Errorf("attempted %d chunks, want the upload to stop after the first failure", len(attempted))
+	}
+	if _, ok := fake.object("bucket", "big.bin"); ok {
+		t.Error("manifest stored despite a failed chunk")
+	}
+}
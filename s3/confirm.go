@@ -0,0 +1,230 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+// ConfirmUpload checks an object uploaded directly by a client, e.g. through a
+// presigned URL or POST policy, against what the client was authorized to
+// upload. Non-conforming objects are deleted and the returned error wraps
+// ErrUploadRejected.
+func (s *s3Service) ConfirmUpload(ctx context.Context, data ConfirmUploadRequest) (FileStat, error) {
+	if err := s.acquire(); err != nil {
+		return FileStat{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateConfirmUpload(data); err != nil {
+		return FileStat{}, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionUpload, data.BucketName, data.Filename); err != nil {
+		return FileStat{}, err
+	}
+
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket:       aws.String(data.BucketName),
+		Key:          aws.String(data.Filename),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return FileStat{}, ErrFileNotFound
+		}
+		log.Printf("failed to get head object %s - %s: %v", data.BucketName, data.Filename, err)
+		return FileStat{}, fmt.Errorf("failed to get head object: %w", err)
+	}
+
+	stat := FileStat{
+		Key:          data.Filename,
+		Size:         aws.ToInt64(head.ContentLength),
+		ContentType:  aws.ToString(head.ContentType),
+		ETag:         aws.ToString(head.ETag),
+		LastModified: aws.ToTime(head.LastModified),
+		StorageClass: string(head.StorageClass),
+		VersionID:    aws.ToString(head.VersionId),
+		Metadata:     head.Metadata,
+	}
+
+	var rejections []error
+	if data.Size > 0 && stat.Size != data.Size {
+		rejections = append(rejections, fmt.Errorf("size is %d, expected %d", stat.Size, data.Size))
+	}
+	if data.MaxSize > 0 && stat.Size > data.MaxSize {
+		rejections = append(rejections, fmt.Errorf("size %d exceeds %d", stat.Size, data.MaxSize))
+	}
+	if data.ContentType != "" && !contentTypeMatches(stat.ContentType, data.ContentType) {
+		rejections = append(rejections, fmt.Errorf("content type is %q, expected %q", stat.ContentType, data.ContentType))
+	}
+	if data.ETag != "" && strings.Trim(stat.ETag, `"`) != strings.Trim(data.ETag, `"`) {
+		rejections = append(rejections, fmt.Errorf("etag is %s, expected %s", stat.ETag, data.ETag))
+	}
+
+	// The declared type is the client's word; the content has to agree.
+	if len(rejections) == 0 && data.ContentType != "" {
+		sniffed, err := s.sniffContentType(ctx, data.BucketName, data.Filename, head)
+		if err != nil {
+			log.Printf("failed to read file %s - %s: %v", data.BucketName, data.Filename, err)
+			return FileStat{}, fmt.Errorf("failed to read file: %w", err)
+		}
+		if sniffedTypeConflicts(sniffed, data.ContentType) {
+			rejections = append(rejections, fmt.Errorf("content is %q, expected %q", sniffed, data.ContentType))
+		}
+	}
+
+	// Only checksum the content once everything cheaper has passed.
+	if len(rejections) == 0 && data.SHA256 != "" {
+		sum, err := s.objectSHA256(ctx, data.BucketName, data.Filename, head)
+		if err != nil {
+			log.Printf("failed to checksum file %s - %s: %v", data.BucketName, data.Filename, err)
+			return FileStat{}, fmt.Errorf("failed to checksum file: %w", err)
+		}
+		if !strings.EqualFold(sum, data.SHA256) {
+			rejections = append(rejections, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, data.SHA256, sum))
+		}
+	}
+
+	if len(rejections) == 0 {
+		if s.catalog != nil {
+			s.catalogObject(ctx, data.BucketName, data.Filename, CatalogEntry{})
+		}
+		return stat, nil
+	}
+
+	log.Printf("rejected upload of %s - %s: %v", data.BucketName, data.Filename, errors.Join(rejections...))
+	if err := s.deleteChecked(ctx, data.BucketName, data.Filename, head); err != nil {
+		log.Printf("failed to remove rejected upload %s: %v", data.Filename, err)
+	}
+
+	return FileStat{}, fmt.Errorf("%w: %w", ErrUploadRejected, errors.Join(rejections...))
+}
+
+// deleteChecked deletes the object version that head describes. A newer write
+// to the key, or the one object of an unversioned bucket once it has changed,
+// is left alone.
+func (s *s3Service) deleteChecked(ctx context.Context, bucketName, key string, head *s3.HeadObjectOutput) error {
+	input := &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+	if head.VersionId != nil {
+		input.VersionId = head.VersionId
+	} else {
+		input.IfMatch = head.ETag
+	}
+
+	_, err := s.s3Cli.DeleteObject(ctx, input)
+	if isNotFound(err) || isPreconditionFailed(err) {
+		return nil
+	}
+	return err
+}
+
+// sniffContentType detects the type of an object from its first 512 bytes.
+func (s *s3Service) sniffContentType(ctx context.Context, bucketName, key string, head *s3.HeadObjectOutput) (string, error) {
+	if aws.ToInt64(head.ContentLength) == 0 {
+		return http.DetectContentType(nil), nil
+	}
+
+	object, err := s.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(bucketName),
+		Key:     aws.String(key),
+		IfMatch: head.ETag,
+		Range:   aws.String("bytes=0-511"),
+	})
+	if err != nil {
+		return "", err
+	}
+	defer object.Body.Close()
+
+	sample, err := io.ReadAll(io.LimitReader(object.Body, 512))
+	if err != nil {
+		return "", err
+	}
+
+	return http.DetectContentType(sample), nil
+}
+
+// sniffContainers lists types that sniff as the generic format they are built
+// on, keyed by that format.
+var sniffContainers = map[string][]string{
+	"application/zip": {"application/vnd.openxmlformats-", "application/vnd.oasis.opendocument.", "application/epub+zip", "application/java-archive"},
+	"text/xml":        {"+xml", "/xml"},
+}
+
+// sniffedTypeConflicts reports whether content sniffed as sniffed cannot be of
+// the expected type. Sniffing recognizes only some formats and falls back to
+// text/plain or application/octet-stream, which are not held against content.
+func sniffedTypeConflicts(sniffed, expected string) bool {
+	sniffed, _, _ = strings.Cut(sniffed, ";")
+	if sniffed == "application/octet-stream" || sniffed == "text/plain" || contentTypeMatches(sniffed, expected) {
+		return false
+	}
+
+	for _, compatible := range sniffContainers[sniffed] {
+		if strings.Contains(strings.ToLower(expected), compatible) {
+			return false
+		}
+	}
+	return true
+}
+
+// objectSHA256 returns the hex SHA-256 of an object's content. The checksum S3
+// stored at upload is used when it covers the whole object; composite checksums
+// of multipart uploads, or a missing checksum, mean downloading the object.
+func (s *s3Service) objectSHA256(ctx context.Context, bucketName, key string, head *s3.HeadObjectOutput) (string, error) {
+	if stored := aws.ToString(head.ChecksumSHA256); stored != "" && head.ChecksumType != types.ChecksumTypeComposite && !strings.Contains(stored, "-") {
+		sum, err := base64.StdEncoding.DecodeString(stored)
+		if err == nil {
+			return hex.EncodeToString(sum), nil
+		}
+	}
+
+	object, err := s.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:  aws.String(bucketName),
+		Key:     aws.String(key),
+		IfMatch: head.ETag,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer object.Body.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, object.Body); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// contentTypeMatches compares ignoring parameters; an expected type ending in
+// "/" matches any subtype, as in CreatePostPolicy.
+func contentTypeMatches(actual, expected string) bool {
+	actual, _, _ = strings.Cut(actual, ";")
+	actual = strings.ToLower(strings.TrimSpace(actual))
+	expected = strings.ToLower(expected)
+	if strings.HasSuffix(expected, "/") {
+		return strings.HasPrefix(actual, expected)
+	}
+
+	expected, _, _ = strings.Cut(expected, ";")
+	return actual == strings.TrimSpace(expected)
+}
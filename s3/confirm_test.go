@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"net/http"
+	"testing"
+)
+
+func pngBytes(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestConfirmUploadContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        func(t *testing.T) []byte
+		expected    string
+		wantErr     error
+	}{
+		{name: "png", contentType: "image/png", body: pngBytes, expected: "image/png"},
+		{name: "any image", contentType: "image/png", body: pngBytes, expected: "image/"},
+		{name: "json sniffs as text", contentType: "application/json", body: func(*testing.T) []byte { return []byte(`{"a":1}`) }, expected: "application/json"},
+		{name: "docx sniffs as zip", contentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", body: func(*testing.T) []byte { return []byte("PK\x03\x04rest") }, expected: "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{name: "html declared as png", contentType: "image/png", body: func(*testing.T) []byte { return []byte("<html><script>alert(1)</script></html>") }, expected: "image/png", wantErr: ErrUploadRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "upload", tt.contentType, tt.body(t), nil)
+			catalog := newFakeCatalog()
+			svc := fake.service(WithCatalog(catalog))
+
+			_, err := svc.ConfirmUpload(context.Background(), ConfirmUploadRequest{BucketName: "bucket", Filename: "upload", ContentType: tt.expected})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ConfirmUpload error = %v, want %v", err, tt.wantErr)
+			}
+
+			_, kept := fake.object("bucket", "upload")
+			_, cataloged := catalog.entry("bucket", "upload")
+			if want := tt.wantErr == nil; kept != want || cataloged != want {
+				t.Errorf("kept = %v, cataloged = %v, want %v", kept, cataloged, want)
+			}
+		})
+	}
+}
+
+func TestConfirmUploadKeepsNewerWrite(t *testing.T) {
+	tests := []struct {
+		name string
+	}{
+		{name: "overwritten before the delete"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "upload", "text/plain", []byte("too large"), nil)
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if r.Method == http.MethodDelete {
+					fake.put("bucket", "upload", "text/plain", []byte("newer"), nil)
+				}
+				return false
+			}
+			svc := fake.service()
+
+			_, err := svc.ConfirmUpload(context.Background(), ConfirmUploadRequest{BucketName: "bucket", Filename: "upload", MaxSize: 5})
+			if !errors.Is(err, ErrUploadRejected) {
+				t.Fatalf("ConfirmUpload error = %v, want %v", err, ErrUploadRejected)
+			}
+
+			o, ok := fake.object("bucket", "upload")
+			if !ok || string(o.body) != "newer" {
+				t.Errorf("newer write to upload was deleted")
+			}
+		})
+	}
+}
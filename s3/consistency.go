@@ -0,0 +1,237 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+const (
+	defaultVisibilityTimeout = 30 * time.Second
+	minVisibilityInterval    = 100 * time.Millisecond
+	maxVisibilityInterval    = 2 * time.Second
+)
+
+// ConsistencyOptions configure WithConsistency. AWS S3 is strongly consistent,
+// but S3-compatible stores and caches in front of them may lag behind writes.
+type ConsistencyOptions struct {
+	// Wait makes UploadFile and DeleteFile poll until their changes are
+	// visible before returning.
+	Wait bool
+	// List also polls ListObjectsV2, for stores whose listings lag behind
+	// reads.
+	List bool
+	// Timeout defaults to 30 seconds. Writes not seen by then are still
+	// reported as pending by Freshness for another Timeout.
+	Timeout time.Duration
+}
+
+func (o ConsistencyOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return defaultVisibilityTimeout
+	}
+	return o.Timeout
+}
+
+// Freshness describes how current a listing is.
+type Freshness struct {
+	// ListedAt is when the first page was fetched.
+	ListedAt time.Time
+	// Pending are keys under the listed prefix that this service wrote or
+	// deleted without having seen the change yet; the listing may not reflect
+	// them. Keys drop out as the pages covering them show the change. Only
+	// tracked with WithConsistency.
+	Pending []string
+}
+
+// pendingWrites remembers writes until they are seen or expire.
+type pendingWrites struct {
+	mu     sync.Mutex
+	writes map[string]map[string]pendingWrite
+}
+
+// pendingWrite is a key written at, or deleted at when exists is false.
+type pendingWrite struct {
+	at     time.Time
+	exists bool
+}
+
+func (p *pendingWrites) add(bucketName string, keys []string, exists bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.writes == nil {
+		p.writes = map[string]map[string]pendingWrite{}
+	}
+	if p.writes[bucketName] == nil {
+		p.writes[bucketName] = map[string]pendingWrite{}
+	}
+	for _, key := range keys {
+		p.writes[bucketName][key] = pendingWrite{at: time.Now(), exists: exists}
+	}
+}
+
+func (p *pendingWrites) done(bucketName, key string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.writes[bucketName], key)
+}
+
+// under returns the pending keys below prefix, dropping those older than ttl.
+func (p *pendingWrites) under(bucketName, prefix string, ttl time.Duration) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keys := []string{}
+	for key, write := range p.writes[bucketName] {
+		switch {
+		case time.Since(write.at) > 2*ttl:
+			delete(p.writes[bucketName], key)
+		case strings.HasPrefix(key, prefix):
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// listed settles the pending keys that one page of a listing covers, the keys
+// in (after, last] or every key after after on the last page, where last is "".
+// Written keys the page lists and deleted keys it does not are seen; the keys
+// still pending are returned.
+func (p *pendingWrites) listed(bucketName string, keys []string, page map[string]bool, after, last string) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	remaining := []string{}
+	for _, key := range keys {
+		write, ok := p.writes[bucketName][key]
+		if !ok {
+			continue
+		}
+		if covered := key > after && (last == "" || key <= last); covered && page[key] == write.exists {
+			delete(p.writes[bucketName], key)
+			continue
+		}
+		remaining = append(remaining, key)
+	}
+
+	return remaining
+}
+
+// WaitForVisibility polls until every key exists, or is gone when exists is
+// false, as seen by HEAD and, when configured, by listings. It gives up with
+// ErrNotVisible after the consistency timeout or when ctx is done.
+func (s *s3Service) WaitForVisibility(ctx context.Context, bucketName string, keys []string, exists bool) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if bucketName == "" {
+		return &ValidationError{Violations: []*Violation{Violationf("BucketName", "bucket name is required")}}
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, bucketName, keys...); err != nil {
+		return err
+	}
+
+	return s.awaitVisible(ctx, bucketName, keys, exists)
+}
+
+func (s *s3Service) awaitVisible(ctx context.Context, bucketName string, keys []string, exists bool) error {
+	var opts ConsistencyOptions
+	if s.consistency != nil {
+		opts = *s.consistency
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+
+	remaining := slices.Clone(keys)
+	for interval := minVisibilityInterval; ; interval = min(2*interval, maxVisibilityInterval) {
+		var unseen []string
+		for _, key := range remaining {
+			visible, err := s.isVisible(ctx, bucketName, key, exists, opts.List)
+			if err != nil && ctx.Err() == nil {
+				return fmt.Errorf("failed to check visibility of %s: %w", key, err)
+			}
+			if visible {
+				s.pending.done(bucketName, key)
+			} else {
+				unseen = append(unseen, key)
+			}
+		}
+		if remaining = unseen; len(remaining) == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			logf(ctx, "%d of %d changes on bucket %s not visible after %v", len(remaining), len(keys), bucketName, opts.timeout())
+			return fmt.Errorf("%w: %s on bucket %s", ErrNotVisible, strings.Join(remaining, ", "), bucketName)
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (s *s3Service) isVisible(ctx context.Context, bucketName, key string, exists, list bool) (bool, error) {
+	_, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil && !isNotFound(err) {
+		return false, err
+	}
+	if (err == nil) != exists {
+		return false, nil
+	}
+	if !list {
+		return true, nil
+	}
+
+	output, err := s.s3Cli.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucketName),
+		Prefix:  aws.String(key),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return false, err
+	}
+	listed := len(output.Contents) > 0 && aws.ToString(output.Contents[0].Key) == key
+
+	return listed == exists, nil
+}
+
+// trackWrites records changes for Freshness and, with Wait set, waits for them.
+func (s *s3Service) trackWrites(ctx context.Context, bucketName string, keys []string, exists bool) error {
+	if s.consistency == nil || len(keys) == 0 {
+		return nil
+	}
+
+	s.pending.add(bucketName, keys, exists)
+	if !s.consistency.Wait {
+		return nil
+	}
+
+	return s.awaitVisible(ctx, bucketName, keys, exists)
+}
+
+func (s *s3Service) freshness(bucketName, prefix string) Freshness {
+	if s.consistency == nil {
+		return Freshness{}
+	}
+	return Freshness{Pending: s.pending.under(bucketName, prefix, s.consistency.timeout())}
+}
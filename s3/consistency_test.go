@@ -0,0 +1,94 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestListFilesSettlesPending(t *testing.T) {
+	tests := []struct {
+		name   string
+		delete bool
+		// lag hides the change from the store, as a lagging listing would.
+		lag         bool
+		wantPending []string
+	}{
+		{name: "written and listed", wantPending: []string{}},
+		{name: "deleted and not listed", delete: true, wantPending: []string{}},
+		{name: "written but not listed yet", lag: true, wantPending: []string{"a.txt"}},
+		{name: "deleted but still listed", delete: true, lag: true, wantPending: []string{"a.txt"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "b.txt", "text/plain", []byte("b"), nil)
+			svc := fake.service(WithConsistency(ConsistencyOptions{}))
+
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.txt",
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader("a")),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.delete {
+				if _, err := svc.DeleteFile(DeleteFileRequest{BucketName: "bucket", Filename: []string{"a.txt"}}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.lag {
+				fake.mu.Lock()
+				if tt.delete {
+					fake.store("bucket", "a.txt", &fakeObject{body: []byte("a")})
+				} else {
+					delete(fake.objects, "bucket/a.txt")
+				}
+				fake.mu.Unlock()
+			}
+
+			files := svc.ListFiles(context.Background(), ListFilesRequest{BucketName: "bucket"})
+			if got := files.Freshness().Pending; !slices.Equal(got, []string{"a.txt"}) {
+				t.Fatalf("Pending before listing = %v, want [a.txt]", got)
+			}
+			for range files.All() {
+			}
+			if err := files.Err(); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := files.Freshness().Pending; !slices.Equal(got, tt.wantPending) {
+				t.Errorf("Pending = %v, want %v", got, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestPendingListedRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		after, last string
+		page        map[string]bool
+		want        []string
+	}{
+		{name: "first page", last: "c", page: map[string]bool{"a": true, "c": true}, want: []string{"d", "e"}},
+		{name: "middle page", after: "c", last: "e", page: map[string]bool{"d": true, "e": true}, want: []string{"a", "b"}},
+		{name: "last page", after: "c", page: map[string]bool{"e": true}, want: []string{"a", "b", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pending pendingWrites
+			pending.add("bucket", []string{"a", "d", "e"}, true)
+			pending.add("bucket", []string{"b"}, false)
+
+			got := pending.listed("bucket", []string{"a", "b", "d", "e"}, tt.page, tt.after, tt.last)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("listed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
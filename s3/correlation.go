@@ -0,0 +1,183 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+)
+
+const DefaultCorrelationHeader = "X-Correlation-Id"
+
+type correlationKey struct{}
+
+// correlation carries the ID of one logical operation and the AWS request ID
+// of the last S3 call made for it.
+type correlation struct {
+	id string
+
+	mu        sync.Mutex
+	requestID string
+}
+
+func (c *correlation) setRequestID(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requestID = id
+}
+
+func (c *correlation) lastRequestID() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.requestID
+}
+
+// WithCorrelationID tags every S3 call made with ctx with id, once the service
+// is created with WithCorrelationIDs.
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, &correlation{id: id})
+}
+
+// CorrelationID returns the ID attached to ctx, if any.
+func CorrelationID(ctx context.Context) string {
+	if c, ok := ctx.Value(correlationKey{}).(*correlation); ok {
+		return c.id
+	}
+	return ""
+}
+
+// RequestID returns the AWS request ID of the last S3 call made with a context
+// derived from WithCorrelationID.
+func RequestID(ctx context.Context) string {
+	if c, ok := ctx.Value(correlationKey{}).(*correlation); ok {
+		return c.lastRequestID()
+	}
+	return ""
+}
+
+// NewCorrelationID returns an ID from idgen.Default.
+func NewCorrelationID() string {
+	return idgen.Default.NewID()
+}
+
+// newID uses the generator set with WithIDGenerator.
+func (s *s3Service) newID() string {
+	if s.ids == nil {
+		return idgen.Default.NewID()
+	}
+	return s.ids.NewID()
+}
+
+// CorrelatedError is returned by failed uploads and deletes when correlation
+// IDs are enabled, so the failure can be found in S3 server logs and in the
+// logs of other systems.
+type CorrelatedError struct {
+	CorrelationID string
+	RequestID     string
+	Err           error
+}
+
+func (e *CorrelatedError) Error() string {
+	if e.RequestID == "" {
+		return fmt.Sprintf("%v (correlation id %s)", e.Err, e.CorrelationID)
+	}
+	return fmt.Sprintf("%v (correlation id %s, request id %s)", e.Err, e.CorrelationID, e.RequestID)
+}
+
+func (e *CorrelatedError) Unwrap() error { return e.Err }
+
+// correlate returns the context of one operation: id, else the ID already on
+// ctx, else a new one. Without WithCorrelationIDs it returns ctx unchanged.
+func (s *s3Service) correlate(ctx context.Context, id string) context.Context {
+	if s.correlationHeader == "" {
+		return ctx
+	}
+
+	if id == "" {
+		id = CorrelationID(ctx)
+	}
+	if id == "" {
+		id = s.newID()
+	}
+
+	return WithCorrelationID(ctx, id)
+}
+
+// correlationID returns the correlation ID of ctx, or "" without
+// WithCorrelationIDs, as the ID was then not sent to S3.
+func (s *s3Service) correlationID(ctx context.Context) string {
+	if s.correlationHeader == "" {
+		return ""
+	}
+	return CorrelationID(ctx)
+}
+
+func (s *s3Service) correlatedError(ctx context.Context, err error) error {
+	if err == nil || s.correlationID(ctx) == "" {
+		return err
+	}
+
+	var respErr *awshttp.ResponseError
+	requestID := RequestID(ctx)
+	if errors.As(err, &respErr) && respErr.ServiceRequestID() != "" {
+		requestID = respErr.ServiceRequestID()
+	}
+
+	return &CorrelatedError{CorrelationID: CorrelationID(ctx), RequestID: requestID, Err: err}
+}
+
+// logf prefixes the message with the correlation ID of ctx.
+func logf(ctx context.Context, format string, args ...any) {
+	if id := CorrelationID(ctx); id != "" {
+		format = "[" + id + "] " + format
+	}
+	log.Printf(format, args...)
+}
+
+// correlationMiddleware sends the correlation ID as a header and in the user
+// agent, which S3 server access logs record, and keeps the AWS request ID of
+// the response.
+func (s *s3Service) correlationMiddleware(stack *middleware.Stack) error {
+	err := stack.Build.Add(middleware.BuildMiddlewareFunc("FileUploaderCorrelationHeader", func(
+		ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler,
+	) (middleware.BuildOutput, middleware.Metadata, error) {
+		if id := CorrelationID(ctx); id != "" {
+			if req, ok := in.Request.(*smithyhttp.Request); ok {
+				req.Header.Set(s.correlationHeader, id)
+				req.Header.Set("User-Agent", req.Header.Get("User-Agent")+" correlation-id/"+id)
+			}
+		}
+
+		return next.HandleBuild(ctx, in)
+	}), middleware.After)
+	if err != nil {
+		return err
+	}
+
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("FileUploaderCorrelationRequestID", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		out, metadata, err := next.HandleInitialize(ctx, in)
+
+		if c, ok := ctx.Value(correlationKey{}).(*correlation); ok {
+			requestID, _ := awsmiddleware.GetRequestIDMetadata(metadata)
+			var respErr *awshttp.ResponseError
+			if requestID == "" && errors.As(err, &respErr) {
+				requestID = respErr.ServiceRequestID()
+			}
+			if requestID != "" {
+				c.setRequestID(requestID)
+			}
+		}
+
+		return out, metadata, err
+	}), middleware.After)
+}
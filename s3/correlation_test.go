@@ -0,0 +1,111 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+)
+
+func TestUploadCorrelation(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []Option
+		header        string
+		ctxID         string
+		requestID     string
+		failUpload    bool
+		wantID        string
+		wantRequestID string
+	}{
+		{name: "disabled", ctxID: "ctx-id", requestID: "request-id"},
+		{name: "generated", opts: []Option{WithCorrelationIDs("")}, wantID: "generated-id", wantRequestID: "aws-request-id"},
+		{name: "from context", opts: []Option{WithCorrelationIDs("")}, ctxID: "ctx-id", wantID: "ctx-id", wantRequestID: "aws-request-id"},
+		{name: "overridden by the request", opts: []Option{WithCorrelationIDs("")}, ctxID: "ctx-id", requestID: "request-id", wantID: "request-id", wantRequestID: "aws-request-id"},
+		{name: "custom header", opts: []Option{WithCorrelationIDs("X-Trace-Id")}, header: "X-Trace-Id", requestID: "request-id", wantID: "request-id", wantRequestID: "aws-request-id"},
+		{name: "failed upload", opts: []Option{WithCorrelationIDs("")}, requestID: "request-id", failUpload: true, wantID: "request-id", wantRequestID: "aws-request-id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			var (
+				mu      sync.Mutex
+				headers http.Header
+			)
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if r.Method != http.MethodPut {
+					return false
+				}
+				mu.Lock()
+				headers = r.Header.Clone()
+				mu.Unlock()
+				w.Header().Set("X-Amz-Request-Id", "aws-request-id")
+				if tt.failUpload {
+					fakeError(w, http.StatusForbidden, "AccessDenied")
+					return true
+				}
+				return false
+			}
+			opts := append([]Option{WithIDGenerator(idgen.GeneratorFunc(func() string { return "generated-id" }))}, tt.opts...)
+			svc := fake.service(opts...)
+
+			ctx := context.Background()
+			if tt.ctxID != "" {
+				ctx = WithCorrelationID(ctx, tt.ctxID)
+			}
+			result, err := svc.UploadFileContext(ctx, UploadFileRequest{
+				BucketName:    "bucket",
+				Filename:      "a.txt",
+				ContentType:   "text/plain",
+				Body:          io.NopCloser(strings.NewReader("a")),
+				CorrelationID: tt.requestID,
+			})
+
+			if tt.failUpload {
+				var correlated *CorrelatedError
+				if !errors.As(err, &correlated) {
+					t.Fatalf("UploadFileContext error = %v, want a CorrelatedError", err)
+				}
+				if correlated.CorrelationID != tt.wantID || correlated.RequestID != tt.wantRequestID {
+					t.Errorf("error carries %q and %q, want %q and %q", correlated.CorrelationID, correlated.RequestID, tt.wantID, tt.wantRequestID)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("UploadFileContext: %v", err)
+				}
+				if result.CorrelationID != tt.wantID || result.RequestID != tt.wantRequestID {
+					t.Errorf("result carries %q and %q, want %q and %q", result.CorrelationID, result.RequestID, tt.wantID, tt.wantRequestID)
+				}
+			}
+
+			header := cmpOr(tt.header, DefaultCorrelationHeader)
+			mu.Lock()
+			defer mu.Unlock()
+			if got := headers.Get(header); got != tt.wantID {
+				t.Errorf("%s header = %q, want %q", header, got, tt.wantID)
+			}
+			if hasAgent := strings.Contains(headers.Get("User-Agent"), "correlation-id/"+tt.wantID); hasAgent != (tt.wantID != "") {
+				t.Errorf("user agent = %q, want the correlation ID in it: %v", headers.Get("User-Agent"), tt.wantID != "")
+			}
+		})
+	}
+}
+
+func TestDeleteCorrelation(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	fake.put("bucket", "a.txt", "text/plain", []byte("a"), nil)
+	svc := fake.service(WithCorrelationIDs(""))
+
+	result, err := svc.DeleteFileContext(WithCorrelationID(context.Background(), "ctx-id"), DeleteFileRequest{BucketName: "bucket", Filename: []string{"a.txt"}})
+	if err != nil {
+		t.Fatalf("DeleteFileContext: %v", err)
+	}
+	if result.CorrelationID != "ctx-id" {
+		t.Errorf("CorrelationID = %q, want ctx-id", result.CorrelationID)
+	}
+}
@@ -0,0 +1,128 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+const browserUploadsCORSRuleID = "file-uploader-browser-uploads"
+
+var defaultBrowserUploadMethods = []string{"GET", "HEAD", "PUT", "POST"}
+
+// SetCORSForBrowserUploads lets origins upload straight to the bucket with
+// presigned URLs and POST policies. ETag is exposed so browsers can complete
+// multipart uploads. Rules not created by this method are kept.
+func (s *s3Service) SetCORSForBrowserUploads(ctx context.Context, bucketName string, origins, methods []string, maxAge time.Duration) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if bucketName == "" {
+		return errors.New("bucket name is required")
+	}
+
+	if len(origins) == 0 {
+		return errors.New("at least one origin is required")
+	}
+
+	if len(methods) == 0 {
+		methods = defaultBrowserUploadMethods
+	}
+
+	rules, err := s.corsRules(ctx, bucketName)
+	if err != nil {
+		return err
+	}
+
+	corsRules := []types.CORSRule{{
+		ID:             aws.String(browserUploadsCORSRuleID),
+		AllowedOrigins: origins,
+		AllowedMethods: methods,
+		AllowedHeaders: []string{"*"},
+		ExposeHeaders:  []string{"ETag", "x-amz-version-id", "x-amz-request-id"},
+		MaxAgeSeconds:  aws.Int32(int32(maxAge.Seconds())),
+	}}
+	// Other rules are put back as S3 returned them, so fields they leave unset,
+	// such as MaxAgeSeconds, stay unset.
+	for _, rule := range rules {
+		if aws.ToString(rule.ID) != browserUploadsCORSRuleID {
+			corsRules = append(corsRules, rule)
+		}
+	}
+
+	_, err = s.s3Cli.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+		Bucket:            aws.String(bucketName),
+		CORSConfiguration: &types.CORSConfiguration{CORSRules: corsRules},
+	})
+	if err != nil {
+		log.Printf("failed to set cors of bucket %s: %v", bucketName, err)
+		return fmt.Errorf("failed to set cors: %w", err)
+	}
+
+	return nil
+}
+
+// GetCORS returns the CORS rules of the bucket, or none when it has no CORS
+// configuration.
+func (s *s3Service) GetCORS(ctx context.Context, bucketName string) ([]CORSRule, error) {
+	if err := s.acquire(); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if bucketName == "" {
+		return nil, errors.New("bucket name is required")
+	}
+
+	return s.getCORS(ctx, bucketName)
+}
+
+func (s *s3Service) getCORS(ctx context.Context, bucketName string) ([]CORSRule, error) {
+	corsRules, err := s.corsRules(ctx, bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]CORSRule, 0, len(corsRules))
+	for _, rule := range corsRules {
+		rules = append(rules, CORSRule{
+			ID:             aws.ToString(rule.ID),
+			AllowedOrigins: rule.AllowedOrigins,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedHeaders: rule.AllowedHeaders,
+			ExposeHeaders:  rule.ExposeHeaders,
+			MaxAge:         time.Duration(aws.ToInt32(rule.MaxAgeSeconds)) * time.Second,
+		})
+	}
+
+	return rules, nil
+}
+
+func (s *s3Service) corsRules(ctx context.Context, bucketName string) ([]types.CORSRule, error) {
+	output, err := s.s3Cli.GetBucketCors(ctx, &s3.GetBucketCorsInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		var apiError smithy.APIError
+		if errors.As(err, &apiError) && apiError.ErrorCode() == "NoSuchCORSConfiguration" {
+			return nil, nil
+		}
+		log.Printf("failed to get cors of bucket %s: %v", bucketName, err)
+		return nil, fmt.Errorf("failed to get cors: %w", err)
+	}
+
+	return output.CORSRules, nil
+}
@@ -0,0 +1,84 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeCORSRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	MaxAgeSeconds  *int     `xml:"MaxAgeSeconds"`
+}
+
+type fakeCORSConfiguration struct {
+	XMLName xml.Name       `xml:"CORSConfiguration"`
+	Rules   []fakeCORSRule `xml:"CORSRule"`
+}
+
+func TestSetCORSForBrowserUploadsKeepsOtherRules(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	var (
+		mu     sync.Mutex
+		stored = []byte(`<CORSConfiguration>
+<CORSRule><ID>no-max-age</ID><AllowedOrigin>https://a.example</AllowedOrigin><AllowedMethod>GET</AllowedMethod></CORSRule>
+<CORSRule><AllowedOrigin>https://b.example</AllowedOrigin><AllowedMethod>GET</AllowedMethod><MaxAgeSeconds>600</MaxAgeSeconds></CORSRule>
+<CORSRule><ID>file-uploader-browser-uploads</ID><AllowedOrigin>https://old.example</AllowedOrigin><AllowedMethod>PUT</AllowedMethod></CORSRule>
+</CORSConfiguration>`)
+	)
+	fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if !r.URL.Query().Has("cors") {
+			return false
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Method == http.MethodPut {
+			stored, _ = io.ReadAll(r.Body)
+			return true
+		}
+		w.Write(stored)
+		return true
+	}
+	svc := fake.service()
+
+	err := svc.SetCORSForBrowserUploads(context.Background(), "bucket", []string{"https://app.example"}, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("SetCORSForBrowserUploads: %v", err)
+	}
+
+	mu.Lock()
+	var config fakeCORSConfiguration
+	err = xml.Unmarshal(stored, &config)
+	mu.Unlock()
+	if err != nil {
+		t.Fatalf("stored configuration: %v", err)
+	}
+	if len(config.Rules) != 3 {
+		t.Fatalf("stored %d rules, want the browser rule replaced and two kept: %+v", len(config.Rules), config.Rules)
+	}
+	browser, noMaxAge, other := config.Rules[0], config.Rules[1], config.Rules[2]
+	if browser.ID != "file-uploader-browser-uploads" || !slices.Equal(browser.AllowedOrigins, []string{"https://app.example"}) || browser.MaxAgeSeconds == nil || *browser.MaxAgeSeconds != 3600 {
+		t.Errorf("browser rule = %+v, want the new origin with a max age of 3600", browser)
+	}
+	if noMaxAge.ID != "no-max-age" || noMaxAge.MaxAgeSeconds != nil {
+		t.Errorf("rule without max age stored as %+v, want it unchanged", noMaxAge)
+	}
+	if other.ID != "" || other.MaxAgeSeconds == nil || *other.MaxAgeSeconds != 600 {
+		t.Errorf("rule without ID stored as %+v, want it unchanged", other)
+	}
+
+	rules, err := svc.GetCORS(context.Background(), "bucket")
+	if err != nil {
+		t.Fatalf("GetCORS: %v", err)
+	}
+	if len(rules) != 3 || rules[1].MaxAge != 0 || rules[2].MaxAge != 10*time.Minute {
+		t.Errorf("GetCORS = %+v, want the stored rules back", rules)
+	}
+}
@@ -0,0 +1,125 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+// SourceKeyMetadata is the user metadata key linking a derived object, such as
+// a thumbnail or a variant, to the key of its original. Objects carrying it are
+// collected by CollectOrphans once the original is gone.
+const SourceKeyMetadata = "source-key"
+
+// CollectOrphans deletes derived objects under data.Prefix whose original no
+// longer exists. Derived objects are recognised by suffix (the video thumbnail
+// suffix by default) and, with CheckMetadata, by SourceKeyMetadata, which costs
+// one HEAD request per remaining object.
+func (s *s3Service) CollectOrphans(ctx context.Context, data OrphanGCRequest) (BatchResult, error) {
+	if err := s.acquire(); err != nil {
+		return BatchResult{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateCollectOrphans(data); err != nil {
+		return BatchResult{}, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDelete, data.BucketName, data.Prefix); err != nil {
+		return BatchResult{}, err
+	}
+
+	suffixes := data.Suffixes
+	if len(suffixes) == 0 {
+		suffixes = []string{thumbnailSuffix}
+	}
+
+	existing := map[string]bool{}
+	candidates := []string{}
+	cutoff := time.Now().Add(-data.MinAge)
+	paginator := s3.NewListObjectsV2Paginator(s.s3Cli, &s3.ListObjectsV2Input{
+		Bucket: aws.String(data.BucketName),
+		Prefix: aws.String(data.Prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("failed to list objects of bucket %s: %v", data.BucketName, err)
+			return BatchResult{}, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			existing[key] = true
+			if aws.ToTime(object.LastModified).Before(cutoff) {
+				candidates = append(candidates, key)
+			}
+		}
+	}
+
+	orphans := []string{}
+	for _, key := range candidates {
+		source, ok := derivedSource(key, suffixes)
+		if !ok && data.CheckMetadata {
+			source, ok = s.metadataSource(ctx, data.BucketName, key)
+		}
+		// A grant on the prefix "a/b" does not cover listed keys like "a/bc".
+		if !ok || s.authorizeKeys(ctx, authz.PermissionDelete, data.BucketName, key) != nil {
+			continue
+		}
+
+		exists := existing[source]
+		if !strings.HasPrefix(source, data.Prefix) {
+			var err error
+			if exists, err = s.isFileExist(ctx, data.BucketName, source); err != nil {
+				continue
+			}
+		}
+
+		if !exists {
+			orphans = append(orphans, key)
+		}
+	}
+
+	// Orphans are deleted like any other file, so the delete guard and the
+	// trash apply and the index and catalog follow.
+	result, err := s.deleteFile(ctx, DeleteFileRequest{BucketName: data.BucketName, Filename: orphans, DryRun: data.DryRun})
+	if len(orphans) > 0 && !data.DryRun {
+		log.Printf("collected %d orphaned derived objects on bucket %s", len(result.Succeeded()), data.BucketName)
+	}
+
+	return result, err
+}
+
+func derivedSource(key string, suffixes []string) (string, bool) {
+	for _, suffix := range suffixes {
+		if source, ok := strings.CutSuffix(key, suffix); ok && source != "" {
+			return source, true
+		}
+	}
+
+	return "", false
+}
+
+func (s *s3Service) metadataSource(ctx context.Context, bucketName, key string) (string, bool) {
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Printf("failed to get head object %s: %v", key, err)
+		return "", false
+	}
+
+	source, ok := head.Metadata[SourceKeyMetadata]
+	return source, ok && source != ""
+}
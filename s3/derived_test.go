@@ -0,0 +1,123 @@
+package s3
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDerivedSource(t *testing.T) {
+	suffixes := []string{".thumbnail.jpg", "-small.png"}
+	tests := []struct {
+		key        string
+		wantSource string
+		wantOK     bool
+	}{
+		{key: "video.mp4.thumbnail.jpg", wantSource: "video.mp4", wantOK: true},
+		{key: "photo.png-small.png", wantSource: "photo.png", wantOK: true},
+		{key: ".thumbnail.jpg"},
+		{key: "video.mp4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			source, ok := derivedSource(tt.key, suffixes)
+			if source != tt.wantSource || ok != tt.wantOK {
+				t.Errorf("derivedSource(%q) = %q, %v, want %q, %v", tt.key, source, ok, tt.wantSource, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCollectOrphans(t *testing.T) {
+	old := time.Now().Add(-2 * time.Hour)
+	tests := []struct {
+		name        string
+		request     OrphanGCRequest
+		wantResults []KeyResult
+		wantKeys    []string
+	}{
+		{
+			name:        "by suffix",
+			request:     OrphanGCRequest{BucketName: "bucket"},
+			wantResults: []KeyResult{{Key: "gone.mp4.thumbnail.jpg", Status: Deleted}},
+			wantKeys:    []string{"kept.mp4", "kept.mp4.thumbnail.jpg", "recent.mp4.thumbnail.jpg", "variant.jpg"},
+		},
+		{
+			name:    "by metadata",
+			request: OrphanGCRequest{BucketName: "bucket", CheckMetadata: true},
+			wantResults: []KeyResult{
+				{Key: "gone.mp4.thumbnail.jpg", Status: Deleted},
+				{Key: "variant.jpg", Status: Deleted},
+			},
+			wantKeys: []string{"kept.mp4", "kept.mp4.thumbnail.jpg", "recent.mp4.thumbnail.jpg"},
+		},
+		{
+			name:        "min age",
+			request:     OrphanGCRequest{BucketName: "bucket", MinAge: 3 * time.Hour},
+			wantResults: []KeyResult{},
+			wantKeys:    []string{"gone.mp4.thumbnail.jpg", "kept.mp4", "kept.mp4.thumbnail.jpg", "recent.mp4.thumbnail.jpg", "variant.jpg"},
+		},
+		{
+			name:        "dry run",
+			request:     OrphanGCRequest{BucketName: "bucket", CheckMetadata: true, DryRun: true},
+			wantResults: []KeyResult{{Key: "gone.mp4.thumbnail.jpg", Status: WouldDelete}, {Key: "variant.jpg", Status: WouldDelete}},
+			wantKeys:    []string{"gone.mp4.thumbnail.jpg", "kept.mp4", "kept.mp4.thumbnail.jpg", "recent.mp4.thumbnail.jpg", "variant.jpg"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			for _, key := range []string{"kept.mp4", "kept.mp4.thumbnail.jpg", "gone.mp4.thumbnail.jpg"} {
+				fake.put("bucket", key, "image/jpeg", []byte("data"), nil).modified = old
+			}
+			fake.put("bucket", "variant.jpg", "image/jpeg", []byte("data"), map[string]string{SourceKeyMetadata: "deleted.png"}).modified = old
+			// Written a moment ago, so the original may still be uploading.
+			fake.put("bucket", "recent.mp4.thumbnail.jpg", "image/jpeg", []byte("data"), nil)
+			tt.request.MinAge = max(tt.request.MinAge, time.Hour)
+
+			result, err := fake.service().CollectOrphans(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("CollectOrphans: %v", err)
+			}
+			got := []KeyResult{}
+			for _, keyResult := range result.Results {
+				got = append(got, KeyResult{Key: keyResult.Key, Status: keyResult.Status})
+			}
+			slices.SortFunc(got, func(a, b KeyResult) int { return strings.Compare(a.Key, b.Key) })
+			if !slices.Equal(got, tt.wantResults) {
+				t.Errorf("results = %+v, want %+v", got, tt.wantResults)
+			}
+			if keys := fake.keys("bucket"); !slices.Equal(keys, tt.wantKeys) {
+				t.Errorf("bucket holds %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestCollectOrphansDeletesLikeDeleteFile(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	indexer, catalog := newFakeIndexer(), newFakeCatalog()
+	svc := fake.service(WithTrash("trash/"), WithIndexer(indexer), WithCatalog(catalog))
+
+	ctx := context.Background()
+	key := "gone.mp4.thumbnail.jpg"
+	fake.put("bucket", key, "image/jpeg", []byte("data"), nil)
+	indexer.Index(ctx, IndexDocument{BucketName: "bucket", Filename: key})
+	catalog.Record(ctx, CatalogEntry{BucketName: "bucket", Key: key})
+
+	if _, err := svc.CollectOrphans(ctx, OrphanGCRequest{BucketName: "bucket", Prefix: "gone"}); err != nil {
+		t.Fatalf("CollectOrphans: %v", err)
+	}
+
+	if keys := fake.keys("bucket"); !slices.Equal(keys, []string{"trash/" + key}) {
+		t.Errorf("bucket holds %v, want the orphan moved to the trash", keys)
+	}
+	if _, ok := indexer.doc("bucket", key); ok {
+		t.Error("orphan is still indexed")
+	}
+	if _, ok := catalog.entry("bucket", key); ok {
+		t.Error("orphan is still cataloged")
+	}
+}
@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/aws/smithy-go/middleware"
+)
+
+func (s *s3Service) clientOptions(o *s3.Options) {
+	o.APIOptions = append(o.APIOptions, s.accessPointMiddleware)
+	o.APIOptions = append(o.APIOptions, s.middleware()...)
+
+	// Access point ARNs carry their own region, which may not be the client's.
+	o.UseARNRegion = true
+
+	if s.endpoint != "" {
+		o.BaseEndpoint = aws.String(s.endpoint)
+		o.UsePathStyle = true
+	}
+
+	if s.dualStack {
+		o.EndpointOptions.UseDualStackEndpoint = aws.DualStackEndpointStateEnabled
+	}
+
+	if s.fips {
+		o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+	}
+}
+
+// controlOptions configures the S3 Control client used for batch operations
+// with the same middleware and endpoint variants as the S3 client.
+func (s *s3Service) controlOptions(o *s3control.Options) {
+	o.APIOptions = append(o.APIOptions, s.middleware()...)
+
+	if s.dualStack {
+		o.EndpointOptions.UseDualStackEndpoint = aws.DualStackEndpointStateEnabled
+	}
+
+	if s.fips {
+		o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+	}
+}
+
+// middleware returns the API options shared by every client of the service:
+// headers, correlation IDs, session refresh, the circuit breaker, timeouts and
+// those added with WithMiddleware.
+func (s *s3Service) middleware() []func(*middleware.Stack) error {
+	fns := []func(*middleware.Stack) error{s.headerMiddleware}
+	if s.correlationHeader != "" {
+		fns = append(fns, s.correlationMiddleware)
+	}
+	if s.credentials != nil {
+		fns = append(fns, s.sessionMiddleware)
+	}
+	if s.breaker != nil {
+		fns = append(fns, s.breakerMiddleware)
+	}
+	if s.timeouts != nil {
+		fns = append(fns, s.timeoutMiddleware)
+	}
+
+	return append(fns, s.apiOptions...)
+}
+
+// accelerationTTL is how long the accelerate configuration of a bucket is
+// cached, so enabling or suspending it takes effect without a restart.
+const accelerationTTL = 10 * time.Minute
+
+// endpointVariant holds the endpoint variants a single transfer asks for on
+// top of those enabled for the whole service.
+type endpointVariant struct {
+	accelerate bool
+	dualStack  bool
+	fips       bool
+}
+
+// acceleration is a cached accelerate configuration of a bucket.
+type acceleration struct {
+	enabled bool
+	checked time.Time
+}
+
+// transferOptions returns the client options for a data transfer on bucketName.
+// Acceleration is only used when the bucket has it enabled; otherwise the request
+// silently falls back to the standard endpoint.
+func (s *s3Service) transferOptions(ctx context.Context, bucketName string, variant endpointVariant) []func(*s3.Options) {
+	var fns []func(*s3.Options)
+	if variant.dualStack && !s.dualStack {
+		fns = append(fns, func(o *s3.Options) {
+			o.EndpointOptions.UseDualStackEndpoint = aws.DualStackEndpointStateEnabled
+		})
+	}
+	fips := variant.fips || s.fips
+	if variant.fips && !s.fips {
+		fns = append(fns, func(o *s3.Options) {
+			o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+		})
+	}
+
+	if !(variant.accelerate || s.accelerate) || fips || isAccessPoint(s.bucketNames.Name(bucketName)) {
+		return fns
+	}
+
+	if !s.isAccelerated(ctx, bucketName) {
+		return fns
+	}
+
+	return append(fns, func(o *s3.Options) {
+		o.UseAccelerate = true
+	})
+}
+
+func (s *s3Service) isAccelerated(ctx context.Context, bucketName string) bool {
+	if cached, ok := s.accelerated.Load(bucketName); ok && time.Since(cached.(acceleration).checked) < accelerationTTL {
+		return cached.(acceleration).enabled
+	}
+
+	output, err := s.s3Cli.GetBucketAccelerateConfiguration(ctx, &s3.GetBucketAccelerateConfigurationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		log.Printf("failed to get accelerate configuration of bucket %s, using standard endpoint: %v", bucketName, err)
+		return false
+	}
+
+	enabled := output.Status == types.BucketAccelerateStatusEnabled
+	s.accelerated.Store(bucketName, acceleration{enabled: enabled, checked: time.Now()})
+	if !enabled {
+		log.Printf("transfer acceleration is not enabled on bucket %s, using standard endpoint", bucketName)
+	}
+
+	return enabled
+}
@@ -0,0 +1,96 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// fakeAcceleration answers accelerate configuration requests with status and
+// counts them.
+func fakeAcceleration(fake *fakeS3, status string, checks *atomic.Int32) {
+	fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if !r.URL.Query().Has("accelerate") {
+			return false
+		}
+		checks.Add(1)
+		fmt.Fprintf(w, `<AccelerateConfiguration><Status>%s</Status></AccelerateConfiguration>`, status)
+		return true
+	}
+}
+
+func TestTransferOptions(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           []Option
+		variant        endpointVariant
+		status         string
+		wantAccelerate bool
+		wantDualStack  bool
+		wantFIPS       bool
+	}{
+		{name: "standard", status: "Enabled"},
+		{name: "accelerated", variant: endpointVariant{accelerate: true}, status: "Enabled", wantAccelerate: true},
+		{name: "acceleration suspended", variant: endpointVariant{accelerate: true}, status: "Suspended"},
+		{name: "accelerated by the service", opts: []Option{WithTransferAcceleration()}, status: "Enabled", wantAccelerate: true},
+		{name: "dual-stack", variant: endpointVariant{dualStack: true, accelerate: true}, status: "Enabled", wantAccelerate: true, wantDualStack: true},
+		{name: "fips", variant: endpointVariant{fips: true}, wantFIPS: true},
+		{name: "fips disables acceleration", variant: endpointVariant{fips: true, accelerate: true}, status: "Enabled", wantFIPS: true},
+		{name: "service fips disables acceleration", opts: []Option{WithFIPS()}, variant: endpointVariant{accelerate: true}, status: "Enabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			var checks atomic.Int32
+			fakeAcceleration(fake, tt.status, &checks)
+			svc := fake.service(tt.opts...).(*s3Service)
+
+			var o s3.Options
+			for _, fn := range svc.transferOptions(context.Background(), "bucket", tt.variant) {
+				fn(&o)
+			}
+
+			if o.UseAccelerate != tt.wantAccelerate {
+				t.Errorf("UseAccelerate = %v, want %v", o.UseAccelerate, tt.wantAccelerate)
+			}
+			if dualStack := o.EndpointOptions.UseDualStackEndpoint == aws.DualStackEndpointStateEnabled; dualStack != tt.wantDualStack {
+				t.Errorf("dual-stack = %v, want %v", dualStack, tt.wantDualStack)
+			}
+			if fips := o.EndpointOptions.UseFIPSEndpoint == aws.FIPSEndpointStateEnabled; fips != tt.wantFIPS {
+				t.Errorf("fips = %v, want %v", fips, tt.wantFIPS)
+			}
+		})
+	}
+}
+
+func TestIsAcceleratedCacheExpires(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	var checks atomic.Int32
+	fakeAcceleration(fake, "Enabled", &checks)
+	svc := fake.service().(*s3Service)
+	ctx := context.Background()
+
+	for range 2 {
+		if !svc.isAccelerated(ctx, "bucket") {
+			t.Fatal("isAccelerated = false, want true")
+		}
+	}
+	if got := checks.Load(); got != 1 {
+		t.Errorf("checked the configuration %d times, want once while cached", got)
+	}
+
+	svc.accelerated.Store("bucket", acceleration{enabled: true, checked: time.Now().Add(-accelerationTTL)})
+	fakeAcceleration(fake, "Suspended", &checks)
+	if svc.isAccelerated(ctx, "bucket") {
+		t.Error("isAccelerated = true after the cache expired and acceleration was suspended")
+	}
+	if got := checks.Load(); got != 2 {
+		t.Errorf("checked the configuration %d times, want again once expired", got)
+	}
+}
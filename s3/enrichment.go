@@ -0,0 +1,264 @@
+package s3
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+const (
+	maxExtractedText = 32 * 1024
+	// maxID3TagSize bounds the ID3 tag read into memory; the text frames are at
+	// its start, and larger tags mostly carry embedded pictures.
+	maxID3TagSize = 1 << 20
+)
+
+type (
+	// Enricher inspects the upload stream and returns metadata about it. It must not
+	// hold on to r after returning; unread bytes are discarded by the service.
+	Enricher interface {
+		Enrich(contentType string, r io.Reader) (Enrichment, error)
+	}
+
+	Enrichment struct {
+		Metadata map[string]string
+		Text     string
+	}
+)
+
+type metadataEnricher struct{}
+
+// NewMetadataEnricher extracts EXIF tags from images, ID3v2 tags from audio, page
+// count and title from PDFs, and a text excerpt from text documents.
+func NewMetadataEnricher() Enricher {
+	return metadataEnricher{}
+}
+
+func (metadataEnricher) Enrich(contentType string, r io.Reader) (Enrichment, error) {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return enrichImage(r)
+	case strings.HasPrefix(contentType, "audio/"):
+		return enrichAudio(r)
+	case contentType == "application/pdf":
+		return enrichPDF(r)
+	case strings.HasPrefix(contentType, "text/"):
+		text, err := io.ReadAll(io.LimitReader(r, maxExtractedText))
+		if err != nil {
+			return Enrichment{}, err
+		}
+		return Enrichment{Text: strings.ToValidUTF8(string(text), "")}, nil
+	default:
+		return Enrichment{}, nil
+	}
+}
+
+func enrichImage(r io.Reader) (Enrichment, error) {
+	x, err := exif.Decode(r)
+	if err != nil {
+		if errors.Is(err, io.EOF) || exif.IsCriticalError(err) {
+			return Enrichment{}, nil
+		}
+		return Enrichment{}, err
+	}
+
+	metadata := map[string]string{}
+	for key, field := range map[string]exif.FieldName{
+		"exif-make":        exif.Make,
+		"exif-model":       exif.Model,
+		"exif-lens-model":  exif.LensModel,
+		"exif-orientation": exif.Orientation,
+	} {
+		tag, err := x.Get(field)
+		if err != nil {
+			continue
+		}
+		metadata[key] = strings.Trim(tag.String(), `"`)
+	}
+
+	if taken, err := x.DateTime(); err == nil {
+		metadata["exif-datetime"] = taken.Format("2006-01-02T15:04:05")
+	}
+
+	if lat, long, err := x.LatLong(); err == nil {
+		metadata["exif-gps"] = fmt.Sprintf("%.6f,%.6f", lat, long)
+	}
+
+	return Enrichment{Metadata: metadata}, nil
+}
+
+var id3Frames = map[string]string{
+	"TIT2": "id3-title",
+	"TPE1": "id3-artist",
+	"TALB": "id3-album",
+	"TYER": "id3-year",
+	"TDRC": "id3-year",
+	"TCON": "id3-genre",
+	"TRCK": "id3-track",
+}
+
+func enrichAudio(r io.Reader) (Enrichment, error) {
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:3]) != "ID3" {
+		return Enrichment{}, nil
+	}
+
+	version := header[3]
+	if version < 3 {
+		return Enrichment{}, nil
+	}
+
+	size := syncsafe(header[6:10])
+	tag := make([]byte, min(size, maxID3TagSize))
+	if _, err := io.ReadFull(r, tag); err != nil {
+		return Enrichment{}, err
+	}
+	if _, err := io.CopyN(io.Discard, r, int64(size-len(tag))); err != nil {
+		return Enrichment{}, err
+	}
+
+	metadata := map[string]string{}
+	for len(tag) >= 10 && tag[0] != 0 {
+		id := string(tag[:4])
+		size := int(binary.BigEndian.Uint32(tag[4:8]))
+		if version == 4 {
+			size = syncsafe(tag[4:8])
+		}
+		if size > len(tag)-10 {
+			break
+		}
+
+		if key, ok := id3Frames[id]; ok && size > 1 {
+			metadata[key] = decodeID3Text(tag[10 : 10+size])
+		}
+		tag = tag[10+size:]
+	}
+
+	return Enrichment{Metadata: metadata}, nil
+}
+
+// syncsafe decodes a 28-bit ID3 size, ignoring the high bit of each byte
+// that a valid tag leaves clear.
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7f)<<21 | int(b[1]&0x7f)<<14 | int(b[2]&0x7f)<<7 | int(b[3]&0x7f)
+}
+
+func decodeID3Text(frame []byte) string {
+	encoding, text := frame[0], frame[1:]
+	switch encoding {
+	case 0:
+		runes := make([]rune, 0, len(text))
+		for _, b := range text {
+			runes = append(runes, rune(b))
+		}
+		return strings.TrimRight(string(runes), "\x00")
+	case 1:
+		var order binary.ByteOrder = binary.BigEndian
+		if len(text) >= 2 && text[0] == 0xFF && text[1] == 0xFE {
+			order = binary.LittleEndian
+		}
+		if len(text) >= 2 && (text[0] == 0xFF || text[0] == 0xFE) {
+			text = text[2:]
+		}
+		return decodeUTF16(text, order)
+	case 2:
+		return decodeUTF16(text, binary.BigEndian)
+	default:
+		return strings.TrimRight(string(text), "\x00")
+	}
+}
+
+func decodeUTF16(b []byte, order binary.ByteOrder) string {
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		units = append(units, order.Uint16(b[i:]))
+	}
+
+	return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+}
+
+// enrich tees body into the enricher running in its own goroutine. The returned
+// finish func must be called once the upload has stopped reading body, on every
+// path; calls after the first return the same result.
+func (s *s3Service) enrich(contentType string, body io.Reader) (io.Reader, func(uploadErr error) Enrichment) {
+	pr, pw := io.Pipe()
+	done := make(chan Enrichment, 1)
+
+	go func() {
+		enrichment, err := s.enricher.Enrich(contentType, pr)
+		if err != nil {
+			log.Printf("failed to enrich upload: %v", err)
+		}
+		io.Copy(io.Discard, pr)
+		done <- enrichment
+	}()
+
+	finish := sync.OnceValue(func() Enrichment {
+		return <-done
+	})
+
+	return io.TeeReader(body, pw), func(uploadErr error) Enrichment {
+		pw.CloseWithError(uploadErr)
+		return finish()
+	}
+}
+
+// storeMetadata copies the object onto itself to replace its user metadata, since
+// values such as a PDF page count are only known once the whole body was read.
+func (s *s3Service) storeMetadata(ctx context.Context, data UploadFileRequest, uploadToken string, metadata map[string]string) error {
+	values := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		if value = metadataValue(value); value != "" {
+			values[key] = value
+		}
+	}
+	// Replacing the metadata would otherwise drop the ownership and upload
+	// token set at upload.
+	maps.Copy(values, ownerMetadata(data))
+	if uploadToken != "" {
+		values[UploadTokenMetadata] = uploadToken
+	}
+
+	_, err := s.s3Cli.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:            aws.String(data.BucketName),
+		Key:               aws.String(data.Filename),
+		CopySource:        aws.String(copySource(data.BucketName, data.Filename)),
+		ContentType:       aws.String(data.ContentType),
+		Metadata:          values,
+		MetadataDirective: types.MetadataDirectiveReplace,
+	})
+	if err != nil {
+		log.Printf("failed to store metadata on file %s: %v", data.Filename, err)
+		return fmt.Errorf("failed to store metadata: %w", err)
+	}
+
+	return nil
+}
+
+// metadataValue makes v safe to send as an x-amz-meta-* header value.
+func metadataValue(v string) string {
+	v = strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, v)
+
+	if len(v) > 256 {
+		v = v[:256]
+	}
+
+	return strings.TrimSpace(v)
+}
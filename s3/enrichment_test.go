@@ -0,0 +1,243 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/spill"
+)
+
+type failingSpillStore struct{}
+
+func (failingSpillStore) Create() (spill.File, error) {
+	return nil, errors.New("disk full")
+}
+
+// blockingEnricher reads its whole input, so it only returns once the pipe
+// feeding it is closed.
+type blockingEnricher struct {
+	done chan struct{}
+}
+
+func (e *blockingEnricher) Enrich(_ string, r io.Reader) (Enrichment, error) {
+	defer close(e.done)
+	_, err := io.Copy(io.Discard, r)
+	return Enrichment{}, err
+}
+
+type stubRunner struct{}
+
+func (stubRunner) Probe(context.Context, string) (VideoMetadata, error) {
+	return VideoMetadata{}, nil
+}
+
+func (stubRunner) Thumbnail(context.Context, string, time.Duration) ([]byte, error) {
+	return nil, nil
+}
+
+// probingRunner records the spooled video it was given.
+type probingRunner struct {
+	stubRunner
+	probed string
+}
+
+func (r *probingRunner) Probe(_ context.Context, path string) (VideoMetadata, error) {
+	data, err := os.ReadFile(path)
+	r.probed = string(data)
+	return VideoMetadata{}, err
+}
+
+func TestVideoProcessingSpillStores(t *testing.T) {
+	tests := []struct {
+		name       string
+		encrypt    bool
+		wantVideo  bool
+		wantProbed string
+	}{
+		{name: "plain disk store", wantVideo: true, wantProbed: "video"},
+		{name: "encrypted disk store", encrypt: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			runner := &probingRunner{}
+			svc := newFakeS3(t, "bucket").service(
+				WithSpillStore(spill.NewDiskStore(spill.DiskOptions{Dir: dir, Encrypt: tt.encrypt})),
+				WithVideoProcessing(runner),
+			)
+
+			result, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.mp4",
+				ContentType: "video/mp4",
+				Body:        io.NopCloser(strings.NewReader("video")),
+			})
+			if err != nil {
+				t.Fatalf("UploadFile: %v", err)
+			}
+
+			if got := result.Video != nil; got != tt.wantVideo {
+				t.Errorf("video metadata set = %v, want %v", got, tt.wantVideo)
+			}
+			if runner.probed != tt.wantProbed {
+				t.Errorf("probed %q, want %q", runner.probed, tt.wantProbed)
+			}
+			if files, _ := os.ReadDir(dir); len(files) != 0 {
+				t.Errorf("spill files left behind: %v", files)
+			}
+		})
+	}
+}
+
+func TestEnrichmentStopsOnEarlyReturn(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{name: "spill file cannot be created", opts: []Option{WithSpillStore(failingSpillStore{}), WithVideoProcessing(stubRunner{})}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enricher := &blockingEnricher{done: make(chan struct{})}
+			svc := newFakeS3(t, "bucket").service(append(tt.opts, WithEnrichment(enricher, false))...)
+
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.mp4",
+				ContentType: "video/mp4",
+				Body:        io.NopCloser(strings.NewReader("data")),
+			})
+			if err == nil {
+				t.Fatal("UploadFile succeeded, want error")
+			}
+
+			select {
+			case <-enricher.done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("enrichment goroutine still running after UploadFile returned")
+			}
+		})
+	}
+}
+
+func TestMetadataEnricher(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        Enrichment
+	}{
+		{name: "text", contentType: "text/plain", body: "hello", want: Enrichment{Text: "hello"}},
+		{name: "not a pdf", contentType: "application/pdf", body: "plain", want: Enrichment{}},
+		{name: "unknown", contentType: "application/zip", body: "PK", want: Enrichment{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewMetadataEnricher().Enrich(tt.contentType, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Text != tt.want.Text || len(got.Metadata) != len(tt.want.Metadata) {
+				t.Fatalf("Enrich = %+v, want %+v", got, tt.want)
+			}
+			for key, value := range tt.want.Metadata {
+				if got.Metadata[key] != value {
+					t.Errorf("%s = %q, want %q", key, got.Metadata[key], value)
+				}
+			}
+		})
+	}
+}
+
+// id3Tag builds an ID3v2 tag of the given version from frames, padded to
+// size bytes when that is larger.
+func id3Tag(version byte, size int, frames ...[]byte) []byte {
+	body := bytes.Join(frames, nil)
+	if len(body) < size {
+		body = append(body, make([]byte, size-len(body))...)
+	}
+	n := len(body)
+	header := []byte{'I', 'D', '3', version, 0, 0, byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+	return append(header, body...)
+}
+
+func id3Frame(version byte, id string, text []byte) []byte {
+	frame := []byte(id)
+	n := len(text)
+	if version == 4 {
+		frame = append(frame, byte(n>>21&0x7f), byte(n>>14&0x7f), byte(n>>7&0x7f), byte(n&0x7f))
+	} else {
+		frame = binary.BigEndian.AppendUint32(frame, uint32(n))
+	}
+	return append(append(frame, 0, 0), text...)
+}
+
+func TestEnrichAudio(t *testing.T) {
+	audio := []byte("audio frames")
+	utf16Title := []byte{1, 0xFF, 0xFE, 'T', 0, 'i', 0, 't', 0, 'l', 0, 'e', 0}
+
+	tests := []struct {
+		name    string
+		input   []byte
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "v2.3",
+			input: id3Tag(3, 0, id3Frame(3, "TIT2", []byte("\x00Title")), id3Frame(3, "TPE1", []byte("\x00Artist"))),
+			want:  map[string]string{"id3-title": "Title", "id3-artist": "Artist"},
+		},
+		{
+			name:  "v2.4 utf-16",
+			input: id3Tag(4, 0, id3Frame(4, "TIT2", utf16Title)),
+			want:  map[string]string{"id3-title": "Title"},
+		},
+		{
+			name:  "tag larger than the read limit",
+			input: id3Tag(3, maxID3TagSize+4096, id3Frame(3, "TALB", []byte("\x00Album"))),
+			want:  map[string]string{"id3-album": "Album"},
+		},
+		{
+			name:  "high bits set in the tag size",
+			input: append([]byte{'I', 'D', '3', 3, 0, 0, 0x80, 0x80, 0x80, 0x80 | 16}, id3Frame(3, "TIT2", []byte("\x00Title"))...),
+			want:  map[string]string{"id3-title": "Title"},
+		},
+		{
+			name:    "truncated tag",
+			input:   []byte("ID3\x03\x00\x00\x00\x00\x01\x00short"),
+			wantErr: true,
+		},
+		{name: "no tag", input: []byte("not an mp3 file"), want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(append(tt.input, audio...))
+			got, err := enrichAudio(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("enrichAudio error = %v, want error: %v", err, tt.wantErr)
+			}
+			if len(got.Metadata) != len(tt.want) {
+				t.Errorf("metadata = %v, want %v", got.Metadata, tt.want)
+			}
+			for key, value := range tt.want {
+				if got.Metadata[key] != value {
+					t.Errorf("%s = %q, want %q", key, got.Metadata[key], value)
+				}
+			}
+
+			// A parsed tag is consumed completely, up to the audio after it.
+			if tt.want != nil {
+				if rest, _ := io.ReadAll(r); !bytes.Equal(rest, audio) {
+					t.Errorf("left %d bytes after the tag, want the %d bytes of audio", len(rest), len(audio))
+				}
+			}
+		})
+	}
+}
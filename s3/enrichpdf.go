@@ -0,0 +1,38 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+)
+
+// maxPDFScanBytes bounds the PDF read into memory; larger files are not
+// enriched, since the cross-reference table pdfcpu needs is at their end.
+const maxPDFScanBytes = 32 * 1024 * 1024
+
+func enrichPDF(r io.Reader) (Enrichment, error) {
+	content, err := io.ReadAll(io.LimitReader(bufio.NewReader(r), maxPDFScanBytes+1))
+	if err != nil {
+		return Enrichment{}, err
+	}
+
+	if !bytes.HasPrefix(content, []byte("%PDF-")) || len(content) > maxPDFScanBytes {
+		return Enrichment{}, nil
+	}
+
+	ctx, err := api.ReadAndValidate(bytes.NewReader(content), nil)
+	if err != nil {
+		return Enrichment{}, fmt.Errorf("failed to read pdf: %w", err)
+	}
+
+	metadata := map[string]string{"pdf-pages": strconv.Itoa(ctx.PageCount)}
+	if ctx.Title != "" {
+		metadata["pdf-title"] = ctx.Title
+	}
+
+	return Enrichment{Metadata: metadata}, nil
+}
@@ -0,0 +1,71 @@
+package s3
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// minimalPDF builds a valid PDF with the given number of empty pages and,
+// unless title is empty, an info dictionary carrying it.
+func minimalPDF(pages int, title string) []byte {
+	kids := make([]string, pages)
+	objects := []string{"<< /Type /Catalog /Pages 2 0 R >>", ""}
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", len(objects)+1)
+		objects = append(objects, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << >> >>")
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages)
+	info := ""
+	if title != "" {
+		objects = append(objects, fmt.Sprintf("<< /Title (%s) >>", title))
+		info = fmt.Sprintf(" /Info %d 0 R", len(objects))
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R%s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, info, xref)
+
+	return buf.Bytes()
+}
+
+func TestEnrichPDF(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "pages and title", input: minimalPDF(3, "Report"), want: map[string]string{"pdf-pages": "3", "pdf-title": "Report"}},
+		{name: "without title", input: minimalPDF(1, ""), want: map[string]string{"pdf-pages": "1"}},
+		{name: "not a pdf", input: []byte("plain")},
+		{name: "corrupt", input: []byte("%PDF-1.4 /Type /Page /Type /Page"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := enrichPDF(bytes.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("enrichPDF error = %v, want error: %v", err, tt.wantErr)
+			}
+			if len(got.Metadata) != len(tt.want) {
+				t.Errorf("metadata = %v, want %v", got.Metadata, tt.want)
+			}
+			for key, value := range tt.want {
+				if got.Metadata[key] != value {
+					t.Errorf("%s = %q, want %q", key, got.Metadata[key], value)
+				}
+			}
+		})
+	}
+}
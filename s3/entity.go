@@ -1,10 +1,62 @@
 package s3
 
-import "errors"
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
 
 var (
 	ErrBucketNotFound = errors.New("bucket not found")
 	ErrFileNotFound   = errors.New("file not found")
+	ErrAccessDenied   = errors.New("access denied")
+	ErrFileExists     = errors.New("file already exists")
+	ErrNotOwner       = errors.New("requester does not own the file")
+
+	ErrProtectedKey            = errors.New("key is protected from deletion")
+	ErrDeleteThresholdExceeded = errors.New("delete exceeds the key threshold, set Force to proceed")
+	ErrServiceClosed           = errors.New("service is shut down")
+	ErrClientUnavailable       = errors.New("s3 client is unavailable")
+
+	ErrContentRejected    = errors.New("content rejected by moderation")
+	ErrContentQuarantined = errors.New("content quarantined by moderation")
+
+	ErrQuarantinePrefixRequired = errors.New("moderation quarantine needs a prefix")
+
+	ErrIndexerNotConfigured = errors.New("search indexer is not configured")
+	ErrCatalogNotConfigured = errors.New("catalog is not configured")
+
+	ErrKeyOutsideNamespace = errors.New("key escapes tenant namespace")
+	ErrQuotaExceeded       = errors.New("tenant storage quota exceeded")
+
+	ErrRestoreNotRequested = errors.New("restore has not been requested")
+
+	ErrTrashNotConfigured = errors.New("soft delete is not configured")
+
+	ErrPolicyNotConfigured = errors.New("tag policy is not configured")
+
+	ErrFailoverNotConfigured = errors.New("failover is not configured")
+
+	ErrNotTransportStream = errors.New("media is not an MPEG transport stream")
+
+	ErrRequestTooLarge = errors.New("request body exceeds size limit")
+	ErrImageTooLarge   = errors.New("image exceeds the pixel limit")
+
+	ErrSourceTooLarge = errors.New("remote file exceeds size limit")
+	// ErrDestinationNotAllowed is returned by UploadFromURL for sources that
+	// resolve to loopback, private, link-local or other special addresses.
+	ErrDestinationNotAllowed = errors.New("remote address is not allowed")
+	ErrChecksumMismatch      = errors.New("checksum mismatch")
+	ErrUploadRejected        = errors.New("upload does not match what was authorized")
+	ErrUploadAborted         = errors.New("upload aborted and cleaned up")
+
+	ErrNotVisible = errors.New("change is not visible yet")
 )
 
 type (
@@ -13,15 +65,502 @@ type (
 		ContentType    string
 		Filename       string
 		Base64Encoding string
+		Body           io.Reader
+		Base64Body     io.Reader
+		Tags           map[string]string
+		Accelerate     bool
+		// DualStack and FIPS use those endpoint variants for this upload even
+		// when the service was not created WithDualStack or WithFIPS.
+		DualStack bool
+		FIPS      bool
+		// Transformers run on the decoded body before the service-wide ones.
+		Transformers []Transformer
+		// Headers are sent with the upload requests, e.g. x-amz-expected-bucket-owner.
+		Headers http.Header
+		// Tenant is matched by tag policy rules; TenantStorage sets it.
+		Tenant string
+		// OwnerID and UploadedBy are stored as object metadata and in the
+		// catalog. Deletes requested by anyone but the owner can be refused.
+		OwnerID    string
+		UploadedBy string
+		// CorrelationID overrides the generated one when WithCorrelationIDs is set.
+		CorrelationID string
+		// Principal is checked when WithAuthorization is set.
+		Principal *authz.Principal
+	}
+
+	UploadFileResult struct {
+		Location string
+		Filename string
+		Metadata map[string]string
+		Text     string
+		// Video is set for video uploads when a MediaRunner is configured.
+		Video *VideoMetadata
+		// CorrelationID and RequestID are only set with WithCorrelationIDs.
+		CorrelationID string
+		RequestID     string
+		// FailedOver is set when the primary bucket was unavailable and the file
+		// was written to the failover bucket until Reconcile moves it back.
+		FailedOver bool
+	}
+
+	VideoMetadata struct {
+		Duration     time.Duration
+		Width        int
+		Height       int
+		Codec        string
+		ThumbnailKey string
 	}
 
 	DeleteFileRequest struct {
 		BucketName string
 		Filename   []string
+		// DryRun reports what would be deleted without deleting anything.
+		DryRun bool
+		// Force allows deleting more keys than the service's DeleteGuard.MaxKeys.
+		Force bool
+		// RequesterID, when set, limits the delete to objects it owns unless
+		// IgnoreOwnership is set, e.g. for moderators.
+		RequesterID     string
+		IgnoreOwnership bool
+		CorrelationID   string
+		Principal       *authz.Principal
+	}
+
+	// KeyResult is the outcome for one key of a batch operation; Err is set
+	// unless the key was deleted.
+	KeyResult struct {
+		Key    string
+		Status DeleteStatus
+		Err    error
+	}
+
+	BatchResult struct {
+		Results       []KeyResult
+		CorrelationID string
 	}
 
 	DownloadFileRequest struct {
 		BucketName string
 		Filename   string
+		Accelerate bool
+		// DualStack and FIPS use those endpoint variants for this download.
+		DualStack bool
+		FIPS      bool
+		// Transformers run after the service-wide download transformers.
+		Transformers []Transformer
+		Headers      http.Header
+		Principal    *authz.Principal
+	}
+
+	AbortStaleUploadsRequest struct {
+		BucketName string
+		Prefix     string
+		OlderThan  time.Duration
+	}
+
+	AbortedUpload struct {
+		Key       string
+		UploadID  string
+		Initiated time.Time
+	}
+
+	PostPolicyRequest struct {
+		BucketName  string
+		Filename    string
+		KeyPrefix   string
+		ContentType string
+		MinSize     int64
+		MaxSize     int64
+		Expires     time.Duration
+	}
+
+	PostPolicy struct {
+		URL     string
+		Fields  map[string]string
+		Expires time.Time
+	}
+
+	OrphanGCRequest struct {
+		BucketName string
+		Prefix     string
+		// Suffixes map derived keys onto their originals by naming convention,
+		// e.g. "video.mp4.thumbnail.jpg" onto "video.mp4".
+		Suffixes      []string
+		CheckMetadata bool
+		// MinAge leaves recently written derived objects alone, in case their
+		// original is still being uploaded.
+		MinAge time.Duration
+		DryRun bool
+	}
+
+	PresignedMultipartRequest struct {
+		BucketName  string
+		Filename    string
+		ContentType string
+		Size        int64
+		// PartSize defaults to 10 MiB and is raised when Size needs more than
+		// 10,000 parts.
+		PartSize int64
+		Expires  time.Duration
+		Tags     map[string]string
+		// OwnerID and UploadedBy are stored as object metadata, as for
+		// UploadFile; OwnerID must be repeated on completion.
+		OwnerID    string
+		UploadedBy string
+	}
+
+	PresignedMultipartUpload struct {
+		UploadID string
+		Filename string
+		PartSize int64
+		Parts    []PresignedPart
+		Expires  time.Time
+	}
+
+	PresignedPart struct {
+		Number int32
+		URL    string
+		Offset int64
+		Size   int64
+	}
+
+	CompletePresignedMultipartRequest struct {
+		BucketName string
+		Filename   string
+		UploadID   string
+		Parts      []CompletedPart
+		// Size and OwnerID are those the upload was created with; an
+		// assembled object that does not match them is deleted.
+		Size    int64
+		OwnerID string
+	}
+
+	CompletedPart struct {
+		Number int32
+		ETag   string
+	}
+
+	// ConfirmUploadRequest describes what a client was authorized to upload;
+	// zero fields are not checked.
+	ConfirmUploadRequest struct {
+		BucketName  string
+		Filename    string
+		Size        int64
+		MaxSize     int64
+		ContentType string
+		// SHA256 is the expected hex digest of the content.
+		SHA256 string
+		ETag   string
+	}
+
+	RestoreFileRequest struct {
+		BucketName string
+		Filename   string
+		Tier       types.Tier
+		Days       int32
+	}
+
+	RestoreStatusRequest struct {
+		BucketName string
+		Filename   string
+	}
+
+	RestoreStatus struct {
+		StorageClass string
+		InProgress   bool
+		Restored     bool
+		ExpiresAt    time.Time
+	}
+
+	QueryObjectRequest struct {
+		BucketName   string
+		Filename     string
+		Expression   string
+		InputFormat  QueryFormat
+		OutputFormat QueryFormat
+		CSVHeader    bool
+		JSONDocument bool
+		Compression  types.CompressionType
+	}
+
+	BatchJobRequest struct {
+		AccountID         string
+		RoleArn           string
+		BucketName        string
+		Filenames         []string
+		ManifestBucket    string
+		ManifestKey       string
+		Operation         BatchOperation
+		DestinationBucket string
+		DestinationPrefix string
+		Tags              map[string]string
+		RestoreDays       int32
+		LambdaArn         string
+		ReportBucket      string
+		ReportPrefix      string
+		Priority          int32
+		Description       string
+	}
+
+	BatchJobStatusRequest struct {
+		AccountID string
+		JobID     string
+	}
+
+	BatchJobStatus struct {
+		JobID          string
+		Status         string
+		Reason         string
+		TotalTasks     int64
+		SucceededTasks int64
+		FailedTasks    int64
+		Failures       []string
+	}
+
+	UsageReportRequest struct {
+		BucketName string
+		Prefix     string
+		// PrefixDepth is how many "/"-separated key segments below Prefix are used to
+		// group per-prefix statistics. Zero disables per-prefix grouping.
+		PrefixDepth int
+	}
+
+	UsageStats struct {
+		ObjectCount   int64            `json:"objectCount"`
+		TotalBytes    int64            `json:"totalBytes"`
+		SizeHistogram map[string]int64 `json:"sizeHistogram"`
+		LastModified  map[string]int64 `json:"lastModified"`
+		Oldest        time.Time        `json:"oldest"`
+		Newest        time.Time        `json:"newest"`
+	}
+
+	UsageReport struct {
+		BucketName  string                 `json:"bucket"`
+		Prefix      string                 `json:"prefix,omitempty"`
+		GeneratedAt time.Time              `json:"generatedAt"`
+		Total       UsageStats             `json:"total"`
+		Prefixes    map[string]*UsageStats `json:"prefixes,omitempty"`
+	}
+
+	MigrateRequest struct {
+		SourceBucket      string
+		DestinationBucket string
+		Prefix            string
+		DestinationPrefix string
+		// Destination streams objects into another service (account, endpoint or
+		// credentials) instead of copying them server-side.
+		Destination    S3Service
+		Concurrency    int
+		CheckpointPath string
+		OnProgress     func(MigrateProgress)
+	}
+
+	MigrateProgress struct {
+		Copied   int64
+		Failed   int64
+		Bytes    int64
+		Filename string
+	}
+
+	MigrateFailure struct {
+		Filename string
+		Err      error
+	}
+
+	MigrateResult struct {
+		Copied int64
+		Bytes  int64
+		Failed []MigrateFailure
+	}
+
+	// ChunkOptions tune UploadChunked. Sizes default to 256 KiB, 1 MiB and 4 MiB;
+	// changing them changes chunk boundaries and so defeats deduplication
+	// against chunks stored before.
+	ChunkOptions struct {
+		Prefix      string
+		MinSize     int
+		AvgSize     int
+		MaxSize     int
+		Concurrency int
+	}
+
+	ChunkedUploadResult struct {
+		Filename string
+		Size     int64
+		Chunks   int
+		// Uploaded counts chunks the bucket did not have; the rest were reused.
+		Uploaded      int
+		Reused        int
+		UploadedBytes int64
+	}
+
+	ReconcileResult struct {
+		Moved int64
+		// Superseded counts failed-over files dropped because the primary
+		// bucket already had a newer copy.
+		Superseded int64
+		Failed     []MigrateFailure
+	}
+
+	ExportOptions struct {
+		Concurrency    int
+		CheckpointPath string
+		OnProgress     func(ExportProgress)
+	}
+
+	ExportProgress struct {
+		Downloaded int64
+		Skipped    int64
+		Failed     int64
+		Bytes      int64
+		Filename   string
+	}
+
+	ExportFailure struct {
+		Filename string
+		Err      error
+	}
+
+	ExportResult struct {
+		Downloaded int64
+		Skipped    int64
+		Bytes      int64
+		Failed     []ExportFailure
+	}
+
+	RequestUploadOptions struct {
+		BucketName string
+		// Filename is the key for UploadFromRequestBody; multipart files are
+		// stored as KeyPrefix plus the base name of the submitted file.
+		Filename  string
+		KeyPrefix string
+		// FileFields limits which form fields are accepted as files; empty
+		// accepts every file part.
+		FileFields []string
+		MaxSize    int64
+		MaxFiles   int
+		Tags       map[string]string
+		Accelerate bool
+	}
+
+	MultipartUploadResult struct {
+		Files  []UploadFileResult
+		Fields map[string]string
+	}
+
+	URLUploadOptions struct {
+		// HTTPClient defaults to http.DefaultClient with the redirect limit applied.
+		HTTPClient *http.Client
+		// AllowedNetworks lets the source, or a redirect, resolve into ranges
+		// that are denied by default, e.g. an internal mirror's subnet.
+		AllowedNetworks []netip.Prefix
+		MaxRedirects    int
+		MaxSize         int64
+		// ContentType overrides the type reported by the remote server.
+		ContentType string
+		// SHA256 is the expected hex digest; on mismatch the upload fails and
+		// nothing is stored.
+		SHA256     string
+		Tags       map[string]string
+		Accelerate bool
+	}
+
+	CORSRule struct {
+		ID             string
+		AllowedOrigins []string
+		AllowedMethods []string
+		AllowedHeaders []string
+		ExposeHeaders  []string
+		MaxAge         time.Duration
+	}
+
+	PlaylistRequest struct {
+		BucketName  string
+		Filename    string
+		SegmentSize int64
+		// Duration is the media length, e.g. UploadFileResult.Video.Duration.
+		Duration time.Duration
+		// URLBuilder serves segments from a CDN such as CloudFront; by default
+		// the playlist uses presigned URLs valid for Expires.
+		URLBuilder URLBuilder
+		Expires    time.Duration
+	}
+
+	Playlist struct {
+		URL         string
+		Size        int64
+		ContentType string
+		Segments    []PlaylistSegment
+	}
+
+	PlaylistSegment struct {
+		Offset   int64
+		Length   int64
+		Duration time.Duration
+	}
+
+	PathUploadOptions struct {
+		// ContentType defaults to the type implied by the file extension, or
+		// sniffed from the content.
+		ContentType string
+		Tags        map[string]string
+		Accelerate  bool
+	}
+
+	ListFilesRequest struct {
+		BucketName string
+		Prefix     string
+		PageSize   int32
+	}
+
+	FileInfo struct {
+		Key          string
+		Size         int64
+		ETag         string
+		StorageClass string
+		LastModified time.Time
+		// URL is only set when the service has a URLBuilder.
+		URL string
+		// OwnerID is only set by ListFilesByOwner.
+		OwnerID string
+		// ListedAt is when the page holding this entry was fetched.
+		ListedAt time.Time
+	}
+
+	FileVersion struct {
+		Key          string
+		VersionID    string
+		IsLatest     bool
+		DeleteMarker bool
+		Size         int64
+		ETag         string
+		LastModified time.Time
+	}
+
+	BucketInfo struct {
+		Name         string
+		Region       string
+		CreationDate time.Time
+	}
+
+	RenameOptions struct {
+		Overwrite OverwritePolicy
+		// RequesterID, when set, limits the rename to files it owns, and
+		// OverwriteReplace to destinations it owns, unless IgnoreOwnership is set.
+		RequesterID     string
+		IgnoreOwnership bool
+	}
+
+	FileStat struct {
+		Key          string
+		Size         int64
+		ContentType  string
+		ETag         string
+		LastModified time.Time
+		StorageClass string
+		VersionID    string
+		Metadata     map[string]string
+		Tags         map[string]string
 	}
 )
@@ -0,0 +1,236 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const gib = 1 << 30
+
+type PlannedOperation string
+
+const (
+	PlanUpload   PlannedOperation = "upload"
+	PlanDownload PlannedOperation = "download"
+	PlanSync     PlannedOperation = "sync"
+)
+
+type (
+	// StoragePrice is the on-demand price of one storage class, in USD.
+	StoragePrice struct {
+		GBMonth         float64
+		PerThousandPUT  float64
+		PerThousandGET  float64
+		RetrievalPerGB  float64
+		MinimumObjectKB int64
+	}
+
+	RegionPricing struct {
+		StorageClasses map[types.StorageClass]StoragePrice
+		EgressPerGB    float64
+	}
+
+	// PricingTable maps region names onto their prices.
+	PricingTable map[string]RegionPricing
+
+	CostPlan struct {
+		Operation    PlannedOperation
+		Region       string
+		StorageClass types.StorageClass
+		// Sizes lists individual file sizes. When empty, FileCount files of
+		// TotalBytes/FileCount bytes each are assumed.
+		Sizes      []int64
+		FileCount  int64
+		TotalBytes int64
+		PartSize   int64
+		// Months is how long uploaded data is kept, for the storage estimate.
+		Months float64
+	}
+
+	CostEstimate struct {
+		Requests      int64
+		RequestCost   float64
+		StorageCost   float64
+		TransferCost  float64
+		RetrievalCost float64
+		Total         float64
+	}
+)
+
+var standardRequests = StoragePrice{PerThousandPUT: 0.005, PerThousandGET: 0.0004}
+
+func regionPricing(standardGBMonth, egressPerGB float64) RegionPricing {
+	price := func(p StoragePrice, gbMonth float64) StoragePrice {
+		p.GBMonth = gbMonth
+		return p
+	}
+
+	return RegionPricing{
+		EgressPerGB: egressPerGB,
+		StorageClasses: map[types.StorageClass]StoragePrice{
+			types.StorageClassStandard:           price(standardRequests, standardGBMonth),
+			types.StorageClassIntelligentTiering: price(standardRequests, standardGBMonth),
+			types.StorageClassStandardIa:         {GBMonth: standardGBMonth * 0.54, PerThousandPUT: 0.01, PerThousandGET: 0.001, RetrievalPerGB: 0.01, MinimumObjectKB: 128},
+			types.StorageClassOnezoneIa:          {GBMonth: standardGBMonth * 0.43, PerThousandPUT: 0.01, PerThousandGET: 0.001, RetrievalPerGB: 0.01, MinimumObjectKB: 128},
+			types.StorageClassGlacierIr:          {GBMonth: standardGBMonth * 0.17, PerThousandPUT: 0.02, PerThousandGET: 0.01, RetrievalPerGB: 0.03, MinimumObjectKB: 128},
+			types.StorageClassGlacier:            {GBMonth: standardGBMonth * 0.16, PerThousandPUT: 0.03, PerThousandGET: 0.0004, RetrievalPerGB: 0.01},
+			types.StorageClassDeepArchive:        {GBMonth: standardGBMonth * 0.043, PerThousandPUT: 0.05, PerThousandGET: 0.0004, RetrievalPerGB: 0.02},
+		},
+	}
+}
+
+// DefaultPricing is a bundled snapshot of public on-demand S3 prices. It is meant
+// for previews; pass an up-to-date PricingTable for anything contractual.
+var DefaultPricing = PricingTable{
+	"us-east-1":      regionPricing(0.023, 0.09),
+	"us-east-2":      regionPricing(0.023, 0.09),
+	"us-west-2":      regionPricing(0.023, 0.09),
+	"eu-west-1":      regionPricing(0.023, 0.09),
+	"eu-central-1":   regionPricing(0.0245, 0.09),
+	"ap-southeast-1": regionPricing(0.025, 0.12),
+	"ap-southeast-3": regionPricing(0.025, 0.132),
+	"ap-northeast-1": regionPricing(0.025, 0.114),
+}
+
+var ErrUnknownPricing = errors.New("no pricing for region or storage class")
+
+func EstimateCost(plan CostPlan) (CostEstimate, error) {
+	return DefaultPricing.Estimate(plan)
+}
+
+func (p PricingTable) Estimate(plan CostPlan) (CostEstimate, error) {
+	region, ok := p[plan.Region]
+	if !ok {
+		return CostEstimate{}, fmt.Errorf("%w: region %s", ErrUnknownPricing, plan.Region)
+	}
+
+	storageClass := plan.StorageClass
+	if storageClass == "" {
+		storageClass = types.StorageClassStandard
+	}
+
+	price, ok := region.StorageClasses[storageClass]
+	if !ok {
+		return CostEstimate{}, fmt.Errorf("%w: storage class %s", ErrUnknownPricing, storageClass)
+	}
+
+	groups, err := plannedSizes(plan)
+	if err != nil {
+		return CostEstimate{}, err
+	}
+
+	partSize := plan.PartSize
+	if partSize <= 0 {
+		partSize = 10 * 1024 * 1024
+	}
+
+	// Byte totals are summed as float64: only their GiB value is used, and a
+	// large FileCount times a 5 TiB object overflows int64.
+	var files, requests int64
+	var totalBytes, billedBytes float64
+	for _, group := range groups {
+		files += group.count
+		if requests, ok = mulAdd(requests, group.count, requestsFor(plan.Operation, group.size, partSize)); !ok {
+			return CostEstimate{}, errors.New("plan needs more requests than can be counted")
+		}
+		totalBytes += float64(group.count) * float64(group.size)
+		billedBytes += float64(group.count) * float64(max(group.size, price.MinimumObjectKB*1024))
+	}
+
+	estimate := CostEstimate{Requests: requests}
+	totalGB := totalBytes / gib
+
+	switch plan.Operation {
+	case PlanUpload, PlanSync:
+		if plan.Operation == PlanSync {
+			// Sync lists the destination first, billed as PUT-class LIST requests.
+			listRequests := int64(math.Ceil(float64(files) / 1000))
+			if requests, ok = mulAdd(requests, listRequests, 1); !ok {
+				return CostEstimate{}, errors.New("plan needs more requests than can be counted")
+			}
+			estimate.Requests = requests
+		}
+		estimate.RequestCost = float64(requests) / 1000 * price.PerThousandPUT
+		estimate.StorageCost = billedBytes / gib * price.GBMonth * plan.Months
+	case PlanDownload:
+		estimate.RequestCost = float64(requests) / 1000 * price.PerThousandGET
+		estimate.TransferCost = totalGB * region.EgressPerGB
+		estimate.RetrievalCost = totalGB * price.RetrievalPerGB
+	default:
+		return CostEstimate{}, fmt.Errorf("unsupported operation %q", plan.Operation)
+	}
+
+	estimate.Total = estimate.RequestCost + estimate.StorageCost + estimate.TransferCost + estimate.RetrievalCost
+	return estimate, nil
+}
+
+// sizeGroup is count files of size bytes each.
+type sizeGroup struct {
+	size, count int64
+}
+
+// plannedSizes groups the files of plan by size. Files given by count are not
+// listed one by one, so the count the caller picks costs no memory.
+func plannedSizes(plan CostPlan) ([]sizeGroup, error) {
+	var groups []sizeGroup
+	if len(plan.Sizes) > 0 {
+		groups = make([]sizeGroup, len(plan.Sizes))
+		for i, size := range plan.Sizes {
+			groups[i] = sizeGroup{size: size, count: 1}
+		}
+	} else {
+		if plan.FileCount <= 0 || plan.TotalBytes < 0 {
+			return nil, errors.New("sizes or file count and total bytes are required")
+		}
+
+		each := plan.TotalBytes / plan.FileCount
+		groups = []sizeGroup{{size: each + plan.TotalBytes%plan.FileCount, count: 1}}
+		if plan.FileCount > 1 {
+			groups = append(groups, sizeGroup{size: each, count: plan.FileCount - 1})
+		}
+	}
+
+	for _, group := range groups {
+		if group.size < 0 || group.size > maxObjectSize {
+			return nil, fmt.Errorf("file size %d is outside the S3 limit of 0 to %d bytes", group.size, int64(maxObjectSize))
+		}
+	}
+
+	return groups, nil
+}
+
+// mulAdd returns sum + a*b for non-negative operands, or false if the result
+// does not fit in an int64.
+func mulAdd(sum, a, b int64) (int64, bool) {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	if hi != 0 || lo > uint64(math.MaxInt64-sum) {
+		return 0, false
+	}
+
+	return sum + int64(lo), true
+}
+
+// requestsFor counts the requests needed to transfer one object: a single request
+// below the part size, otherwise one ranged GET per part for downloads and
+// create + one per part + complete for uploads. Like the uploader, the part size
+// grows to keep an object within the S3 part limit.
+func requestsFor(operation PlannedOperation, size, partSize int64) int64 {
+	if size <= partSize {
+		return 1
+	}
+
+	maxParts := int64(manager.MaxUploadParts)
+	partSize = max(partSize, (size+maxParts-1)/maxParts)
+
+	parts := (size + partSize - 1) / partSize
+	if operation == PlanDownload {
+		return parts
+	}
+
+	return parts + 2
+}
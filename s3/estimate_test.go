@@ -0,0 +1,39 @@
+package s3
+
+import "testing"
+
+func TestEstimateCost(t *testing.T) {
+	const tib = 1 << 40
+
+	tests := []struct {
+		name         string
+		plan         CostPlan
+		wantRequests int64
+		wantStorage  bool
+		wantErr      bool
+	}{
+		{name: "listed sizes", plan: CostPlan{Operation: PlanDownload, Sizes: []int64{1, 25 << 20}}, wantRequests: 4},
+		{name: "uneven split", plan: CostPlan{Operation: PlanUpload, FileCount: 3, TotalBytes: 10}, wantRequests: 3},
+		{name: "huge file count", plan: CostPlan{Operation: PlanUpload, FileCount: 1 << 40, TotalBytes: 1 << 50}, wantRequests: 1 << 40},
+		{name: "largest object", plan: CostPlan{Operation: PlanUpload, Sizes: []int64{5 * tib}}, wantRequests: 10_002},
+		{name: "object above the limit", plan: CostPlan{Operation: PlanUpload, FileCount: 1, TotalBytes: 5*tib + 1}, wantErr: true},
+		{name: "billed bytes beyond int64", plan: CostPlan{Operation: PlanUpload, StorageClass: "STANDARD_IA", FileCount: 1 << 50, Months: 1}, wantRequests: 1 << 50, wantStorage: true},
+		{name: "requests beyond int64", plan: CostPlan{Operation: PlanUpload, FileCount: 1 << 61, TotalBytes: 1 << 62, PartSize: 1}, wantErr: true},
+		{name: "negative size", plan: CostPlan{Operation: PlanUpload, Sizes: []int64{-1}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.plan.Region = "us-east-1"
+			got, err := EstimateCost(tt.plan)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EstimateCost error = %v, want error %v", err, tt.wantErr)
+			}
+			if got.Requests != tt.wantRequests {
+				t.Errorf("Requests = %d, want %d", got.Requests, tt.wantRequests)
+			}
+			if tt.wantStorage && got.StorageCost <= 0 {
+				t.Errorf("StorageCost = %v, want a positive cost", got.StorageCost)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const mib = 1024 * 1024
+
+// commonPartSizes are tried by MatchETag when the part size of a multipart
+// object is unknown: this package's uploader default first, then the AWS SDK
+// and CLI defaults.
+var commonPartSizes = []int64{10 * mib, 5 * mib, 8 * mib, 16 * mib, 64 * mib, 100 * mib}
+
+// ComputeETag returns the ETag S3 assigns to an unencrypted (or SSE-S3)
+// object of size bytes uploaded with partSize parts: the hex MD5 for a single
+// part, otherwise the MD5 of the concatenated part digests followed by
+// "-<parts>". Parts are hashed concurrently.
+func ComputeETag(r io.ReaderAt, size, partSize int64) (string, error) {
+	return computeETag(r, size, partSize, size > partSize)
+}
+
+// ComputeMultipartETag is ComputeETag for an object known to have been
+// uploaded in parts, which has a "-1" ETag even when it fits in one part.
+func ComputeMultipartETag(r io.ReaderAt, size, partSize int64) (string, error) {
+	return computeETag(r, size, partSize, true)
+}
+
+func computeETag(r io.ReaderAt, size, partSize int64, multipart bool) (string, error) {
+	if partSize <= 0 {
+		return "", errors.New("part size must be greater than zero")
+	}
+
+	if !multipart {
+		hash := md5.New()
+		if _, err := io.Copy(hash, io.NewSectionReader(r, 0, size)); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	parts := max(int((size+partSize-1)/partSize), 1)
+	digests := make([][]byte, parts)
+	errs := make([]error, parts)
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(runtime.GOMAXPROCS(0), parts) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range next {
+				offset := int64(part) * partSize
+				hash := md5.New()
+				_, errs[part] = io.Copy(hash, io.NewSectionReader(r, offset, min(partSize, size-offset)))
+				digests[part] = hash.Sum(nil)
+			}
+		}()
+	}
+
+	for part := range parts {
+		next <- part
+	}
+	close(next)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return "", err
+	}
+
+	hash := md5.New()
+	for _, digest := range digests {
+		hash.Write(digest)
+	}
+
+	return fmt.Sprintf("%s-%d", hex.EncodeToString(hash.Sum(nil)), parts), nil
+}
+
+func FileETag(path string, partSize int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	return ComputeETag(file, info.Size(), partSize)
+}
+
+// MatchETag reports whether the local file has the given remote ETag without
+// downloading the object. For multipart ETags the part size is derived from
+// the part count and common upload part sizes.
+func MatchETag(path, etag string) (bool, error) {
+	etag = strings.Trim(etag, `"`)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	size := info.Size()
+
+	_, count, multipart := strings.Cut(etag, "-")
+	if !multipart {
+		local, err := ComputeETag(file, size, max(size, 1))
+		return err == nil && local == etag, err
+	}
+
+	parts, err := strconv.ParseInt(count, 10, 64)
+	if err != nil || parts < 1 {
+		return false, fmt.Errorf("invalid multipart etag %q", etag)
+	}
+
+	for _, partSize := range candidatePartSizes(size, parts) {
+		local, err := ComputeMultipartETag(file, size, partSize)
+		if err != nil {
+			return false, err
+		}
+		if local == etag {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// candidatePartSizes returns part sizes that split size into exactly parts
+// parts, preferring common sizes and whole MiB values.
+func candidatePartSizes(size, parts int64) []int64 {
+	candidates := []int64{}
+	fits := func(partSize int64) bool {
+		return partSize > 0 && (size+partSize-1)/partSize == parts && !slices.Contains(candidates, partSize)
+	}
+
+	for _, partSize := range commonPartSizes {
+		if fits(partSize) {
+			candidates = append(candidates, partSize)
+		}
+	}
+
+	exact := (size + parts - 1) / parts
+	if rounded := (exact + mib - 1) / mib * mib; fits(rounded) {
+		candidates = append(candidates, rounded)
+	}
+	if fits(exact) {
+		candidates = append(candidates, exact)
+	}
+
+	return candidates
+}
@@ -0,0 +1,54 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestComputeETag(t *testing.T) {
+	data := []byte("hello world")
+	sum := md5.Sum(data)
+	partSum := md5.Sum(sum[:])
+	first, second := md5.Sum(data[:6]), md5.Sum(data[6:])
+	twoPartSum := md5.Sum(append(first[:], second[:]...))
+
+	tests := []struct {
+		name      string
+		partSize  int64
+		multipart bool
+		want      string
+	}{
+		{name: "single part", partSize: 16, want: hex.EncodeToString(sum[:])},
+		{name: "one part multipart", partSize: 16, multipart: true, want: hex.EncodeToString(partSum[:]) + "-1"},
+		{name: "exact fit multipart", partSize: int64(len(data)), multipart: true, want: hex.EncodeToString(partSum[:]) + "-1"},
+		{name: "two parts", partSize: 6, want: hex.EncodeToString(twoPartSum[:]) + "-2"},
+		{name: "two parts multipart", partSize: 6, multipart: true, want: hex.EncodeToString(twoPartSum[:]) + "-2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compute := ComputeETag
+			if tt.multipart {
+				compute = ComputeMultipartETag
+			}
+			got, err := compute(bytes.NewReader(data), int64(len(data)), tt.partSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("etag = %s, want %s", got, tt.want)
+			}
+
+			path := filepath.Join(t.TempDir(), "data")
+			if err := os.WriteFile(path, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if match, err := MatchETag(path, `"`+tt.want+`"`); err != nil || !match {
+				t.Errorf("MatchETag(%s) = %v, %v, want a match", tt.want, match, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+)
+
+// Export downloads every object under prefix into localDir, keeping the key
+// hierarchy below prefix. Files already present with the object's size and
+// ETag are skipped, so repeated exports only fetch what changed. Progress is
+// checkpointed after each listed page so an interrupted run can resume.
+func (s *s3Service) Export(ctx context.Context, bucketName, prefix, localDir string, opts ExportOptions) (ExportResult, error) {
+	if err := s.acquire(); err != nil {
+		return ExportResult{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateExport(bucketName, localDir); err != nil {
+		return ExportResult{}, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, bucketName, prefix); err != nil {
+		return ExportResult{}, err
+	}
+
+	if err := os.MkdirAll(localDir, 0o755); err != nil {
+		return ExportResult{}, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	startAfter, err := readCheckpoint(opts.CheckpointPath)
+	if err != nil {
+		return ExportResult{}, err
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultMigrateLimit
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}
+	if startAfter != "" {
+		input.StartAfter = aws.String(startAfter)
+	}
+
+	progress := &exportCounter{}
+	var failuresMu sync.Mutex
+	result := ExportResult{}
+	downloader := manager.NewDownloader(s.s3Cli)
+
+	paginator := s3.NewListObjectsV2Paginator(s.s3Cli, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("failed to list objects of bucket %s: %v", bucketName, err)
+			return progress.result(result), fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for _, object := range page.Contents {
+			if ctx.Err() != nil {
+				break
+			}
+
+			key := aws.ToString(object.Key)
+			localPath, ok := exportPath(localDir, prefix, key)
+			if !ok || s.authorizeKeys(ctx, authz.PermissionDownload, bucketName, key) != nil {
+				continue
+			}
+
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(object types.Object) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				skipped, err := s.exportObject(ctx, downloader, bucketName, object, localPath)
+				switch {
+				case err != nil:
+					log.Printf("failed to export file %s: %v", key, err)
+					failuresMu.Lock()
+					result.Failed = append(result.Failed, ExportFailure{Filename: key, Err: err})
+					failuresMu.Unlock()
+					progress.failed.Add(1)
+				case skipped:
+					progress.skipped.Add(1)
+				default:
+					progress.downloaded.Add(1)
+					progress.bytes.Add(aws.ToInt64(object.Size))
+				}
+
+				if opts.OnProgress != nil {
+					opts.OnProgress(progress.snapshot(key))
+				}
+			}(object)
+		}
+		wg.Wait()
+
+		if err := ctx.Err(); err != nil {
+			return progress.result(result), err
+		}
+
+		if len(page.Contents) > 0 && len(result.Failed) == 0 {
+			lastKey := aws.ToString(page.Contents[len(page.Contents)-1].Key)
+			if err := writeCheckpoint(opts.CheckpointPath, lastKey); err != nil {
+				return progress.result(result), err
+			}
+		}
+	}
+
+	result = progress.result(result)
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("failed to export %d files", len(result.Failed))
+	}
+
+	if opts.CheckpointPath != "" {
+		if err := os.Remove(opts.CheckpointPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("failed to remove checkpoint %s: %v", opts.CheckpointPath, err)
+		}
+	}
+
+	return result, nil
+}
+
+// exportObject downloads object into a temporary file next to localPath and
+// renames it into place, so an interrupted download never leaves a partial
+// file that a later run would have to detect.
+func (s *s3Service) exportObject(ctx context.Context, downloader *manager.Downloader, bucketName string, object types.Object, localPath string) (bool, error) {
+	if info, err := os.Stat(localPath); err == nil && info.Mode().IsRegular() && info.Size() == aws.ToInt64(object.Size) {
+		if match, err := MatchETag(localPath, aws.ToString(object.ETag)); err == nil && match {
+			return true, nil
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+		return false, err
+	}
+
+	file, err := idgen.CreateTemp(s.ids, filepath.Dir(localPath), "."+filepath.Base(localPath)+".*.part")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = downloader.Download(ctx, file, &s3.GetObjectInput{
+		Bucket:  aws.String(bucketName),
+		Key:     object.Key,
+		IfMatch: object.ETag,
+	})
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err := os.Rename(file.Name(), localPath); err != nil {
+		return false, err
+	}
+
+	if modified := aws.ToTime(object.LastModified); !modified.IsZero() {
+		if err := os.Chtimes(localPath, modified, modified); err != nil {
+			log.Printf("failed to set modification time of %s: %v", localPath, err)
+		}
+	}
+
+	return false, nil
+}
+
+// exportPath maps key below prefix onto a path inside localDir. Folder markers
+// and keys that would resolve to localDir itself are not exported.
+func exportPath(localDir, prefix, key string) (string, bool) {
+	if strings.HasSuffix(key, "/") {
+		return "", false
+	}
+
+	name := zipEntryName(strings.TrimPrefix(key, prefix))
+	if name == "" || name == "." {
+		return "", false
+	}
+
+	return filepath.Join(localDir, filepath.FromSlash(name)), true
+}
+
+type exportCounter struct {
+	downloaded atomic.Int64
+	skipped    atomic.Int64
+	failed     atomic.Int64
+	bytes      atomic.Int64
+}
+
+func (e *exportCounter) snapshot(key string) ExportProgress {
+	return ExportProgress{
+		Downloaded: e.downloaded.Load(),
+		Skipped:    e.skipped.Load(),
+		Failed:     e.failed.Load(),
+		Bytes:      e.bytes.Load(),
+		Filename:   key,
+	}
+}
+
+func (e *exportCounter) result(result ExportResult) ExportResult {
+	result.Downloaded = e.downloaded.Load()
+	result.Skipped = e.skipped.Load()
+	result.Bytes = e.bytes.Load()
+	return result
+}
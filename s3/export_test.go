@@ -0,0 +1,143 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	fake.put("bucket", "docs/a.txt", "text/plain", []byte("a"), nil)
+	fake.put("bucket", "docs/sub/b.txt", "text/plain", []byte("bb"), nil)
+	fake.put("bucket", "docs/folder/", "application/x-directory", nil, nil)
+	fake.put("bucket", "other.txt", "text/plain", []byte("other"), nil)
+	svc := fake.service()
+
+	dir := t.TempDir()
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var reported []string
+	result, err := svc.Export(context.Background(), "bucket", "docs/", dir, ExportOptions{
+		CheckpointPath: checkpoint,
+		OnProgress: func(p ExportProgress) {
+			mu.Lock()
+			defer mu.Unlock()
+			reported = append(reported, p.Filename)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if result.Downloaded != 1 || result.Skipped != 1 || result.Bytes != 2 {
+		t.Errorf("result = %+v, want 1 downloaded, 1 skipped and 2 bytes", result)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "sub", "b.txt")); err != nil || string(content) != "bb" {
+		t.Errorf("exported sub/b.txt = %q, %v, want bb", content, err)
+	}
+	for _, name := range []string{"folder", "other.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s exported, want it left out", name)
+		}
+	}
+	slices.Sort(reported)
+	if !slices.Equal(reported, []string{"docs/a.txt", "docs/sub/b.txt"}) {
+		t.Errorf("progress reported for %v, want both files", reported)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Error("checkpoint kept after a complete export")
+	}
+
+	result, err = svc.Export(context.Background(), "bucket", "docs/", dir, ExportOptions{})
+	if err != nil || result.Downloaded != 0 || result.Skipped != 2 {
+		t.Errorf("repeated Export = %+v, %v, want everything skipped", result, err)
+	}
+}
+
+func TestExportResumes(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	fake.put("bucket", "a.txt", "text/plain", []byte("a"), nil)
+	fake.put("bucket", "b.txt", "text/plain", []byte("b"), nil)
+	dir := t.TempDir()
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+	if err := os.WriteFile(checkpoint, []byte("a.txt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := fake.service().Export(context.Background(), "bucket", "", dir, ExportOptions{CheckpointPath: checkpoint})
+	if err != nil || result.Downloaded != 1 {
+		t.Fatalf("Export = %+v, %v, want only the file after the checkpoint", result, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Error("a.txt exported although the checkpoint is past it")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "b.txt")); err != nil {
+		t.Errorf("b.txt not exported: %v", err)
+	}
+}
+
+func TestExportDownloadFails(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	fake.put("bucket", "a.txt", "text/plain", []byte("a"), nil)
+	fake.put("bucket", "b.txt", "text/plain", []byte("b"), nil)
+	fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodGet || r.URL.Path != "/bucket/b.txt" {
+			return false
+		}
+		fakeError(w, http.StatusForbidden, "AccessDenied")
+		return true
+	}
+	dir := t.TempDir()
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint")
+
+	result, err := fake.service().Export(context.Background(), "bucket", "", dir, ExportOptions{CheckpointPath: checkpoint})
+	if err == nil {
+		t.Fatal("Export succeeded")
+	}
+	if result.Downloaded != 1 || len(result.Failed) != 1 || result.Failed[0].Filename != "b.txt" {
+		t.Errorf("result = %+v, want a.txt exported and b.txt failed", result)
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Error("checkpoint written past a failed file")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "a.txt" {
+		t.Errorf("export directory holds %v, want only a.txt", entries)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		bucket         string
+		localDir       bool
+		wantValidation bool
+	}{
+		{name: "missing bucket name", localDir: true, wantValidation: true},
+		{name: "missing local directory", bucket: "bucket", wantValidation: true},
+		{name: "unknown bucket", bucket: "missing", localDir: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localDir := ""
+			if tt.localDir {
+				localDir = t.TempDir()
+			}
+
+			_, err := newFakeS3(t, "bucket").service().Export(context.Background(), tt.bucket, "", localDir, ExportOptions{})
+			var validationErr *ValidationError
+			if err == nil || errors.As(err, &validationErr) != tt.wantValidation {
+				t.Errorf("Export error = %v, want a validation error: %v", err, tt.wantValidation)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/breaker"
+)
+
+const defaultFailoverPrefix = "failover/"
+
+// FailoverPolicy names where uploads go when the primary bucket stays
+// unavailable after the client's retries. Failed-over objects are stored as
+// Prefix+bucket/key, which is how Reconcile finds them and where they belong.
+type FailoverPolicy struct {
+	BucketName string
+	// Backup is a service built with NewS3Service, usually for another region.
+	// Nil writes to BucketName through this service.
+	Backup S3Service
+	// Prefix defaults to "failover/".
+	Prefix string
+}
+
+func (p *FailoverPolicy) prefix() string {
+	if p.Prefix == "" {
+		return defaultFailoverPrefix
+	}
+	return p.Prefix
+}
+
+func (p *FailoverPolicy) key(bucketName, key string) string {
+	return p.prefix() + bucketName + "/" + key
+}
+
+// isUnavailable reports whether err means the primary could not be reached,
+// as opposed to rejecting the request.
+func isUnavailable(err error) bool {
+	var maxAttempts *retry.MaxAttemptsError
+	if errors.As(err, &maxAttempts) || errors.Is(err, breaker.ErrOpen) || errors.Is(err, ErrClientUnavailable) {
+		return true
+	}
+
+	var respErr *awsHttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() >= http.StatusInternalServerError
+}
+
+// uploadWithFailover uploads to the primary bucket and, when it is unavailable,
+// replays the body into the failover bucket. Seekable bodies are rewound; other
+// bodies are spilled while the primary reads them.
+func (s *s3Service) uploadWithFailover(ctx context.Context, data UploadFileRequest) (UploadFileResult, error) {
+	data.CorrelationID = CorrelationID(s.correlate(ctx, data.CorrelationID))
+
+	body := uploadBody(data)
+	primary := data
+	primary.Base64Body, primary.Base64Encoding = nil, ""
+
+	// Transformer stages read the body in goroutines that can outlive
+	// uploadFile, so the first attempt is cut off before the body is replayed.
+	// A seekable body read by nothing else is passed as is, keeping ReadAt.
+	attempt := &attemptReader{r: body}
+	var replay func() (io.Reader, error)
+	if seeker, ok := body.(io.ReadSeeker); ok {
+		offset, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return UploadFileResult{}, err
+		}
+		primary.Body = body
+		if len(data.Transformers) > 0 || len(s.uploadTransformers) > 0 {
+			primary.Body = attempt
+		}
+		replay = func() (io.Reader, error) {
+			attempt.stop()
+			_, err := seeker.Seek(offset, io.SeekStart)
+			return seeker, err
+		}
+	} else {
+		spool, err := s.spillStore().Create()
+		if err != nil {
+			return UploadFileResult{}, err
+		}
+		defer removeSpool(spool)
+
+		attempt.r = io.TeeReader(body, spool)
+		primary.Body = attempt
+		replay = func() (io.Reader, error) {
+			attempt.stop()
+			return io.MultiReader(io.NewSectionReader(spool, 0, spool.Size()), body), nil
+		}
+	}
+
+	result, err := s.uploadFile(ctx, primary)
+	if err == nil || !isUnavailable(err) {
+		return result, err
+	}
+
+	var replayErr error
+	if primary.Body, replayErr = replay(); replayErr != nil {
+		return UploadFileResult{}, fmt.Errorf("%w; failed to replay file for failover: %w", err, replayErr)
+	}
+	return s.failOver(ctx, primary, err)
+}
+
+var errAttemptStopped = errors.New("upload attempt was stopped for failover")
+
+// attemptReader hands the body to one upload attempt until stop is called.
+// stop waits for a Read in progress, so no bytes the attempt takes are missing
+// from the spool afterwards.
+type attemptReader struct {
+	mu      sync.Mutex
+	r       io.Reader
+	stopped bool
+}
+
+func (a *attemptReader) Read(p []byte) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.stopped {
+		return 0, errAttemptStopped
+	}
+	return a.r.Read(p)
+}
+
+func (a *attemptReader) stop() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stopped = true
+}
+
+// failOver writes data to the failover bucket after the primary failed with
+// cause.
+func (s *s3Service) failOver(ctx context.Context, data UploadFileRequest, cause error) (UploadFileResult, error) {
+	policy := s.failover
+	log.Printf("bucket %s is unavailable, failing over %s to bucket %s: %v", data.BucketName, data.Filename, policy.BucketName, cause)
+
+	backup := data
+	backup.BucketName = policy.BucketName
+	backup.Filename = policy.key(data.BucketName, data.Filename)
+
+	var (
+		result UploadFileResult
+		err    error
+	)
+	if policy.Backup != nil {
+		result, err = policy.Backup.UploadFile(backup)
+	} else if err = s.validateUploadFile(ctx, backup); err == nil {
+		result, err = s.uploadFile(ctx, backup)
+	}
+	if err != nil {
+		return UploadFileResult{}, fmt.Errorf("%w; failover to bucket %s failed: %w", cause, policy.BucketName, err)
+	}
+
+	result.Filename = data.Filename
+	result.FailedOver = true
+	return result, nil
+}
+
+// Reconcile moves failed-over objects back to their primary bucket. An object
+// that was written to the primary again since is kept there and the backup copy
+// dropped. Reconcile stops early while the primary is still unavailable.
+func (s *s3Service) Reconcile(ctx context.Context) (ReconcileResult, error) {
+	if err := s.acquire(); err != nil {
+		return ReconcileResult{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	policy := s.failover
+	if policy == nil {
+		return ReconcileResult{}, ErrFailoverNotConfigured
+	}
+
+	backup := s
+	if policy.Backup != nil {
+		var ok bool
+		if backup, ok = policy.Backup.(*s3Service); !ok {
+			return ReconcileResult{}, errors.New("failover backup must be created by NewS3Service")
+		}
+	}
+
+	result := ReconcileResult{}
+	paginator := s3.NewListObjectsV2Paginator(backup.s3Cli, &s3.ListObjectsV2Input{
+		Bucket: aws.String(policy.BucketName),
+		Prefix: aws.String(policy.prefix()),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("failed to list failover bucket %s: %v", policy.BucketName, err)
+			return result, fmt.Errorf("failed to list failed-over files: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			key := aws.ToString(object.Key)
+			bucketName, filename, ok := strings.Cut(strings.TrimPrefix(key, policy.prefix()), "/")
+			if !ok || bucketName == "" || filename == "" {
+				log.Printf("skipping unexpected key %s in failover bucket %s", key, policy.BucketName)
+				continue
+			}
+
+			superseded, err := backup.reconcileObject(ctx, s, policy, bucketName, filename, object)
+			switch {
+			case err != nil && isUnavailable(err):
+				return result, fmt.Errorf("bucket %s is still unavailable: %w", bucketName, err)
+			case err != nil:
+				log.Printf("failed to reconcile file %s to bucket %s: %v", filename, bucketName, err)
+				result.Failed = append(result.Failed, MigrateFailure{Filename: key, Err: err})
+			case superseded:
+				result.Superseded++
+			default:
+				result.Moved++
+			}
+		}
+	}
+
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("failed to reconcile %d files", len(result.Failed))
+	}
+
+	return result, nil
+}
+
+// reconcileObject moves one object from the failover bucket of s back to
+// primary, and reports whether a newer primary copy made the move unnecessary.
+func (s *s3Service) reconcileObject(ctx context.Context, primary *s3Service, policy *FailoverPolicy, bucketName, filename string, object types.Object) (bool, error) {
+	head, err := primary.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(filename),
+	})
+	if err != nil && !isNotFound(err) {
+		return false, err
+	}
+
+	key := aws.ToString(object.Key)
+	superseded := err == nil && aws.ToTime(head.LastModified).After(aws.ToTime(object.LastModified))
+	if !superseded {
+		prefix := policy.key(bucketName, "")
+		err = s.migrateObject(ctx, primary, MigrateRequest{
+			SourceBucket:      policy.BucketName,
+			DestinationBucket: bucketName,
+			Prefix:            prefix,
+		}, object)
+		if err != nil {
+			return false, err
+		}
+
+		if primary.catalog != nil {
+			primary.catalogObject(ctx, bucketName, filename, s.catalogedEntry(ctx, policy.BucketName, key))
+		}
+	}
+
+	if err := s.deleteObject(ctx, policy.BucketName, key); err != nil {
+		return superseded, err
+	}
+
+	if s.catalog != nil {
+		s.uncatalog(ctx, policy.BucketName, []string{key})
+	}
+	return superseded, nil
+}
@@ -0,0 +1,80 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// drainingTransformer passes its input through a pipe and, once the reader of
+// that pipe goes away, keeps consuming its input until it ends.
+type drainingTransformer struct{}
+
+func (drainingTransformer) Transform(_ context.Context, contentType string, r io.Reader) (io.Reader, string, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		_, err := io.Copy(pw, r)
+		pw.CloseWithError(err)
+		if err != nil {
+			io.Copy(io.Discard, r)
+		}
+	}()
+	return pr, contentType, nil
+}
+
+// onlyReader hides every method but Read, so the body cannot be rewound.
+type onlyReader struct {
+	io.Reader
+}
+
+func TestFailoverReplaysWholeBody(t *testing.T) {
+	// Larger than a part, so the primary fails while the stage still has
+	// bytes left to read.
+	const size = 11 << 20
+	want := bytes.Repeat([]byte("0123456789abcdef"), size/16)
+
+	tests := []struct {
+		name string
+		body func() io.Reader
+	}{
+		{name: "seekable", body: func() io.Reader { return bytes.NewReader(want) }},
+		{name: "spilled", body: func() io.Reader { return onlyReader{bytes.NewReader(want)} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "primary", "backup")
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if !strings.HasPrefix(r.URL.Path, "/primary/") || r.Method == http.MethodHead {
+					return false
+				}
+				fakeError(w, http.StatusServiceUnavailable, "ServiceUnavailable")
+				return true
+			}
+			svc := fake.service(WithFailover(FailoverPolicy{BucketName: "backup"}), WithUploadTransformers(drainingTransformer{}))
+
+			result, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "primary",
+				Filename:    "a.bin",
+				ContentType: "application/octet-stream",
+				Body:        tt.body(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.FailedOver {
+				t.Fatal("upload did not fail over")
+			}
+
+			o, ok := fake.object("backup", "failover/primary/a.bin")
+			if !ok {
+				t.Fatalf("failed-over object missing, have %v", fake.keys("backup"))
+			}
+			if !bytes.Equal(o.body, want) {
+				t.Errorf("failed-over object has %d bytes, want %d", len(o.body), len(want))
+			}
+		})
+	}
+}
@@ -0,0 +1,607 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// fakeS3 is an in-memory, path-style S3 server covering the calls the service
+// makes: buckets, objects with metadata and tags, copies, ranges, conditional
+// deletes, listings, batch deletes and multipart uploads.
+type fakeS3 struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	buckets  map[string]bool
+	objects  map[string]*fakeObject
+	uploads  map[string]map[int][]byte
+	versions int
+	requests []string
+	// initiated keeps the content type, metadata and tags an upload was
+	// created with, which its object gets on completion.
+	initiated map[string]*fakeObject
+
+	// intercept, when set, may answer a request before the fake does.
+	intercept func(w http.ResponseWriter, r *http.Request) bool
+}
+
+type fakeObject struct {
+	body         []byte
+	contentType  string
+	metadata     map[string]string
+	tags         url.Values
+	etag         string
+	versionID    string
+	storageClass string
+	modified     time.Time
+}
+
+func newFakeS3(t testing.TB, buckets ...string) *fakeS3 {
+	t.Helper()
+
+	// The service takes its region from the environment, like the SDK.
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	f := &fakeS3{buckets: map[string]bool{}, objects: map[string]*fakeObject{}, uploads: map[string]map[int][]byte{}, initiated: map[string]*fakeObject{}}
+	for _, bucket := range buckets {
+		f.buckets[bucket] = true
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serveHTTP))
+	t.Cleanup(f.Close)
+
+	return f
+}
+
+func (f *fakeS3) service(opts ...Option) S3Service {
+	opts = append([]Option{
+		WithEndpoint(f.URL),
+		WithCredentials(credentials.NewStaticCredentialsProvider("test", "test", "")),
+	}, opts...)
+	return NewS3Service("us-east-1", opts...)
+}
+
+func (f *fakeS3) put(bucketName, key, contentType string, body []byte, metadata map[string]string) *fakeObject {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.store(bucketName, key, &fakeObject{body: body, contentType: contentType, metadata: metadata, tags: url.Values{}})
+}
+
+// store saves o under key; f.mu must be held.
+func (f *fakeS3) store(bucketName, key string, o *fakeObject) *fakeObject {
+	sum := md5.Sum(o.body)
+	f.versions++
+	o.etag = `"` + hex.EncodeToString(sum[:]) + `"`
+	o.versionID = strconv.Itoa(f.versions)
+	o.modified = time.Now().UTC().Truncate(time.Second)
+	if o.metadata == nil {
+		o.metadata = map[string]string{}
+	}
+	f.objects[bucketName+"/"+key] = o
+
+	return o
+}
+
+func (f *fakeS3) object(bucketName, key string) (*fakeObject, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	o, ok := f.objects[bucketName+"/"+key]
+	return o, ok
+}
+
+func (f *fakeS3) keys(bucketName string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := []string{}
+	for name := range f.objects {
+		if key, ok := strings.CutPrefix(name, bucketName+"/"); ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+func (f *fakeS3) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if f.intercept != nil && f.intercept(w, r) {
+		return
+	}
+
+	body, err := readFakeBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+	if key == "" {
+		f.serveBucket(w, r, bucketName, query, body)
+		return
+	}
+	if !f.buckets[bucketName] {
+		fakeError(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	name := bucketName + "/" + key
+	o, exists := f.objects[name]
+	switch {
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		id := strconv.Itoa(len(f.uploads) + 1)
+		f.uploads[id] = map[int][]byte{}
+		tags, _ := url.ParseQuery(r.Header.Get("X-Amz-Tagging"))
+		f.initiated[id] = &fakeObject{contentType: r.Header.Get("Content-Type"), metadata: fakeMetadata(r.Header), tags: tags}
+		fmt.Fprintf(w, `<InitiateMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>`, bucketName, key, id)
+	case r.Method == http.MethodPut && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			fakeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		number, _ := strconv.Atoi(query.Get("partNumber"))
+		if source := r.Header.Get("X-Amz-Copy-Source"); source != "" {
+			data, ok := f.copySource(source, r.Header.Get("X-Amz-Copy-Source-Range"))
+			if !ok {
+				fakeError(w, http.StatusNotFound, "NoSuchKey")
+				return
+			}
+			parts[number] = data
+			fmt.Fprintf(w, `<CopyPartResult><ETag>"%d"</ETag></CopyPartResult>`, number)
+			return
+		}
+		parts[number] = body
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, number))
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			fakeError(w, http.StatusNotFound, "NoSuchUpload")
+			return
+		}
+		numbers := []int{}
+		for number := range parts {
+			numbers = append(numbers, number)
+		}
+		slices.Sort(numbers)
+		var data []byte
+		for _, number := range numbers {
+			data = append(data, parts[number]...)
+		}
+		object := &fakeObject{body: data, tags: url.Values{}}
+		if initiated, ok := f.initiated[query.Get("uploadId")]; ok {
+			object.contentType, object.metadata, object.tags = initiated.contentType, initiated.metadata, initiated.tags
+		}
+		delete(f.uploads, query.Get("uploadId"))
+		delete(f.initiated, query.Get("uploadId"))
+		stored := f.store(bucketName, key, object)
+		stored.etag = fmt.Sprintf(`"%s-%d"`, strings.Trim(stored.etag, `"`), len(numbers))
+		fmt.Fprintf(w, `<CompleteMultipartUploadResult><Bucket>%s</Bucket><Key>%s</Key><ETag>%s</ETag></CompleteMultipartUploadResult>`, bucketName, key, stored.etag)
+	case r.Method == http.MethodDelete && query.Has("uploadId"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case query.Has("tagging"):
+		if !exists {
+			fakeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		if r.Method == http.MethodPut {
+			var tagging struct {
+				Tags []struct{ Key, Value string } `xml:"TagSet>Tag"`
+			}
+			xml.Unmarshal(body, &tagging)
+			o.tags = url.Values{}
+			for _, tag := range tagging.Tags {
+				o.tags.Set(tag.Key, tag.Value)
+			}
+			return
+		}
+		fmt.Fprint(w, `<Tagging><TagSet>`)
+		for tagKey := range o.tags {
+			fmt.Fprintf(w, `<Tag><Key>%s</Key><Value>%s</Value></Tag>`, tagKey, o.tags.Get(tagKey))
+		}
+		fmt.Fprint(w, `</TagSet></Tagging>`)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		source, ok := f.objects[strings.TrimPrefix(unescapePath(r.Header.Get("X-Amz-Copy-Source")), "/")]
+		if !ok {
+			fakeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		copied := *source
+		if r.Header.Get("X-Amz-Metadata-Directive") == "REPLACE" {
+			copied.metadata, copied.contentType = fakeMetadata(r.Header), r.Header.Get("Content-Type")
+		}
+		if r.Header.Get("X-Amz-Tagging-Directive") == "REPLACE" {
+			copied.tags, _ = url.ParseQuery(r.Header.Get("X-Amz-Tagging"))
+		}
+		if class := r.Header.Get("X-Amz-Storage-Class"); class != "" {
+			copied.storageClass = class
+		}
+		stored := f.store(bucketName, key, &copied)
+		w.Header().Set("X-Amz-Version-Id", stored.versionID)
+		fmt.Fprintf(w, `<CopyObjectResult><ETag>%s</ETag><LastModified>%s</LastModified></CopyObjectResult>`, stored.etag, stored.modified.Format(time.RFC3339))
+	case r.Method == http.MethodPut:
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			fakeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		tags, _ := url.ParseQuery(r.Header.Get("X-Amz-Tagging"))
+		stored := f.store(bucketName, key, &fakeObject{
+			body:         body,
+			contentType:  r.Header.Get("Content-Type"),
+			metadata:     fakeMetadata(r.Header),
+			tags:         tags,
+			storageClass: r.Header.Get("X-Amz-Storage-Class"),
+		})
+		w.Header().Set("ETag", stored.etag)
+		w.Header().Set("X-Amz-Version-Id", stored.versionID)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		if !exists {
+			fakeError(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		header := w.Header()
+		header.Set("Content-Type", o.contentType)
+		header.Set("ETag", o.etag)
+		header.Set("Last-Modified", o.modified.Format(http.TimeFormat))
+		header.Set("X-Amz-Version-Id", o.versionID)
+		if o.storageClass != "" {
+			header.Set("X-Amz-Storage-Class", o.storageClass)
+		}
+		for name, value := range o.metadata {
+			header.Set("X-Amz-Meta-"+name, value)
+		}
+		start, end := int64(0), int64(len(o.body))
+		if spec, ok := strings.CutPrefix(r.Header.Get("Range"), "bytes="); ok {
+			first, last, _ := strings.Cut(spec, "-")
+			start, _ = strconv.ParseInt(first, 10, 64)
+			if last != "" {
+				n, _ := strconv.ParseInt(last, 10, 64)
+				end = min(n+1, end)
+			}
+			header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end-1, len(o.body)))
+			header.Set("Content-Length", strconv.FormatInt(end-start, 10))
+			w.WriteHeader(http.StatusPartialContent)
+		} else {
+			header.Set("Content-Length", strconv.Itoa(len(o.body)))
+		}
+		if r.Method == http.MethodGet {
+			w.Write(o.body[start:end])
+		}
+	case r.Method == http.MethodDelete:
+		if exists && (r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != o.etag ||
+			query.Get("versionId") != "" && query.Get("versionId") != o.versionID) {
+			fakeError(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return
+		}
+		delete(f.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fakeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+func (f *fakeS3) serveBucket(w http.ResponseWriter, r *http.Request, bucketName string, query url.Values, body []byte) {
+	switch {
+	case bucketName == "" && r.Method == http.MethodGet:
+		names := []string{}
+		for name := range f.buckets {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		fmt.Fprint(w, `<ListAllMyBucketsResult><Buckets>`)
+		for _, name := range names {
+			fmt.Fprintf(w, `<Bucket><Name>%s</Name><BucketRegion>us-east-1</BucketRegion></Bucket>`, name)
+		}
+		fmt.Fprint(w, `</Buckets></ListAllMyBucketsResult>`)
+	case r.Method == http.MethodPut:
+		f.buckets[bucketName] = true
+		return
+	case !f.buckets[bucketName]:
+		fakeError(w, http.StatusNotFound, "NoSuchBucket")
+	case r.Method == http.MethodHead:
+	case r.Method == http.MethodGet && query.Has("accelerate"):
+		fmt.Fprint(w, `<AccelerateConfiguration/>`)
+	case r.Method == http.MethodGet && query.Has("list-type"):
+		// The continuation token is the last key of the previous page.
+		after := cmpOr(query.Get("continuation-token"), query.Get("start-after"))
+		keys, truncated := f.page(bucketName, query.Get("prefix"), after, query.Get("max-keys"))
+		fmt.Fprintf(w, `<ListBucketResult><Name>%s</Name><Prefix>%s</Prefix><IsTruncated>%t</IsTruncated>`, bucketName, query.Get("prefix"), truncated)
+		if truncated {
+			fmt.Fprintf(w, `<NextContinuationToken>%s</NextContinuationToken>`, xmlEscape(keys[len(keys)-1]))
+		}
+		for _, key := range keys {
+			o := f.objects[bucketName+"/"+key]
+			fmt.Fprintf(w, `<Contents><Key>%s</Key><Size>%d</Size><ETag>%s</ETag><LastModified>%s</LastModified><StorageClass>%s</StorageClass></Contents>`,
+				xmlEscape(key), len(o.body), xmlEscape(o.etag), o.modified.Format(time.RFC3339), cmpOr(o.storageClass, "STANDARD"))
+		}
+		fmt.Fprint(w, `</ListBucketResult>`)
+	case r.Method == http.MethodGet && query.Has("versions"):
+		// Only the current version of each key is kept.
+		keys, truncated := f.page(bucketName, query.Get("prefix"), query.Get("key-marker"), query.Get("max-keys"))
+		fmt.Fprintf(w, `<ListVersionsResult><Name>%s</Name><IsTruncated>%t</IsTruncated>`, bucketName, truncated)
+		if truncated {
+			fmt.Fprintf(w, `<NextKeyMarker>%s</NextKeyMarker>`, xmlEscape(keys[len(keys)-1]))
+		}
+		for _, key := range keys {
+			o := f.objects[bucketName+"/"+key]
+			fmt.Fprintf(w, `<Version><Key>%s</Key><VersionId>%s</VersionId><IsLatest>true</IsLatest><Size>%d</Size><ETag>%s</ETag><LastModified>%s</LastModified></Version>`,
+				xmlEscape(key), o.versionID, len(o.body), xmlEscape(o.etag), o.modified.Format(time.RFC3339))
+		}
+		fmt.Fprint(w, `</ListVersionsResult>`)
+	case r.Method == http.MethodPost && query.Has("delete"):
+		var request struct {
+			Objects []struct{ Key string } `xml:"Object"`
+		}
+		if err := xml.Unmarshal(body, &request); err != nil {
+			fakeError(w, http.StatusBadRequest, "MalformedXML")
+			return
+		}
+		fmt.Fprint(w, `<DeleteResult>`)
+		for _, o := range request.Objects {
+			delete(f.objects, bucketName+"/"+o.Key)
+			fmt.Fprintf(w, `<Deleted><Key>%s</Key></Deleted>`, xmlEscape(o.Key))
+		}
+		fmt.Fprint(w, `</DeleteResult>`)
+	default:
+		fakeError(w, http.StatusNotImplemented, "NotImplemented")
+	}
+}
+
+// page returns up to maxKeys sorted keys of bucketName under prefix that come
+// after marker, and whether more follow; f.mu must be held.
+func (f *fakeS3) page(bucketName, prefix, marker, maxKeys string) ([]string, bool) {
+	limit, err := strconv.Atoi(maxKeys)
+	if err != nil || limit <= 0 {
+		limit = 1000
+	}
+
+	keys := []string{}
+	for name := range f.objects {
+		if key, ok := strings.CutPrefix(name, bucketName+"/"); ok && strings.HasPrefix(key, prefix) && key > marker {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	if len(keys) > limit {
+		return keys[:limit], true
+	}
+
+	return keys, false
+}
+
+// copySource returns the bytes of an x-amz-copy-source, optionally limited to
+// an x-amz-copy-source-range; f.mu must be held.
+func (f *fakeS3) copySource(source, byteRange string) ([]byte, bool) {
+	o, ok := f.objects[strings.TrimPrefix(unescapePath(source), "/")]
+	if !ok {
+		return nil, false
+	}
+	spec, ok := strings.CutPrefix(byteRange, "bytes=")
+	if !ok {
+		return o.body, true
+	}
+	first, last, _ := strings.Cut(spec, "-")
+	start, _ := strconv.Atoi(first)
+	end, _ := strconv.Atoi(last)
+
+	return o.body[start : end+1], true
+}
+
+// readFakeBody reads a request body, decoding the aws-chunked encoding the SDK
+// uses for streamed payloads with trailing checksums.
+func readFakeBody(r *http.Request) ([]byte, error) {
+	if !strings.Contains(r.Header.Get("Content-Encoding"), "aws-chunked") {
+		return io.ReadAll(r.Body)
+	}
+
+	var body []byte
+	reader := bufio.NewReader(r.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeField, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeField, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return body, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			return nil, err
+		}
+		body = append(body, chunk[:size]...)
+	}
+}
+
+func fakeMetadata(header http.Header) map[string]string {
+	metadata := map[string]string{}
+	for name, values := range header {
+		if key, ok := strings.CutPrefix(strings.ToLower(name), "x-amz-meta-"); ok {
+			metadata[key] = values[0]
+		}
+	}
+	return metadata
+}
+
+func fakeError(w http.ResponseWriter, status int, code string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<Error><Code>%s</Code><Message>%s</Message></Error>`, code, code)
+}
+
+func unescapePath(path string) string {
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		return unescaped
+	}
+	return path
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func cmpOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+// fakeIndexer keeps indexed documents by "bucket/filename".
+type fakeIndexer struct {
+	mu   sync.Mutex
+	docs map[string]IndexDocument
+}
+
+func newFakeIndexer() *fakeIndexer {
+	return &fakeIndexer{docs: map[string]IndexDocument{}}
+}
+
+func (f *fakeIndexer) Index(_ context.Context, doc IndexDocument) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.docs[doc.BucketName+"/"+doc.Filename] = doc
+	return nil
+}
+
+func (f *fakeIndexer) Remove(_ context.Context, bucketName, filename string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.docs, bucketName+"/"+filename)
+	return nil
+}
+
+func (f *fakeIndexer) Search(context.Context, SearchRequest) ([]SearchHit, error) {
+	return nil, nil
+}
+
+func (f *fakeIndexer) doc(bucketName, filename string) (IndexDocument, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	doc, ok := f.docs[bucketName+"/"+filename]
+	return doc, ok
+}
+
+// fakeCatalog keeps catalog entries by "bucket/key".
+type fakeCatalog struct {
+	mu      sync.Mutex
+	entries map[string]CatalogEntry
+}
+
+func newFakeCatalog() *fakeCatalog {
+	return &fakeCatalog{entries: map[string]CatalogEntry{}}
+}
+
+func (f *fakeCatalog) Record(_ context.Context, entry CatalogEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.entries[entry.BucketName+"/"+entry.Key] = entry
+	return nil
+}
+
+func (f *fakeCatalog) Rename(_ context.Context, bucketName, oldKey, newKey string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if entry, ok := f.entries[bucketName+"/"+oldKey]; ok {
+		delete(f.entries, bucketName+"/"+oldKey)
+		entry.Key = newKey
+		f.entries[bucketName+"/"+newKey] = entry
+	}
+	return nil
+}
+
+func (f *fakeCatalog) Remove(_ context.Context, bucketName, key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.entries, bucketName+"/"+key)
+	return nil
+}
+
+func (f *fakeCatalog) Query(_ context.Context, query CatalogQuery) ([]CatalogEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries := []CatalogEntry{}
+	for _, entry := range f.entries {
+		if entry.BucketName == query.BucketName && strings.HasPrefix(entry.Key, query.Prefix) &&
+			cmpOr(query.Owner, entry.Owner) == entry.Owner && cmpOr(query.UploadedBy, entry.UploadedBy) == entry.UploadedBy {
+			entries = append(entries, entry)
+		}
+	}
+	slices.SortFunc(entries, func(a, b CatalogEntry) int { return strings.Compare(a.Key, b.Key) })
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[:query.Limit]
+	}
+	return entries, nil
+}
+
+func (f *fakeCatalog) entry(bucketName, key string) (CatalogEntry, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, ok := f.entries[bucketName+"/"+key]
+	return entry, ok
+}
+
+func TestFakeRoundTrip(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	svc := fake.service()
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "empty", body: ""},
+		{name: "text", body: "hello world"},
+		{name: "large", body: strings.Repeat("0123456789", 100_000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    tt.name + ".txt",
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader(tt.body)),
+			})
+			if err != nil {
+				t.Fatalf("UploadFile: %v", err)
+			}
+
+			got, err := svc.DownloadFile(DownloadFileRequest{BucketName: "bucket", Filename: tt.name + ".txt"})
+			if err != nil {
+				t.Fatalf("DownloadFile: %v", err)
+			}
+			if string(got) != tt.body {
+				t.Errorf("downloaded %d bytes, want %d", len(got), len(tt.body))
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// UploadTokenMetadata identifies the upload that wrote an object, so cleanup
+// after a failed upload never deletes an object written by someone else.
+const UploadTokenMetadata = "upload-token"
+
+// abortUpload cleans up after an upload that failed under WithUploadGuarantee:
+// the multipart upload is aborted, and an object that became visible anyway,
+// e.g. when the response of the last request was lost, is deleted once its
+// upload token proves it is ours. Cleanup ignores ctx, which may be the very
+// context that was cancelled.
+func (s *s3Service) abortUpload(ctx context.Context, data UploadFileRequest, token string, uploadErr error) error {
+	var multipartErr manager.MultiUploadFailure
+	if errors.As(uploadErr, &multipartErr) && multipartErr.UploadID() != "" {
+		s.abortMultipart(data.BucketName, data.Filename, aws.String(multipartErr.UploadID()))
+	}
+
+	if err := s.deleteUpload(ctx, data, token); err != nil {
+		return fmt.Errorf("%w: %v (%v)", ErrUploadAborted, uploadErr, err)
+	}
+
+	return fmt.Errorf("%w: %w", ErrUploadAborted, uploadErr)
+}
+
+// discardUpload deletes an object that was uploaded completely but whose
+// follow-up steps, such as storing metadata, failed.
+func (s *s3Service) discardUpload(ctx context.Context, data UploadFileRequest, token string, stepErr error) error {
+	if !s.uploadGuarantee || errors.Is(stepErr, ErrContentRejected) || errors.Is(stepErr, ErrContentQuarantined) {
+		return stepErr
+	}
+
+	if err := s.deleteUpload(ctx, data, token); err != nil {
+		return fmt.Errorf("%w: %w (%v)", ErrUploadAborted, stepErr, err)
+	}
+
+	return fmt.Errorf("%w: %w", ErrUploadAborted, stepErr)
+}
+
+// deleteUpload deletes the object at data.Filename if it still carries token.
+// The delete is conditional on the ETag that was checked, so an upload to the
+// same key that finished in between is left alone.
+func (s *s3Service) deleteUpload(ctx context.Context, data UploadFileRequest, token string) error {
+	cleanupCtx := context.WithoutCancel(ctx)
+	head, err := s.s3Cli.HeadObject(cleanupCtx, &s3.HeadObjectInput{
+		Bucket: aws.String(data.BucketName),
+		Key:    aws.String(data.Filename),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		logf(ctx, "failed to check for upload of %s to clean up: %v", data.Filename, err)
+		return fmt.Errorf("cleanup could not be verified: %w", err)
+	}
+
+	if head.Metadata[UploadTokenMetadata] != token {
+		logf(ctx, "left %s in place, it was written by another upload", data.Filename)
+		return nil
+	}
+
+	_, err = s.s3Cli.DeleteObject(cleanupCtx, &s3.DeleteObjectInput{
+		Bucket:  aws.String(data.BucketName),
+		Key:     aws.String(data.Filename),
+		IfMatch: head.ETag,
+	})
+	switch {
+	case err == nil:
+		logf(ctx, "deleted upload of %s", data.Filename)
+		return nil
+	case isNotFound(err) || isPreconditionFailed(err):
+		return nil
+	default:
+		logf(ctx, "failed to delete upload of %s: %v", data.Filename, err)
+		return fmt.Errorf("cleanup failed: %w", err)
+	}
+}
+
+func isPreconditionFailed(err error) bool {
+	var respErr *awsHttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusPreconditionFailed
+}
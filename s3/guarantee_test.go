@@ -0,0 +1,89 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type metadataEnricherStub struct{}
+
+func (metadataEnricherStub) Enrich(_ string, r io.Reader) (Enrichment, error) {
+	_, err := io.Copy(io.Discard, r)
+	return Enrichment{Metadata: map[string]string{"pages": "1"}}, err
+}
+
+type cleanModerator struct{}
+
+func (cleanModerator) Moderate(context.Context, ModerationInput) (ModerationResult, error) {
+	return ModerationResult{}, nil
+}
+
+func TestDiscardUpload(t *testing.T) {
+	tests := []struct {
+		name string
+		// fail reports whether the follow-up request r is rejected.
+		fail func(r *http.Request) bool
+		// overwrite writes another upload to the key just before the failure.
+		overwrite bool
+		wantBody  string
+	}{
+		{
+			name: "metadata copy fails",
+			fail: func(r *http.Request) bool { return r.Header.Get("x-amz-copy-source") != "" },
+		},
+		{
+			name:      "another upload finished in between",
+			fail:      func(r *http.Request) bool { return r.Header.Get("x-amz-copy-source") != "" },
+			overwrite: true,
+			wantBody:  "theirs",
+		},
+		{
+			name: "tagging fails after metadata was stored",
+			fail: func(r *http.Request) bool { return r.URL.Query().Has("tagging") },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if r.Method != http.MethodPut || !tt.fail(r) {
+					return false
+				}
+				if tt.overwrite {
+					fake.put("bucket", "a.txt", "text/plain", []byte("theirs"), nil)
+				}
+				fakeError(w, http.StatusForbidden, "AccessDenied")
+				return true
+			}
+			svc := fake.service(
+				WithUploadGuarantee(),
+				WithEnrichment(metadataEnricherStub{}, true),
+				WithModeration(cleanModerator{}, ModerationPolicy{Action: ModerationTag}),
+			)
+
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.txt",
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader("ours")),
+			})
+			if !errors.Is(err, ErrUploadAborted) {
+				t.Fatalf("UploadFile = %v, want ErrUploadAborted", err)
+			}
+
+			o, ok := fake.object("bucket", "a.txt")
+			switch {
+			case tt.wantBody == "" && ok:
+				t.Errorf("upload left behind with %q", o.body)
+			case tt.wantBody != "" && !ok:
+				t.Errorf("object of the other upload was deleted")
+			case ok && string(o.body) != tt.wantBody:
+				t.Errorf("object holds %q, want %q", o.body, tt.wantBody)
+			}
+		})
+	}
+}
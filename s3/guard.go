@@ -0,0 +1,63 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DeleteGuard protects against bulk or accidental deletes by automated jobs.
+type DeleteGuard struct {
+	// ProtectedPrefixes can never be deleted, not even with Force.
+	ProtectedPrefixes []string
+	// MaxKeys is the number of existing keys one DeleteFile call may remove
+	// without Force. Larger requests are answered with a dry run instead.
+	MaxKeys int
+}
+
+func (g DeleteGuard) isProtected(key string) bool {
+	for _, prefix := range g.ProtectedPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// guardDelete drops protected keys from filenames, recording them in result.
+func (s *s3Service) guardDelete(filenames []string, result *BatchResult) []string {
+	allowed := make([]string, 0, len(filenames))
+	for _, filename := range filenames {
+		if s.deleteGuard.isProtected(filename) {
+			result.Results = append(result.Results, KeyResult{
+				Key:    filename,
+				Status: DeleteProtected,
+				Err:    fmt.Errorf("%s: %w", filename, ErrProtectedKey),
+			})
+			continue
+		}
+		allowed = append(allowed, filename)
+	}
+
+	return allowed
+}
+
+// dryRun reports keys as WouldDelete. It is the answer to an explicit DryRun
+// and to requests over the guard threshold without Force, which also fail with
+// ErrDeleteThresholdExceeded so the caller can show what would be removed.
+func (s *s3Service) dryRun(data DeleteFileRequest, keys []string, result *BatchResult) (bool, error) {
+	overThreshold := s.deleteGuard.MaxKeys > 0 && len(keys) > s.deleteGuard.MaxKeys && !data.Force
+	if !data.DryRun && !overThreshold {
+		return false, nil
+	}
+
+	for _, key := range keys {
+		result.Results = append(result.Results, KeyResult{Key: key, Status: WouldDelete})
+	}
+
+	if !data.DryRun {
+		return true, fmt.Errorf("%w: %d keys, limit is %d", ErrDeleteThresholdExceeded, len(keys), s.deleteGuard.MaxKeys)
+	}
+
+	return true, result.Err()
+}
@@ -0,0 +1,51 @@
+package s3
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+type requestHeadersKey struct{}
+
+// WithRequestHeaders attaches headers to every S3 call made with ctx, on top of
+// the service-wide headers from WithHeaders. Headers are added before signing.
+func WithRequestHeaders(ctx context.Context, headers http.Header) context.Context {
+	if len(headers) == 0 {
+		return ctx
+	}
+
+	if existing, ok := ctx.Value(requestHeadersKey{}).(http.Header); ok {
+		merged := existing.Clone()
+		for key, values := range headers {
+			merged[http.CanonicalHeaderKey(key)] = values
+		}
+		headers = merged
+	}
+
+	return context.WithValue(ctx, requestHeadersKey{}, headers)
+}
+
+// headerMiddleware sets the service-wide headers and then those carried by the
+// request context, so per-request values win.
+func (s *s3Service) headerMiddleware(stack *middleware.Stack) error {
+	return stack.Build.Add(middleware.BuildMiddlewareFunc("FileUploaderHeaders", func(
+		ctx context.Context, in middleware.BuildInput, next middleware.BuildHandler,
+	) (middleware.BuildOutput, middleware.Metadata, error) {
+		if req, ok := in.Request.(*smithyhttp.Request); ok {
+			for key, values := range s.headers {
+				req.Header[http.CanonicalHeaderKey(key)] = values
+			}
+
+			if headers, ok := ctx.Value(requestHeadersKey{}).(http.Header); ok {
+				for key, values := range headers {
+					req.Header[http.CanonicalHeaderKey(key)] = values
+				}
+			}
+		}
+
+		return next.HandleBuild(ctx, in)
+	}), middleware.After)
+}
@@ -0,0 +1,247 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+type (
+	// Indexer keeps a search index in sync with the objects managed by the service.
+	// Elasticsearch and OpenSearch are supported out of the box; other engines such
+	// as Bleve can be plugged in by implementing this interface.
+	Indexer interface {
+		Index(ctx context.Context, doc IndexDocument) error
+		Remove(ctx context.Context, bucketName, filename string) error
+		Search(ctx context.Context, query SearchRequest) ([]SearchHit, error)
+	}
+
+	IndexDocument struct {
+		BucketName  string            `json:"bucket"`
+		Filename    string            `json:"filename"`
+		ContentType string            `json:"contentType,omitempty"`
+		Metadata    map[string]string `json:"metadata,omitempty"`
+		Tags        map[string]string `json:"tags,omitempty"`
+		Text        string            `json:"text,omitempty"`
+		IndexedAt   time.Time         `json:"indexedAt"`
+	}
+
+	SearchRequest struct {
+		Query      string
+		BucketName string
+		Limit      int
+	}
+
+	SearchHit struct {
+		IndexDocument
+		Score float64
+	}
+)
+
+func (s *s3Service) Search(ctx context.Context, query SearchRequest) ([]SearchHit, error) {
+	if err := s.acquire(); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if s.indexer == nil {
+		return nil, ErrIndexerNotConfigured
+	}
+
+	if strings.TrimSpace(query.Query) == "" {
+		return nil, errors.New("query is required")
+	}
+
+	hits, err := s.indexer.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return authorizedItems(ctx, s, authz.PermissionDownload, hits, func(hit SearchHit) (string, string) {
+		return hit.BucketName, hit.Filename
+	})
+}
+
+func (s *s3Service) indexUpload(ctx context.Context, data UploadFileRequest, result UploadFileResult) {
+	err := s.indexer.Index(ctx, IndexDocument{
+		BucketName:  data.BucketName,
+		Filename:    data.Filename,
+		ContentType: data.ContentType,
+		Metadata:    result.Metadata,
+		Tags:        data.Tags,
+		Text:        result.Text,
+		IndexedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("failed to index file %s on bucket %s: %v", data.Filename, data.BucketName, err)
+	}
+}
+
+// indexObject indexes an object written without an upload, such as a rename
+// target, from the content type, metadata and tags stored with it.
+func (s *s3Service) indexObject(ctx context.Context, bucketName, key string, head *s3.HeadObjectOutput) {
+	tags, err := s.getObjectTags(ctx, bucketName, key)
+	if err != nil {
+		log.Printf("failed to get tags of file %s for index: %v", key, err)
+	}
+
+	metadata := maps.Clone(head.Metadata)
+	for _, internal := range []string{OwnerIDMetadata, UploadedByMetadata, UploadTokenMetadata} {
+		delete(metadata, internal)
+	}
+
+	err = s.indexer.Index(ctx, IndexDocument{
+		BucketName:  bucketName,
+		Filename:    key,
+		ContentType: aws.ToString(head.ContentType),
+		Metadata:    metadata,
+		Tags:        tags,
+		IndexedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		log.Printf("failed to index file %s on bucket %s: %v", key, bucketName, err)
+	}
+}
+
+func (s *s3Service) unindex(ctx context.Context, bucketName string, filenames []string) {
+	for _, filename := range filenames {
+		if err := s.indexer.Remove(ctx, bucketName, filename); err != nil {
+			log.Printf("failed to remove file %s on bucket %s from index: %v", filename, bucketName, err)
+		}
+	}
+}
+
+type elasticsearchIndexer struct {
+	endpoint string
+	index    string
+	client   *http.Client
+}
+
+// NewElasticsearchIndexer talks to the Elasticsearch/OpenSearch REST API at endpoint.
+// A nil client uses http.DefaultClient.
+func NewElasticsearchIndexer(endpoint, index string, client *http.Client) Indexer {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &elasticsearchIndexer{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		index:    index,
+		client:   client,
+	}
+}
+
+func documentID(bucketName, filename string) string {
+	return url.PathEscape(bucketName + "/" + filename)
+}
+
+func (e *elasticsearchIndexer) Index(ctx context.Context, doc IndexDocument) error {
+	return e.do(ctx, http.MethodPut, "/_doc/"+documentID(doc.BucketName, doc.Filename), doc, nil)
+}
+
+func (e *elasticsearchIndexer) Remove(ctx context.Context, bucketName, filename string) error {
+	err := e.do(ctx, http.MethodDelete, "/_doc/"+documentID(bucketName, filename), nil, nil)
+	if errors.Is(err, errIndexDocumentNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+func (e *elasticsearchIndexer) Search(ctx context.Context, query SearchRequest) ([]SearchHit, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	boolQuery := map[string]any{
+		"must": map[string]any{
+			"simple_query_string": map[string]any{
+				"query":  query.Query,
+				"fields": []string{"filename^2", "text", "metadata.*", "tags.*"},
+			},
+		},
+	}
+	if query.BucketName != "" {
+		boolQuery["filter"] = map[string]any{"term": map[string]any{"bucket.keyword": query.BucketName}}
+	}
+
+	var response struct {
+		Hits struct {
+			Hits []struct {
+				Score  float64       `json:"_score"`
+				Source IndexDocument `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	err := e.do(ctx, http.MethodPost, "/_search", map[string]any{
+		"size":  limit,
+		"query": map[string]any{"bool": boolQuery},
+	}, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]SearchHit, 0, len(response.Hits.Hits))
+	for _, hit := range response.Hits.Hits {
+		hits = append(hits, SearchHit{IndexDocument: hit.Source, Score: hit.Score})
+	}
+
+	return hits, nil
+}
+
+var errIndexDocumentNotFound = errors.New("index document not found")
+
+func (e *elasticsearchIndexer) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, e.endpoint+"/"+url.PathEscape(e.index)+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errIndexDocumentNotFound
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("index request %s %s failed with status %d: %s", method, path, resp.StatusCode, msg)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
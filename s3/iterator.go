@@ -0,0 +1,99 @@
+package s3
+
+import (
+	"context"
+	"iter"
+	"slices"
+	"time"
+)
+
+// Iterator walks a paginated listing, fetching further pages as it goes. Any
+// error, including cancellation of the listing's context, stops the iteration
+// and is reported by Err once the loop ends.
+type Iterator[T any] struct {
+	ctx      context.Context
+	nextPage func(ctx context.Context) (page []T, more bool, err error)
+	err      error
+
+	freshness Freshness
+}
+
+func newIterator[T any](ctx context.Context, nextPage func(ctx context.Context) ([]T, bool, error)) *Iterator[T] {
+	return &Iterator[T]{ctx: ctx, nextPage: nextPage}
+}
+
+// newOperationIterator is newIterator for listings of s: each page fetch is an
+// in-flight operation, so Shutdown waits for it and cancels it past its deadline.
+func newOperationIterator[T any](s *s3Service, ctx context.Context, nextPage func(ctx context.Context) ([]T, bool, error)) *Iterator[T] {
+	return newIterator(ctx, func(ctx context.Context) ([]T, bool, error) {
+		if err := s.acquire(); err != nil {
+			return nil, false, err
+		}
+		defer s.release()
+		ctx, cancel := s.operation(ctx)
+		defer cancel()
+
+		return nextPage(ctx)
+	})
+}
+
+// All returns a range-over-func sequence. An Iterator can be consumed once.
+func (it *Iterator[T]) All() iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for more := true; more; {
+			if err := it.ctx.Err(); err != nil {
+				it.err = err
+				return
+			}
+
+			if it.freshness.ListedAt.IsZero() {
+				it.freshness.ListedAt = time.Now()
+			}
+
+			var page []T
+			page, more, it.err = it.nextPage(it.ctx)
+			if it.err != nil {
+				return
+			}
+
+			for _, item := range page {
+				if !yield(item) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Chan delivers the items on a channel that is closed when the listing ends.
+// Callers that stop reading early must cancel the listing's context so the
+// producing goroutine can exit.
+func (it *Iterator[T]) Chan() <-chan T {
+	ch := make(chan T)
+
+	go func() {
+		defer close(ch)
+		for item := range it.All() {
+			select {
+			case ch <- item:
+			case <-it.ctx.Done():
+				it.err = it.ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return ch
+}
+
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// Freshness tells how current the listing is; ListedAt is set once iteration
+// has started.
+func (it *Iterator[T]) Freshness() Freshness {
+	freshness := it.freshness
+	freshness.Pending = slices.Clone(freshness.Pending)
+	return freshness
+}
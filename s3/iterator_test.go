@@ -0,0 +1,135 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+// pagedIterator serves pages in order and fails with err once they run out,
+// unless err is nil. It counts the pages fetched.
+func pagedIterator(ctx context.Context, pages [][]int, err error, fetched *int) *Iterator[int] {
+	return newIterator(ctx, func(context.Context) ([]int, bool, error) {
+		*fetched++
+		if len(pages) == 0 {
+			return nil, false, err
+		}
+		page := pages[0]
+		pages = pages[1:]
+		return page, len(pages) > 0 || err != nil, nil
+	})
+}
+
+func TestIterator(t *testing.T) {
+	errList := errors.New("list failed")
+	tests := []struct {
+		name        string
+		pages       [][]int
+		err         error
+		stopAfter   int
+		wantItems   []int
+		wantFetched int
+		wantErr     error
+	}{
+		{name: "pages", pages: [][]int{{1, 2}, {}, {3}}, wantItems: []int{1, 2, 3}, wantFetched: 3},
+		{name: "empty", wantItems: []int{}, wantFetched: 1},
+		{name: "error after pages", pages: [][]int{{1, 2}}, err: errList, wantItems: []int{1, 2}, wantFetched: 2, wantErr: errList},
+		{name: "stopped early", pages: [][]int{{1, 2}, {3}}, stopAfter: 1, wantItems: []int{1}, wantFetched: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetched := 0
+			it := pagedIterator(context.Background(), tt.pages, tt.err, &fetched)
+
+			items := []int{}
+			for item := range it.All() {
+				items = append(items, item)
+				if len(items) == tt.stopAfter {
+					break
+				}
+			}
+
+			if !slices.Equal(items, tt.wantItems) {
+				t.Errorf("items = %v, want %v", items, tt.wantItems)
+			}
+			if fetched != tt.wantFetched {
+				t.Errorf("fetched %d pages, want %d", fetched, tt.wantFetched)
+			}
+			if err := it.Err(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Err = %v, want %v", err, tt.wantErr)
+			}
+			if it.Freshness().ListedAt.IsZero() {
+				t.Error("ListedAt not set once iteration started")
+			}
+		})
+	}
+}
+
+func TestIteratorCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fetched := 0
+	it := pagedIterator(ctx, [][]int{{1}, {2}}, nil, &fetched)
+
+	items := []int{}
+	for item := range it.All() {
+		items = append(items, item)
+		cancel()
+	}
+
+	if !slices.Equal(items, []int{1}) || fetched != 1 {
+		t.Errorf("got %v from %d pages, want the first page only", items, fetched)
+	}
+	if err := it.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestIteratorChan(t *testing.T) {
+	errList := errors.New("list failed")
+	fetched := 0
+	it := pagedIterator(context.Background(), [][]int{{1, 2}, {3}}, errList, &fetched)
+
+	items := []int{}
+	for item := range it.Chan() {
+		items = append(items, item)
+	}
+
+	if !slices.Equal(items, []int{1, 2, 3}) {
+		t.Errorf("items = %v, want [1 2 3]", items)
+	}
+	if err := it.Err(); !errors.Is(err, errList) {
+		t.Errorf("Err = %v, want %v", err, errList)
+	}
+}
+
+func TestIteratorChanCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fetched := 0
+	it := pagedIterator(ctx, [][]int{{1, 2}, {3}}, nil, &fetched)
+
+	ch := it.Chan()
+	if item := <-ch; item != 1 {
+		t.Fatalf("first item = %d, want 1", item)
+	}
+	// The reader stops early and cancels, which must let the producer exit.
+	cancel()
+	for range ch {
+	}
+
+	if err := it.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestFailedIterator(t *testing.T) {
+	errInvalid := errors.New("invalid")
+	it := failedIterator[int](context.Background(), errInvalid)
+	for item := range it.All() {
+		t.Errorf("failed iterator yielded %d", item)
+	}
+	if err := it.Err(); !errors.Is(err, errInvalid) {
+		t.Errorf("Err = %v, want %v", err, errInvalid)
+	}
+}
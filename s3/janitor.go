@@ -0,0 +1,125 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+func (s *s3Service) AbortStaleUploads(ctx context.Context, data AbortStaleUploadsRequest) ([]AbortedUpload, error) {
+	if err := s.acquire(); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateAbortStaleUploads(data); err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDelete, data.BucketName, data.Prefix); err != nil {
+		return nil, err
+	}
+
+	return s.abortStaleUploads(ctx, data)
+}
+
+func (s *s3Service) abortStaleUploads(ctx context.Context, data AbortStaleUploadsRequest) ([]AbortedUpload, error) {
+	cutoff := time.Now().Add(-data.OlderThan)
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(data.BucketName),
+	}
+	if data.Prefix != "" {
+		input.Prefix = aws.String(data.Prefix)
+	}
+
+	aborted := []AbortedUpload{}
+	var errs []error
+	for {
+		output, err := s.s3Cli.ListMultipartUploads(ctx, input)
+		if err != nil {
+			log.Printf("failed to list multipart uploads on bucket %s: %v", data.BucketName, err)
+			return aborted, fmt.Errorf("failed to list multipart uploads: %w", err)
+		}
+
+		for _, upload := range output.Uploads {
+			if upload.Initiated == nil || upload.Initiated.After(cutoff) {
+				continue
+			}
+
+			// A grant on the prefix "a/b" does not cover listed keys like "a/bc".
+			if s.authorizeKeys(ctx, authz.PermissionDelete, data.BucketName, aws.ToString(upload.Key)) != nil {
+				continue
+			}
+
+			_, err = s.s3Cli.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(data.BucketName),
+				Key:      upload.Key,
+				UploadId: upload.UploadId,
+			})
+			if err != nil {
+				log.Printf("failed to abort multipart upload %s of %s: %v", aws.ToString(upload.UploadId), aws.ToString(upload.Key), err)
+				errs = append(errs, err)
+				continue
+			}
+
+			aborted = append(aborted, AbortedUpload{
+				Key:       aws.ToString(upload.Key),
+				UploadID:  aws.ToString(upload.UploadId),
+				Initiated: aws.ToTime(upload.Initiated),
+			})
+		}
+
+		if !aws.ToBool(output.IsTruncated) {
+			break
+		}
+
+		input.KeyMarker = output.NextKeyMarker
+		input.UploadIdMarker = output.NextUploadIdMarker
+	}
+
+	if len(aborted) > 0 {
+		log.Printf("aborted %d stale multipart uploads on bucket %s", len(aborted), data.BucketName)
+	}
+
+	return aborted, errors.Join(errs...)
+}
+
+// StartUploadJanitor runs AbortStaleUploads every interval until ctx is done or the
+// service is shut down. It blocks, so callers usually run it in its own goroutine.
+func (s *s3Service) StartUploadJanitor(ctx context.Context, data AbortStaleUploadsRequest, interval time.Duration) error {
+	if err := s.validateAbortStaleUploads(data); err != nil {
+		return err
+	}
+
+	if interval <= 0 {
+		return errors.New("interval must be greater than zero")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.ctx.Done():
+			return ErrServiceClosed
+		case <-ticker.C:
+			if _, err := s.AbortStaleUploads(ctx, data); err != nil {
+				if errors.Is(err, ErrServiceClosed) {
+					return err
+				}
+				log.Printf("upload janitor run on bucket %s failed: %v", data.BucketName, err)
+			}
+		}
+	}
+}
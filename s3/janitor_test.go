@@ -0,0 +1,163 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeUpload struct {
+	key, id string
+	age     time.Duration
+}
+
+// fakeUploadListing serves ListMultipartUploads from pages and records the
+// upload IDs aborted, failing the abort of failID.
+type fakeUploadListing struct {
+	mu      sync.Mutex
+	prefix  string
+	aborted []string
+}
+
+func (l *fakeUploadListing) intercept(pages [][]fakeUpload, failID string) func(http.ResponseWriter, *http.Request) bool {
+	return func(w http.ResponseWriter, r *http.Request) bool {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodGet && query.Has("uploads"):
+			l.mu.Lock()
+			l.prefix = query.Get("prefix")
+			l.mu.Unlock()
+			page := 0
+			if marker := query.Get("key-marker"); marker != "" {
+				fmt.Sscan(marker, &page)
+			}
+			fmt.Fprintf(w, `<ListMultipartUploadsResult><Bucket>bucket</Bucket><IsTruncated>%t</IsTruncated>`, page+1 < len(pages))
+			if page+1 < len(pages) {
+				fmt.Fprintf(w, `<NextKeyMarker>%d</NextKeyMarker><NextUploadIdMarker>marker</NextUploadIdMarker>`, page+1)
+			}
+			for _, upload := range pages[page] {
+				fmt.Fprintf(w, `<Upload><Key>%s</Key><UploadId>%s</UploadId><Initiated>%s</Initiated></Upload>`,
+					upload.key, upload.id, time.Now().Add(-upload.age).UTC().Format(time.RFC3339))
+			}
+			fmt.Fprint(w, `</ListMultipartUploadsResult>`)
+			return true
+		case r.Method == http.MethodDelete && query.Has("uploadId"):
+			if query.Get("uploadId") == failID {
+				fakeError(w, http.StatusForbidden, "AccessDenied")
+				return true
+			}
+			l.mu.Lock()
+			l.aborted = append(l.aborted, query.Get("uploadId"))
+			l.mu.Unlock()
+			w.WriteHeader(http.StatusNoContent)
+			return true
+		}
+		return false
+	}
+}
+
+func TestAbortStaleUploads(t *testing.T) {
+	pages := [][]fakeUpload{
+		{{key: "in/a.bin", id: "stale-1", age: 48 * time.Hour}, {key: "in/b.bin", id: "recent", age: time.Hour}},
+		{{key: "in/c.bin", id: "stale-2", age: 25 * time.Hour}},
+	}
+	tests := []struct {
+		name        string
+		failID      string
+		wantAborted []string
+		wantErr     bool
+	}{
+		{name: "stale uploads", wantAborted: []string{"stale-1", "stale-2"}},
+		{name: "abort fails", failID: "stale-1", wantAborted: []string{"stale-2"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			listing := &fakeUploadListing{}
+			fake.intercept = listing.intercept(pages, tt.failID)
+			svc := fake.service()
+
+			aborted, err := svc.AbortStaleUploads(context.Background(), AbortStaleUploadsRequest{BucketName: "bucket", Prefix: "in/", OlderThan: 24 * time.Hour})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AbortStaleUploads error = %v, want error: %v", err, tt.wantErr)
+			}
+
+			ids := []string{}
+			for _, upload := range aborted {
+				ids = append(ids, upload.UploadID)
+				if !strings.HasPrefix(upload.Key, "in/") || upload.Initiated.IsZero() {
+					t.Errorf("aborted upload = %+v, want its key and initiation time", upload)
+				}
+			}
+			listing.mu.Lock()
+			defer listing.mu.Unlock()
+			if !slices.Equal(ids, tt.wantAborted) || !slices.Equal(listing.aborted, tt.wantAborted) {
+				t.Errorf("reported %v and aborted %v, want %v", ids, listing.aborted, tt.wantAborted)
+			}
+			if listing.prefix != "in/" {
+				t.Errorf("listed prefix %q, want in/", listing.prefix)
+			}
+		})
+	}
+}
+
+func TestAbortStaleUploadsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request AbortStaleUploadsRequest
+	}{
+		{name: "missing bucket name", request: AbortStaleUploadsRequest{OlderThan: time.Hour}},
+		{name: "missing age", request: AbortStaleUploadsRequest{BucketName: "bucket"}},
+		{name: "listing fails", request: AbortStaleUploadsRequest{BucketName: "bucket", OlderThan: time.Hour}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The fake does not list multipart uploads on its own.
+			if _, err := newFakeS3(t, "bucket").service().AbortStaleUploads(context.Background(), tt.request); err == nil {
+				t.Error("AbortStaleUploads succeeded")
+			}
+		})
+	}
+}
+
+func TestStartUploadJanitor(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	listing := &fakeUploadListing{}
+	fake.intercept = listing.intercept([][]fakeUpload{{{key: "a.bin", id: "stale", age: 48 * time.Hour}}}, "")
+	svc := fake.service()
+	request := AbortStaleUploadsRequest{BucketName: "bucket", OlderThan: 24 * time.Hour}
+
+	if err := svc.StartUploadJanitor(context.Background(), request, 0); err == nil {
+		t.Error("StartUploadJanitor without an interval succeeded")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- svc.StartUploadJanitor(ctx, request, 5*time.Millisecond) }()
+
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(5 * time.Millisecond) {
+		listing.mu.Lock()
+		runs := len(listing.aborted)
+		listing.mu.Unlock()
+		if runs >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("janitor aborted %d times, want it to run on every tick", runs)
+		}
+	}
+
+	if err := svc.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := <-done; !errors.Is(err, ErrServiceClosed) {
+		t.Errorf("StartUploadJanitor after Shutdown = %v, want %v", err, ErrServiceClosed)
+	}
+}
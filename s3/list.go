@@ -0,0 +1,194 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+func (s *s3Service) ListFiles(ctx context.Context, data ListFilesRequest) *Iterator[FileInfo] {
+	if err := s.validateListFiles(data.BucketName); err != nil {
+		return failedIterator[FileInfo](ctx, err)
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.BucketName, data.Prefix); err != nil {
+		return failedIterator[FileInfo](ctx, err)
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(data.BucketName),
+		Prefix: aws.String(data.Prefix),
+	}
+	if data.PageSize > 0 {
+		input.MaxKeys = aws.Int32(data.PageSize)
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s.s3Cli, input)
+	var (
+		it    *Iterator[FileInfo]
+		after string
+	)
+	it = newOperationIterator(s, ctx, func(ctx context.Context) ([]FileInfo, bool, error) {
+		if !paginator.HasMorePages() {
+			return nil, false, nil
+		}
+
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list files on bucket %s: %w", data.BucketName, err)
+		}
+		listedAt := time.Now()
+
+		// The page shows whether the pending changes in its key range landed.
+		if len(it.freshness.Pending) > 0 {
+			page := make(map[string]bool, len(output.Contents))
+			for _, object := range output.Contents {
+				page[aws.ToString(object.Key)] = true
+			}
+			last := ""
+			if paginator.HasMorePages() && len(output.Contents) > 0 {
+				last = aws.ToString(output.Contents[len(output.Contents)-1].Key)
+			}
+			it.freshness.Pending = s.pending.listed(data.BucketName, it.freshness.Pending, page, after, last)
+			after = last
+		}
+
+		objects, err := authorizedItems(ctx, s, authz.PermissionDownload, output.Contents, func(object types.Object) (string, string) {
+			return data.BucketName, aws.ToString(object.Key)
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		files := make([]FileInfo, 0, len(objects))
+		for _, object := range objects {
+			location, err := s.objectURL(ctx, data.BucketName, aws.ToString(object.Key), "")
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to build file url: %w", err)
+			}
+
+			files = append(files, FileInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				ETag:         aws.ToString(object.ETag),
+				StorageClass: string(object.StorageClass),
+				LastModified: aws.ToTime(object.LastModified),
+				URL:          location,
+				ListedAt:     listedAt,
+			})
+		}
+
+		return files, paginator.HasMorePages(), nil
+	})
+	it.freshness = s.freshness(data.BucketName, data.Prefix)
+
+	return it
+}
+
+// ListFileVersions lists object versions and delete markers, ordered by key
+// within each page.
+func (s *s3Service) ListFileVersions(ctx context.Context, data ListFilesRequest) *Iterator[FileVersion] {
+	if err := s.validateListFiles(data.BucketName); err != nil {
+		return failedIterator[FileVersion](ctx, err)
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.BucketName, data.Prefix); err != nil {
+		return failedIterator[FileVersion](ctx, err)
+	}
+
+	input := &s3.ListObjectVersionsInput{
+		Bucket: aws.String(data.BucketName),
+		Prefix: aws.String(data.Prefix),
+	}
+	if data.PageSize > 0 {
+		input.MaxKeys = aws.Int32(data.PageSize)
+	}
+
+	paginator := s3.NewListObjectVersionsPaginator(s.s3Cli, input)
+	return newOperationIterator(s, ctx, func(ctx context.Context) ([]FileVersion, bool, error) {
+		if !paginator.HasMorePages() {
+			return nil, false, nil
+		}
+
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list file versions on bucket %s: %w", data.BucketName, err)
+		}
+
+		versions := make([]FileVersion, 0, len(output.Versions)+len(output.DeleteMarkers))
+		for _, version := range output.Versions {
+			versions = append(versions, FileVersion{
+				Key:          aws.ToString(version.Key),
+				VersionID:    aws.ToString(version.VersionId),
+				IsLatest:     aws.ToBool(version.IsLatest),
+				Size:         aws.ToInt64(version.Size),
+				ETag:         aws.ToString(version.ETag),
+				LastModified: aws.ToTime(version.LastModified),
+			})
+		}
+		for _, marker := range output.DeleteMarkers {
+			versions = append(versions, FileVersion{
+				Key:          aws.ToString(marker.Key),
+				VersionID:    aws.ToString(marker.VersionId),
+				IsLatest:     aws.ToBool(marker.IsLatest),
+				DeleteMarker: true,
+				LastModified: aws.ToTime(marker.LastModified),
+			})
+		}
+
+		versions, err = authorizedItems(ctx, s, authz.PermissionDownload, versions, func(version FileVersion) (string, string) {
+			return data.BucketName, version.Key
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		slices.SortStableFunc(versions, func(a, b FileVersion) int {
+			if c := strings.Compare(a.Key, b.Key); c != 0 {
+				return c
+			}
+			return b.LastModified.Compare(a.LastModified)
+		})
+
+		return versions, paginator.HasMorePages(), nil
+	})
+}
+
+func (s *s3Service) ListBuckets(ctx context.Context) *Iterator[BucketInfo] {
+	paginator := s3.NewListBucketsPaginator(s.s3Cli, &s3.ListBucketsInput{})
+	return newOperationIterator(s, ctx, func(ctx context.Context) ([]BucketInfo, bool, error) {
+		if !paginator.HasMorePages() {
+			return nil, false, nil
+		}
+
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to list buckets: %w", err)
+		}
+
+		buckets := make([]BucketInfo, 0, len(output.Buckets))
+		for _, bucket := range output.Buckets {
+			buckets = append(buckets, BucketInfo{
+				Name:         aws.ToString(bucket.Name),
+				Region:       aws.ToString(bucket.BucketRegion),
+				CreationDate: aws.ToTime(bucket.CreationDate),
+			})
+		}
+
+		return buckets, paginator.HasMorePages(), nil
+	})
+}
+
+func failedIterator[T any](ctx context.Context, err error) *Iterator[T] {
+	return newIterator(ctx, func(context.Context) ([]T, bool, error) {
+		return nil, false, err
+	})
+}
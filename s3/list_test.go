@@ -0,0 +1,137 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestListFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   ListFilesRequest
+		wantKeys  []string
+		wantPages int
+		wantErr   bool
+	}{
+		{name: "one page", request: ListFilesRequest{BucketName: "bucket"}, wantKeys: []string{"a/1.txt", "a/2.txt", "a/3.txt", "b/1.txt", "c.txt"}, wantPages: 1},
+		{name: "paged", request: ListFilesRequest{BucketName: "bucket", PageSize: 2}, wantKeys: []string{"a/1.txt", "a/2.txt", "a/3.txt", "b/1.txt", "c.txt"}, wantPages: 3},
+		{name: "prefix", request: ListFilesRequest{BucketName: "bucket", Prefix: "a/", PageSize: 2}, wantKeys: []string{"a/1.txt", "a/2.txt", "a/3.txt"}, wantPages: 2},
+		{name: "missing bucket name", request: ListFilesRequest{}, wantKeys: []string{}, wantErr: true},
+		{name: "unknown bucket", request: ListFilesRequest{BucketName: "missing"}, wantKeys: []string{}, wantPages: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			for _, key := range []string{"c.txt", "a/2.txt", "b/1.txt", "a/1.txt", "a/3.txt"} {
+				fake.put("bucket", key, "text/plain", []byte(key), nil)
+			}
+			svc := fake.service()
+
+			files := svc.ListFiles(context.Background(), tt.request)
+			keys := []string{}
+			for file := range files.All() {
+				keys = append(keys, file.Key)
+				if file.Size != int64(len(file.Key)) || file.ETag == "" || file.ListedAt.IsZero() {
+					t.Errorf("file %s = %+v, want its size, etag and listing time", file.Key, file)
+				}
+			}
+
+			if !slices.Equal(keys, tt.wantKeys) {
+				t.Errorf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+			if err := files.Err(); (err != nil) != tt.wantErr {
+				t.Errorf("Err = %v, want error: %v", err, tt.wantErr)
+			}
+			fake.mu.Lock()
+			pages := 0
+			for _, request := range fake.requests {
+				if strings.Contains(request, "list-type=2") {
+					pages++
+				}
+			}
+			fake.mu.Unlock()
+			if pages != tt.wantPages {
+				t.Errorf("listed %d pages, want %d", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestListFilesCancelled(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	for _, key := range []string{"a.txt", "b.txt", "c.txt"} {
+		fake.put("bucket", key, "text/plain", []byte(key), nil)
+	}
+	svc := fake.service()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	files := svc.ListFiles(ctx, ListFilesRequest{BucketName: "bucket", PageSize: 1})
+	keys := []string{}
+	for file := range files.All() {
+		keys = append(keys, file.Key)
+		cancel()
+	}
+
+	if !slices.Equal(keys, []string{"a.txt"}) {
+		t.Errorf("keys = %v, want only the first page", keys)
+	}
+	if err := files.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Err = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestListFileVersions(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	for _, key := range []string{"b.txt", "a.txt", "c.txt"} {
+		fake.put("bucket", key, "text/plain", []byte(key), nil)
+	}
+	svc := fake.service()
+
+	versions := svc.ListFileVersions(context.Background(), ListFilesRequest{BucketName: "bucket", PageSize: 2})
+	keys := []string{}
+	for version := range versions.Chan() {
+		keys = append(keys, version.Key)
+		if version.VersionID == "" || !version.IsLatest || version.DeleteMarker || version.Size != int64(len(version.Key)) {
+			t.Errorf("version of %s = %+v, want the latest version with its size", version.Key, version)
+		}
+	}
+
+	if err := versions.Err(); err != nil {
+		t.Fatalf("Err = %v", err)
+	}
+	if want := []string{"a.txt", "b.txt", "c.txt"}; !slices.Equal(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+
+	failed := svc.ListFileVersions(context.Background(), ListFilesRequest{BucketName: "missing"})
+	for range failed.All() {
+		t.Error("listing versions of a missing bucket yielded a version")
+	}
+	if failed.Err() == nil {
+		t.Error("listing versions of a missing bucket succeeded")
+	}
+}
+
+func TestListBuckets(t *testing.T) {
+	fake := newFakeS3(t, "photos", "documents")
+	buckets := fake.service().ListBuckets(context.Background())
+
+	names := []string{}
+	for bucket := range buckets.All() {
+		names = append(names, bucket.Name)
+		if bucket.Region != "us-east-1" {
+			t.Errorf("bucket %s region = %q, want us-east-1", bucket.Name, bucket.Region)
+		}
+	}
+
+	if err := buckets.Err(); err != nil {
+		t.Fatalf("Err = %v", err)
+	}
+	if want := []string{"documents", "photos"}; !slices.Equal(names, want) {
+		t.Errorf("buckets = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,357 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+const (
+	maxCopyObjectSize   = 5 * 1024 * 1024 * 1024
+	maxObjectSize       = 5 * 1024 * 1024 * 1024 * 1024
+	copyPartSize        = 512 * 1024 * 1024
+	defaultMigrateLimit = 8
+)
+
+// Migrate copies every object under data.Prefix from SourceBucket to
+// DestinationBucket, preserving metadata, tags and storage class. Objects are
+// copied server-side unless a Destination service is given, in which case they are
+// streamed through this process (e.g. across accounts or endpoints). Progress is
+// checkpointed after each listed page so an interrupted run can resume.
+func (s *s3Service) Migrate(ctx context.Context, data MigrateRequest) (MigrateResult, error) {
+	if err := s.acquire(); err != nil {
+		return MigrateResult{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateMigrate(data); err != nil {
+		return MigrateResult{}, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.SourceBucket, data.Prefix); err != nil {
+		return MigrateResult{}, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionUpload, data.DestinationBucket, data.DestinationPrefix); err != nil {
+		return MigrateResult{}, err
+	}
+
+	dst := s
+	if data.Destination != nil {
+		var ok bool
+		if dst, ok = data.Destination.(*s3Service); !ok {
+			return MigrateResult{}, errors.New("destination must be created by NewS3Service")
+		}
+	}
+
+	startAfter, err := readCheckpoint(data.CheckpointPath)
+	if err != nil {
+		return MigrateResult{}, err
+	}
+
+	concurrency := data.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultMigrateLimit
+	}
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(data.SourceBucket),
+		Prefix: aws.String(data.Prefix),
+	}
+	if startAfter != "" {
+		input.StartAfter = aws.String(startAfter)
+	}
+
+	progress := &migrateCounter{}
+	var failuresMu sync.Mutex
+	result := MigrateResult{}
+
+	paginator := s3.NewListObjectsV2Paginator(s.s3Cli, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("failed to list objects of bucket %s: %v", data.SourceBucket, err)
+			return progress.result(result), fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for _, object := range page.Contents {
+			if ctx.Err() != nil {
+				break
+			}
+
+			// A grant on the prefix "a/b" does not cover listed keys like "a/bc".
+			key := aws.ToString(object.Key)
+			if s.authorizeKeys(ctx, authz.PermissionDownload, data.SourceBucket, key) != nil ||
+				s.authorizeKeys(ctx, authz.PermissionUpload, data.DestinationBucket, data.DestinationPrefix+strings.TrimPrefix(key, data.Prefix)) != nil {
+				continue
+			}
+
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(object types.Object) {
+				defer func() {
+					<-sem
+					wg.Done()
+				}()
+
+				if err := s.migrateObject(ctx, dst, data, object); err != nil {
+					log.Printf("failed to migrate file %s: %v", key, err)
+					failuresMu.Lock()
+					result.Failed = append(result.Failed, MigrateFailure{Filename: key, Err: err})
+					failuresMu.Unlock()
+					progress.failed.Add(1)
+				} else {
+					progress.copied.Add(1)
+					progress.bytes.Add(aws.ToInt64(object.Size))
+				}
+
+				if data.OnProgress != nil {
+					data.OnProgress(progress.snapshot(key))
+				}
+			}(object)
+		}
+		wg.Wait()
+
+		if err := ctx.Err(); err != nil {
+			return progress.result(result), err
+		}
+
+		// Once a page has failures the checkpoint stays put, so a resumed run
+		// retries the failed objects.
+		if len(page.Contents) > 0 && len(result.Failed) == 0 {
+			lastKey := aws.ToString(page.Contents[len(page.Contents)-1].Key)
+			if err := writeCheckpoint(data.CheckpointPath, lastKey); err != nil {
+				return progress.result(result), err
+			}
+		}
+	}
+
+	result = progress.result(result)
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("failed to migrate %d files", len(result.Failed))
+	}
+
+	if data.CheckpointPath != "" {
+		if err := os.Remove(data.CheckpointPath); err != nil && !os.IsNotExist(err) {
+			log.Printf("failed to remove checkpoint %s: %v", data.CheckpointPath, err)
+		}
+	}
+
+	return result, nil
+}
+
+func (s *s3Service) migrateObject(ctx context.Context, dst *s3Service, data MigrateRequest, object types.Object) error {
+	key := aws.ToString(object.Key)
+	dstKey := data.DestinationPrefix + strings.TrimPrefix(key, data.Prefix)
+	storageClass := types.StorageClass(object.StorageClass)
+
+	switch {
+	case dst != s:
+		return s.streamObject(ctx, dst, data, key, dstKey, storageClass)
+	case aws.ToInt64(object.Size) > maxCopyObjectSize:
+		return s.multipartCopy(ctx, data, key, dstKey, aws.ToInt64(object.Size), storageClass)
+	default:
+		_, err := s.s3Cli.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:            aws.String(data.DestinationBucket),
+			Key:               aws.String(dstKey),
+			CopySource:        aws.String(copySource(data.SourceBucket, key)),
+			MetadataDirective: types.MetadataDirectiveCopy,
+			TaggingDirective:  types.TaggingDirectiveCopy,
+			StorageClass:      storageClass,
+		})
+		return err
+	}
+}
+
+// multipartCopy copies objects above the 5 GiB CopyObject limit part by part;
+// metadata and tags have to be carried over explicitly in that case.
+func (s *s3Service) multipartCopy(ctx context.Context, data MigrateRequest, key, dstKey string, size int64, storageClass types.StorageClass) error {
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(data.SourceBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.multipartCopyHead(ctx, data, key, dstKey, size, storageClass, head)
+}
+
+// multipartCopyHead is multipartCopy with the source's headers already read;
+// the copy gets head's content headers and metadata.
+func (s *s3Service) multipartCopyHead(ctx context.Context, data MigrateRequest, key, dstKey string, size int64, storageClass types.StorageClass, head *s3.HeadObjectOutput) error {
+	tagging, err := s.objectTagging(ctx, data.SourceBucket, key)
+	if err != nil {
+		return err
+	}
+
+	upload, err := s.s3Cli.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:          aws.String(data.DestinationBucket),
+		Key:             aws.String(dstKey),
+		ContentType:     head.ContentType,
+		ContentEncoding: head.ContentEncoding,
+		CacheControl:    head.CacheControl,
+		Metadata:        head.Metadata,
+		StorageClass:    storageClass,
+		Tagging:         tagging,
+	})
+	if err != nil {
+		return err
+	}
+
+	partSize := copyPartSizeFor(size)
+	parts := []types.CompletedPart{}
+	for start, number := int64(0), int32(1); start < size; start, number = start+partSize, number+1 {
+		end := min(start+partSize, size) - 1
+		part, err := s.s3Cli.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          aws.String(data.DestinationBucket),
+			Key:             aws.String(dstKey),
+			UploadId:        upload.UploadId,
+			PartNumber:      aws.Int32(number),
+			CopySource:      aws.String(copySource(data.SourceBucket, key)),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+		})
+		if err != nil {
+			s.abortMultipart(data.DestinationBucket, dstKey, upload.UploadId)
+			return err
+		}
+		parts = append(parts, types.CompletedPart{ETag: part.CopyPartResult.ETag, PartNumber: aws.Int32(number)})
+	}
+
+	_, err = s.s3Cli.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(data.DestinationBucket),
+		Key:             aws.String(dstKey),
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		s.abortMultipart(data.DestinationBucket, dstKey, upload.UploadId)
+	}
+
+	return err
+}
+
+// copyPartSizeFor raises copyPartSize so that size fits in
+// manager.MaxUploadParts parts.
+func copyPartSizeFor(size int64) int64 {
+	maxParts := int64(manager.MaxUploadParts)
+	return max(copyPartSize, (size+maxParts-1)/maxParts)
+}
+
+func (s *s3Service) abortMultipart(bucketName, key string, uploadID *string) {
+	_, err := s.s3Cli.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+	if err != nil {
+		log.Printf("failed to abort multipart upload of %s: %v", key, err)
+	}
+}
+
+// streamObject downloads from this service and uploads through dst, for copies
+// that cannot be done server-side.
+func (s *s3Service) streamObject(ctx context.Context, dst *s3Service, data MigrateRequest, key, dstKey string, storageClass types.StorageClass) error {
+	object, err := s.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(data.SourceBucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return err
+	}
+	defer object.Body.Close()
+
+	tagging, err := s.objectTagging(ctx, data.SourceBucket, key)
+	if err != nil {
+		return err
+	}
+
+	_, err = manager.NewUploader(dst.s3Cli).Upload(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(data.DestinationBucket),
+		Key:             aws.String(dstKey),
+		Body:            object.Body,
+		ContentType:     object.ContentType,
+		ContentEncoding: object.ContentEncoding,
+		CacheControl:    object.CacheControl,
+		Metadata:        object.Metadata,
+		StorageClass:    storageClass,
+		Tagging:         tagging,
+	})
+
+	return err
+}
+
+func (s *s3Service) objectTagging(ctx context.Context, bucketName, key string) (*string, error) {
+	tags, err := s.getObjectTags(ctx, bucketName, key)
+	if err != nil || len(tags) == 0 {
+		return nil, err
+	}
+
+	return aws.String(encodeTags(tags)), nil
+}
+
+func readCheckpoint(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(content)), nil
+}
+
+func writeCheckpoint(path, key string) error {
+	if path == "" {
+		return nil
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(key), 0o600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+type migrateCounter struct {
+	copied atomic.Int64
+	failed atomic.Int64
+	bytes  atomic.Int64
+}
+
+func (m *migrateCounter) snapshot(key string) MigrateProgress {
+	return MigrateProgress{
+		Copied:   m.copied.Load(),
+		Failed:   m.failed.Load(),
+		Bytes:    m.bytes.Load(),
+		Filename: key,
+	}
+}
+
+func (m *migrateCounter) result(result MigrateResult) MigrateResult {
+	result.Copied = m.copied.Load()
+	result.Bytes = m.bytes.Load()
+	return result
+}
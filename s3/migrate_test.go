@@ -0,0 +1,31 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+)
+
+func TestCopyPartSizeFor(t *testing.T) {
+	const maxParts = int64(manager.MaxUploadParts)
+	tests := []struct {
+		name string
+		size int64
+		want int64
+	}{
+		{name: "just over the copy limit", size: maxCopyObjectSize + 1, want: copyPartSize},
+		{name: "default part size fills every part", size: copyPartSize * maxParts, want: copyPartSize},
+		{name: "largest object", size: maxObjectSize, want: (maxObjectSize + maxParts - 1) / maxParts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := copyPartSizeFor(tt.size)
+			if got != tt.want {
+				t.Errorf("copyPartSizeFor(%d) = %d, want %d", tt.size, got, tt.want)
+			}
+			if parts := (tt.size + got - 1) / got; parts > maxParts || got > maxCopyObjectSize {
+				t.Errorf("copyPartSizeFor(%d) = %d needs %d parts", tt.size, got, parts)
+			}
+		})
+	}
+}
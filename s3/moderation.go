@@ -0,0 +1,298 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	"github.com/aws/aws-sdk-go-v2/service/comprehend"
+	comprehendTypes "github.com/aws/aws-sdk-go-v2/service/comprehend/types"
+	"github.com/aws/aws-sdk-go-v2/service/rekognition"
+	rekognitionTypes "github.com/aws/aws-sdk-go-v2/service/rekognition/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type ModerationAction string
+
+const (
+	ModerationReject     ModerationAction = "reject"
+	ModerationQuarantine ModerationAction = "quarantine"
+	ModerationTag        ModerationAction = "tag"
+)
+
+type (
+	Moderator interface {
+		Moderate(ctx context.Context, input ModerationInput) (ModerationResult, error)
+	}
+
+	ModerationPolicy struct {
+		Action           ModerationAction
+		QuarantinePrefix string
+	}
+
+	ModerationInput struct {
+		BucketName  string
+		Filename    string
+		ContentType string
+	}
+
+	ModerationLabel struct {
+		Name       string
+		Confidence float32
+	}
+
+	ModerationResult struct {
+		Flagged bool
+		Labels  []ModerationLabel
+	}
+)
+
+func (s *s3Service) moderate(ctx context.Context, data UploadFileRequest) error {
+	// Quarantining to the bare key would leave flagged files where they are.
+	if s.moderationPolicy.Action == ModerationQuarantine && s.moderationPolicy.QuarantinePrefix == "" {
+		return s.moderationFailed(ctx, data, ErrQuarantinePrefixRequired)
+	}
+
+	result, err := s.moderator.Moderate(ctx, ModerationInput{
+		BucketName:  data.BucketName,
+		Filename:    data.Filename,
+		ContentType: data.ContentType,
+	})
+	if err != nil {
+		return s.moderationFailed(ctx, data, err)
+	}
+
+	switch s.moderationPolicy.Action {
+	case ModerationTag:
+		return s.tagModeration(ctx, data, result)
+	case ModerationQuarantine:
+		if !result.Flagged {
+			return nil
+		}
+		quarantineKey := s.moderationPolicy.QuarantinePrefix + data.Filename
+		if err := s.moveObject(ctx, data.BucketName, data.Filename, quarantineKey); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrContentQuarantined, labelNames(result.Labels))
+	default:
+		if !result.Flagged {
+			return nil
+		}
+		if err := s.deleteObject(ctx, data.BucketName, data.Filename); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrContentRejected, labelNames(result.Labels))
+	}
+}
+
+// moderationFailed removes an upload that could not be moderated.
+func (s *s3Service) moderationFailed(ctx context.Context, data UploadFileRequest, err error) error {
+	log.Printf("failed to moderate file %s on bucket %s: %v", data.Filename, data.BucketName, err)
+	if err := s.deleteObject(ctx, data.BucketName, data.Filename); err != nil {
+		log.Printf("failed to remove unmoderated file %s: %v", data.Filename, err)
+	}
+	return fmt.Errorf("failed to moderate file: %w", err)
+}
+
+func (s *s3Service) tagModeration(ctx context.Context, data UploadFileRequest, result ModerationResult) error {
+	status := "clean"
+	if result.Flagged {
+		status = "flagged"
+	}
+
+	tagSet := objectTags(data.Tags)
+	tagSet = append(tagSet, types.Tag{Key: aws.String("moderation-status"), Value: aws.String(status)})
+	if names := sanitizeTagValue(labelNames(result.Labels)); names != "" {
+		tagSet = append(tagSet, types.Tag{Key: aws.String("moderation-labels"), Value: aws.String(names)})
+	}
+
+	_, err := s.s3Cli.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(data.BucketName),
+		Key:     aws.String(data.Filename),
+		Tagging: &types.Tagging{TagSet: tagSet},
+	})
+	if err != nil {
+		log.Printf("failed to tag file %s with moderation labels: %v", data.Filename, err)
+		return fmt.Errorf("failed to tag file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *s3Service) deleteObject(ctx context.Context, bucketName, filename string) error {
+	_, err := s.s3Cli.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(filename),
+	})
+	return err
+}
+
+func (s *s3Service) moveObject(ctx context.Context, bucketName, srcKey, dstKey string) error {
+	_, err := s.s3Cli.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(bucketName),
+		Key:        aws.String(dstKey),
+		CopySource: aws.String(copySource(bucketName, srcKey)),
+	})
+	if err != nil {
+		log.Printf("failed to copy file %s to %s: %v", srcKey, dstKey, err)
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+
+	return s.deleteObject(ctx, bucketName, srcKey)
+}
+
+// copySource formats the CopySource of an object; objects behind an access point
+// ARN are addressed as "<arn>/object/<key>".
+func copySource(bucketName, key string) string {
+	if arn.IsARN(bucketName) {
+		return url.PathEscape(bucketName + "/object/" + key)
+	}
+	return url.PathEscape(bucketName + "/" + key)
+}
+
+func labelNames(labels []ModerationLabel) string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.Name)
+	}
+
+	return strings.Join(names, ",")
+}
+
+// sanitizeTagValue keeps only the characters S3 accepts in tag values.
+func sanitizeTagValue(value string) string {
+	value = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case strings.ContainsRune(" +-=._:/@", r):
+			return r
+		case r == ',':
+			return ':'
+		}
+		return -1
+	}, value)
+
+	if len(value) > 256 {
+		value = value[:256]
+	}
+
+	return value
+}
+
+const (
+	maxToxicSegments     = 10
+	maxToxicSegmentBytes = 1000
+)
+
+type awsModerator struct {
+	rekognitionCli *rekognition.Client
+	comprehendCli  *comprehend.Client
+	s3Cli          *s3.Client
+	minConfidence  float32
+}
+
+// NewAWSModerator scans images with Rekognition and text with Comprehend. Other
+// content types pass through unflagged.
+func NewAWSModerator(cfg aws.Config, minConfidence float32) Moderator {
+	return &awsModerator{
+		rekognitionCli: rekognition.NewFromConfig(cfg),
+		comprehendCli:  comprehend.NewFromConfig(cfg),
+		s3Cli:          s3.NewFromConfig(cfg),
+		minConfidence:  minConfidence,
+	}
+}
+
+func (m *awsModerator) Moderate(ctx context.Context, input ModerationInput) (ModerationResult, error) {
+	switch {
+	case strings.HasPrefix(input.ContentType, "image/"):
+		return m.moderateImage(ctx, input)
+	case strings.HasPrefix(input.ContentType, "text/"):
+		return m.moderateText(ctx, input)
+	default:
+		return ModerationResult{}, nil
+	}
+}
+
+func (m *awsModerator) moderateImage(ctx context.Context, input ModerationInput) (ModerationResult, error) {
+	output, err := m.rekognitionCli.DetectModerationLabels(ctx, &rekognition.DetectModerationLabelsInput{
+		Image: &rekognitionTypes.Image{
+			S3Object: &rekognitionTypes.S3Object{
+				Bucket: aws.String(input.BucketName),
+				Name:   aws.String(input.Filename),
+			},
+		},
+		MinConfidence: aws.Float32(m.minConfidence),
+	})
+	if err != nil {
+		return ModerationResult{}, err
+	}
+
+	result := ModerationResult{}
+	for _, label := range output.ModerationLabels {
+		result.Labels = append(result.Labels, ModerationLabel{
+			Name:       aws.ToString(label.Name),
+			Confidence: aws.ToFloat32(label.Confidence),
+		})
+	}
+	result.Flagged = len(result.Labels) > 0
+
+	return result, nil
+}
+
+func (m *awsModerator) moderateText(ctx context.Context, input ModerationInput) (ModerationResult, error) {
+	object, err := m.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(input.BucketName),
+		Key:    aws.String(input.Filename),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", maxToxicSegments*maxToxicSegmentBytes-1)),
+	})
+	if err != nil {
+		return ModerationResult{}, err
+	}
+	defer object.Body.Close()
+
+	text, err := io.ReadAll(object.Body)
+	if err != nil {
+		return ModerationResult{}, err
+	}
+
+	segments := []comprehendTypes.TextSegment{}
+	for start := 0; start < len(text); start += maxToxicSegmentBytes {
+		end := min(start+maxToxicSegmentBytes, len(text))
+		segments = append(segments, comprehendTypes.TextSegment{Text: aws.String(strings.ToValidUTF8(string(text[start:end]), ""))})
+	}
+	if len(segments) == 0 {
+		return ModerationResult{}, nil
+	}
+
+	output, err := m.comprehendCli.DetectToxicContent(ctx, &comprehend.DetectToxicContentInput{
+		LanguageCode: comprehendTypes.LanguageCodeEn,
+		TextSegments: segments,
+	})
+	if err != nil {
+		return ModerationResult{}, err
+	}
+
+	result := ModerationResult{}
+	confidence := m.minConfidence / 100
+	for _, toxic := range output.ResultList {
+		for _, label := range toxic.Labels {
+			if aws.ToFloat32(label.Score) < confidence {
+				continue
+			}
+			result.Labels = append(result.Labels, ModerationLabel{
+				Name:       string(label.Name),
+				Confidence: aws.ToFloat32(label.Score) * 100,
+			})
+		}
+	}
+	result.Flagged = len(result.Labels) > 0
+
+	return result, nil
+}
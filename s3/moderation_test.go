@@ -0,0 +1,48 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type flaggingModerator struct{}
+
+func (flaggingModerator) Moderate(context.Context, ModerationInput) (ModerationResult, error) {
+	return ModerationResult{Flagged: true, Labels: []ModerationLabel{{Name: "Violence", Confidence: 99}}}, nil
+}
+
+func TestModerationQuarantine(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		wantErr  error
+		wantKeys []string
+	}{
+		{name: "prefix", prefix: "quarantine/", wantErr: ErrContentQuarantined, wantKeys: []string{"quarantine/a.txt"}},
+		{name: "prefix without slash", prefix: "quarantine", wantErr: ErrContentQuarantined, wantKeys: []string{"quarantine/a.txt"}},
+		{name: "empty prefix", wantErr: ErrQuarantinePrefixRequired, wantKeys: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			svc := fake.service(WithModeration(flaggingModerator{}, ModerationPolicy{Action: ModerationQuarantine, QuarantinePrefix: tt.prefix}))
+
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.txt",
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader("flagged")),
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UploadFile error = %v, want %v", err, tt.wantErr)
+			}
+			if got := fake.keys("bucket"); !slices.Equal(got, tt.wantKeys) {
+				t.Errorf("bucket holds %v, want %v", got, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+// NextAvailableKey returns desiredKey if it is free, otherwise the first free
+// "name (N).ext" variant. Candidates are found by listing the shared prefix once
+// instead of probing each one with a HEAD request.
+func (s *s3Service) NextAvailableKey(ctx context.Context, bucketName, desiredKey string) (string, error) {
+	if err := s.acquire(); err != nil {
+		return "", err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if bucketName == "" {
+		return "", errors.New("bucket name is required")
+	}
+
+	if desiredKey == "" {
+		return "", errors.New("key is required")
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, bucketName, desiredKey); err != nil {
+		return "", err
+	}
+
+	return s.nextAvailableKey(ctx, bucketName, desiredKey)
+}
+
+func (s *s3Service) nextAvailableKey(ctx context.Context, bucketName, desiredKey string) (string, error) {
+	ext := path.Ext(desiredKey)
+	if strings.Contains(ext, "/") {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(desiredKey, ext)
+
+	taken := map[int]bool{}
+	paginator := s3.NewListObjectsV2Paginator(s.s3Cli, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(stem),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("failed to list objects of bucket %s: %v", bucketName, err)
+			return "", fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			if n, ok := suffixNumber(aws.ToString(object.Key), stem, ext); ok {
+				taken[n] = true
+			}
+		}
+	}
+
+	if !taken[0] {
+		return desiredKey, nil
+	}
+
+	n := 1
+	for taken[n] {
+		n++
+	}
+
+	return fmt.Sprintf("%s (%d)%s", stem, n, ext), nil
+}
+
+// suffixNumber reports whether key is stem+ext (0) or "stem (N)"+ext (N).
+func suffixNumber(key, stem, ext string) (int, bool) {
+	rest, ok := strings.CutPrefix(key, stem)
+	if !ok {
+		return 0, false
+	}
+
+	rest, ok = strings.CutSuffix(rest, ext)
+	if !ok {
+		return 0, false
+	}
+
+	if rest == "" {
+		return 0, true
+	}
+
+	digits, ok := strings.CutPrefix(rest, " (")
+	if !ok {
+		return 0, false
+	}
+
+	digits, ok = strings.CutSuffix(digits, ")")
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(digits)
+	if err != nil || n < 1 || strconv.Itoa(n) != digits {
+		return 0, false
+	}
+
+	return n, true
+}
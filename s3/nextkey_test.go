@@ -0,0 +1,54 @@
+package s3
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNextAvailableKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		desired  string
+		want     string
+	}{
+		{name: "free", existing: []string{"b.txt"}, desired: "a.txt", want: "a.txt"},
+		{name: "taken", existing: []string{"a.txt"}, desired: "a.txt", want: "a (1).txt"},
+		{name: "first gap", existing: []string{"a.txt", "a (1).txt", "a (3).txt"}, desired: "a.txt", want: "a (2).txt"},
+		{name: "similar names ignored", existing: []string{"a.txt", "a (1).txt.bak", "a (01).txt", "a (x).txt", "ab.txt"}, desired: "a.txt", want: "a (1).txt"},
+		{name: "original free beside a variant", existing: []string{"a (1).txt"}, desired: "a.txt", want: "a.txt"},
+		{name: "no extension", existing: []string{"dir.v2/readme"}, desired: "dir.v2/readme", want: "dir.v2/readme (1)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			for _, key := range tt.existing {
+				fake.put("bucket", key, "text/plain", nil, nil)
+			}
+
+			got, err := fake.service().NextAvailableKey(context.Background(), "bucket", tt.desired)
+			if err != nil || got != tt.want {
+				t.Errorf("NextAvailableKey = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextAvailableKeyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		bucket string
+		key    string
+	}{
+		{name: "missing bucket name", key: "a.txt"},
+		{name: "missing key", bucket: "bucket"},
+		{name: "unknown bucket", bucket: "missing", key: "a.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := newFakeS3(t, "bucket").service().NextAvailableKey(context.Background(), tt.bucket, tt.key); err == nil {
+				t.Errorf("NextAvailableKey = %q, want an error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,330 @@
+package s3
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/smithy-go/middleware"
+
+	"github.com/KurniawanHendiW/file-uploader/breaker"
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+	"github.com/KurniawanHendiW/file-uploader/spill"
+)
+
+type Option func(*s3Service)
+
+// WithModeration checks uploads with moderator. ModerationQuarantine needs a
+// QuarantinePrefix; uploads fail moderation without one.
+func WithModeration(moderator Moderator, policy ModerationPolicy) Option {
+	return func(s *s3Service) {
+		if policy.QuarantinePrefix != "" && !strings.HasSuffix(policy.QuarantinePrefix, "/") {
+			policy.QuarantinePrefix += "/"
+		}
+		s.moderator = moderator
+		s.moderationPolicy = policy
+	}
+}
+
+// WithEnrichment runs enricher over every upload. When storeAsMetadata is set the
+// extracted values are also written to the object's user metadata.
+func WithEnrichment(enricher Enricher, storeAsMetadata bool) Option {
+	return func(s *s3Service) {
+		s.enricher = enricher
+		s.storeEnrichment = storeAsMetadata
+	}
+}
+
+func WithIndexer(indexer Indexer) Option {
+	return func(s *s3Service) {
+		s.indexer = indexer
+	}
+}
+
+// WithCatalog records uploads, renames and deletes in catalog.
+func WithCatalog(catalog Catalog) Option {
+	return func(s *s3Service) {
+		s.catalog = catalog
+	}
+}
+
+// WithCredentials overrides the default credential chain, e.g. for per-tenant roles.
+func WithCredentials(provider aws.CredentialsProvider) Option {
+	return func(s *s3Service) {
+		s.loadOptions = append(s.loadOptions, config.WithCredentialsProvider(provider))
+	}
+}
+
+// WithEndpoint points the client at an S3-compatible server such as MinIO or
+// LocalStack, using path-style addressing.
+func WithEndpoint(endpoint string) Option {
+	return func(s *s3Service) {
+		s.endpoint = endpoint
+	}
+}
+
+// WithTransferAcceleration uses the S3 accelerate endpoint for uploads and downloads
+// on buckets that have acceleration enabled.
+func WithTransferAcceleration() Option {
+	return func(s *s3Service) {
+		s.accelerate = true
+	}
+}
+
+func WithDualStack() Option {
+	return func(s *s3Service) {
+		s.dualStack = true
+	}
+}
+
+// WithFIPS uses FIPS endpoints. S3 has no accelerated FIPS endpoint, so this
+// disables transfer acceleration.
+func WithFIPS() Option {
+	return func(s *s3Service) {
+		s.fips = true
+	}
+}
+
+// WithUploadRules adds custom rules that run after the built-in upload checks.
+func WithUploadRules(rules ...Rule[UploadFileRequest]) Option {
+	return func(s *s3Service) {
+		s.uploadRules = append(s.uploadRules, rules...)
+	}
+}
+
+func WithDeleteRules(rules ...Rule[DeleteFileRequest]) Option {
+	return func(s *s3Service) {
+		s.deleteRules = append(s.deleteRules, rules...)
+	}
+}
+
+func WithDownloadRules(rules ...Rule[DownloadFileRequest]) Option {
+	return func(s *s3Service) {
+		s.downloadRules = append(s.downloadRules, rules...)
+	}
+}
+
+// WithAuthorization checks every operation on keys against the request's
+// Principal, or else the one authz.Middleware stored in the caller's context.
+// Reads need download, writes upload and removals delete permission; a rename
+// needs delete on the old key and upload on the new one. Listings, searches,
+// catalog queries and usage reports need download on their prefix and leave
+// out keys the principal may not read; EnforceTagPolicy and AbortStaleUploads
+// need the permission of what they do on their prefix and skip other keys.
+// Bucket operations, batch job status and Reconcile are not checked.
+func WithAuthorization() Option {
+	return func(s *s3Service) {
+		s.authorization = true
+	}
+}
+
+// WithTrash makes DeleteFile move objects under prefix instead of deleting them.
+// Deleting a key that is already in the trash removes it permanently.
+func WithTrash(prefix string) Option {
+	return func(s *s3Service) {
+		if prefix != "" && !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		s.trashPrefix = prefix
+	}
+}
+
+// WithVersionedTrash relies on bucket versioning for soft delete: DeleteFile
+// leaves a delete marker, which RestoreFromTrash removes again.
+func WithVersionedTrash() Option {
+	return func(s *s3Service) {
+		s.versionedTrash = true
+	}
+}
+
+// WithUploadTransformers runs transformers, in order, on every upload after the
+// request's own transformers. Enrichment and video processing still see the
+// untransformed body.
+func WithUploadTransformers(transformers ...Transformer) Option {
+	return func(s *s3Service) {
+		s.uploadTransformers = append(s.uploadTransformers, transformers...)
+	}
+}
+
+func WithDownloadTransformers(transformers ...Transformer) Option {
+	return func(s *s3Service) {
+		s.downloadTransformers = append(s.downloadTransformers, transformers...)
+	}
+}
+
+// WithVideoProcessing probes video uploads with runner and stores a poster frame
+// next to each video as "<key>.thumbnail.jpg". Videos are spooled to the spill
+// store for ffmpeg, so it needs one that gives files a path: with an encrypted
+// disk store, videos are uploaded unprocessed and a warning is logged.
+func WithVideoProcessing(runner MediaRunner) Option {
+	return func(s *s3Service) {
+		s.mediaRunner = runner
+	}
+}
+
+// WithHeaders sends headers with every S3 request, e.g. x-amz-expected-bucket-owner
+// or tracing headers. Use WithRequestHeaders for per-request values.
+func WithHeaders(headers http.Header) Option {
+	return func(s *s3Service) {
+		if s.headers == nil {
+			s.headers = http.Header{}
+		}
+		for key, values := range headers {
+			s.headers[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+}
+
+// WithMiddleware adds smithy middleware to the underlying S3 client.
+func WithMiddleware(fns ...func(*middleware.Stack) error) Option {
+	return func(s *s3Service) {
+		s.apiOptions = append(s.apiOptions, fns...)
+	}
+}
+
+// WithURLBuilder renders upload locations and listing URLs with builder instead
+// of the location returned by S3.
+func WithURLBuilder(builder URLBuilder) Option {
+	return func(s *s3Service) {
+		s.urlBuilder = builder
+	}
+}
+
+// WithPresignedURLs returns presigned download URLs valid for expires.
+func WithPresignedURLs(expires time.Duration) Option {
+	return func(s *s3Service) {
+		s.urlBuilder = s.presignedURL(expires)
+	}
+}
+
+func WithDeleteGuard(guard DeleteGuard) Option {
+	return func(s *s3Service) {
+		s.deleteGuard = guard
+	}
+}
+
+// WithBulkDelete sets how DeleteFile and CollectOrphans batch, parallelize and
+// retry DeleteObjects calls.
+func WithBulkDelete(opts BulkDeleteOptions) Option {
+	return func(s *s3Service) {
+		s.bulkDelete = opts
+	}
+}
+
+// WithCorrelationIDs tags uploads and deletes with a correlation ID, taken from
+// the request, the context or generated. It is logged, sent to S3 in header
+// (DefaultCorrelationHeader when empty) and the user agent, and returned in
+// results and errors together with the AWS request ID.
+func WithCorrelationIDs(header string) Option {
+	return func(s *s3Service) {
+		if header == "" {
+			header = DefaultCorrelationHeader
+		}
+		s.correlationHeader = header
+	}
+}
+
+// WithBucketNames lets callers pass logical bucket names, e.g. "uploads",
+// which are resolved with names from ResolveBucketNames on every call.
+func WithBucketNames(names BucketNames) Option {
+	return func(s *s3Service) {
+		s.bucketNames = names
+	}
+}
+
+// WithSpillStore sets where uploads that have to be buffered, such as videos
+// spooled for ffmpeg, are kept. The default is an unencrypted spill.NewDiskStore
+// in os.TempDir.
+func WithSpillStore(store spill.Store) Option {
+	return func(s *s3Service) {
+		s.spill = store
+	}
+}
+
+// WithUploadGuarantee makes failed or cancelled uploads leave nothing behind:
+// multipart uploads are aborted and any object that became visible is deleted.
+// Such uploads return an error wrapping ErrUploadAborted.
+func WithUploadGuarantee() Option {
+	return func(s *s3Service) {
+		s.uploadGuarantee = true
+	}
+}
+
+// WithFailover writes uploads to a backup bucket when the primary bucket is
+// unavailable after retries. Call Reconcile to move them back.
+func WithFailover(policy FailoverPolicy) Option {
+	return func(s *s3Service) {
+		s.failover = &policy
+	}
+}
+
+// WithConsistency tracks uploads and deletes until they are visible, so
+// listings report them in Freshness, and optionally waits for them.
+func WithConsistency(opts ConsistencyOptions) Option {
+	return func(s *s3Service) {
+		s.consistency = &opts
+	}
+}
+
+// WithIDGenerator sets how correlation IDs, upload tokens, batch job request
+// tokens and temp names are generated; idgen.Default is used otherwise.
+func WithIDGenerator(g idgen.Generator) Option {
+	return func(s *s3Service) {
+		s.ids = g
+	}
+}
+
+// WithCircuitBreaker fails S3 calls fast while b is open. One breaker can be
+// shared by several services talking to the same region.
+func WithCircuitBreaker(b *breaker.Breaker) Option {
+	return func(s *s3Service) {
+		s.breaker = b
+	}
+}
+
+// WithTimeouts applies per-operation deadlines to calls whose context has none.
+func WithTimeouts(timeouts Timeouts) Option {
+	return func(s *s3Service) {
+		timeouts = timeouts.withDefaults()
+		s.timeouts = &timeouts
+	}
+}
+
+// WithTransport gives the service its own HTTP transport configured by opts.
+func WithTransport(opts TransportOptions) Option {
+	return WithHTTPClient(NewHTTPClient(opts))
+}
+
+// WithHTTPClient sends requests through client, which may be shared across
+// services to pool connections.
+func WithHTTPClient(client aws.HTTPClient) Option {
+	return func(s *s3Service) {
+		s.httpClient = client
+		s.loadOptions = append(s.loadOptions, config.WithHTTPClient(client))
+	}
+}
+
+// WithBufferPool takes part buffers for streamed uploads from pool. Share one
+// pool between services to enforce a process-wide memory cap.
+func WithBufferPool(pool *BufferPool) Option {
+	return func(s *s3Service) {
+		s.bufferPool = pool
+	}
+}
+
+// WithTagPolicy tags uploads by policy rules and enables EnforceTagPolicy.
+func WithTagPolicy(policy TagPolicy) Option {
+	return func(s *s3Service) {
+		s.tagPolicy = &policy
+	}
+}
+
+// WithAccountID sets the account owning the Multi-Region Access Points used by
+// alias, so "<alias>.mrap" can be passed wherever a bucket name is expected.
+func WithAccountID(accountID string) Option {
+	return func(s *s3Service) {
+		s.accountID = accountID
+	}
+}
@@ -0,0 +1,162 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+// User metadata keys recording who owns and who uploaded an object. The owner
+// is stored as the SHA-256 of its ID, see ownerDigest.
+const (
+	OwnerIDMetadata    = "owner-id"
+	UploadedByMetadata = "uploaded-by"
+)
+
+func ownerMetadata(data UploadFileRequest) map[string]string {
+	metadata := map[string]string{}
+	if data.OwnerID != "" {
+		metadata[OwnerIDMetadata] = ownerDigest(data.OwnerID)
+	}
+
+	if data.UploadedBy != "" {
+		metadata[UploadedByMetadata] = metadataValue(data.UploadedBy)
+	}
+
+	return metadata
+}
+
+// ownerDigest encodes an owner ID for metadata. Unlike metadataValue, which
+// drops non-ASCII runes and truncates, distinct IDs never encode alike, so a
+// requester cannot pass as the owner of an object.
+func ownerDigest(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// authorizeDelete refuses deleting key on behalf of data.RequesterID unless
+// the object's metadata records them as its owner. Objects without an owner can only be deleted with
+// IgnoreOwnership.
+func authorizeDelete(ctx context.Context, data DeleteFileRequest, key string, metadata map[string]string) (KeyResult, bool) {
+	if data.RequesterID == "" || data.IgnoreOwnership {
+		return KeyResult{}, true
+	}
+
+	if err := checkOwner(ctx, key, metadata, data.RequesterID); err != nil {
+		return KeyResult{Key: key, Status: DeleteAccessDenied, Err: err}, false
+	}
+
+	return KeyResult{}, true
+}
+
+// checkOwner fails with ErrNotOwner unless metadata records requesterID as the
+// owner of key.
+func checkOwner(ctx context.Context, key string, metadata map[string]string, requesterID string) error {
+	if owner := metadata[OwnerIDMetadata]; owner == "" || owner != ownerDigest(requesterID) {
+		logf(ctx, "refused change of file %s requested by %s", key, requesterID)
+		return fmt.Errorf("%s: %w", key, ErrNotOwner)
+	}
+
+	return nil
+}
+
+// ListFilesByOwner lists the files owned by ownerID. With a catalog the listing
+// is a catalog query; otherwise every listed object is checked with a HEAD
+// request, which is slow on large buckets.
+func (s *s3Service) ListFilesByOwner(ctx context.Context, data ListFilesRequest, ownerID string) *Iterator[FileInfo] {
+	if err := s.validateListFilesByOwner(data.BucketName, ownerID); err != nil {
+		return failedIterator[FileInfo](ctx, err)
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.BucketName, data.Prefix); err != nil {
+		return failedIterator[FileInfo](ctx, err)
+	}
+
+	if s.catalog != nil {
+		return s.catalogFilesByOwner(ctx, data, ownerID)
+	}
+
+	files := s.ListFiles(ctx, data)
+	return newOperationIterator(s, ctx, func(ctx context.Context) ([]FileInfo, bool, error) {
+		page, more, err := files.nextPage(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+
+		owned := make([]FileInfo, 0, len(page))
+		for _, file := range page {
+			head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(data.BucketName),
+				Key:    aws.String(file.Key),
+			})
+			if err != nil {
+				if isNotFound(err) {
+					continue
+				}
+				return nil, false, fmt.Errorf("failed to get head object %s: %w", file.Key, err)
+			}
+
+			if head.Metadata[OwnerIDMetadata] == ownerDigest(ownerID) {
+				file.OwnerID = ownerID
+				owned = append(owned, file)
+			}
+		}
+
+		return owned, more, nil
+	})
+}
+
+func (s *s3Service) catalogFilesByOwner(ctx context.Context, data ListFilesRequest, ownerID string) *Iterator[FileInfo] {
+	limit := int(data.PageSize)
+	if limit <= 0 {
+		limit = defaultCatalogLimit
+	}
+
+	offset := 0
+	return newOperationIterator(s, ctx, func(ctx context.Context) ([]FileInfo, bool, error) {
+		entries, err := s.catalog.Query(ctx, CatalogQuery{
+			BucketName: data.BucketName,
+			Prefix:     data.Prefix,
+			Owner:      ownerID,
+			Limit:      limit,
+			Offset:     offset,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to query catalog: %w", err)
+		}
+		more := len(entries) == limit
+		offset += len(entries)
+
+		entries, err = authorizedItems(ctx, s, authz.PermissionDownload, entries, func(entry CatalogEntry) (string, string) {
+			return data.BucketName, entry.Key
+		})
+		if err != nil {
+			return nil, false, err
+		}
+
+		files := make([]FileInfo, 0, len(entries))
+		for _, entry := range entries {
+			location, err := s.objectURL(ctx, data.BucketName, entry.Key, "")
+			if err != nil {
+				return nil, false, fmt.Errorf("failed to build file url: %w", err)
+			}
+
+			files = append(files, FileInfo{
+				Key:          entry.Key,
+				Size:         entry.Size,
+				ETag:         entry.Checksum,
+				LastModified: entry.UpdatedAt,
+				URL:          location,
+				OwnerID:      entry.Owner,
+			})
+		}
+
+		return files, more, nil
+	})
+}
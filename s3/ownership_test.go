@@ -0,0 +1,95 @@
+package s3
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDeleteRequiresExactOwner(t *testing.T) {
+	long := strings.Repeat("a", 300)
+
+	tests := []struct {
+		name        string
+		owner       string
+		requester   string
+		wantDeleted bool
+	}{
+		{name: "owner", owner: "ü-alice", requester: "ü-alice", wantDeleted: true},
+		{name: "non-ascii stripped", owner: "ü-alice", requester: "-alice"},
+		{name: "control characters stripped", owner: "alice\x00", requester: "alice"},
+		{name: "surrounding spaces trimmed", owner: " alice ", requester: "alice"},
+		{name: "shared long prefix", owner: long + "-alice", requester: long + "-mallory"},
+		{name: "no owner", owner: "", requester: "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			svc := fake.service()
+
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.txt",
+				ContentType: "text/plain",
+				OwnerID:     tt.owner,
+				Body:        io.NopCloser(strings.NewReader("data")),
+			})
+			if err != nil {
+				t.Fatalf("UploadFile: %v", err)
+			}
+
+			result, _ := svc.DeleteFile(DeleteFileRequest{BucketName: "bucket", Filename: []string{"a.txt"}, RequesterID: tt.requester})
+			if len(result.Results) != 1 {
+				t.Fatalf("DeleteFile results = %+v", result.Results)
+			}
+
+			deleted := result.Results[0].Status == Deleted
+			if deleted != tt.wantDeleted {
+				t.Errorf("deleted = %v (status %s), want %v", deleted, result.Results[0].Status, tt.wantDeleted)
+			}
+			if _, exists := fake.object("bucket", "a.txt"); exists == tt.wantDeleted {
+				t.Errorf("object exists = %v after delete by %q", exists, tt.requester)
+			}
+		})
+	}
+}
+
+func TestDeleteHeadsKeysOnlyWhenNeeded(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   DeleteFileRequest
+		wantHeads int
+	}{
+		{name: "plain delete", request: DeleteFileRequest{}, wantHeads: 0},
+		{name: "dry run", request: DeleteFileRequest{DryRun: true}, wantHeads: 2},
+		{name: "ownership", request: DeleteFileRequest{RequesterID: "alice"}, wantHeads: 2},
+		{name: "ownership ignored", request: DeleteFileRequest{RequesterID: "alice", IgnoreOwnership: true}, wantHeads: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "a.txt", "text/plain", []byte("a"), map[string]string{OwnerIDMetadata: ownerDigest("alice")})
+			fake.put("bucket", "b.txt", "text/plain", []byte("b"), map[string]string{OwnerIDMetadata: ownerDigest("alice")})
+			var heads atomic.Int32
+			fake.intercept = func(_ http.ResponseWriter, r *http.Request) bool {
+				if r.Method == http.MethodHead {
+					heads.Add(1)
+				}
+				return false
+			}
+			svc := fake.service()
+
+			request := tt.request
+			request.BucketName, request.Filename = "bucket", []string{"a.txt", "b.txt"}
+			if _, err := svc.DeleteFile(request); err != nil {
+				t.Fatalf("DeleteFile: %v", err)
+			}
+
+			if got := int(heads.Load()); got != tt.wantHeads {
+				t.Errorf("HEAD requests = %d, want %d", got, tt.wantHeads)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// UploadFromPath uploads a local file. The open file is handed to the multipart
+// manager as is, so parts are read straight from disk at their offsets instead of
+// going through a base64 payload or a temporary copy.
+func (s *s3Service) UploadFromPath(ctx context.Context, localPath, bucketName, key string, opts PathUploadOptions) (UploadFileResult, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		log.Printf("failed to open file %s: %v", localPath, err)
+		return UploadFileResult{}, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return UploadFileResult{}, fmt.Errorf("failed to stat file: %w", err)
+	}
+	if info.IsDir() {
+		return UploadFileResult{}, fmt.Errorf("%s is a directory", localPath)
+	}
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		if contentType, err = fileContentType(file); err != nil {
+			return UploadFileResult{}, err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return UploadFileResult{}, err
+	}
+
+	return s.uploadContext(ctx, UploadFileRequest{
+		BucketName:  bucketName,
+		ContentType: contentType,
+		Filename:    key,
+		Body:        file,
+		Tags:        opts.Tags,
+		Accelerate:  opts.Accelerate,
+	})
+}
+
+// fileContentType guesses the type from the extension, falling back to sniffing
+// the first 512 bytes.
+func fileContentType(file *os.File) (string, error) {
+	if contentType := mime.TypeByExtension(filepath.Ext(file.Name())); contentType != "" {
+		return requestContentType(contentType), nil
+	}
+
+	head := make([]byte, 512)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return requestContentType(http.DetectContentType(head[:n])), nil
+}
@@ -0,0 +1,61 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUploadFromPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		cancel  bool
+		wantErr []error
+	}{
+		{name: "uploaded"},
+		{name: "cancelled during upload", cancel: true, wantErr: []error{context.Canceled, ErrUploadAborted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localPath := filepath.Join(t.TempDir(), "a.txt")
+			if err := os.WriteFile(localPath, []byte("hello"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			fake := newFakeS3(t, "bucket")
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if !tt.cancel || r.Method != http.MethodPut {
+					return false
+				}
+				cancel()
+				select {
+				case <-r.Context().Done():
+				case <-time.After(2 * time.Second):
+					fakeError(w, http.StatusInternalServerError, "InternalError")
+				}
+				return true
+			}
+			svc := fake.service(WithUploadGuarantee())
+
+			_, err := svc.UploadFromPath(ctx, localPath, "bucket", "a.txt", PathUploadOptions{})
+			for _, want := range tt.wantErr {
+				if !errors.Is(err, want) {
+					t.Errorf("UploadFromPath = %v, want %v", err, want)
+				}
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Fatal(err)
+			}
+
+			if _, ok := fake.object("bucket", "a.txt"); ok != (tt.wantErr == nil) {
+				t.Errorf("object stored = %v", ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+const (
+	defaultSegmentSize     = 4 * 1024 * 1024
+	defaultPlaylistExpires = time.Hour
+
+	tsPacketSize = 188
+	tsSyncByte   = 0x47
+)
+
+// MediaPlaylist splits an MPEG transport stream into byte ranges a player can
+// fetch progressively from S3 or a CDN, without the application proxying any
+// bytes. Segments end on TS packet boundaries; other containers return
+// ErrNotTransportStream, since byte ranges of them are not valid HLS segments.
+// Segment durations are estimated from data.Duration in proportion to their
+// size and add up to it, so constant bitrate media seeks most accurately.
+func (s *s3Service) MediaPlaylist(ctx context.Context, data PlaylistRequest) (Playlist, error) {
+	if err := s.acquire(); err != nil {
+		return Playlist{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validatePlaylist(data); err != nil {
+		return Playlist{}, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.BucketName, data.Filename); err != nil {
+		return Playlist{}, err
+	}
+
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(data.BucketName),
+		Key:    aws.String(data.Filename),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return Playlist{}, ErrFileNotFound
+		}
+		log.Printf("failed to get head object %s: %v", data.Filename, err)
+		return Playlist{}, err
+	}
+
+	size := aws.ToInt64(head.ContentLength)
+	if err := s.checkTransportStream(ctx, data, size); err != nil {
+		return Playlist{}, err
+	}
+
+	builder := data.URLBuilder
+	if builder == nil {
+		expires := data.Expires
+		if expires <= 0 {
+			expires = defaultPlaylistExpires
+		}
+		builder = s.presignedURL(expires)
+	}
+
+	objectURL, err := builder(ctx, data.BucketName, data.Filename)
+	if err != nil {
+		return Playlist{}, fmt.Errorf("failed to build file url: %w", err)
+	}
+
+	segmentSize := data.SegmentSize
+	if segmentSize <= 0 {
+		segmentSize = defaultSegmentSize
+	}
+	segmentSize = max(segmentSize/tsPacketSize, 1) * tsPacketSize
+
+	playlist := Playlist{URL: objectURL, Size: size, ContentType: aws.ToString(head.ContentType)}
+	for offset := int64(0); offset < size; offset += segmentSize {
+		end := min(offset+segmentSize, size)
+		// Durations come from the cumulative position so rounding never adds up.
+		playlist.Segments = append(playlist.Segments, PlaylistSegment{
+			Offset:   offset,
+			Length:   end - offset,
+			Duration: mediaPosition(data.Duration, end, size) - mediaPosition(data.Duration, offset, size),
+		})
+	}
+
+	return playlist, nil
+}
+
+func mediaPosition(duration time.Duration, offset, size int64) time.Duration {
+	return time.Duration(float64(duration) * float64(offset) / float64(size))
+}
+
+// checkTransportStream reads the first two packets of the object and checks
+// their sync bytes.
+func (s *s3Service) checkTransportStream(ctx context.Context, data PlaylistRequest, size int64) error {
+	if size == 0 || size%tsPacketSize != 0 {
+		return fmt.Errorf("%w: size %d is not a multiple of %d", ErrNotTransportStream, size, tsPacketSize)
+	}
+
+	head := min(size, 2*tsPacketSize)
+	output, err := s.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(data.BucketName),
+		Key:    aws.String(data.Filename),
+		Range:  aws.String(fmt.Sprintf("bytes=0-%d", head-1)),
+	})
+	if err != nil {
+		log.Printf("failed to read start of %s: %v", data.Filename, err)
+		return err
+	}
+	defer output.Body.Close()
+
+	packets := make([]byte, head)
+	if _, err := io.ReadFull(output.Body, packets); err != nil {
+		return fmt.Errorf("failed to read start of file: %w", err)
+	}
+
+	for offset := int64(0); offset < head; offset += tsPacketSize {
+		if packets[offset] != tsSyncByte {
+			return ErrNotTransportStream
+		}
+	}
+
+	return nil
+}
+
+// M3U8 renders the playlist as an HLS media playlist using EXT-X-BYTERANGE, so
+// every segment points at the same URL with a different byte range.
+func (p Playlist) M3U8() string {
+	var target time.Duration
+	for _, segment := range p.Segments {
+		target = max(target, segment.Duration)
+	}
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:4\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(target.Seconds())))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n")
+	for _, segment := range p.Segments {
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", segment.Duration.Seconds())
+		fmt.Fprintf(&b, "#EXT-X-BYTERANGE:%d@%d\n", segment.Length, segment.Offset)
+		b.WriteString(p.URL + "\n")
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+
+	return b.String()
+}
+
+// Range returns the HTTP Range header value that fetches the segment.
+func (s PlaylistSegment) Range() string {
+	return fmt.Sprintf("bytes=%d-%d", s.Offset, s.Offset+s.Length-1)
+}
@@ -0,0 +1,74 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func transportStream(packets int) []byte {
+	packet := append([]byte{tsSyncByte}, bytes.Repeat([]byte{0xff}, tsPacketSize-1)...)
+	return bytes.Repeat(packet, packets)
+}
+
+func TestMediaPlaylist(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		segmentSize int64
+		wantLengths []int64
+		wantErr     error
+	}{
+		{name: "aligned to packets", body: transportStream(5), segmentSize: 500, wantLengths: []int64{376, 376, 188}},
+		{name: "smaller than a packet", body: transportStream(2), segmentSize: 100, wantLengths: []int64{188, 188}},
+		{name: "single segment", body: transportStream(3), wantLengths: []int64{564}},
+		{name: "mp4", body: append([]byte("\x00\x00\x00\x18ftypmp42"), make([]byte, 2*tsPacketSize-12)...), wantErr: ErrNotTransportStream},
+		{name: "truncated packet", body: transportStream(2)[:300], wantErr: ErrNotTransportStream},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "a.ts", "video/mp2t", tt.body, nil)
+			svc := fake.service()
+
+			const duration = 10 * time.Second
+			playlist, err := svc.MediaPlaylist(context.Background(), PlaylistRequest{
+				BucketName:  "bucket",
+				Filename:    "a.ts",
+				SegmentSize: tt.segmentSize,
+				Duration:    duration,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MediaPlaylist = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			var total time.Duration
+			var offset int64
+			lengths := []int64{}
+			for _, segment := range playlist.Segments {
+				if segment.Offset != offset {
+					t.Errorf("segment at %d, want %d", segment.Offset, offset)
+				}
+				offset += segment.Length
+				total += segment.Duration
+				lengths = append(lengths, segment.Length)
+			}
+			if !slices.Equal(lengths, tt.wantLengths) {
+				t.Errorf("segment lengths = %v, want %v", lengths, tt.wantLengths)
+			}
+			if total != duration {
+				t.Errorf("segments last %v, want %v", total, duration)
+			}
+			if m3u8 := playlist.M3U8(); strings.Count(m3u8, "#EXT-X-BYTERANGE:") != len(tt.wantLengths) {
+				t.Errorf("M3U8 has the wrong number of byte ranges:\n%s", m3u8)
+			}
+		})
+	}
+}
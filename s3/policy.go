@@ -0,0 +1,357 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+type PolicyAction string
+
+const (
+	PolicyArchive    PolicyAction = "archive"
+	PolicyTransition PolicyAction = "transition"
+	PolicyDelete     PolicyAction = "delete"
+)
+
+// PolicyModifiedMetadata keeps the last modification time of objects that a
+// policy action rewrote in place, so their age survives the copy.
+const PolicyModifiedMetadata = "policy-modified"
+
+type (
+	// TagPolicy declares data-governance behaviour: Rules tag objects as they are
+	// uploaded and Actions act on those tags once objects are old enough.
+	TagPolicy struct {
+		Rules   []TagRule
+		Actions []TagAction
+	}
+
+	// TagRule tags uploads matching every condition that is set. Size conditions
+	// only match uploads whose size is known up front.
+	TagRule struct {
+		Prefix string
+		// ContentTypes are path.Match patterns such as "image/*".
+		ContentTypes []string
+		MinSize      int64
+		MaxSize      int64
+		Tenant       string
+		// Tags override tags of the same key given with the upload.
+		Tags map[string]string
+	}
+
+	// TagAction runs on objects tagged TagKey=TagValue (any value when TagValue
+	// is empty) last modified more than After ago.
+	TagAction struct {
+		TagKey   string
+		TagValue string
+		After    time.Duration
+		Action   PolicyAction
+		// StorageClass is the target of PolicyTransition; PolicyArchive defaults
+		// to GLACIER.
+		StorageClass types.StorageClass
+	}
+
+	PolicyActionResult struct {
+		Key    string
+		Action PolicyAction
+		Err    error
+	}
+
+	PolicyResult struct {
+		Results []PolicyActionResult
+	}
+)
+
+func (r TagRule) matches(data UploadFileRequest, size int64) bool {
+	if !strings.HasPrefix(data.Filename, r.Prefix) {
+		return false
+	}
+
+	if r.Tenant != "" && r.Tenant != data.Tenant {
+		return false
+	}
+
+	if len(r.ContentTypes) > 0 {
+		mediaType, _, _ := mime.ParseMediaType(data.ContentType)
+		matched := false
+		for _, pattern := range r.ContentTypes {
+			if ok, _ := path.Match(pattern, mediaType); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if r.MinSize > 0 && (size < 0 || size < r.MinSize) {
+		return false
+	}
+
+	if r.MaxSize > 0 && (size < 0 || size > r.MaxSize) {
+		return false
+	}
+
+	return true
+}
+
+// policyTags merges the tags of every matching rule into the upload's tags.
+func (s *s3Service) policyTags(data UploadFileRequest) map[string]string {
+	return s.sizedPolicyTags(data, knownSize(data))
+}
+
+// sizedPolicyTags is policyTags for uploads whose body is not at hand, such as
+// presigned ones, so their declared size is matched instead.
+func (s *s3Service) sizedPolicyTags(data UploadFileRequest, size int64) map[string]string {
+	if s.tagPolicy == nil || len(s.tagPolicy.Rules) == 0 {
+		return data.Tags
+	}
+
+	tags := maps.Clone(data.Tags)
+	for _, rule := range s.tagPolicy.Rules {
+		if !rule.matches(data, size) {
+			continue
+		}
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		maps.Copy(tags, rule.Tags)
+	}
+
+	return tags
+}
+
+func (a TagAction) matches(tags map[string]string, lastModified time.Time) bool {
+	value, ok := tags[a.TagKey]
+	if !ok || (a.TagValue != "" && value != a.TagValue) {
+		return false
+	}
+
+	return time.Since(lastModified) >= a.After
+}
+
+// EnforceTagPolicy runs the configured tag actions once over every object under
+// prefix. The first matching action wins for each object.
+func (s *s3Service) EnforceTagPolicy(ctx context.Context, bucketName, prefix string) (PolicyResult, error) {
+	if err := s.acquire(); err != nil {
+		return PolicyResult{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if s.tagPolicy == nil || len(s.tagPolicy.Actions) == 0 {
+		return PolicyResult{}, ErrPolicyNotConfigured
+	}
+
+	if bucketName == "" {
+		return PolicyResult{}, errors.New("bucket name is required")
+	}
+
+	for _, action := range s.tagPolicy.Actions {
+		if err := s.authorizeKeys(ctx, action.permission(), bucketName, prefix); err != nil {
+			return PolicyResult{}, err
+		}
+	}
+
+	minAge := s.tagPolicy.Actions[0].After
+	for _, action := range s.tagPolicy.Actions {
+		minAge = min(minAge, action.After)
+	}
+	cutoff := time.Now().Add(-minAge)
+
+	result := PolicyResult{}
+	paginator := s3.NewListObjectsV2Paginator(s.s3Cli, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("failed to list objects of bucket %s: %v", bucketName, err)
+			return result, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			key := aws.ToString(object.Key)
+			lastModified, err := s.policyModified(ctx, bucketName, object)
+			if err != nil {
+				log.Printf("failed to get age of file %s: %v", key, err)
+				continue
+			}
+			if lastModified.After(cutoff) {
+				continue
+			}
+
+			tags, err := s.getObjectTags(ctx, bucketName, key)
+			if err != nil {
+				log.Printf("failed to get tags of file %s: %v", key, err)
+				continue
+			}
+
+			for _, action := range s.tagPolicy.Actions {
+				if !action.matches(tags, lastModified) {
+					continue
+				}
+
+				// A grant on the prefix "a/b" does not cover listed keys like "a/bc".
+				if s.authorizeKeys(ctx, action.permission(), bucketName, key) != nil {
+					break
+				}
+
+				if skip := action.Action != PolicyDelete && object.StorageClass == types.ObjectStorageClass(action.storageClass()); !skip {
+					err := s.applyPolicyAction(ctx, bucketName, key, aws.ToInt64(object.Size), lastModified, action)
+					if err != nil {
+						log.Printf("failed to %s file %s: %v", action.Action, key, err)
+					}
+					result.Results = append(result.Results, PolicyActionResult{Key: key, Action: action.Action, Err: err})
+				}
+				break
+			}
+		}
+	}
+
+	var errs []error
+	for _, actionResult := range result.Results {
+		if actionResult.Err != nil {
+			errs = append(errs, actionResult.Err)
+		}
+	}
+	if len(result.Results) > 0 {
+		log.Printf("applied tag policy to %d files on bucket %s", len(result.Results)-len(errs), bucketName)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// permission is what an action needs: deleting needs delete permission, and
+// rewriting an object into another storage class needs upload permission.
+func (a TagAction) permission() authz.Permission {
+	if a.Action == PolicyDelete {
+		return authz.PermissionDelete
+	}
+
+	return authz.PermissionUpload
+}
+
+func (a TagAction) storageClass() types.StorageClass {
+	if a.StorageClass == "" && a.Action == PolicyArchive {
+		return types.StorageClassGlacier
+	}
+
+	return a.StorageClass
+}
+
+// policyModified returns when object was last written other than by a policy
+// action. Only objects outside the standard storage class can have been
+// rewritten by one, so only those are looked up.
+func (s *s3Service) policyModified(ctx context.Context, bucketName string, object types.Object) (time.Time, error) {
+	lastModified := aws.ToTime(object.LastModified)
+	if object.StorageClass == "" || object.StorageClass == types.ObjectStorageClassStandard {
+		return lastModified, nil
+	}
+
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    object.Key,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if modified, err := time.Parse(time.RFC3339, head.Metadata[PolicyModifiedMetadata]); err == nil {
+		return modified, nil
+	}
+	return lastModified, nil
+}
+
+func (s *s3Service) applyPolicyAction(ctx context.Context, bucketName, key string, size int64, lastModified time.Time, action TagAction) error {
+	switch action.Action {
+	case PolicyDelete:
+		_, err := s.deleteFile(ctx, DeleteFileRequest{BucketName: bucketName, Filename: []string{key}, Force: true})
+		return err
+	case PolicyArchive, PolicyTransition:
+		if action.storageClass() == "" {
+			return errors.New("storage class is required for transition")
+		}
+
+		head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return err
+		}
+
+		// Objects are rewritten in place; only the storage class changes and
+		// the age the actions go by is kept in the metadata.
+		head.Metadata = maps.Clone(head.Metadata)
+		if head.Metadata == nil {
+			head.Metadata = map[string]string{}
+		}
+		head.Metadata[PolicyModifiedMetadata] = lastModified.UTC().Format(time.RFC3339)
+
+		if size > maxCopyObjectSize {
+			return s.multipartCopyHead(ctx, MigrateRequest{SourceBucket: bucketName, DestinationBucket: bucketName}, key, key, size, action.storageClass(), head)
+		}
+
+		_, err = s.s3Cli.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:             aws.String(bucketName),
+			Key:                aws.String(key),
+			CopySource:         aws.String(copySource(bucketName, key)),
+			MetadataDirective:  types.MetadataDirectiveReplace,
+			ContentType:        head.ContentType,
+			ContentEncoding:    head.ContentEncoding,
+			ContentDisposition: head.ContentDisposition,
+			ContentLanguage:    head.ContentLanguage,
+			CacheControl:       head.CacheControl,
+			Metadata:           head.Metadata,
+			TaggingDirective:   types.TaggingDirectiveCopy,
+			StorageClass:       action.storageClass(),
+		})
+		return err
+	default:
+		return fmt.Errorf("unknown policy action %q", action.Action)
+	}
+}
+
+// StartPolicyEnforcer runs EnforceTagPolicy every interval until ctx is done or
+// the service is shut down. It blocks, so callers usually run it in its own
+// goroutine.
+func (s *s3Service) StartPolicyEnforcer(ctx context.Context, bucketName, prefix string, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("interval must be greater than zero")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.ctx.Done():
+			return ErrServiceClosed
+		case <-ticker.C:
+			if _, err := s.EnforceTagPolicy(ctx, bucketName, prefix); err != nil {
+				if errors.Is(err, ErrServiceClosed) || errors.Is(err, ErrPolicyNotConfigured) {
+					return err
+				}
+				log.Printf("tag policy run on bucket %s failed: %v", bucketName, err)
+			}
+		}
+	}
+}
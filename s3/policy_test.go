@@ -0,0 +1,108 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestEnforceTagPolicyDelete(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantKeys []string
+		wantErr  error
+	}{
+		{name: "deleted", wantKeys: []string{}},
+		{name: "moved to trash", opts: []Option{WithTrash("trash/")}, wantKeys: []string{"trash/old.txt"}},
+		{name: "protected", opts: []Option{WithDeleteGuard(DeleteGuard{ProtectedPrefixes: []string{"old"}})}, wantKeys: []string{"old.txt"}, wantErr: ErrProtectedKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			o := fake.put("bucket", "old.txt", "text/plain", []byte("old"), nil)
+			o.tags.Set("retention", "short")
+			o.modified = time.Now().Add(-48 * time.Hour)
+
+			catalog := newFakeCatalog()
+			catalog.Record(context.Background(), CatalogEntry{BucketName: "bucket", Key: "old.txt"})
+			svc := fake.service(append(tt.opts, WithCatalog(catalog), WithTagPolicy(TagPolicy{
+				Actions: []TagAction{{TagKey: "retention", After: 24 * time.Hour, Action: PolicyDelete}},
+			}))...)
+
+			_, err := svc.EnforceTagPolicy(context.Background(), "bucket", "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("EnforceTagPolicy error = %v, want %v", err, tt.wantErr)
+			}
+
+			if got := fake.keys("bucket"); !slices.Equal(got, tt.wantKeys) {
+				t.Errorf("bucket holds %v, want %v", got, tt.wantKeys)
+			}
+			if _, cataloged := catalog.entry("bucket", "old.txt"); cataloged != (tt.wantErr != nil) {
+				t.Errorf("old.txt cataloged = %v, want %v", cataloged, tt.wantErr != nil)
+			}
+		})
+	}
+}
+
+func TestEnforceTagPolicyArchiveKeepsAge(t *testing.T) {
+	tests := []struct {
+		name        string
+		age         time.Duration
+		wantDeleted bool
+	}{
+		{name: "old enough to delete", age: 48 * time.Hour, wantDeleted: true},
+		{name: "too young to delete", age: 30 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			o := fake.put("bucket", "a.txt", "text/plain", []byte("a"), map[string]string{"owner": "u1"})
+			o.tags.Set("retention", "short")
+			o.modified = time.Now().Add(-tt.age).UTC().Truncate(time.Second)
+
+			archive := fake.service(WithTagPolicy(TagPolicy{
+				Actions: []TagAction{{TagKey: "retention", After: 24 * time.Hour, Action: PolicyArchive}},
+			}))
+			if _, err := archive.EnforceTagPolicy(context.Background(), "bucket", ""); err != nil {
+				t.Fatal(err)
+			}
+
+			archived, ok := fake.object("bucket", "a.txt")
+			if !ok || archived.storageClass != "GLACIER" {
+				t.Fatalf("a.txt was not archived")
+			}
+			if archived.metadata["owner"] != "u1" || archived.contentType != "text/plain" {
+				t.Errorf("archive lost the object's headers: %v, %s", archived.metadata, archived.contentType)
+			}
+
+			// Archiving again is skipped, and age-based actions still count from
+			// the original write.
+			deleteAfter := fake.service(WithTagPolicy(TagPolicy{
+				Actions: []TagAction{
+					{TagKey: "retention", After: 24 * time.Hour, Action: PolicyArchive},
+					{TagKey: "retention", After: 36 * time.Hour, Action: PolicyDelete},
+				},
+			}))
+			result, err := deleteAfter.EnforceTagPolicy(context.Background(), "bucket", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.Results) != 0 {
+				t.Fatalf("archived object was acted on again: %+v", result.Results)
+			}
+
+			deleteOnly := fake.service(WithTagPolicy(TagPolicy{
+				Actions: []TagAction{{TagKey: "retention", After: 36 * time.Hour, Action: PolicyDelete}},
+			}))
+			if _, err := deleteOnly.EnforceTagPolicy(context.Background(), "bucket", ""); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := fake.object("bucket", "a.txt"); ok == tt.wantDeleted {
+				t.Errorf("a.txt deleted = %v, want %v", !ok, tt.wantDeleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+const defaultPostPolicyExpiry = 15 * time.Minute
+
+// CreatePostPolicy signs a browser POST policy so HTML forms can upload straight to
+// S3 while the key prefix, content type and size stay under server control.
+func (s *s3Service) CreatePostPolicy(ctx context.Context, data PostPolicyRequest) (PostPolicy, error) {
+	if err := s.acquire(); err != nil {
+		return PostPolicy{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validatePostPolicy(data); err != nil {
+		return PostPolicy{}, err
+	}
+
+	expires := data.Expires
+	if expires == 0 {
+		expires = defaultPostPolicyExpiry
+	}
+
+	key := data.Filename
+	conditions := []any{}
+	if key == "" {
+		key = data.KeyPrefix + "${filename}"
+		conditions = append(conditions, []any{"starts-with", "$key", data.KeyPrefix})
+	}
+
+	// Keys under a KeyPrefix are authorized as the key template, so a prefix
+	// that does not end on a "/" is only allowed by a grant on an enclosing one.
+	size := data.MaxSize
+	if size <= 0 {
+		size = math.MaxInt64
+	}
+	action := authz.Action{Permission: authz.PermissionUpload, BucketName: data.BucketName, Filename: key, Size: size}
+	if err := s.authorize(ctx, nil, action); err != nil {
+		return PostPolicy{}, err
+	}
+
+	fields := map[string]string{}
+	if data.ContentType != "" {
+		if strings.HasSuffix(data.ContentType, "/") {
+			conditions = append(conditions, []any{"starts-with", "$Content-Type", data.ContentType})
+		} else {
+			conditions = append(conditions, map[string]string{"Content-Type": data.ContentType})
+			fields["Content-Type"] = data.ContentType
+		}
+	}
+
+	if data.MaxSize > 0 {
+		conditions = append(conditions, []any{"content-length-range", data.MinSize, data.MaxSize})
+	}
+
+	request, err := s3.NewPresignClient(s.s3Cli).PresignPostObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(data.BucketName),
+		Key:    aws.String(key),
+	}, func(o *s3.PresignPostOptions) {
+		o.Expires = expires
+		o.Conditions = conditions
+	})
+	if err != nil {
+		log.Printf("failed to create post policy for bucket %s: %v", data.BucketName, err)
+		return PostPolicy{}, fmt.Errorf("failed to create post policy: %w", err)
+	}
+
+	for name, value := range request.Values {
+		fields[name] = value
+	}
+
+	return PostPolicy{
+		URL:     request.URL,
+		Fields:  fields,
+		Expires: time.Now().Add(expires),
+	}, nil
+}
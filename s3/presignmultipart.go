@@ -0,0 +1,238 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+const (
+	defaultPresignedPartSize = 10 * 1024 * 1024
+	defaultPresignedExpiry   = time.Hour
+)
+
+// CreatePresignedMultipart starts a multipart upload and presigns a PUT URL for
+// every part, so clients can upload huge files straight to S3. Clients send
+// part N to Parts[N-1].URL with a Content-Length of Parts[N-1].Size, keep the
+// ETag response header of each part and report them to
+// CompletePresignedMultipart. As with UploadFile, existing files are not
+// replaced and the tag policy applies.
+func (s *s3Service) CreatePresignedMultipart(ctx context.Context, data PresignedMultipartRequest) (PresignedMultipartUpload, error) {
+	if err := s.acquire(); err != nil {
+		return PresignedMultipartUpload{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validatePresignedMultipart(ctx, data); err != nil {
+		return PresignedMultipartUpload{}, err
+	}
+
+	action := authz.Action{Permission: authz.PermissionUpload, BucketName: data.BucketName, Filename: data.Filename, Size: data.Size}
+	if err := s.authorize(ctx, nil, action); err != nil {
+		return PresignedMultipartUpload{}, err
+	}
+
+	partSize := data.PartSize
+	if partSize == 0 {
+		partSize = defaultPresignedPartSize
+	}
+	partSize = max(partSize, data.Size/int64(manager.MaxUploadParts)+1)
+
+	expires := data.Expires
+	if expires == 0 {
+		expires = defaultPresignedExpiry
+	}
+
+	upload := UploadFileRequest{
+		BucketName:  data.BucketName,
+		Filename:    data.Filename,
+		ContentType: data.ContentType,
+		Tags:        data.Tags,
+		OwnerID:     data.OwnerID,
+		UploadedBy:  data.UploadedBy,
+	}
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(data.BucketName),
+		Key:    aws.String(data.Filename),
+	}
+	if data.ContentType != "" {
+		input.ContentType = aws.String(data.ContentType)
+	}
+	if tags := s.sizedPolicyTags(upload, data.Size); len(tags) > 0 {
+		input.Tagging = aws.String(encodeTags(tags))
+	}
+	if metadata := ownerMetadata(upload); len(metadata) > 0 {
+		input.Metadata = metadata
+	}
+
+	created, err := s.s3Cli.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		log.Printf("failed to create multipart upload of %s: %v", data.Filename, err)
+		return PresignedMultipartUpload{}, fmt.Errorf("failed to create multipart upload: %w", err)
+	}
+
+	result := PresignedMultipartUpload{
+		UploadID: aws.ToString(created.UploadId),
+		Filename: data.Filename,
+		PartSize: partSize,
+		Expires:  time.Now().Add(expires),
+	}
+
+	presigner := s3.NewPresignClient(s.s3Cli)
+	for number, offset := int32(1), int64(0); offset < data.Size; number, offset = number+1, offset+partSize {
+		size := min(partSize, data.Size-offset)
+		// Signing the length keeps a client from sending more than declared.
+		request, err := presigner.PresignUploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(data.BucketName),
+			Key:           aws.String(data.Filename),
+			UploadId:      created.UploadId,
+			PartNumber:    aws.Int32(number),
+			ContentLength: aws.Int64(size),
+		}, s3.WithPresignExpires(expires))
+		if err != nil {
+			s.abortMultipart(data.BucketName, data.Filename, created.UploadId)
+			return PresignedMultipartUpload{}, fmt.Errorf("failed to presign part %d: %w", number, err)
+		}
+
+		result.Parts = append(result.Parts, PresignedPart{
+			Number: number,
+			URL:    request.URL,
+			Offset: offset,
+			Size:   size,
+		})
+	}
+
+	return result, nil
+}
+
+// CompletePresignedMultipart assembles the parts reported by the client. The
+// assembled object is deleted, and the returned error wraps ErrUploadRejected,
+// when its size or owner differ from the request; otherwise it is moderated
+// and cataloged like an upload.
+func (s *s3Service) CompletePresignedMultipart(ctx context.Context, data CompletePresignedMultipartRequest) (UploadFileResult, error) {
+	if err := s.acquire(); err != nil {
+		return UploadFileResult{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateCompletePresignedMultipart(data); err != nil {
+		return UploadFileResult{}, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionUpload, data.BucketName, data.Filename); err != nil {
+		return UploadFileResult{}, err
+	}
+
+	parts := make([]types.CompletedPart, 0, len(data.Parts))
+	for _, part := range data.Parts {
+		parts = append(parts, types.CompletedPart{ETag: aws.String(part.ETag), PartNumber: aws.Int32(part.Number)})
+	}
+	slices.SortFunc(parts, func(a, b types.CompletedPart) int {
+		return int(aws.ToInt32(a.PartNumber) - aws.ToInt32(b.PartNumber))
+	})
+
+	output, err := s.s3Cli.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(data.BucketName),
+		Key:             aws.String(data.Filename),
+		UploadId:        aws.String(data.UploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		log.Printf("failed to complete multipart upload of %s: %v", data.Filename, err)
+		return UploadFileResult{}, fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(data.BucketName),
+		Key:    aws.String(data.Filename),
+	})
+	if err != nil {
+		log.Printf("failed to get head object %s - %s: %v", data.BucketName, data.Filename, err)
+		return UploadFileResult{}, fmt.Errorf("failed to get head object: %w", err)
+	}
+
+	var rejections []error
+	if size := aws.ToInt64(head.ContentLength); size != data.Size {
+		rejections = append(rejections, fmt.Errorf("size is %d, expected %d", size, data.Size))
+	}
+	if data.OwnerID != "" && head.Metadata[OwnerIDMetadata] != ownerDigest(data.OwnerID) {
+		rejections = append(rejections, fmt.Errorf("%w: %s", ErrNotOwner, data.Filename))
+	}
+	if len(rejections) > 0 {
+		log.Printf("rejected upload of %s - %s: %v", data.BucketName, data.Filename, errors.Join(rejections...))
+		if err := s.deleteChecked(ctx, data.BucketName, data.Filename, head); err != nil {
+			log.Printf("failed to remove rejected upload %s: %v", data.Filename, err)
+		}
+		return UploadFileResult{}, fmt.Errorf("%w: %w", ErrUploadRejected, errors.Join(rejections...))
+	}
+
+	upload := UploadFileRequest{
+		BucketName:  data.BucketName,
+		Filename:    data.Filename,
+		ContentType: aws.ToString(head.ContentType),
+		OwnerID:     data.OwnerID,
+	}
+	if s.moderator != nil || s.catalog != nil {
+		if upload.Tags, err = s.getObjectTags(ctx, data.BucketName, data.Filename); err != nil {
+			log.Printf("failed to get tags of file %s: %v", data.Filename, err)
+		}
+	}
+
+	if s.moderator != nil {
+		if err := s.moderate(ctx, upload); err != nil {
+			return UploadFileResult{}, err
+		}
+	}
+
+	if s.catalog != nil {
+		s.catalogObject(ctx, data.BucketName, data.Filename, CatalogEntry{Owner: data.OwnerID, Tags: upload.Tags})
+	}
+
+	location, err := s.objectURL(ctx, data.BucketName, data.Filename, aws.ToString(output.Location))
+	if err != nil {
+		return UploadFileResult{}, fmt.Errorf("failed to build file url: %w", err)
+	}
+
+	return UploadFileResult{Location: location, Filename: data.Filename}, nil
+}
+
+// AbortPresignedMultipart discards an upload the client gave up on, freeing
+// the storage held by its uploaded parts.
+func (s *s3Service) AbortPresignedMultipart(ctx context.Context, bucketName, key, uploadID string) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.authorizeKeys(ctx, authz.PermissionUpload, bucketName, key); err != nil {
+		return err
+	}
+
+	_, err := s.s3Cli.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		log.Printf("failed to abort multipart upload %s of %s: %v", uploadID, key, err)
+		return fmt.Errorf("failed to abort multipart upload: %w", err)
+	}
+
+	return nil
+}
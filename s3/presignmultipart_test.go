@@ -0,0 +1,102 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCreatePresignedMultipartRejectsExistingFile(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	fake.put("bucket", "big.bin", "application/octet-stream", []byte("data"), nil)
+	svc := fake.service()
+
+	_, err := svc.CreatePresignedMultipart(context.Background(), PresignedMultipartRequest{BucketName: "bucket", Filename: "big.bin", Size: 1})
+	if !errors.Is(err, ErrFileExists) {
+		t.Errorf("CreatePresignedMultipart = %v, want %v", err, ErrFileExists)
+	}
+}
+
+func TestCompletePresignedMultipart(t *testing.T) {
+	const body = "hello world"
+
+	tests := []struct {
+		name       string
+		size       int64
+		ownerID    string
+		wantErr    error
+		wantStored bool
+	}{
+		{name: "as declared", size: int64(len(body)), ownerID: "alice", wantStored: true},
+		{name: "size differs", size: int64(len(body)) + 1, ownerID: "alice", wantErr: ErrUploadRejected},
+		{name: "owner differs", size: int64(len(body)), ownerID: "mallory", wantErr: ErrUploadRejected},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			catalog := newFakeCatalog()
+			svc := fake.service(WithCatalog(catalog), WithTagPolicy(TagPolicy{Rules: []TagRule{{MaxSize: 100, Tags: map[string]string{"class": "small"}}}}))
+
+			upload, err := svc.CreatePresignedMultipart(context.Background(), PresignedMultipartRequest{
+				BucketName: "bucket",
+				Filename:   "big.bin",
+				Size:       int64(len(body)),
+				OwnerID:    "alice",
+			})
+			if err != nil {
+				t.Fatalf("CreatePresignedMultipart: %v", err)
+			}
+			if len(upload.Parts) != 1 {
+				t.Fatalf("got %d parts, want 1", len(upload.Parts))
+			}
+
+			part := upload.Parts[0]
+			partURL, err := url.Parse(part.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if signed := partURL.Query().Get("X-Amz-SignedHeaders"); !strings.Contains(signed, "content-length") {
+				t.Errorf("signed headers = %q, want content-length signed", signed)
+			}
+			req, err := http.NewRequest(http.MethodPut, part.URL, bytes.NewReader([]byte(body)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			_, err = svc.CompletePresignedMultipart(context.Background(), CompletePresignedMultipartRequest{
+				BucketName: "bucket",
+				Filename:   "big.bin",
+				UploadID:   upload.UploadID,
+				Parts:      []CompletedPart{{Number: part.Number, ETag: resp.Header.Get("ETag")}},
+				Size:       tt.size,
+				OwnerID:    tt.ownerID,
+			})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("CompletePresignedMultipart = %v, want %v", err, tt.wantErr)
+			}
+
+			o, stored := fake.object("bucket", "big.bin")
+			if stored != tt.wantStored {
+				t.Fatalf("stored = %v, want %v", stored, tt.wantStored)
+			}
+			if !stored {
+				return
+			}
+			if o.metadata[OwnerIDMetadata] != ownerDigest("alice") || o.tags.Get("class") != "small" {
+				t.Errorf("stored metadata %v and tags %v, want owner and policy tags", o.metadata, o.tags)
+			}
+			if entry, _ := catalog.entry("bucket", "big.bin"); entry.Owner != "alice" || entry.Size != int64(len(body)) {
+				t.Errorf("catalog entry = %+v, want owner alice and size %d", entry, len(body))
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+type QueryFormat string
+
+const (
+	QueryFormatCSV     QueryFormat = "csv"
+	QueryFormatJSON    QueryFormat = "json"
+	QueryFormatParquet QueryFormat = "parquet"
+)
+
+// QueryObject runs an S3 Select SQL expression over a CSV, JSON or Parquet object
+// and streams the matching records back. The caller must close the reader.
+func (s *s3Service) QueryObject(ctx context.Context, data QueryObjectRequest) (io.ReadCloser, error) {
+	if err := s.acquire(); err != nil {
+		return nil, err
+	}
+	ctx, cancel := s.operation(ctx)
+	// The operation lasts until the result stream ends, not until this returns.
+	done := func() {
+		cancel()
+		s.release()
+	}
+
+	if err := s.validateQueryObject(data); err != nil {
+		done()
+		return nil, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.BucketName, data.Filename); err != nil {
+		done()
+		return nil, err
+	}
+
+	input, err := querySerialization(data)
+	if err != nil {
+		done()
+		return nil, err
+	}
+
+	output, err := s.s3Cli.SelectObjectContent(ctx, &s3.SelectObjectContentInput{
+		Bucket:              aws.String(data.BucketName),
+		Key:                 aws.String(data.Filename),
+		Expression:          aws.String(data.Expression),
+		ExpressionType:      types.ExpressionTypeSql,
+		InputSerialization:  input,
+		OutputSerialization: queryOutput(data.OutputFormat),
+	})
+	if err != nil {
+		done()
+		log.Printf("failed to query file %s - %s: %v", data.BucketName, data.Filename, err)
+		return nil, fmt.Errorf("failed to query file: %w", err)
+	}
+
+	stream := output.GetStream()
+	pr, pw := io.Pipe()
+	go func() {
+		defer done()
+		defer stream.Close()
+		// A caller that stops reading must not hold up Shutdown.
+		stop := context.AfterFunc(ctx, func() {
+			pw.CloseWithError(context.Cause(ctx))
+		})
+		defer stop()
+
+		for event := range stream.Events() {
+			switch v := event.(type) {
+			case *types.SelectObjectContentEventStreamMemberRecords:
+				if _, err := pw.Write(v.Value.Payload); err != nil {
+					return
+				}
+			case *types.SelectObjectContentEventStreamMemberEnd:
+				pw.Close()
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+
+		// The stream closed without an End event, so the result may be truncated.
+		pw.CloseWithError(io.ErrUnexpectedEOF)
+	}()
+
+	return pr, nil
+}
+
+func querySerialization(data QueryObjectRequest) (*types.InputSerialization, error) {
+	input := &types.InputSerialization{CompressionType: data.Compression}
+	if input.CompressionType == "" {
+		input.CompressionType = types.CompressionTypeNone
+	}
+
+	switch data.InputFormat {
+	case QueryFormatCSV:
+		headerInfo := types.FileHeaderInfoNone
+		if data.CSVHeader {
+			headerInfo = types.FileHeaderInfoUse
+		}
+		input.CSV = &types.CSVInput{FileHeaderInfo: headerInfo}
+	case QueryFormatJSON:
+		jsonType := types.JSONTypeLines
+		if data.JSONDocument {
+			jsonType = types.JSONTypeDocument
+		}
+		input.JSON = &types.JSONInput{Type: jsonType}
+	case QueryFormatParquet:
+		input.Parquet = &types.ParquetInput{}
+	default:
+		return nil, fmt.Errorf("unsupported input format %q", data.InputFormat)
+	}
+
+	return input, nil
+}
+
+func queryOutput(format QueryFormat) *types.OutputSerialization {
+	if format == QueryFormatCSV {
+		return &types.OutputSerialization{CSV: &types.CSVOutput{}}
+	}
+
+	return &types.OutputSerialization{JSON: &types.JSONOutput{RecordDelimiter: aws.String("\n")}}
+}
@@ -0,0 +1,130 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+)
+
+// selectEvent is one message of a SelectObjectContent event stream.
+type selectEvent struct {
+	eventType string
+	payload   string
+	// errorCode makes it an error message instead of an event.
+	errorCode string
+}
+
+func writeSelectEvents(t *testing.T, w http.ResponseWriter, events []selectEvent) {
+	t.Helper()
+
+	encoder := eventstream.NewEncoder()
+	w.Header().Set("Content-Type", "application/vnd.amazon.eventstream")
+	for _, event := range events {
+		var headers eventstream.Headers
+		if event.errorCode != "" {
+			headers.Set(":message-type", eventstream.StringValue("error"))
+			headers.Set(":error-code", eventstream.StringValue(event.errorCode))
+			headers.Set(":error-message", eventstream.StringValue(event.payload))
+		} else {
+			headers.Set(":message-type", eventstream.StringValue("event"))
+			headers.Set(":event-type", eventstream.StringValue(event.eventType))
+			headers.Set(":content-type", eventstream.StringValue("application/octet-stream"))
+		}
+		message := eventstream.Message{Headers: headers}
+		if event.errorCode == "" {
+			message.Payload = []byte(event.payload)
+		}
+		if err := encoder.Encode(w, message); err != nil {
+			t.Errorf("encode %s event: %v", event.eventType, err)
+		}
+	}
+}
+
+func TestQueryObject(t *testing.T) {
+	tests := []struct {
+		name        string
+		events      []selectEvent
+		status      int
+		want        string
+		wantErr     bool
+		wantReadErr string
+	}{
+		{
+			name:   "records",
+			events: []selectEvent{{eventType: "Records", payload: "a\n"}, {eventType: "Stats"}, {eventType: "Records", payload: "b\n"}, {eventType: "End"}},
+			want:   "a\nb\n",
+		},
+		{
+			name:        "truncated",
+			events:      []selectEvent{{eventType: "Records", payload: "a\n"}},
+			want:        "a\n",
+			wantReadErr: io.ErrUnexpectedEOF.Error(),
+		},
+		{
+			name:        "error event",
+			events:      []selectEvent{{eventType: "Records", payload: "a\n"}, {errorCode: "CSVParsingError", payload: "bad row"}},
+			want:        "a\n",
+			wantReadErr: "CSVParsingError",
+		},
+		{name: "rejected", status: http.StatusBadRequest, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "a.csv", "text/csv", []byte("a\nb\n"), nil)
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if !r.URL.Query().Has("select") {
+					return false
+				}
+				if tt.status != 0 {
+					fakeError(w, tt.status, "InvalidExpression")
+					return true
+				}
+				writeSelectEvents(t, w, tt.events)
+				return true
+			}
+			svc := fake.service()
+
+			body, err := svc.QueryObject(context.Background(), QueryObjectRequest{
+				BucketName:  "bucket",
+				Filename:    "a.csv",
+				Expression:  "SELECT * FROM s3object",
+				InputFormat: QueryFormatCSV,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("QueryObject error = %v, want error: %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			defer body.Close()
+
+			got, err := io.ReadAll(body)
+			if string(got) != tt.want {
+				t.Errorf("read %q, want %q", got, tt.want)
+			}
+			switch {
+			case tt.wantReadErr == "" && err != nil:
+				t.Errorf("read error = %v", err)
+			case tt.wantReadErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantReadErr)):
+				t.Errorf("read error = %v, want one mentioning %s", err, tt.wantReadErr)
+			}
+		})
+	}
+}
+
+func TestQueryObjectUnsupportedFormat(t *testing.T) {
+	_, err := newFakeS3(t, "bucket").service().QueryObject(context.Background(), QueryObjectRequest{
+		BucketName:  "bucket",
+		Filename:    "a.xml",
+		Expression:  "SELECT * FROM s3object",
+		InputFormat: "xml",
+	})
+	if err == nil {
+		t.Error("QueryObject of an unsupported format succeeded")
+	}
+}
@@ -0,0 +1,245 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const defaultMaxRedirects = 5
+
+// deniedNetworks are never fetched by UploadFromURL unless allowed through
+// URLUploadOptions.AllowedNetworks: loopback, private, link-local (including
+// the 169.254.169.254 metadata service) and other special-purpose ranges.
+var deniedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// UploadFromURL streams a remote HTTP(S) resource into the bucket. The body is
+// piped straight into UploadFile, so nothing is buffered on disk.
+func (s *s3Service) UploadFromURL(ctx context.Context, sourceURL, bucketName, key string, opts URLUploadOptions) (UploadFileResult, error) {
+	if err := s.validateUploadFromURL(sourceURL, bucketName, key, opts); err != nil {
+		return UploadFileResult{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return UploadFileResult{}, err
+	}
+
+	resp, err := remoteClient(opts).Do(req)
+	if err != nil {
+		log.Printf("failed to fetch %s: %v", sourceURL, err)
+		return UploadFileResult{}, fmt.Errorf("failed to fetch remote file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return UploadFileResult{}, fmt.Errorf("failed to fetch remote file: unexpected status %s", resp.Status)
+	}
+
+	if opts.MaxSize > 0 && resp.ContentLength > opts.MaxSize {
+		return UploadFileResult{}, ErrSourceTooLarge
+	}
+
+	contentType := opts.ContentType
+	if contentType == "" {
+		contentType = requestContentType(resp.Header.Get("Content-Type"))
+	}
+
+	body := &remoteBody{r: resp.Body, limit: opts.MaxSize, hash: sha256.New(), sha256: opts.SHA256}
+	result, err := s.uploadContext(ctx, UploadFileRequest{
+		BucketName:  bucketName,
+		ContentType: contentType,
+		Filename:    key,
+		Body:        body,
+		Tags:        opts.Tags,
+		Accelerate:  opts.Accelerate,
+	})
+	if err != nil {
+		switch {
+		case body.exceeded:
+			return UploadFileResult{}, ErrSourceTooLarge
+		case body.mismatch != nil:
+			return UploadFileResult{}, body.mismatch
+		}
+		return UploadFileResult{}, err
+	}
+
+	return result, nil
+}
+
+// remoteClient limits redirects and guards every destination with
+// checkDestination. Connections of an *http.Transport are checked when they
+// are dialled, after DNS resolution, which also covers redirects and DNS
+// rebinding; proxies are not used since they would resolve the host instead.
+// Other transports only get their request hosts resolved and checked.
+func remoteClient(opts URLUploadOptions) *http.Client {
+	client := http.DefaultClient
+	if opts.HTTPClient != nil {
+		client = opts.HTTPClient
+	}
+
+	maxRedirects := opts.MaxRedirects
+	if maxRedirects == 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	limited := *client
+	limited.Transport = guardTransport(client.Transport, opts.AllowedNetworks)
+	limited.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+			return errors.New("redirect to unsupported scheme")
+		}
+		if addr, err := netip.ParseAddr(strings.Trim(req.URL.Hostname(), "[]")); err == nil {
+			if err := checkDestination(addr, opts.AllowedNetworks); err != nil {
+				return err
+			}
+		}
+		if client.CheckRedirect != nil {
+			return client.CheckRedirect(req, via)
+		}
+		return nil
+	}
+
+	return &limited
+}
+
+func guardTransport(transport http.RoundTripper, allowed []netip.Prefix) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	base, ok := transport.(*http.Transport)
+	if !ok {
+		return &resolvingGuard{next: transport, allowed: allowed}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			return checkDestination(addrPort.Addr(), allowed)
+		},
+	}
+
+	guarded := base.Clone()
+	guarded.Proxy = nil
+	guarded.DialContext = dialer.DialContext
+	guarded.DialTLSContext = nil
+
+	return guarded
+}
+
+// resolvingGuard checks the resolved addresses of every request host before
+// handing the request to a transport whose dialing cannot be hooked.
+type resolvingGuard struct {
+	next    http.RoundTripper
+	allowed []netip.Prefix
+}
+
+func (g *resolvingGuard) RoundTrip(req *http.Request) (*http.Response, error) {
+	addrs, err := net.DefaultResolver.LookupNetIP(req.Context(), "ip", req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	for _, addr := range addrs {
+		if err := checkDestination(addr, g.allowed); err != nil {
+			return nil, err
+		}
+	}
+
+	return g.next.RoundTrip(req)
+}
+
+func checkDestination(addr netip.Addr, allowed []netip.Prefix) error {
+	addr = addr.Unmap()
+	for _, prefix := range allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	for _, prefix := range deniedNetworks {
+		if prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s", ErrDestinationNotAllowed, addr)
+		}
+	}
+
+	return nil
+}
+
+// remoteBody hashes the remote stream and fails once it grows past the limit,
+// or at its end when the digest is not the expected one. Either aborts the
+// in-progress upload before the object is stored, indexed or cataloged.
+type remoteBody struct {
+	r        io.Reader
+	limit    int64
+	read     int64
+	exceeded bool
+	hash     hash.Hash
+	sha256   string
+	mismatch error
+}
+
+func (b *remoteBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.hash.Write(p[:n])
+	b.read += int64(n)
+
+	if b.limit > 0 && b.read > b.limit {
+		b.exceeded = true
+		return n, ErrSourceTooLarge
+	}
+
+	if err == io.EOF && b.sha256 != "" {
+		if sum := hex.EncodeToString(b.hash.Sum(nil)); !strings.EqualFold(sum, b.sha256) {
+			b.mismatch = fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, b.sha256, sum)
+			return n, b.mismatch
+		}
+	}
+
+	return n, err
+}
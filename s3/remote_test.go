@@ -0,0 +1,148 @@
+package s3
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestCheckDestination(t *testing.T) {
+	tests := []struct {
+		addr    string
+		allowed []netip.Prefix
+		wantErr bool
+	}{
+		{addr: "127.0.0.1", wantErr: true},
+		{addr: "169.254.169.254", wantErr: true},
+		{addr: "10.1.2.3", wantErr: true},
+		{addr: "172.20.0.1", wantErr: true},
+		{addr: "192.168.1.1", wantErr: true},
+		{addr: "100.64.0.1", wantErr: true},
+		{addr: "0.0.0.0", wantErr: true},
+		{addr: "::1", wantErr: true},
+		{addr: "fd00::1", wantErr: true},
+		{addr: "fe80::1", wantErr: true},
+		{addr: "::ffff:127.0.0.1", wantErr: true},
+		{addr: "8.8.8.8"},
+		{addr: "2606:4700::1111"},
+		{addr: "127.0.0.1", allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}},
+		{addr: "10.1.2.3", allowed: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s allowing %v", tt.addr, tt.allowed), func(t *testing.T) {
+			err := checkDestination(netip.MustParseAddr(tt.addr), tt.allowed)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkDestination(%s) = %v, want error %v", tt.addr, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrDestinationNotAllowed) {
+				t.Errorf("checkDestination(%s) = %v, want ErrDestinationNotAllowed", tt.addr, err)
+			}
+		})
+	}
+}
+
+func TestUploadFromURLDestinations(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "remote data")
+	}))
+	defer source.Close()
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+	tests := []struct {
+		name    string
+		path    string
+		allowed []netip.Prefix
+		wantErr error
+	}{
+		{name: "loopback denied", path: "/file", wantErr: ErrDestinationNotAllowed},
+		{name: "loopback allowed", path: "/file", allowed: loopback},
+		{name: "redirect to metadata service", path: "/redirect", allowed: loopback, wantErr: ErrDestinationNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			svc := fake.service()
+
+			_, err := svc.UploadFromURL(context.Background(), source.URL+tt.path, "bucket", "a.txt", URLUploadOptions{AllowedNetworks: tt.allowed})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UploadFromURL = %v, want %v", err, tt.wantErr)
+			}
+
+			if o, ok := fake.object("bucket", "a.txt"); ok != (tt.wantErr == nil) {
+				t.Errorf("object stored = %v", ok)
+			} else if ok && string(o.body) != "remote data" {
+				t.Errorf("stored %q", o.body)
+			}
+		})
+	}
+}
+
+func TestUploadFromURLChecksum(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "remote data")
+	}))
+	defer source.Close()
+	sum := sha256.Sum256([]byte("remote data"))
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+	tests := []struct {
+		name        string
+		sha256      string
+		primaryDown bool
+		wantErr     error
+	}{
+		{name: "match", sha256: hex.EncodeToString(sum[:])},
+		{name: "mismatch", sha256: strings.Repeat("0", 64), wantErr: ErrChecksumMismatch},
+		{name: "mismatch after failover", sha256: strings.Repeat("0", 64), primaryDown: true, wantErr: ErrChecksumMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket", "backup")
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if !tt.primaryDown || !strings.HasPrefix(r.URL.Path, "/bucket/") {
+					return false
+				}
+				fakeError(w, http.StatusServiceUnavailable, "ServiceUnavailable")
+				return true
+			}
+			catalog, indexer := newFakeCatalog(), newFakeIndexer()
+			svc := fake.service(WithCatalog(catalog), WithIndexer(indexer), WithFailover(FailoverPolicy{BucketName: "backup"}))
+
+			_, err := svc.UploadFromURL(context.Background(), source.URL, "bucket", "a.txt", URLUploadOptions{AllowedNetworks: loopback, SHA256: tt.sha256})
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("UploadFromURL = %v, want %v", err, tt.wantErr)
+			}
+
+			o, stored := fake.object("bucket", "a.txt")
+			_, failedOver := fake.object("backup", "failover/bucket/a.txt")
+			_, indexed := indexer.doc("bucket", "a.txt")
+			_, cataloged := catalog.entry("bucket", "a.txt")
+			if tt.wantErr == nil {
+				if !stored || string(o.body) != "remote data" || !indexed || !cataloged {
+					t.Errorf("stored %v, indexed %v, cataloged %v, want the remote data recorded", stored, indexed, cataloged)
+				}
+				return
+			}
+			if stored || failedOver {
+				t.Errorf("stored %v, failover copy stored %v, want nothing written", stored, failedOver)
+			}
+			if indexed || cataloged {
+				t.Errorf("indexed %v, cataloged %v, want a rejected upload left out", indexed, cataloged)
+			}
+			if _, ok := catalog.entry("backup", "failover/bucket/a.txt"); ok {
+				t.Error("rejected failover copy is cataloged")
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+type OverwritePolicy string
+
+const (
+	// OverwriteFail refuses to rename onto an existing key. It is the default.
+	OverwriteFail    OverwritePolicy = "fail"
+	OverwriteReplace OverwritePolicy = "replace"
+	// OverwriteSkip leaves both objects untouched when the destination exists.
+	OverwriteSkip OverwritePolicy = "skip"
+	// OverwriteRenameWithSuffix renames onto the next free "name (N).ext" key.
+	OverwriteRenameWithSuffix OverwritePolicy = "rename"
+)
+
+// RenameFile copies oldKey to newKey server-side, keeping content type,
+// metadata, tags and storage class, then deletes oldKey, and returns the key the
+// file ended up under. It is not atomic: if the delete fails both keys exist and
+// the error says so.
+func (s *s3Service) RenameFile(ctx context.Context, bucketName, oldKey, newKey string, opts RenameOptions) (string, error) {
+	if err := s.acquire(); err != nil {
+		return "", err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateRenameFile(bucketName, oldKey, newKey, opts); err != nil {
+		return "", err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDelete, bucketName, oldKey); err != nil {
+		return "", err
+	}
+
+	if s.deleteGuard.isProtected(oldKey) {
+		return "", fmt.Errorf("%s: %w", oldKey, ErrProtectedKey)
+	}
+
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(oldKey),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return "", ErrFileNotFound
+		}
+		return "", err
+	}
+
+	if opts.RequesterID != "" && !opts.IgnoreOwnership {
+		if err := checkOwner(ctx, oldKey, head.Metadata, opts.RequesterID); err != nil {
+			return "", err
+		}
+	}
+
+	switch opts.Overwrite {
+	case OverwriteReplace:
+		if err := s.authorizeKeys(ctx, authz.PermissionDelete, bucketName, newKey); err != nil {
+			return "", err
+		}
+
+		if err := s.checkReplace(ctx, bucketName, newKey, opts); err != nil {
+			return "", err
+		}
+	case OverwriteRenameWithSuffix:
+		if newKey, err = s.nextAvailableKey(ctx, bucketName, newKey); err != nil {
+			return "", err
+		}
+	default:
+		exists, err := s.isFileExist(ctx, bucketName, newKey)
+		if err != nil {
+			return "", err
+		}
+
+		if exists {
+			if opts.Overwrite == OverwriteSkip {
+				return oldKey, nil
+			}
+			return "", fmt.Errorf("%w: %s", ErrFileExists, newKey)
+		}
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionUpload, bucketName, newKey); err != nil {
+		return "", err
+	}
+
+	size := aws.ToInt64(head.ContentLength)
+	storageClass := types.StorageClass(head.StorageClass)
+	if size > maxCopyObjectSize {
+		err = s.multipartCopy(ctx, MigrateRequest{SourceBucket: bucketName, DestinationBucket: bucketName}, oldKey, newKey, size, storageClass)
+	} else {
+		_, err = s.s3Cli.CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:            aws.String(bucketName),
+			Key:               aws.String(newKey),
+			CopySource:        aws.String(copySource(bucketName, oldKey)),
+			MetadataDirective: types.MetadataDirectiveCopy,
+			TaggingDirective:  types.TaggingDirectiveCopy,
+			StorageClass:      storageClass,
+		})
+	}
+	if err != nil {
+		log.Printf("failed to copy file %s to %s: %v", oldKey, newKey, err)
+		return "", fmt.Errorf("failed to rename file: %w", err)
+	}
+
+	if err := s.deleteObject(ctx, bucketName, oldKey); err != nil {
+		log.Printf("failed to delete %s after copying it to %s: %v", oldKey, newKey, err)
+		return newKey, fmt.Errorf("file copied to %s but %s could not be deleted: %w", newKey, oldKey, err)
+	}
+
+	if s.indexer != nil {
+		s.indexObject(ctx, bucketName, newKey, head)
+		s.unindex(ctx, bucketName, []string{oldKey})
+	}
+
+	if s.catalog != nil {
+		if err := s.catalog.Rename(ctx, bucketName, oldKey, newKey); err != nil {
+			log.Printf("failed to rename file %s to %s in catalog: %v", oldKey, newKey, err)
+		}
+	}
+
+	return newKey, nil
+}
+
+// checkReplace applies the delete guard and ownership to the object that
+// OverwriteReplace would overwrite, as replacing it deletes its content.
+func (s *s3Service) checkReplace(ctx context.Context, bucketName, key string, opts RenameOptions) error {
+	checkOwnership := opts.RequesterID != "" && !opts.IgnoreOwnership
+	if !s.deleteGuard.isProtected(key) && !checkOwnership {
+		return nil
+	}
+
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if s.deleteGuard.isProtected(key) {
+		return fmt.Errorf("%s: %w", key, ErrProtectedKey)
+	}
+
+	return checkOwner(ctx, key, head.Metadata, opts.RequesterID)
+}
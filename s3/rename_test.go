@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRenameFileIndex(t *testing.T) {
+	tests := []struct {
+		name      string
+		overwrite OverwritePolicy
+		existing  bool
+		wantKey   string
+		wantErr   error
+	}{
+		{name: "renamed", wantKey: "b.txt"},
+		{name: "suffixed", overwrite: OverwriteRenameWithSuffix, existing: true, wantKey: "b (1).txt"},
+		{name: "refused", existing: true, wantKey: "a.txt", wantErr: ErrFileExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			indexer := newFakeIndexer()
+			svc := fake.service(WithIndexer(indexer))
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.txt",
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader("hello")),
+				Tags:        map[string]string{"team": "docs"},
+				OwnerID:     "alice",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing {
+				fake.put("bucket", "b.txt", "text/plain", []byte("other"), nil)
+			}
+
+			key, err := svc.RenameFile(context.Background(), "bucket", "a.txt", "b.txt", RenameOptions{Overwrite: tt.overwrite})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RenameFile = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				key = "a.txt"
+			}
+			if key != tt.wantKey {
+				t.Fatalf("RenameFile = %s, want %s", key, tt.wantKey)
+			}
+
+			doc, ok := indexer.doc("bucket", tt.wantKey)
+			if !ok {
+				t.Fatalf("%s is not indexed", tt.wantKey)
+			}
+			if doc.ContentType != "text/plain" || doc.Tags["team"] != "docs" {
+				t.Errorf("indexed %+v, want the content type and tags of a.txt", doc)
+			}
+			if _, ok := doc.Metadata[OwnerIDMetadata]; ok {
+				t.Errorf("indexed internal metadata %v", doc.Metadata)
+			}
+			if _, ok := indexer.doc("bucket", "a.txt"); ok != (tt.wantKey == "a.txt") {
+				t.Errorf("a.txt indexed = %v", ok)
+			}
+		})
+	}
+}
+
+func TestRenameFileChecksOwnerAndGuard(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      RenameOptions
+		existing  string
+		protected bool
+		wantErr   error
+	}{
+		{name: "owner", opts: RenameOptions{RequesterID: "alice"}},
+		{name: "not the owner", opts: RenameOptions{RequesterID: "mallory"}, wantErr: ErrNotOwner},
+		{name: "ignoring ownership", opts: RenameOptions{RequesterID: "mallory", IgnoreOwnership: true}},
+		{
+			name:     "replacing an owned destination",
+			opts:     RenameOptions{Overwrite: OverwriteReplace, RequesterID: "alice"},
+			existing: "alice",
+		},
+		{
+			name:     "replacing someone else's destination",
+			opts:     RenameOptions{Overwrite: OverwriteReplace, RequesterID: "alice"},
+			existing: "bob",
+			wantErr:  ErrNotOwner,
+		},
+		{
+			name:      "replacing a protected destination",
+			opts:      RenameOptions{Overwrite: OverwriteReplace},
+			existing:  "bob",
+			protected: true,
+			wantErr:   ErrProtectedKey,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			svc := fake.service(WithDeleteGuard(DeleteGuard{ProtectedPrefixes: []string{"kept/"}}))
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.txt",
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader("hello")),
+				OwnerID:     "alice",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			newKey := "b.txt"
+			if tt.protected {
+				newKey = "kept/b.txt"
+			}
+			if tt.existing != "" {
+				fake.put("bucket", newKey, "text/plain", []byte("other"), map[string]string{OwnerIDMetadata: ownerDigest(tt.existing)})
+			}
+
+			_, err = svc.RenameFile(context.Background(), "bucket", "a.txt", newKey, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RenameFile = %v, want %v", err, tt.wantErr)
+			}
+
+			if _, exists := fake.object("bucket", "a.txt"); exists == (tt.wantErr == nil) {
+				t.Errorf("a.txt exists = %v after RenameFile returned %v", exists, err)
+			}
+			if tt.existing != "" && tt.wantErr != nil {
+				if o, _ := fake.object("bucket", newKey); string(o.body) != "other" {
+					t.Errorf("%s was overwritten", newKey)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package s3
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+// sizeBuckets are the upper bounds (exclusive) of the size histogram buckets.
+var sizeBuckets = []struct {
+	label string
+	limit int64
+}{
+	{"<1KiB", 1 << 10},
+	{"<1MiB", 1 << 20},
+	{"<10MiB", 10 << 20},
+	{"<100MiB", 100 << 20},
+	{"<1GiB", 1 << 30},
+	{">=1GiB", -1},
+}
+
+func (s *s3Service) GenerateUsageReport(ctx context.Context, data UsageReportRequest) (UsageReport, error) {
+	if err := s.acquire(); err != nil {
+		return UsageReport{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if data.BucketName == "" {
+		return UsageReport{}, errors.New("bucket name is required")
+	}
+
+	if data.PrefixDepth < 0 {
+		return UsageReport{}, errors.New("prefix depth must not be negative")
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.BucketName, data.Prefix); err != nil {
+		return UsageReport{}, err
+	}
+
+	report := UsageReport{
+		BucketName:  data.BucketName,
+		Prefix:      data.Prefix,
+		GeneratedAt: time.Now().UTC(),
+		Total:       newUsageStats(),
+		Prefixes:    map[string]*UsageStats{},
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s.s3Cli, &s3.ListObjectsV2Input{
+		Bucket: aws.String(data.BucketName),
+		Prefix: aws.String(data.Prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("failed to list objects of bucket %s: %v", data.BucketName, err)
+			return UsageReport{}, fmt.Errorf("failed to list objects: %w", err)
+		}
+
+		objects, err := authorizedItems(ctx, s, authz.PermissionDownload, page.Contents, func(object types.Object) (string, string) {
+			return data.BucketName, aws.ToString(object.Key)
+		})
+		if err != nil {
+			return UsageReport{}, err
+		}
+
+		for _, object := range objects {
+			size, modified := aws.ToInt64(object.Size), aws.ToTime(object.LastModified)
+			report.Total.add(size, modified)
+
+			if data.PrefixDepth == 0 {
+				continue
+			}
+
+			group := groupPrefix(strings.TrimPrefix(aws.ToString(object.Key), data.Prefix), data.PrefixDepth)
+			stats, ok := report.Prefixes[group]
+			if !ok {
+				created := newUsageStats()
+				stats = &created
+				report.Prefixes[group] = stats
+			}
+			stats.add(size, modified)
+		}
+	}
+
+	return report, nil
+}
+
+func newUsageStats() UsageStats {
+	return UsageStats{
+		SizeHistogram: map[string]int64{},
+		LastModified:  map[string]int64{},
+	}
+}
+
+func (u *UsageStats) add(size int64, modified time.Time) {
+	u.ObjectCount++
+	u.TotalBytes += size
+
+	for _, bucket := range sizeBuckets {
+		if bucket.limit < 0 || size < bucket.limit {
+			u.SizeHistogram[bucket.label]++
+			break
+		}
+	}
+
+	u.LastModified[modified.UTC().Format("2006-01")]++
+	if u.Oldest.IsZero() || modified.Before(u.Oldest) {
+		u.Oldest = modified
+	}
+	if modified.After(u.Newest) {
+		u.Newest = modified
+	}
+}
+
+// groupPrefix returns the first depth segments of key, e.g. "a/b/" for depth 2.
+// Keys with fewer segments are grouped under their directory, or "/" at the root.
+func groupPrefix(key string, depth int) string {
+	segments := strings.Split(key, "/")
+	if len(segments) <= depth {
+		segments = segments[:len(segments)-1]
+	} else {
+		segments = segments[:depth]
+	}
+
+	if len(segments) == 0 {
+		return "/"
+	}
+
+	return strings.Join(segments, "/") + "/"
+}
+
+func (r UsageReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes one row per prefix (plus a total row) with counts, bytes and the
+// size histogram columns.
+func (r UsageReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"prefix", "objects", "bytes", "oldest", "newest"}
+	for _, bucket := range sizeBuckets {
+		header = append(header, bucket.label)
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	prefixes := make([]string, 0, len(r.Prefixes))
+	for prefix := range r.Prefixes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	rows := []struct {
+		name  string
+		stats UsageStats
+	}{{"*", r.Total}}
+	for _, prefix := range prefixes {
+		rows = append(rows, struct {
+			name  string
+			stats UsageStats
+		}{prefix, *r.Prefixes[prefix]})
+	}
+
+	for _, row := range rows {
+		record := []string{
+			row.name,
+			strconv.FormatInt(row.stats.ObjectCount, 10),
+			strconv.FormatInt(row.stats.TotalBytes, 10),
+			formatReportTime(row.stats.Oldest),
+			formatReportTime(row.stats.Newest),
+		}
+		for _, bucket := range sizeBuckets {
+			record = append(record, strconv.FormatInt(row.stats.SizeHistogram[bucket.label], 10))
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatReportTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,92 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestGenerateUsageReport(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	march := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	may := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	fake.put("bucket", "docs/a/1.txt", "text/plain", make([]byte, 10), nil).modified = march
+	fake.put("bucket", "docs/a/2.txt", "text/plain", make([]byte, 2048), nil).modified = may
+	fake.put("bucket", "docs/b.txt", "text/plain", make([]byte, 20), nil).modified = may
+	fake.put("bucket", "other.txt", "text/plain", make([]byte, 5), nil).modified = may
+	svc := fake.service()
+
+	report, err := svc.GenerateUsageReport(context.Background(), UsageReportRequest{BucketName: "bucket", Prefix: "docs/", PrefixDepth: 1})
+	if err != nil {
+		t.Fatalf("GenerateUsageReport: %v", err)
+	}
+
+	total := report.Total
+	if total.ObjectCount != 3 || total.TotalBytes != 2078 {
+		t.Errorf("total = %d objects and %d bytes, want 3 and 2078", total.ObjectCount, total.TotalBytes)
+	}
+	if total.SizeHistogram["<1KiB"] != 2 || total.SizeHistogram["<1MiB"] != 1 {
+		t.Errorf("size histogram = %v, want two small objects and one below 1MiB", total.SizeHistogram)
+	}
+	if total.LastModified["2026-03"] != 1 || total.LastModified["2026-05"] != 2 || !total.Oldest.Equal(march) || !total.Newest.Equal(may) {
+		t.Errorf("modification stats = %v from %v to %v, want one in March and two in May", total.LastModified, total.Oldest, total.Newest)
+	}
+	if a, root := report.Prefixes["a/"], report.Prefixes["/"]; len(report.Prefixes) != 2 || a == nil || a.ObjectCount != 2 || root == nil || root.ObjectCount != 1 {
+		t.Errorf("prefixes = %v, want a/ with 2 objects and / with 1", report.Prefixes)
+	}
+
+	var out bytes.Buffer
+	if err := report.WriteCSV(&out); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("read CSV: %v", err)
+	}
+	names := []string{}
+	for _, record := range records[1:] {
+		names = append(names, record[0])
+	}
+	if !slices.Equal(names, []string{"*", "/", "a/"}) || records[1][1] != "3" || records[1][2] != "2078" {
+		t.Errorf("CSV rows = %v, want the total and then each prefix", records)
+	}
+}
+
+func TestGenerateUsageReportErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		request UsageReportRequest
+	}{
+		{name: "missing bucket name", request: UsageReportRequest{}},
+		{name: "negative depth", request: UsageReportRequest{BucketName: "bucket", PrefixDepth: -1}},
+		{name: "unknown bucket", request: UsageReportRequest{BucketName: "missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newFakeS3(t, "bucket").service().GenerateUsageReport(context.Background(), tt.request); err == nil {
+				t.Error("GenerateUsageReport succeeded")
+			}
+		})
+	}
+}
+
+func TestGroupPrefix(t *testing.T) {
+	tests := []struct {
+		key   string
+		depth int
+		want  string
+	}{
+		{key: "a/b/c.txt", depth: 1, want: "a/"},
+		{key: "a/b/c.txt", depth: 2, want: "a/b/"},
+		{key: "a/b/c.txt", depth: 3, want: "a/b/"},
+		{key: "c.txt", depth: 1, want: "/"},
+	}
+	for _, tt := range tests {
+		if got := groupPrefix(tt.key, tt.depth); got != tt.want {
+			t.Errorf("groupPrefix(%q, %d) = %q, want %q", tt.key, tt.depth, got, tt.want)
+		}
+	}
+}
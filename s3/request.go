@@ -0,0 +1,173 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+const (
+	defaultContentType = "application/octet-stream"
+	maxFormFieldSize   = 1 << 20
+)
+
+// ParseAndUploadMultipart streams every file part of a multipart/form-data
+// request straight into UploadFile, without buffering the form in memory or on
+// disk. Plain form fields are returned alongside the uploads.
+func (s *s3Service) ParseAndUploadMultipart(r *http.Request, opts RequestUploadOptions) (MultipartUploadResult, error) {
+	if err := s.validateRequestUpload(r, opts); err != nil {
+		return MultipartUploadResult{}, err
+	}
+
+	body := limitBody(r, opts.MaxSize)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return MultipartUploadResult{}, fmt.Errorf("failed to read multipart form: %w", err)
+	}
+
+	result := MultipartUploadResult{Fields: map[string]string{}}
+	var fieldBytes int64
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return result, body.err(fmt.Errorf("failed to read multipart form: %w", err))
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(io.LimitReader(part, maxFormFieldSize-fieldBytes+1))
+			if err != nil {
+				return result, body.err(fmt.Errorf("failed to read form field %s: %w", part.FormName(), err))
+			}
+
+			fieldBytes += int64(len(value))
+			if fieldBytes > maxFormFieldSize {
+				return result, ErrRequestTooLarge
+			}
+
+			result.Fields[part.FormName()] = string(value)
+			continue
+		}
+
+		if len(opts.FileFields) > 0 && !slices.Contains(opts.FileFields, part.FormName()) {
+			continue
+		}
+
+		if opts.MaxFiles > 0 && len(result.Files) == opts.MaxFiles {
+			return result, fmt.Errorf("at most %d files can be uploaded", opts.MaxFiles)
+		}
+
+		filename := path.Base(strings.ReplaceAll(part.FileName(), "\\", "/"))
+		if filename == "." || filename == "/" {
+			return result, fmt.Errorf("invalid filename %q", part.FileName())
+		}
+
+		uploaded, err := s.uploadContext(r.Context(), UploadFileRequest{
+			BucketName:  opts.BucketName,
+			ContentType: requestContentType(part.Header.Get("Content-Type")),
+			Filename:    opts.KeyPrefix + filename,
+			Body:        part,
+			Tags:        opts.Tags,
+			Accelerate:  opts.Accelerate,
+		})
+		if err != nil {
+			return result, body.err(err)
+		}
+
+		result.Files = append(result.Files, uploaded)
+	}
+
+	return result, nil
+}
+
+// UploadFromRequestBody stores the raw request body as opts.Filename, using the
+// request's Content-Type.
+func (s *s3Service) UploadFromRequestBody(r *http.Request, opts RequestUploadOptions) (UploadFileResult, error) {
+	if err := s.validateRequestUpload(r, opts); err != nil {
+		return UploadFileResult{}, err
+	}
+
+	if opts.Filename == "" {
+		return UploadFileResult{}, errors.New("filename is required")
+	}
+
+	if opts.MaxSize > 0 && r.ContentLength > opts.MaxSize {
+		return UploadFileResult{}, ErrRequestTooLarge
+	}
+
+	body := limitBody(r, opts.MaxSize)
+	result, err := s.uploadContext(r.Context(), UploadFileRequest{
+		BucketName:  opts.BucketName,
+		ContentType: requestContentType(r.Header.Get("Content-Type")),
+		Filename:    opts.KeyPrefix + opts.Filename,
+		Body:        r.Body,
+		Tags:        opts.Tags,
+		Accelerate:  opts.Accelerate,
+	})
+	if err != nil {
+		return UploadFileResult{}, body.err(err)
+	}
+
+	return result, nil
+}
+
+func requestContentType(header string) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return defaultContentType
+	}
+
+	return mediaType
+}
+
+// limitedBody replaces the request body so that reading past the limit fails,
+// and lets callers report the overrun as ErrRequestTooLarge.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func limitBody(r *http.Request, limit int64) *limitedBody {
+	body := &limitedBody{ReadCloser: r.Body, remaining: limit}
+	if limit > 0 {
+		r.Body = body
+	}
+
+	return body
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		b.exceeded = true
+		return 0, ErrRequestTooLarge
+	}
+
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		b.exceeded = true
+		return n, ErrRequestTooLarge
+	}
+
+	return n, err
+}
+
+func (b *limitedBody) err(err error) error {
+	if b.exceeded {
+		return ErrRequestTooLarge
+	}
+
+	return err
+}
@@ -0,0 +1,144 @@
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type formPart struct {
+	field    string
+	filename string
+	content  string
+}
+
+func multipartRequest(t *testing.T, parts ...formPart) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for _, part := range parts {
+		var (
+			w   io.Writer
+			err error
+		)
+		if part.filename == "" {
+			w, err = form.CreateFormField(part.field)
+		} else {
+			w, err = form.CreateFormFile(part.field, part.filename)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, part.content)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	return r
+}
+
+func TestParseAndUploadMultipart(t *testing.T) {
+	parts := []formPart{
+		{field: "title", content: "holiday"},
+		{field: "file", filename: `C:\photos\a.jpg`, content: "first"},
+		{field: "attachment", filename: "b.txt", content: "second"},
+	}
+	tests := []struct {
+		name       string
+		opts       RequestUploadOptions
+		wantKeys   []string
+		wantFields []string
+		wantErr    error
+	}{
+		{name: "all files", opts: RequestUploadOptions{KeyPrefix: "in/"}, wantKeys: []string{"in/a.jpg", "in/b.txt"}, wantFields: []string{"title"}},
+		{name: "file fields", opts: RequestUploadOptions{FileFields: []string{"attachment"}}, wantKeys: []string{"b.txt"}, wantFields: []string{"title"}},
+		{name: "max files", opts: RequestUploadOptions{MaxFiles: 1}, wantKeys: []string{"a.jpg"}, wantErr: errors.New("at most 1 files can be uploaded")},
+		{name: "max size", opts: RequestUploadOptions{MaxSize: 300}, wantKeys: []string{}, wantErr: ErrRequestTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			svc := fake.service()
+			tt.opts.BucketName = "bucket"
+
+			result, err := svc.ParseAndUploadMultipart(multipartRequest(t, parts...), tt.opts)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("ParseAndUploadMultipart: %v", err)
+			case tt.wantErr != nil && (err == nil || !errors.Is(err, tt.wantErr) && err.Error() != tt.wantErr.Error()):
+				t.Fatalf("ParseAndUploadMultipart error = %v, want %v", err, tt.wantErr)
+			}
+
+			if keys := fake.keys("bucket"); !slices.Equal(keys, tt.wantKeys) {
+				t.Errorf("bucket holds %v, want %v", keys, tt.wantKeys)
+			}
+			if len(result.Files) != len(tt.wantKeys) {
+				t.Errorf("reported %d files, want %d", len(result.Files), len(tt.wantKeys))
+			}
+			if tt.wantErr == nil {
+				if fields := slices.Sorted(maps.Keys(result.Fields)); !slices.Equal(fields, tt.wantFields) {
+					t.Errorf("fields = %v, want %v", fields, tt.wantFields)
+				}
+			}
+		})
+	}
+}
+
+func TestUploadFromRequestBody(t *testing.T) {
+	tests := []struct {
+		name            string
+		opts            RequestUploadOptions
+		contentType     string
+		chunked         bool
+		wantContentType string
+		wantErr         error
+	}{
+		{name: "stored", opts: RequestUploadOptions{Filename: "a.txt", MaxSize: 100}, contentType: "text/plain; charset=utf-8", wantContentType: "text/plain"},
+		{name: "default content type", opts: RequestUploadOptions{Filename: "a.bin"}, wantContentType: defaultContentType},
+		{name: "declared too large", opts: RequestUploadOptions{Filename: "a.txt", MaxSize: 4}, wantErr: ErrRequestTooLarge},
+		{name: "streamed too large", opts: RequestUploadOptions{Filename: "a.txt", MaxSize: 4}, chunked: true, wantErr: ErrRequestTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			svc := fake.service()
+			tt.opts.BucketName = "bucket"
+
+			r := httptest.NewRequest(http.MethodPut, "/upload", strings.NewReader("content"))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.chunked {
+				r.ContentLength = -1
+			}
+
+			_, err := svc.UploadFromRequestBody(r, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UploadFromRequestBody error = %v, want %v", err, tt.wantErr)
+			}
+
+			object, stored := fake.object("bucket", tt.opts.Filename)
+			if stored != (tt.wantErr == nil) {
+				t.Fatalf("stored = %v, want %v", stored, tt.wantErr == nil)
+			}
+			if stored && (string(object.body) != "content" || object.contentType != tt.wantContentType) {
+				t.Errorf("stored %q as %s, want the body as %s", object.body, object.contentType, tt.wantContentType)
+			}
+		})
+	}
+
+	if _, err := newFakeS3(t, "bucket").service().UploadFromRequestBody(httptest.NewRequest(http.MethodPut, "/", nil), RequestUploadOptions{BucketName: "bucket"}); err == nil {
+		t.Error("UploadFromRequestBody without a filename succeeded")
+	}
+}
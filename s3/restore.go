@@ -0,0 +1,171 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+const defaultRestorePollInterval = time.Minute
+
+var restoreExpiryPattern = regexp.MustCompile(`expiry-date="([^"]+)"`)
+
+// RestoreFile initiates a restore of an archived (Glacier or Deep Archive) object.
+// Requesting a restore that is already in progress is not an error.
+func (s *s3Service) RestoreFile(ctx context.Context, data RestoreFileRequest) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateRestoreFile(data); err != nil {
+		return err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.BucketName, data.Filename); err != nil {
+		return err
+	}
+
+	tier := data.Tier
+	if tier == "" {
+		tier = types.TierStandard
+	}
+
+	_, err := s.s3Cli.RestoreObject(ctx, &s3.RestoreObjectInput{
+		Bucket: aws.String(data.BucketName),
+		Key:    aws.String(data.Filename),
+		RestoreRequest: &types.RestoreRequest{
+			Days:                 aws.Int32(data.Days),
+			GlacierJobParameters: &types.GlacierJobParameters{Tier: tier},
+		},
+	})
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode() == "RestoreAlreadyInProgress" {
+			return nil
+		}
+		log.Printf("failed to restore file %s - %s: %v", data.BucketName, data.Filename, err)
+		return fmt.Errorf("failed to restore file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *s3Service) GetRestoreStatus(ctx context.Context, data RestoreStatusRequest) (RestoreStatus, error) {
+	if err := s.acquire(); err != nil {
+		return RestoreStatus{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	return s.restoreStatus(ctx, data)
+}
+
+func (s *s3Service) restoreStatus(ctx context.Context, data RestoreStatusRequest) (RestoreStatus, error) {
+	if data.BucketName == "" {
+		return RestoreStatus{}, errors.New("bucket name is required")
+	}
+
+	if data.Filename == "" {
+		return RestoreStatus{}, errors.New("filename is required")
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, data.BucketName, data.Filename); err != nil {
+		return RestoreStatus{}, err
+	}
+
+	output, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(data.BucketName),
+		Key:    aws.String(data.Filename),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return RestoreStatus{}, ErrFileNotFound
+		}
+		log.Printf("get head object %s got error: %v", data.Filename, err)
+		return RestoreStatus{}, err
+	}
+
+	return parseRestoreStatus(output.StorageClass, aws.ToString(output.Restore)), nil
+}
+
+// WaitForRestore polls until the restored copy is available or ctx is done.
+func (s *s3Service) WaitForRestore(ctx context.Context, data RestoreStatusRequest, interval time.Duration) (RestoreStatus, error) {
+	if err := s.acquire(); err != nil {
+		return RestoreStatus{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if interval <= 0 {
+		interval = defaultRestorePollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status, err := s.restoreStatus(ctx, data)
+		if err != nil {
+			return status, err
+		}
+
+		if status.Restored || !status.Archived() {
+			return status, nil
+		}
+
+		if !status.InProgress {
+			return status, ErrRestoreNotRequested
+		}
+
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseRestoreStatus reads the x-amz-restore header, e.g.
+// ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT".
+func parseRestoreStatus(storageClass types.StorageClass, restore string) RestoreStatus {
+	status := RestoreStatus{StorageClass: string(storageClass)}
+	if restore == "" {
+		return status
+	}
+
+	status.InProgress = strings.Contains(restore, `ongoing-request="true"`)
+	status.Restored = !status.InProgress
+	if match := restoreExpiryPattern.FindStringSubmatch(restore); match != nil {
+		if expiresAt, err := http.ParseTime(match[1]); err == nil {
+			status.ExpiresAt = expiresAt
+		}
+	}
+
+	return status
+}
+
+func (r RestoreStatus) Archived() bool {
+	switch types.StorageClass(r.StorageClass) {
+	case types.StorageClassGlacier, types.StorageClassDeepArchive:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,175 @@
+package s3
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+const maxKeyLength = 1024
+
+type (
+	// Rule checks one aspect of a request. It returns nil, a *Violation for
+	// invalid input, or any other error to abort validation entirely.
+	Rule[T any] func(data T) error
+
+	// Rules runs every rule and reports all violations together.
+	Rules[T any] []Rule[T]
+
+	Violation struct {
+		Field string
+		Err   error
+	}
+
+	// ValidationError lists every violated rule. errors.Is and errors.As see
+	// through it to the individual violations.
+	ValidationError struct {
+		Violations []*Violation
+	}
+)
+
+func (v *Violation) Error() string { return v.Err.Error() }
+func (v *Violation) Unwrap() error { return v.Err }
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Error())
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		errs = append(errs, v)
+	}
+
+	return errs
+}
+
+func Violationf(field, format string, args ...any) *Violation {
+	return &Violation{Field: field, Err: fmt.Errorf(format, args...)}
+}
+
+func (r Rules[T]) Validate(data T) error {
+	var violations []*Violation
+	for _, rule := range r {
+		err := rule(data)
+		if err == nil {
+			continue
+		}
+
+		var violation *Violation
+		if !errors.As(err, &violation) {
+			return err
+		}
+		violations = append(violations, violation)
+	}
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+
+	return nil
+}
+
+// nested applies rules to the part of data that get returns.
+func nested[T, U any](get func(data T) U, rules Rules[U]) Rules[T] {
+	nested := make(Rules[T], 0, len(rules))
+	for _, rule := range rules {
+		nested = append(nested, func(data T) error { return rule(get(data)) })
+	}
+
+	return nested
+}
+
+// Check reports message as a violation of field unless ok holds.
+func Check[T any](field, message string, ok func(data T) bool) Rule[T] {
+	return func(data T) error {
+		if !ok(data) {
+			return &Violation{Field: field, Err: errors.New(message)}
+		}
+		return nil
+	}
+}
+
+func Required[T any](field, name string, value func(data T) string) Rule[T] {
+	return Check(field, name+" is required", func(data T) bool { return value(data) != "" })
+}
+
+// KeyFormat rejects keys S3 would refuse or that are ambiguous as paths.
+func KeyFormat[T any](field string, key func(data T) string) Rule[T] {
+	return func(data T) error {
+		k := key(data)
+		switch {
+		case k == "":
+			return nil
+		case len(k) > maxKeyLength:
+			return Violationf(field, "key must be at most %d bytes", maxKeyLength)
+		case !utf8.ValidString(k):
+			return Violationf(field, "key must be valid UTF-8")
+		case strings.ContainsFunc(k, func(r rune) bool { return r < 0x20 || r == 0x7f }):
+			return Violationf(field, "key must not contain control characters")
+		}
+
+		return nil
+	}
+}
+
+func ValidContentType[T any](field string, contentType func(data T) string) Rule[T] {
+	return func(data T) error {
+		if _, err := mime.ExtensionsByType(contentType(data)); err != nil {
+			return &Violation{Field: field, Err: err}
+		}
+		return nil
+	}
+}
+
+// AllowedContentTypes accepts exact media types or patterns such as "image/*".
+func AllowedContentTypes(allowed ...string) Rule[UploadFileRequest] {
+	return func(data UploadFileRequest) error {
+		mediaType, _, err := mime.ParseMediaType(data.ContentType)
+		if err != nil {
+			return &Violation{Field: "ContentType", Err: err}
+		}
+
+		for _, pattern := range allowed {
+			if ok, _ := path.Match(pattern, mediaType); ok {
+				return nil
+			}
+		}
+
+		return Violationf("ContentType", "content type %s is not allowed", mediaType)
+	}
+}
+
+// MaxUploadSize rejects uploads whose size is known up front to exceed limit:
+// inline base64 payloads and bodies exposing Len, such as bytes.Reader.
+func MaxUploadSize(limit int64) Rule[UploadFileRequest] {
+	return func(data UploadFileRequest) error {
+		if size := knownSize(data); size > limit {
+			return Violationf("Body", "file size %d exceeds limit of %d bytes", size, limit)
+		}
+		return nil
+	}
+}
+
+// knownSize returns the upload size when it is known before streaming, or -1.
+func knownSize(data UploadFileRequest) int64 {
+	switch {
+	case data.Body != nil:
+		if sized, ok := data.Body.(interface{ Len() int }); ok {
+			return int64(sized.Len())
+		}
+		return -1
+	case data.Base64Body == nil:
+		return int64(len(strings.TrimRight(data.Base64Encoding, "="))) * 3 / 4
+	default:
+		return -1
+	}
+}
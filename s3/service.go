@@ -5,64 +5,215 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
-	uuid "github.com/satori/go.uuid"
-
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsHttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
 	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+	"github.com/KurniawanHendiW/file-uploader/breaker"
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+	"github.com/KurniawanHendiW/file-uploader/spill"
 )
 
 type S3Service interface {
 	CreateBucket(bucketName string) error
-	UploadFile(data UploadFileRequest) (string, error)
-	DeleteFile(data DeleteFileRequest) error
+	UploadFile(data UploadFileRequest) (UploadFileResult, error)
+	DeleteFile(data DeleteFileRequest) (BatchResult, error)
 	DownloadFile(data DownloadFileRequest) ([]byte, error)
+	UploadFileContext(ctx context.Context, data UploadFileRequest) (UploadFileResult, error)
+	DeleteFileContext(ctx context.Context, data DeleteFileRequest) (BatchResult, error)
+	DownloadFileContext(ctx context.Context, data DownloadFileRequest) ([]byte, error)
+	StreamZip(ctx context.Context, bucketName string, keys []string, w io.Writer) error
+	ParseAndUploadMultipart(r *http.Request, opts RequestUploadOptions) (MultipartUploadResult, error)
+	UploadFromRequestBody(r *http.Request, opts RequestUploadOptions) (UploadFileResult, error)
+	StatFile(ctx context.Context, bucketName, key string) (FileStat, error)
+	FileExists(ctx context.Context, bucketName, key string) (bool, error)
+	RenameFile(ctx context.Context, bucketName, oldKey, newKey string, opts RenameOptions) (string, error)
+	NextAvailableKey(ctx context.Context, bucketName, desiredKey string) (string, error)
+	RestoreFromTrash(ctx context.Context, data DeleteFileRequest) (BatchResult, error)
+	CollectOrphans(ctx context.Context, data OrphanGCRequest) (BatchResult, error)
+	EmptyTrash(ctx context.Context, bucketName string, olderThan time.Duration) (int, error)
+	ListFiles(ctx context.Context, data ListFilesRequest) *Iterator[FileInfo]
+	ListFilesByOwner(ctx context.Context, data ListFilesRequest, ownerID string) *Iterator[FileInfo]
+	ListFileVersions(ctx context.Context, data ListFilesRequest) *Iterator[FileVersion]
+	ListBuckets(ctx context.Context) *Iterator[BucketInfo]
+	UploadFromURL(ctx context.Context, sourceURL, bucketName, key string, opts URLUploadOptions) (UploadFileResult, error)
+	UploadFromPath(ctx context.Context, localPath, bucketName, key string, opts PathUploadOptions) (UploadFileResult, error)
+	RestoreFile(ctx context.Context, data RestoreFileRequest) error
+	GetRestoreStatus(ctx context.Context, data RestoreStatusRequest) (RestoreStatus, error)
+	WaitForRestore(ctx context.Context, data RestoreStatusRequest, interval time.Duration) (RestoreStatus, error)
+	Migrate(ctx context.Context, data MigrateRequest) (MigrateResult, error)
+	UploadChunked(ctx context.Context, data UploadFileRequest, opts ChunkOptions) (ChunkedUploadResult, error)
+	DownloadChunked(ctx context.Context, bucketName, key string, w io.Writer) error
+	Export(ctx context.Context, bucketName, prefix, localDir string, opts ExportOptions) (ExportResult, error)
+	GenerateUsageReport(ctx context.Context, data UsageReportRequest) (UsageReport, error)
+	SubmitBatchJob(ctx context.Context, data BatchJobRequest) (string, error)
+	GetBatchJobStatus(ctx context.Context, data BatchJobStatusRequest) (BatchJobStatus, error)
+	WaitForBatchJob(ctx context.Context, data BatchJobStatusRequest, interval time.Duration) (BatchJobStatus, error)
+	QueryObject(ctx context.Context, data QueryObjectRequest) (io.ReadCloser, error)
+	ConfirmUpload(ctx context.Context, data ConfirmUploadRequest) (FileStat, error)
+	CreatePresignedMultipart(ctx context.Context, data PresignedMultipartRequest) (PresignedMultipartUpload, error)
+	CompletePresignedMultipart(ctx context.Context, data CompletePresignedMultipartRequest) (UploadFileResult, error)
+	AbortPresignedMultipart(ctx context.Context, bucketName, key, uploadID string) error
+	CreatePostPolicy(ctx context.Context, data PostPolicyRequest) (PostPolicy, error)
+	Search(ctx context.Context, query SearchRequest) ([]SearchHit, error)
+	QueryCatalog(ctx context.Context, query CatalogQuery) ([]CatalogEntry, error)
+	AbortStaleUploads(ctx context.Context, data AbortStaleUploadsRequest) ([]AbortedUpload, error)
+	StartUploadJanitor(ctx context.Context, data AbortStaleUploadsRequest, interval time.Duration) error
+	SetCORSForBrowserUploads(ctx context.Context, bucketName string, origins, methods []string, maxAge time.Duration) error
+	GetCORS(ctx context.Context, bucketName string) ([]CORSRule, error)
+	MediaPlaylist(ctx context.Context, data PlaylistRequest) (Playlist, error)
+	EnforceTagPolicy(ctx context.Context, bucketName, prefix string) (PolicyResult, error)
+	StartPolicyEnforcer(ctx context.Context, bucketName, prefix string, interval time.Duration) error
+	Reconcile(ctx context.Context) (ReconcileResult, error)
+	WaitForVisibility(ctx context.Context, bucketName string, keys []string, exists bool) error
+	Shutdown(ctx context.Context) error
 }
 
 type s3Service struct {
-	region string
-	s3Cli  *s3.Client
+	region     string
+	awsCfg     aws.Config
+	s3Cli      *s3.Client
+	controlCli *s3control.Client
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	closed   bool
+	inFlight sync.WaitGroup
+
+	moderator        Moderator
+	moderationPolicy ModerationPolicy
+
+	enricher        Enricher
+	storeEnrichment bool
+
+	indexer Indexer
+	catalog Catalog
+
+	loadOptions []func(*config.LoadOptions) error
+	httpClient  aws.HTTPClient
+	session     *credentialSession
+	credentials *aws.CredentialsCache
+
+	endpoint    string
+	accelerate  bool
+	dualStack   bool
+	fips        bool
+	accelerated sync.Map
+
+	trashPrefix    string
+	versionedTrash bool
+
+	mediaRunner MediaRunner
+
+	urlBuilder URLBuilder
+
+	deleteGuard DeleteGuard
+	bulkDelete  BulkDeleteOptions
+
+	correlationHeader string
+
+	bucketNames BucketNames
+
+	spill spill.Store
+
+	uploadGuarantee bool
+	failover        *FailoverPolicy
+	consistency     *ConsistencyOptions
+	ids             idgen.Generator
+	pending         pendingWrites
+
+	breaker    *breaker.Breaker
+	timeouts   *Timeouts
+	bufferPool *BufferPool
+	tagPolicy  *TagPolicy
+	accountID  string
+
+	headers    http.Header
+	apiOptions []func(*middleware.Stack) error
+
+	uploadTransformers   []Transformer
+	downloadTransformers []Transformer
+
+	uploadRules   Rules[UploadFileRequest]
+	deleteRules   Rules[DeleteFileRequest]
+	downloadRules Rules[DownloadFileRequest]
+
+	authorization bool
 }
 
-func NewS3Service(region string) S3Service {
+func NewS3Service(region string, opts ...Option) S3Service {
+	ctx, cancel := context.WithCancel(context.Background())
 	s3Svc := &s3Service{
 		region: region,
+		ctx:    ctx,
+		cancel: cancel,
 	}
 
-	if err := s3Svc.initSession(); err != nil {
-		log.Fatalln(err)
+	for _, opt := range opts {
+		opt(s3Svc)
 	}
 
+	s3Svc.initSession()
+
 	return s3Svc
 }
 
-func (s *s3Service) initSession() error {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+// initSession builds the client. It never fails: when the configuration cannot
+// be loaded yet, calls return a ClientInitError until loading succeeds.
+func (s *s3Service) initSession() {
+	cfg, err := config.LoadDefaultConfig(s.ctx, s.loadOptions...)
+	s.session = &credentialSession{loadOptions: s.loadOptions}
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("failed to load aws config, retrying on first call: %v", err)
+		cfg = aws.Config{Region: s.region, HTTPClient: s.httpClient}
+		s.session.err = err
+	} else {
+		s.session.provider = cfg.Credentials
 	}
 
-	s.s3Cli = s3.NewFromConfig(cfg)
+	if cfg.Credentials == nil || !aws.IsCredentialsProvider(cfg.Credentials, aws.AnonymousCredentials{}) {
+		s.credentials = aws.NewCredentialsCache(s.session)
+		cfg.Credentials = s.credentials
+	}
 
-	return nil
+	s.awsCfg = cfg
+	s.s3Cli = s3.NewFromConfig(cfg, s.clientOptions)
+	s.controlCli = s3control.NewFromConfig(cfg, s.controlOptions)
 }
 
 func (s *s3Service) CreateBucket(bucketName string) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(s.ctx)
+	defer cancel()
+
+	return s.createBucket(ctx, bucketName)
+}
+
+func (s *s3Service) createBucket(ctx context.Context, bucketName string) error {
 	if bucketName == "" {
 		return errors.New("bucket name is required")
 	}
 
-	_, err := s.s3Cli.CreateBucket(context.TODO(), &s3.CreateBucketInput{
+	_, err := s.s3Cli.CreateBucket(ctx, &s3.CreateBucketInput{
 		Bucket: aws.String(bucketName),
 		CreateBucketConfiguration: &types.CreateBucketConfiguration{
 			LocationConstraint: types.BucketLocationConstraint(s.region),
@@ -76,8 +227,12 @@ func (s *s3Service) CreateBucket(bucketName string) error {
 	return nil
 }
 
-func (s *s3Service) isExistBucket(bucketName string) (bool, error) {
-	_, err := s.s3Cli.HeadBucket(context.TODO(), &s3.HeadBucketInput{
+func (s *s3Service) isExistBucket(ctx context.Context, bucketName string) (bool, error) {
+	if isAccessPoint(s.bucketNames.Name(bucketName)) {
+		return true, nil
+	}
+
+	_, err := s.s3Cli.HeadBucket(ctx, &s3.HeadBucketInput{
 		Bucket: aws.String(bucketName),
 	})
 	if err != nil {
@@ -96,60 +251,228 @@ func (s *s3Service) isExistBucket(bucketName string) (bool, error) {
 	return true, nil
 }
 
-func (s *s3Service) UploadFile(data UploadFileRequest) (string, error) {
-	if err := s.validateUploadFile(data); err != nil {
-		return "", err
+func (s *s3Service) UploadFile(data UploadFileRequest) (UploadFileResult, error) {
+	return s.uploadContext(s.ctx, data)
+}
+
+// UploadFileContext is UploadFile bound to ctx, which also carries the
+// principal stored by authz.Middleware.
+func (s *s3Service) UploadFileContext(ctx context.Context, data UploadFileRequest) (UploadFileResult, error) {
+	return s.uploadContext(ctx, data)
+}
+
+// uploadContext is UploadFile for entry points that take a ctx, so cancelling
+// it stops the upload and WithUploadGuarantee cleans up after it.
+func (s *s3Service) uploadContext(ctx context.Context, data UploadFileRequest) (UploadFileResult, error) {
+	if err := s.acquire(); err != nil {
+		return UploadFileResult{}, err
 	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
 
-	pathFile := filepath.Base(fmt.Sprintf("%s/%s/%s", os.TempDir(), uuid.NewV4().String(), data.Filename))
-	if err := createFile(data.Base64Encoding, pathFile); err != nil {
-		return "", err
+	data, err := s.authorizeUpload(ctx, data)
+	if err != nil {
+		return UploadFileResult{}, err
 	}
 
-	defer func() {
-		if err := removeFile(pathFile); err != nil {
-			log.Printf("failed to remove file %s: %v", pathFile, err)
+	if err := s.validateUploadFile(ctx, data); err != nil {
+		if s.failover != nil && isUnavailable(err) {
+			data.Tags = s.policyTags(data)
+			return s.failOver(ctx, data, err)
 		}
-	}()
+		return UploadFileResult{}, err
+	}
+	data.Tags = s.policyTags(data)
 
-	file, err := os.Open(pathFile)
-	if err != nil {
-		return "", err
+	if s.failover != nil {
+		return s.uploadWithFailover(ctx, data)
 	}
+	return s.uploadFile(ctx, data)
+}
+
+func (s *s3Service) uploadFile(ctx context.Context, data UploadFileRequest) (UploadFileResult, error) {
+	ctx = s.correlate(ctx, data.CorrelationID)
 
-	bucketExist, err := s.isExistBucket(data.BucketName)
+	// Checks that can fail run before the pipeline below starts any goroutines.
+	bucketExist, err := s.isExistBucket(ctx, data.BucketName)
 	if err != nil {
-		return "", err
+		return UploadFileResult{}, err
 	}
 
 	if !bucketExist {
-		if err = s.CreateBucket(data.BucketName); err != nil {
-			return "", err
+		if err = s.createBucket(ctx, data.BucketName); err != nil {
+			return UploadFileResult{}, err
+		}
+	}
+
+	// Enrichment and video processing inspect the body as the caller sent it;
+	// transformers only shape what is stored, so moderation, the index and the
+	// catalog describe the stored object and its transformed content type.
+	body := uploadBody(data)
+	var finishEnrichment func(uploadErr error) Enrichment
+	if s.enricher != nil {
+		body, finishEnrichment = s.enrich(data.ContentType, body)
+		defer finishEnrichment(ErrUploadAborted)
+	}
+
+	var spool spill.File
+	if s.mediaRunner != nil && isVideo(data.ContentType) {
+		var spooled io.Reader
+		switch spooled, spool, err = s.spoolVideo(body); {
+		case errors.Is(err, spill.ErrNoPath):
+			logf(ctx, "skipping video processing of %s: the spill store cannot give ffmpeg a file path, e.g. because it encrypts", data.Filename)
+		case err != nil:
+			return UploadFileResult{}, err
+		default:
+			body = spooled
+			defer removeSpool(spool)
 		}
 	}
 
+	body, contentType, closeTransformers, err := applyTransformers(ctx, data.ContentType, body, data.Transformers, s.uploadTransformers)
+	if err != nil {
+		return UploadFileResult{}, fmt.Errorf("failed to transform file: %w", err)
+	}
+	defer closeTransformers()
+	data.ContentType = contentType
+
 	var partMiBs int64 = 10
 	uploader := manager.NewUploader(s.s3Cli, func(u *manager.Uploader) {
 		u.PartSize = partMiBs * 1024 * 1024
-	})
+	}, manager.WithUploaderRequestOptions(s.transferOptions(ctx, data.BucketName, endpointVariant{accelerate: data.Accelerate, dualStack: data.DualStack, fips: data.FIPS})...))
 
 	timeStartUpload := time.Now()
-	output, err := uploader.Upload(context.TODO(), &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket:      aws.String(data.BucketName),
 		Key:         aws.String(data.Filename),
 		ContentType: aws.String(data.ContentType),
-		Body:        file,
-	})
-	log.Printf("upload file %s to bucket %s took %vs", data.Filename, data.BucketName, time.Since(timeStartUpload).Seconds())
+		Body:        body,
+	}
+	if len(data.Tags) > 0 {
+		input.Tagging = aws.String(encodeTags(data.Tags))
+	}
+	metadata, uploadToken := s.uploadMetadata(data)
+	if len(metadata) > 0 {
+		input.Metadata = metadata
+	}
+
+	var location string
+	uploadCtx := WithRequestHeaders(ctx, data.Headers)
+	if s.bufferPool != nil && !isReaderAtSeeker(body) {
+		location, err = s.pooledUpload(uploadCtx, input, s.transferOptions(ctx, data.BucketName, endpointVariant{accelerate: data.Accelerate, dualStack: data.DualStack, fips: data.FIPS})...)
+	} else {
+		var output *manager.UploadOutput
+		if output, err = uploader.Upload(uploadCtx, input); err == nil {
+			location = output.Location
+		}
+	}
+	logf(ctx, "upload file %s to bucket %s took %vs", data.Filename, data.BucketName, time.Since(timeStartUpload).Seconds())
+
+	var enrichment Enrichment
+	if finishEnrichment != nil {
+		enrichment = finishEnrichment(err)
+	}
+
 	if err != nil {
-		return "", fmt.Errorf("failed to upload file: %v", err)
+		if s.uploadGuarantee {
+			return UploadFileResult{}, s.correlatedError(ctx, s.abortUpload(ctx, data, uploadToken, err))
+		}
+		return UploadFileResult{}, s.correlatedError(ctx, fmt.Errorf("failed to upload file: %w", err))
 	}
 
-	return output.Location, nil
+	location, err = s.objectURL(ctx, data.BucketName, data.Filename, location)
+	if err != nil {
+		return UploadFileResult{}, s.discardUpload(ctx, data, uploadToken, fmt.Errorf("failed to build file url: %w", err))
+	}
+
+	result := UploadFileResult{
+		Location: location,
+		Filename: data.Filename,
+		Metadata: enrichment.Metadata,
+		Text:     enrichment.Text,
+		// RequestID is that of the upload itself, before any follow-up calls.
+		CorrelationID: s.correlationID(ctx),
+		RequestID:     RequestID(ctx),
+	}
+
+	if s.storeEnrichment && len(enrichment.Metadata) > 0 {
+		if err = s.storeMetadata(ctx, data, uploadToken, enrichment.Metadata); err != nil {
+			return UploadFileResult{}, s.discardUpload(ctx, data, uploadToken, err)
+		}
+	}
+
+	if s.moderator != nil {
+		if err = s.moderate(ctx, data); err != nil {
+			return UploadFileResult{}, s.discardUpload(ctx, data, uploadToken, err)
+		}
+	}
+
+	if spool != nil {
+		path, err := spool.Path()
+		if err == nil {
+			result.Video, err = s.processVideo(ctx, data, path)
+		}
+		if err != nil {
+			logf(ctx, "failed to process video %s: %v", data.Filename, err)
+		}
+	}
+
+	// The upload succeeded either way, so the result is returned with the error.
+	if err = s.recordUpload(ctx, data, result); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+// uploadMetadata returns the metadata an upload is stored with: its owner and,
+// with the upload guarantee, the token cleanup checks before deleting it.
+func (s *s3Service) uploadMetadata(data UploadFileRequest) (map[string]string, string) {
+	metadata := ownerMetadata(data)
+	var token string
+	if s.uploadGuarantee {
+		token = s.newID()
+		metadata[UploadTokenMetadata] = token
+	}
+
+	return metadata, token
+}
+
+// recordUpload indexes and catalogs a stored upload and tracks it until it is
+// visible.
+func (s *s3Service) recordUpload(ctx context.Context, data UploadFileRequest, result UploadFileResult) error {
+	if s.indexer != nil {
+		s.indexUpload(ctx, data, result)
+	}
+
+	if s.catalog != nil {
+		s.catalogObject(ctx, data.BucketName, data.Filename, CatalogEntry{Owner: data.OwnerID, UploadedBy: data.UploadedBy, Tags: data.Tags})
+	}
+
+	return s.trackWrites(ctx, data.BucketName, []string{data.Filename}, true)
 }
 
-func (s *s3Service) isFileExist(bucketName, filename string) (bool, error) {
-	_, err := s.s3Cli.HeadObject(context.TODO(), &s3.HeadObjectInput{
+func encodeTags(tags map[string]string) string {
+	values := url.Values{}
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+
+	return values.Encode()
+}
+
+func objectTags(tags map[string]string) []types.Tag {
+	tagSet := make([]types.Tag, 0, len(tags))
+	for key, value := range tags {
+		tagSet = append(tagSet, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	return tagSet
+}
+
+func (s *s3Service) isFileExist(ctx context.Context, bucketName, filename string) (bool, error) {
+	_, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(filename),
 	})
@@ -171,78 +494,120 @@ func (s *s3Service) isFileExist(bucketName, filename string) (bool, error) {
 	return true, nil
 }
 
-func createFile(fileBase64, pathFile string) error {
-	dec, err := base64.StdEncoding.DecodeString(fileBase64)
-	if err != nil {
-		return err
+func isNotFound(err error) bool {
+	var respErr *awsHttp.ResponseError
+	return errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound
+}
+
+// uploadBody returns the reader streamed into the uploader, so the payload is
+// never written to disk. Base64 payloads are decoded on the fly instead of
+// being materialised as a second, decoded buffer.
+func uploadBody(data UploadFileRequest) io.Reader {
+	switch {
+	case data.Body != nil:
+		return data.Body
+	case data.Base64Body != nil:
+		return base64.NewDecoder(base64.StdEncoding, data.Base64Body)
+	default:
+		return base64.NewDecoder(base64.StdEncoding, strings.NewReader(data.Base64Encoding))
 	}
+}
 
-	file, err := os.Create(pathFile)
-	if err != nil {
-		return err
+func (s *s3Service) DeleteFile(data DeleteFileRequest) (BatchResult, error) {
+	return s.DeleteFileContext(s.ctx, data)
+}
+
+// DeleteFileContext is DeleteFile bound to ctx, which also carries the
+// principal stored by authz.Middleware.
+func (s *s3Service) DeleteFileContext(ctx context.Context, data DeleteFileRequest) (BatchResult, error) {
+	if err := s.acquire(); err != nil {
+		return BatchResult{}, err
 	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
 
-	if _, err = file.Write(dec); err != nil {
-		return err
+	for _, filename := range data.Filename {
+		action := authz.Action{Permission: authz.PermissionDelete, BucketName: data.BucketName, Filename: filename}
+		if err := s.authorize(ctx, data.Principal, action); err != nil {
+			return BatchResult{}, err
+		}
 	}
 
-	return nil
+	if err := s.validateDeleteFile(data); err != nil {
+		return BatchResult{}, err
+	}
+
+	return s.deleteFile(s.correlate(ctx, data.CorrelationID), data)
 }
 
-func removeFile(pathFile string) error {
-	if err := os.RemoveAll(pathFile); err != nil {
-		return err
+// deleteFile deletes validated keys, honouring the delete guard, ownership and
+// the trash, and keeps the index, catalog and consistency tracking in step.
+func (s *s3Service) deleteFile(ctx context.Context, data DeleteFileRequest) (BatchResult, error) {
+	result := BatchResult{Results: make([]KeyResult, 0, len(data.Filename)), CorrelationID: s.correlationID(ctx)}
+	fileExist := s.existingKeys(ctx, data, s.guardDelete(data.Filename, &result), &result)
+
+	if stop, err := s.dryRun(data, fileExist, &result); stop {
+		return result, err
 	}
 
-	return nil
-}
+	var trashed []string
+	if s.trashPrefix != "" {
+		trashed, fileExist = s.moveToTrash(ctx, data.BucketName, fileExist, &result)
+	}
 
-func (s *s3Service) DeleteFile(data DeleteFileRequest) error {
-	if err := s.validateDeleteFile(data); err != nil {
-		return err
+	if len(fileExist) == 0 && len(trashed) == 0 {
+		return result, result.Err()
 	}
 
-	fileExist := []string{}
-	for _, filename := range data.Filename {
-		isExist, err := s.isFileExist(data.BucketName, filename)
-		if err != nil {
-			return err
-		}
+	// Trashed keys are gone from their old location as much as deleted ones.
+	deleted := append(trashed, s.deleteKeys(ctx, data.BucketName, fileExist, &result)...)
 
-		if isExist {
-			fileExist = append(fileExist, filename)
-		}
+	if s.indexer != nil && len(deleted) > 0 {
+		s.unindex(ctx, data.BucketName, deleted)
 	}
 
-	var objectIds []types.ObjectIdentifier
-	for _, key := range fileExist {
-		objectIds = append(objectIds, types.ObjectIdentifier{Key: aws.String(key)})
+	if s.catalog != nil {
+		s.uncatalog(ctx, data.BucketName, deleted)
 	}
 
-	_, err := s.s3Cli.DeleteObjects(context.TODO(), &s3.DeleteObjectsInput{
-		Bucket: aws.String(data.BucketName),
-		Delete: &types.Delete{Objects: objectIds},
-	})
-	if err != nil {
-		log.Printf("failed to delete files %v: %v", fileExist, err)
-		return fmt.Errorf("failed to delete files")
+	if err := s.trackWrites(ctx, data.BucketName, deleted, false); err != nil {
+		return result, errors.Join(result.Err(), err)
 	}
 
-	return nil
+	return result, result.Err()
 }
 
 func (s *s3Service) DownloadFile(data DownloadFileRequest) ([]byte, error) {
-	if err := s.validateDownloadFile(data); err != nil {
+	return s.DownloadFileContext(s.ctx, data)
+}
+
+// DownloadFileContext is DownloadFile bound to ctx, which also carries the
+// principal stored by authz.Middleware.
+func (s *s3Service) DownloadFileContext(ctx context.Context, data DownloadFileRequest) ([]byte, error) {
+	if err := s.acquire(); err != nil {
+		return nil, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	action := authz.Action{Permission: authz.PermissionDownload, BucketName: data.BucketName, Filename: data.Filename}
+	if err := s.authorize(ctx, data.Principal, action); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateDownloadFile(ctx, data); err != nil {
 		return nil, err
 	}
 
 	var partMiBs int64 = 10
 	downloader := manager.NewDownloader(s.s3Cli, func(d *manager.Downloader) {
 		d.PartSize = partMiBs * 1024 * 1024
-	})
+	}, manager.WithDownloaderClientOptions(s.transferOptions(ctx, data.BucketName, endpointVariant{accelerate: data.Accelerate, dualStack: data.DualStack, fips: data.FIPS})...))
 
 	buffer := manager.NewWriteAtBuffer([]byte{})
-	_, err := downloader.Download(context.TODO(), buffer, &s3.GetObjectInput{
+	_, err := downloader.Download(WithRequestHeaders(ctx, data.Headers), buffer, &s3.GetObjectInput{
 		Bucket: aws.String(data.BucketName),
 		Key:    aws.String(data.Filename),
 	})
@@ -251,5 +616,10 @@ func (s *s3Service) DownloadFile(data DownloadFileRequest) ([]byte, error) {
 		return nil, fmt.Errorf("failed to download file")
 	}
 
-	return buffer.Bytes(), nil
+	body, err := s.transformDownload(ctx, data, buffer.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to transform file: %w", err)
+	}
+
+	return body, nil
 }
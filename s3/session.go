@@ -0,0 +1,141 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+const (
+	minSessionBackoff = time.Second
+	maxSessionBackoff = time.Minute
+)
+
+// ClientInitError is returned while the AWS configuration or credentials cannot
+// be loaded. Calls fail fast with it until RetryAt, after which the next call
+// tries again. It matches ErrClientUnavailable with errors.Is.
+type ClientInitError struct {
+	Err     error
+	RetryAt time.Time
+}
+
+func (e *ClientInitError) Error() string {
+	return fmt.Sprintf("s3 client unavailable, retrying after %s: %v", e.RetryAt.Format(time.RFC3339), e.Err)
+}
+
+func (e *ClientInitError) Unwrap() []error {
+	return []error{ErrClientUnavailable, e.Err}
+}
+
+// expiredSessionCodes are API errors after which cached credentials are dropped
+// and the configuration is loaded again.
+var expiredSessionCodes = map[string]bool{
+	"ExpiredToken":          true,
+	"ExpiredTokenException": true,
+	"InvalidToken":          true,
+	"TokenRefreshRequired":  true,
+	"InvalidAccessKeyId":    true,
+}
+
+// credentialSession is the credentials provider of the client. It loads the
+// AWS configuration lazily and reloads it, with exponential backoff, whenever
+// credentials cannot be retrieved, so the client itself never has to be
+// replaced.
+type credentialSession struct {
+	loadOptions []func(*config.LoadOptions) error
+
+	mu       sync.Mutex
+	provider aws.CredentialsProvider
+	err      error
+	retryAt  time.Time
+	backoff  time.Duration
+}
+
+func (c *credentialSession) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	provider, err := c.currentProvider(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+
+	credentials, err := provider.Retrieve(ctx)
+	if err != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.provider = nil
+		return aws.Credentials{}, c.fail(err)
+	}
+
+	c.mu.Lock()
+	c.backoff = 0
+	c.mu.Unlock()
+
+	return credentials, nil
+}
+
+func (c *credentialSession) currentProvider(ctx context.Context) (aws.CredentialsProvider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider != nil {
+		return c.provider, nil
+	}
+
+	if time.Now().Before(c.retryAt) {
+		return nil, &ClientInitError{Err: c.err, RetryAt: c.retryAt}
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, c.loadOptions...)
+	if err != nil {
+		return nil, c.fail(err)
+	}
+
+	if cfg.Credentials == nil {
+		return nil, c.fail(errors.New("no credentials provider configured"))
+	}
+
+	c.provider = cfg.Credentials
+	return c.provider, nil
+}
+
+// fail records err and schedules the next attempt. c.mu must be held.
+func (c *credentialSession) fail(err error) error {
+	c.backoff = min(max(c.backoff*2, minSessionBackoff), maxSessionBackoff)
+	c.err = err
+	c.retryAt = time.Now().Add(c.backoff)
+	log.Printf("failed to load aws credentials, retrying in %v: %v", c.backoff, err)
+
+	return &ClientInitError{Err: err, RetryAt: c.retryAt}
+}
+
+func (c *credentialSession) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.provider = nil
+}
+
+// sessionMiddleware drops the credentials when S3 reports them as expired or
+// unknown, so the next call reloads them instead of failing the same way.
+func (s *s3Service) sessionMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("FileUploaderSession", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		out, metadata, err := next.HandleInitialize(ctx, in)
+
+		var apiError smithy.APIError
+		if errors.As(err, &apiError) && expiredSessionCodes[apiError.ErrorCode()] {
+			log.Printf("aws session rejected with %s, reloading credentials", apiError.ErrorCode())
+			s.session.invalidate()
+			s.credentials.Invalidate()
+		}
+
+		return out, metadata, err
+	}), middleware.After)
+}
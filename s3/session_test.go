@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// countingProvider serves static credentials, or err when set, and counts how
+// often it was asked.
+type countingProvider struct {
+	calls atomic.Int32
+	err   atomic.Pointer[error]
+}
+
+func (p *countingProvider) Retrieve(context.Context) (aws.Credentials, error) {
+	p.calls.Add(1)
+	if err := p.err.Load(); err != nil {
+		return aws.Credentials{}, *err
+	}
+	return aws.Credentials{AccessKeyID: "test", SecretAccessKey: "test", Source: "counting"}, nil
+}
+
+func TestCredentialSessionRetry(t *testing.T) {
+	errCreds := errors.New("no credentials")
+	provider := &countingProvider{}
+	provider.err.Store(&errCreds)
+	session := &credentialSession{loadOptions: []func(*config.LoadOptions) error{config.WithCredentialsProvider(provider)}}
+
+	_, err := session.Retrieve(context.Background())
+	var initErr *ClientInitError
+	if !errors.As(err, &initErr) || !errors.Is(err, ErrClientUnavailable) || !errors.Is(err, errCreds) {
+		t.Fatalf("Retrieve error = %v, want a ClientInitError wrapping %v", err, errCreds)
+	}
+	if initErr.RetryAt.Before(time.Now()) {
+		t.Errorf("RetryAt = %v, want it in the future", initErr.RetryAt)
+	}
+
+	if _, err := session.Retrieve(context.Background()); !errors.Is(err, errCreds) || provider.calls.Load() != 1 {
+		t.Errorf("Retrieve before RetryAt = %v after %d retrievals, want it to fail fast", err, provider.calls.Load())
+	}
+
+	provider.err.Store(nil)
+	session.mu.Lock()
+	session.retryAt = time.Time{}
+	session.mu.Unlock()
+	credentials, err := session.Retrieve(context.Background())
+	if err != nil || credentials.Source != "counting" {
+		t.Fatalf("Retrieve after RetryAt = %+v, %v, want the provider's credentials", credentials, err)
+	}
+	if session.backoff != 0 {
+		t.Errorf("backoff = %v after a success, want it reset", session.backoff)
+	}
+}
+
+func TestCredentialSessionBackoff(t *testing.T) {
+	session := &credentialSession{}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 32 * time.Second, time.Minute, time.Minute}
+	for i, backoff := range want {
+		session.mu.Lock()
+		session.fail(errors.New("no credentials"))
+		got := session.backoff
+		session.mu.Unlock()
+		if got != backoff {
+			t.Errorf("attempt %d: backoff = %v, want %v", i+1, got, backoff)
+		}
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	tests := []struct {
+		name           string
+		code           string
+		wantRetrievals int32
+	}{
+		{name: "expired token", code: "ExpiredToken", wantRetrievals: 2},
+		{name: "unknown access key", code: "InvalidAccessKeyId", wantRetrievals: 2},
+		{name: "other error", code: "AccessDenied", wantRetrievals: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			var rejected atomic.Bool
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if r.Method != http.MethodPut || rejected.Swap(true) {
+					return false
+				}
+				fakeError(w, http.StatusForbidden, tt.code)
+				return true
+			}
+			provider := &countingProvider{}
+			svc := fake.service(WithCredentials(provider))
+
+			upload := func() error {
+				_, err := svc.UploadFile(UploadFileRequest{
+					BucketName:  "bucket",
+					Filename:    "a.txt",
+					ContentType: "text/plain",
+					Body:        io.NopCloser(strings.NewReader("a")),
+				})
+				return err
+			}
+			if err := upload(); err == nil || !strings.Contains(err.Error(), tt.code) {
+				t.Fatalf("first UploadFile error = %v, want %s", err, tt.code)
+			}
+			if err := upload(); err != nil {
+				t.Fatalf("second UploadFile: %v", err)
+			}
+			if got := provider.calls.Load(); got != tt.wantRetrievals {
+				t.Errorf("credentials retrieved %d times, want %d", got, tt.wantRetrievals)
+			}
+		})
+	}
+}
+
+func TestClientUnavailable(t *testing.T) {
+	errCreds := errors.New("no credentials")
+	provider := &countingProvider{}
+	provider.err.Store(&errCreds)
+	svc := newFakeS3(t, "bucket").service(WithCredentials(provider))
+
+	_, err := svc.UploadFile(UploadFileRequest{
+		BucketName:  "bucket",
+		Filename:    "a.txt",
+		ContentType: "text/plain",
+		Body:        io.NopCloser(strings.NewReader("a")),
+	})
+	if !errors.Is(err, ErrClientUnavailable) || !errors.Is(err, errCreds) {
+		t.Errorf("UploadFile error = %v, want %v wrapping %v", err, ErrClientUnavailable, errCreds)
+	}
+}
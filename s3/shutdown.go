@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"context"
+	"log"
+)
+
+// acquire registers an in-flight operation, failing once Shutdown has been called.
+func (s *s3Service) acquire() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrServiceClosed
+	}
+
+	s.inFlight.Add(1)
+	return nil
+}
+
+func (s *s3Service) release() {
+	s.inFlight.Done()
+}
+
+// operation derives the context of a call from the caller's ctx, cancelled as
+// well once Shutdown gives up waiting, so a caller context that never ends
+// cannot hold Shutdown past its deadline.
+func (s *s3Service) operation(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.AfterFunc(s.ctx, func() {
+		cancel(ErrServiceClosed)
+	})
+
+	return ctx, func() {
+		stop()
+		cancel(nil)
+	}
+}
+
+// Shutdown stops accepting new operations and waits for in-flight ones to finish.
+// If ctx expires first, in-flight operations are cancelled, which makes the upload
+// manager abort any incomplete multipart uploads before returning.
+func (s *s3Service) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		log.Printf("shutdown deadline reached, cancelling in-flight operations: %v", ctx.Err())
+		s.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,198 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdownCancelsOperationsPastDeadline(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(svc S3Service, ctx context.Context) error
+	}{
+		{name: "StatFile", call: func(svc S3Service, ctx context.Context) error {
+			_, err := svc.StatFile(ctx, "bucket", "a.txt")
+			return err
+		}},
+		{name: "FileExists", call: func(svc S3Service, ctx context.Context) error {
+			_, err := svc.FileExists(ctx, "bucket", "a.txt")
+			return err
+		}},
+		{name: "GetRestoreStatus", call: func(svc S3Service, ctx context.Context) error {
+			_, err := svc.GetRestoreStatus(ctx, RestoreStatusRequest{BucketName: "bucket", Filename: "a.txt"})
+			return err
+		}},
+		{name: "WaitForRestore", call: func(svc S3Service, ctx context.Context) error {
+			_, err := svc.WaitForRestore(ctx, RestoreStatusRequest{BucketName: "bucket", Filename: "a.txt"}, time.Millisecond)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			started, finished := make(chan struct{}, 1), make(chan struct{})
+			t.Cleanup(func() { close(finished) })
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if r.Method != http.MethodHead {
+					return false
+				}
+				started <- struct{}{}
+				select {
+				case <-r.Context().Done():
+				case <-finished:
+				}
+				return true
+			}
+			svc := fake.service()
+
+			callErr := make(chan error, 1)
+			go func() {
+				// The caller's context never ends on its own.
+				callErr <- tt.call(svc, context.Background())
+			}()
+			<-started
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			shutdown := make(chan error, 1)
+			go func() {
+				shutdown <- svc.Shutdown(ctx)
+			}()
+
+			select {
+			case err := <-shutdown:
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("Shutdown = %v, want DeadlineExceeded", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Shutdown hung on an operation whose caller context never ends")
+			}
+
+			if err := <-callErr; err == nil {
+				t.Errorf("%s succeeded after being cancelled by Shutdown", tt.name)
+			}
+		})
+	}
+}
+
+func TestShutdownRejectsNewOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(svc S3Service) error
+	}{
+		{name: "StatFile", call: func(svc S3Service) error {
+			_, err := svc.StatFile(context.Background(), "bucket", "a.txt")
+			return err
+		}},
+		{name: "Search", call: func(svc S3Service) error {
+			_, err := svc.Search(context.Background(), SearchRequest{Query: "a"})
+			return err
+		}},
+		{name: "CreatePostPolicy", call: func(svc S3Service) error {
+			_, err := svc.CreatePostPolicy(context.Background(), PostPolicyRequest{BucketName: "bucket", Filename: "a.txt"})
+			return err
+		}},
+		{name: "GetRestoreStatus", call: func(svc S3Service) error {
+			_, err := svc.GetRestoreStatus(context.Background(), RestoreStatusRequest{BucketName: "bucket", Filename: "a.txt"})
+			return err
+		}},
+		{name: "GetBatchJobStatus", call: func(svc S3Service) error {
+			_, err := svc.GetBatchJobStatus(context.Background(), BatchJobStatusRequest{AccountID: "123456789012", JobID: "job"})
+			return err
+		}},
+		{name: "QueryObject", call: func(svc S3Service) error {
+			_, err := svc.QueryObject(context.Background(), QueryObjectRequest{BucketName: "bucket", Filename: "a.csv", Expression: "SELECT * FROM s3object"})
+			return err
+		}},
+		{name: "QueryCatalog", call: func(svc S3Service) error {
+			_, err := svc.QueryCatalog(context.Background(), CatalogQuery{BucketName: "bucket"})
+			return err
+		}},
+		{name: "GenerateUsageReport", call: func(svc S3Service) error {
+			_, err := svc.GenerateUsageReport(context.Background(), UsageReportRequest{BucketName: "bucket"})
+			return err
+		}},
+		{name: "ListFiles", call: func(svc S3Service) error {
+			files := svc.ListFiles(context.Background(), ListFilesRequest{BucketName: "bucket"})
+			for range files.All() {
+			}
+			return files.Err()
+		}},
+		{name: "ListFilesByOwner", call: func(svc S3Service) error {
+			files := svc.ListFilesByOwner(context.Background(), ListFilesRequest{BucketName: "bucket"}, "owner")
+			for range files.All() {
+			}
+			return files.Err()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newFakeS3(t, "bucket").service()
+			if err := svc.Shutdown(context.Background()); err != nil {
+				t.Fatalf("Shutdown: %v", err)
+			}
+
+			if err := tt.call(svc); !errors.Is(err, ErrServiceClosed) {
+				t.Errorf("%s after Shutdown = %v, want ErrServiceClosed", tt.name, err)
+			}
+		})
+	}
+}
+
+func TestBucketChecksFollowRequestContext(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(svc S3Service, ctx context.Context) error
+	}{
+		{name: "UploadFileContext", call: func(svc S3Service, ctx context.Context) error {
+			_, err := svc.UploadFileContext(ctx, UploadFileRequest{BucketName: "bucket", Filename: "a.txt", ContentType: "text/plain", Body: io.NopCloser(strings.NewReader("a"))})
+			return err
+		}},
+		{name: "DownloadFileContext", call: func(svc S3Service, ctx context.Context) error {
+			_, err := svc.DownloadFileContext(ctx, DownloadFileRequest{BucketName: "bucket", Filename: "a.txt"})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			finished := make(chan struct{})
+			t.Cleanup(func() { close(finished) })
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				// Only bucket requests hang, so the call can only end through
+				// the bucket check honouring its context.
+				if strings.Trim(r.URL.Path, "/") != "bucket" {
+					return false
+				}
+				select {
+				case <-r.Context().Done():
+				case <-finished:
+				}
+				return true
+			}
+			svc := fake.service()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			callErr := make(chan error, 1)
+			go func() {
+				callErr <- tt.call(svc, ctx)
+			}()
+
+			select {
+			case err := <-callErr:
+				if err == nil {
+					t.Errorf("%s succeeded with a hanging bucket check", tt.name)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("%s ignored the cancellation of its context", tt.name)
+			}
+		})
+	}
+}
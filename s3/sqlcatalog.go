@@ -0,0 +1,317 @@
+package s3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type SQLDialect string
+
+const (
+	Postgres SQLDialect = "postgres"
+	SQLite   SQLDialect = "sqlite"
+
+	defaultCatalogLimit = 100
+)
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type sqlCatalog struct {
+	db      *sql.DB
+	dialect SQLDialect
+	table   string
+	tags    string
+}
+
+// NewSQLCatalog keeps the catalog in table (and table_tags) of db, creating
+// them when missing. The caller opens db with a driver of its choice, e.g. pgx
+// for Postgres or modernc.org/sqlite.
+func NewSQLCatalog(ctx context.Context, db *sql.DB, dialect SQLDialect, table string) (Catalog, error) {
+	if dialect != Postgres && dialect != SQLite {
+		return nil, fmt.Errorf("unsupported sql dialect %q", dialect)
+	}
+
+	if !sqlIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+
+	c := &sqlCatalog{db: db, dialect: dialect, table: table, tags: table + "_tags"}
+	if err := c.migrate(ctx); err != nil {
+		return nil, fmt.Errorf("failed to create catalog tables: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *sqlCatalog) migrate(ctx context.Context) error {
+	timestamp := "TIMESTAMP"
+	if c.dialect == SQLite {
+		timestamp = "DATETIME"
+	}
+
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			bucket TEXT NOT NULL,
+			object_key TEXT NOT NULL,
+			size BIGINT NOT NULL,
+			checksum TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			owner TEXT NOT NULL,
+			uploaded_by TEXT NOT NULL DEFAULT '',
+			created_at %s NOT NULL,
+			updated_at %s NOT NULL,
+			PRIMARY KEY (bucket, object_key)
+		)`, c.table, timestamp, timestamp),
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			bucket TEXT NOT NULL,
+			object_key TEXT NOT NULL,
+			tag_key TEXT NOT NULL,
+			tag_value TEXT NOT NULL,
+			PRIMARY KEY (bucket, object_key, tag_key)
+		)`, c.tags),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_owner_idx ON %s (owner)`, c.table, c.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_tag_idx ON %s (tag_key, tag_value)`, c.tags, c.tags),
+	}
+	for _, statement := range statements {
+		if _, err := c.db.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	// Tables created before uploaded_by existed get it added.
+	return c.addColumn(ctx, "uploaded_by", "TEXT NOT NULL DEFAULT ''")
+}
+
+func (c *sqlCatalog) addColumn(ctx context.Context, column, definition string) error {
+	if c.dialect == Postgres {
+		_, err := c.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s`, c.table, column, definition))
+		return err
+	}
+
+	var exists int
+	err := c.db.QueryRowContext(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM pragma_table_info('%s') WHERE name = ?`, c.table), column).Scan(&exists)
+	if err != nil || exists > 0 {
+		return err
+	}
+
+	_, err = c.db.ExecContext(ctx, fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, column, definition))
+	return err
+}
+
+// placeholders renders the statement's "?" placeholders for the dialect.
+func (c *sqlCatalog) placeholders(query string) string {
+	if c.dialect != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+func (c *sqlCatalog) Record(ctx context.Context, entry CatalogEntry) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// created_at is kept when an object is overwritten.
+	_, err = tx.ExecContext(ctx, c.placeholders(fmt.Sprintf(`INSERT INTO %s
+		(bucket, object_key, size, checksum, content_type, owner, uploaded_by, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (bucket, object_key) DO UPDATE SET
+			size = excluded.size, checksum = excluded.checksum, content_type = excluded.content_type,
+			owner = excluded.owner, uploaded_by = excluded.uploaded_by, updated_at = excluded.updated_at`, c.table)),
+		entry.BucketName, entry.Key, entry.Size, entry.Checksum, entry.ContentType, entry.Owner,
+		entry.UploadedBy, entry.CreatedAt, entry.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	if err := c.deleteTags(ctx, tx, entry.BucketName, entry.Key); err != nil {
+		return err
+	}
+
+	for key, value := range entry.Tags {
+		_, err := tx.ExecContext(ctx, c.placeholders(fmt.Sprintf(
+			`INSERT INTO %s (bucket, object_key, tag_key, tag_value) VALUES (?, ?, ?, ?)`, c.tags)),
+			entry.BucketName, entry.Key, key, value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (c *sqlCatalog) Rename(ctx context.Context, bucketName, oldKey, newKey string) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// A stale entry under newKey would collide with the renamed one.
+	if err := c.deleteEntry(ctx, tx, bucketName, newKey); err != nil {
+		return err
+	}
+
+	for _, table := range []string{c.table, c.tags} {
+		_, err := tx.ExecContext(ctx, c.placeholders(fmt.Sprintf(
+			`UPDATE %s SET object_key = ? WHERE bucket = ? AND object_key = ?`, table)),
+			newKey, bucketName, oldKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (c *sqlCatalog) Remove(ctx context.Context, bucketName, key string) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := c.deleteEntry(ctx, tx, bucketName, key); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (c *sqlCatalog) deleteEntry(ctx context.Context, tx *sql.Tx, bucketName, key string) error {
+	if err := c.deleteTags(ctx, tx, bucketName, key); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, c.placeholders(fmt.Sprintf(
+		`DELETE FROM %s WHERE bucket = ? AND object_key = ?`, c.table)), bucketName, key)
+	return err
+}
+
+func (c *sqlCatalog) deleteTags(ctx context.Context, tx *sql.Tx, bucketName, key string) error {
+	_, err := tx.ExecContext(ctx, c.placeholders(fmt.Sprintf(
+		`DELETE FROM %s WHERE bucket = ? AND object_key = ?`, c.tags)), bucketName, key)
+	return err
+}
+
+func (c *sqlCatalog) Query(ctx context.Context, query CatalogQuery) ([]CatalogEntry, error) {
+	conditions := []string{}
+	args := []any{}
+	where := func(condition string, values ...any) {
+		conditions = append(conditions, condition)
+		args = append(args, values...)
+	}
+
+	if query.BucketName != "" {
+		where("bucket = ?", query.BucketName)
+	}
+	if query.Prefix != "" {
+		where(`object_key LIKE ? ESCAPE '\'`, likePrefix(query.Prefix))
+	}
+	if query.Owner != "" {
+		where("owner = ?", query.Owner)
+	}
+	if query.UploadedBy != "" {
+		where("uploaded_by = ?", query.UploadedBy)
+	}
+	if query.ContentType != "" {
+		where("content_type = ?", query.ContentType)
+	}
+	if query.MinSize > 0 {
+		where("size >= ?", query.MinSize)
+	}
+	if query.MaxSize > 0 {
+		where("size <= ?", query.MaxSize)
+	}
+	if !query.ModifiedAfter.IsZero() {
+		where("updated_at > ?", query.ModifiedAfter)
+	}
+	if !query.ModifiedBefore.IsZero() {
+		where("updated_at < ?", query.ModifiedBefore)
+	}
+	for key, value := range query.Tags {
+		where(fmt.Sprintf(`EXISTS (SELECT 1 FROM %s t WHERE t.bucket = %s.bucket AND t.object_key = %s.object_key
+			AND t.tag_key = ? AND t.tag_value = ?)`, c.tags, c.table, c.table), key, value)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultCatalogLimit
+	}
+
+	statement := fmt.Sprintf(`SELECT bucket, object_key, size, checksum, content_type, owner, uploaded_by, created_at, updated_at FROM %s`, c.table)
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	statement += " ORDER BY bucket, object_key LIMIT ? OFFSET ?"
+	args = append(args, limit, max(query.Offset, 0))
+
+	rows, err := c.db.QueryContext(ctx, c.placeholders(statement), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []CatalogEntry{}
+	for rows.Next() {
+		entry := CatalogEntry{}
+		if err := rows.Scan(&entry.BucketName, &entry.Key, &entry.Size, &entry.Checksum, &entry.ContentType,
+			&entry.Owner, &entry.UploadedBy, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		if entries[i].Tags, err = c.entryTags(ctx, entries[i].BucketName, entries[i].Key); err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+func (c *sqlCatalog) entryTags(ctx context.Context, bucketName, key string) (map[string]string, error) {
+	rows, err := c.db.QueryContext(ctx, c.placeholders(fmt.Sprintf(
+		`SELECT tag_key, tag_value FROM %s WHERE bucket = ? AND object_key = ?`, c.tags)), bucketName, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		tags[key] = value
+	}
+
+	return tags, rows.Err()
+}
+
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
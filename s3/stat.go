@@ -0,0 +1,113 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+// StatFile returns an object's attributes, user metadata and tags without
+// downloading it.
+func (s *s3Service) StatFile(ctx context.Context, bucketName, key string) (FileStat, error) {
+	if err := s.acquire(); err != nil {
+		return FileStat{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateStatFile(bucketName, key); err != nil {
+		return FileStat{}, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, bucketName, key); err != nil {
+		return FileStat{}, err
+	}
+
+	head, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return FileStat{}, ErrFileNotFound
+		}
+		log.Printf("failed to stat file %s - %s: %v", bucketName, key, err)
+		return FileStat{}, fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	tags, err := s.getObjectTags(ctx, bucketName, key)
+	if err != nil {
+		log.Printf("failed to get tags of file %s - %s: %v", bucketName, key, err)
+		return FileStat{}, fmt.Errorf("failed to get file tags: %w", err)
+	}
+
+	storageClass := string(head.StorageClass)
+	if storageClass == "" {
+		storageClass = "STANDARD"
+	}
+
+	return FileStat{
+		Key:          key,
+		Size:         aws.ToInt64(head.ContentLength),
+		ContentType:  aws.ToString(head.ContentType),
+		ETag:         aws.ToString(head.ETag),
+		LastModified: aws.ToTime(head.LastModified),
+		StorageClass: storageClass,
+		VersionID:    aws.ToString(head.VersionId),
+		Metadata:     head.Metadata,
+		Tags:         tags,
+	}, nil
+}
+
+func (s *s3Service) FileExists(ctx context.Context, bucketName, key string) (bool, error) {
+	if err := s.acquire(); err != nil {
+		return false, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if err := s.validateStatFile(bucketName, key); err != nil {
+		return false, err
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, bucketName, key); err != nil {
+		return false, err
+	}
+
+	_, err := s.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check file: %w", err)
+	}
+
+	return true, nil
+}
+
+func (s *s3Service) getObjectTags(ctx context.Context, bucketName, key string) (map[string]string, error) {
+	output, err := s.s3Cli.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(output.TagSet))
+	for _, tag := range output.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return tags, nil
+}
@@ -0,0 +1,198 @@
+package s3
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+type bucketStorage struct {
+	svc        *s3Service
+	bucketName string
+}
+
+// NewStorage exposes one bucket of svc through the provider-neutral
+// storage.Storage interface.
+func NewStorage(svc S3Service, bucketName string) (storage.Storage, error) {
+	s, ok := svc.(*s3Service)
+	if !ok {
+		return nil, errors.New("service must be created by NewS3Service")
+	}
+
+	if bucketName == "" {
+		return nil, errors.New("bucket name is required")
+	}
+
+	return &bucketStorage{svc: s, bucketName: bucketName}, nil
+}
+
+func (b *bucketStorage) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	output, err := b.svc.s3Cli.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(b.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return storage.ObjectInfo{}, storage.ErrNotExist
+		}
+		return storage.ObjectInfo{}, err
+	}
+
+	return storage.ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		ETag:         aws.ToString(output.ETag),
+		MD5:          etagMD5(aws.ToString(output.ETag), output.ServerSideEncryption, output.SSECustomerAlgorithm),
+		LastModified: aws.ToTime(output.LastModified),
+		Metadata:     output.Metadata,
+	}, nil
+}
+
+func (b *bucketStorage) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	output, err := b.svc.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, storage.ObjectInfo{}, storage.ErrNotExist
+		}
+		return nil, storage.ObjectInfo{}, err
+	}
+
+	return output.Body, storage.ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(output.ContentLength),
+		ContentType:  aws.ToString(output.ContentType),
+		ETag:         aws.ToString(output.ETag),
+		MD5:          etagMD5(aws.ToString(output.ETag), output.ServerSideEncryption, output.SSECustomerAlgorithm),
+		LastModified: aws.ToTime(output.LastModified),
+		Metadata:     output.Metadata,
+	}, nil
+}
+
+func (b *bucketStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	byteRange := fmt.Sprintf("bytes=%d-", offset)
+	if length >= 0 {
+		byteRange += strconv.FormatInt(offset+length-1, 10)
+	}
+
+	output, err := b.svc.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucketName),
+		Key:    aws.String(key),
+		Range:  aws.String(byteRange),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, storage.ErrNotExist
+		}
+		return nil, err
+	}
+
+	return output.Body, nil
+}
+
+func (b *bucketStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	request, err := s3.NewPresignClient(b.svc.s3Cli).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(b.bucketName),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", err
+	}
+
+	return request.URL, nil
+}
+
+func (b *bucketStorage) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (storage.ObjectInfo, error) {
+	input := &s3.PutObjectInput{
+		Bucket:   aws.String(b.bucketName),
+		Key:      aws.String(key),
+		Body:     r,
+		Metadata: opts.Metadata,
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+
+	output, err := manager.NewUploader(b.svc.s3Cli).Upload(ctx, input)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	return storage.ObjectInfo{
+		Key:         key,
+		Size:        opts.Size,
+		ContentType: opts.ContentType,
+		ETag:        aws.ToString(output.ETag),
+		MD5:         etagMD5(aws.ToString(output.ETag), output.ServerSideEncryption, nil),
+		Metadata:    opts.Metadata,
+	}, nil
+}
+
+func (b *bucketStorage) Delete(ctx context.Context, key string) error {
+	return b.svc.deleteObject(ctx, b.bucketName, key)
+}
+
+func (b *bucketStorage) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	paginator := s3.NewListObjectsV2Paginator(b.svc.s3Cli, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, object := range page.Contents {
+			// Listings do not say how an object is encrypted, so its ETag
+			// cannot be trusted as a digest; MD5 is left to Stat and Get.
+			err := fn(storage.ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				ETag:         aws.ToString(object.ETag),
+				LastModified: aws.ToTime(object.LastModified),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// etagMD5 returns the MD5 digest encoded in the ETag of an unencrypted or
+// SSE-S3 single-part object. The ETags of multipart, SSE-KMS and SSE-C objects
+// are not digests of the content, so nil is returned for them.
+func etagMD5(etag string, sse types.ServerSideEncryption, customerAlgorithm *string) []byte {
+	if customerAlgorithm != nil || (sse != "" && sse != types.ServerSideEncryptionAes256) {
+		return nil
+	}
+
+	etag = strings.Trim(etag, `"`)
+	if len(etag) != 32 {
+		return nil
+	}
+
+	sum, err := hex.DecodeString(etag)
+	if err != nil {
+		return nil
+	}
+
+	return sum
+}
@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+func TestEtagMD5(t *testing.T) {
+	const digest = `"5d41402abc4b2a76b9719d911017c592"`
+
+	tests := []struct {
+		name              string
+		etag              string
+		sse               types.ServerSideEncryption
+		customerAlgorithm *string
+		wantNil           bool
+	}{
+		{name: "unencrypted", etag: digest},
+		{name: "SSE-S3", etag: digest, sse: types.ServerSideEncryptionAes256},
+		{name: "SSE-KMS", etag: digest, sse: types.ServerSideEncryptionAwsKms, wantNil: true},
+		{name: "DSSE-KMS", etag: digest, sse: types.ServerSideEncryptionAwsKmsDsse, wantNil: true},
+		{name: "SSE-C", etag: digest, customerAlgorithm: aws.String("AES256"), wantNil: true},
+		{name: "multipart", etag: `"5d41402abc4b2a76b9719d911017c592-2"`, wantNil: true},
+		{name: "not hex", etag: `"zz41402abc4b2a76b9719d911017c592"`, wantNil: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := etagMD5(tt.etag, tt.sse, tt.customerAlgorithm); (got == nil) != tt.wantNil {
+				t.Errorf("etagMD5(%s) = %x, want nil %v", tt.etag, got, tt.wantNil)
+			}
+		})
+	}
+}
+
+func TestStorageMD5(t *testing.T) {
+	tests := []struct {
+		name    string
+		sse     string
+		wantMD5 bool
+	}{
+		{name: "unencrypted", wantMD5: true},
+		{name: "SSE-KMS", sse: "aws:kms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "a.txt", "text/plain", []byte("hello"), nil)
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if tt.sse != "" {
+					w.Header().Set("x-amz-server-side-encryption", tt.sse)
+				}
+				return false
+			}
+			store, err := NewStorage(fake.service(), "bucket")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			info, err := store.Stat(context.Background(), "a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := md5.Sum([]byte("hello"))
+			if tt.wantMD5 && !bytes.Equal(info.MD5, sum[:]) {
+				t.Errorf("Stat MD5 = %x, want %x", info.MD5, sum)
+			}
+			if !tt.wantMD5 && info.MD5 != nil {
+				t.Errorf("Stat MD5 = %x, want nil", info.MD5)
+			}
+		})
+	}
+}
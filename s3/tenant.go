@@ -0,0 +1,268 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type Tenant struct {
+	ID          string
+	BucketName  string
+	Prefix      string
+	QuotaBytes  int64
+	Credentials aws.CredentialsProvider
+}
+
+// TenantStorage scopes every operation to a tenant's bucket and key prefix. Keys
+// passed in are relative to the tenant namespace and may never escape it.
+type TenantStorage struct {
+	tenant Tenant
+	svc    *s3Service
+
+	mu          sync.Mutex
+	usage       int64
+	usageLoaded bool
+	// reserved counts bytes of uploads still in progress.
+	reserved int64
+}
+
+func NewTenantStorage(region string, tenant Tenant, opts ...Option) (*TenantStorage, error) {
+	if tenant.ID == "" {
+		return nil, errors.New("tenant id is required")
+	}
+
+	if tenant.BucketName == "" {
+		return nil, errors.New("bucket name is required")
+	}
+
+	if tenant.Prefix != "" && !strings.HasSuffix(tenant.Prefix, "/") {
+		tenant.Prefix += "/"
+	}
+
+	if tenant.Credentials != nil {
+		opts = append(opts, WithCredentials(tenant.Credentials))
+	}
+
+	return &TenantStorage{
+		tenant: tenant,
+		svc:    NewS3Service(region, opts...).(*s3Service),
+	}, nil
+}
+
+// scopedKey maps a tenant-relative key onto the bucket, rejecting anything that
+// could resolve outside the tenant prefix.
+func (t *TenantStorage) scopedKey(key string) (string, error) {
+	if key == "" {
+		return "", errors.New("filename is required")
+	}
+
+	if strings.HasPrefix(key, "/") || strings.Contains(key, "\\") || strings.IndexFunc(key, unicode.IsControl) >= 0 {
+		return "", fmt.Errorf("%w: %s", ErrKeyOutsideNamespace, key)
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." || segment == "." || segment == "" {
+			return "", fmt.Errorf("%w: %s", ErrKeyOutsideNamespace, key)
+		}
+	}
+
+	return t.tenant.Prefix + key, nil
+}
+
+func (t *TenantStorage) checkBucket(bucketName string) error {
+	if bucketName != "" && bucketName != t.tenant.BucketName {
+		return fmt.Errorf("%w: bucket %s", ErrKeyOutsideNamespace, bucketName)
+	}
+
+	return nil
+}
+
+func (t *TenantStorage) UploadFile(data UploadFileRequest) (UploadFileResult, error) {
+	return t.UploadFileContext(t.svc.ctx, data)
+}
+
+// UploadFileContext is UploadFile bound to ctx. Uploads reserve quota as their
+// body is read, so concurrent uploads cannot together exceed QuotaBytes.
+func (t *TenantStorage) UploadFileContext(ctx context.Context, data UploadFileRequest) (UploadFileResult, error) {
+	if err := t.checkBucket(data.BucketName); err != nil {
+		return UploadFileResult{}, err
+	}
+
+	key, err := t.scopedKey(data.Filename)
+	if err != nil {
+		return UploadFileResult{}, err
+	}
+
+	relative := data.Filename
+	data.BucketName = t.tenant.BucketName
+	data.Filename = key
+	data.Tenant = t.tenant.ID
+
+	var counter *quotaReader
+	if t.tenant.QuotaBytes > 0 {
+		if _, err := t.Usage(ctx); err != nil {
+			return UploadFileResult{}, err
+		}
+
+		counter = &quotaReader{r: uploadBody(data), tenant: t}
+		data.Body, data.Base64Body, data.Base64Encoding = counter, nil, ""
+	}
+
+	result, err := t.svc.UploadFileContext(ctx, data)
+	if counter != nil {
+		t.settle(counter.read, err == nil)
+	}
+	if err != nil {
+		if counter != nil && counter.exceeded {
+			return UploadFileResult{}, ErrQuotaExceeded
+		}
+		return UploadFileResult{}, err
+	}
+
+	result.Filename = relative
+	return result, nil
+}
+
+func (t *TenantStorage) DeleteFile(data DeleteFileRequest) (BatchResult, error) {
+	return t.DeleteFileContext(t.svc.ctx, data)
+}
+
+func (t *TenantStorage) DeleteFileContext(ctx context.Context, data DeleteFileRequest) (BatchResult, error) {
+	if err := t.checkBucket(data.BucketName); err != nil {
+		return BatchResult{}, err
+	}
+
+	keys := make([]string, 0, len(data.Filename))
+	for _, filename := range data.Filename {
+		key, err := t.scopedKey(filename)
+		if err != nil {
+			return BatchResult{}, err
+		}
+		keys = append(keys, key)
+	}
+
+	data.BucketName = t.tenant.BucketName
+	data.Filename = keys
+
+	result, err := t.svc.DeleteFileContext(ctx, data)
+	for i := range result.Results {
+		result.Results[i].Key = strings.TrimPrefix(result.Results[i].Key, t.tenant.Prefix)
+	}
+
+	// Deleted sizes are not known here, so usage is recomputed on the next upload.
+	t.mu.Lock()
+	t.usageLoaded = false
+	t.mu.Unlock()
+
+	return result, err
+}
+
+func (t *TenantStorage) DownloadFile(data DownloadFileRequest) ([]byte, error) {
+	return t.DownloadFileContext(t.svc.ctx, data)
+}
+
+func (t *TenantStorage) DownloadFileContext(ctx context.Context, data DownloadFileRequest) ([]byte, error) {
+	if err := t.checkBucket(data.BucketName); err != nil {
+		return nil, err
+	}
+
+	key, err := t.scopedKey(data.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	data.BucketName = t.tenant.BucketName
+	data.Filename = key
+
+	return t.svc.DownloadFileContext(ctx, data)
+}
+
+// Usage returns the bytes stored under the tenant namespace. It lists the prefix
+// once and then tracks uploads made through this TenantStorage.
+func (t *TenantStorage) Usage(ctx context.Context) (int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.usageLoaded {
+		return t.usage, nil
+	}
+
+	var total int64
+	paginator := s3.NewListObjectsV2Paginator(t.svc.s3Cli, &s3.ListObjectsV2Input{
+		Bucket: aws.String(t.tenant.BucketName),
+		Prefix: aws.String(t.tenant.Prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			log.Printf("failed to compute usage of tenant %s: %v", t.tenant.ID, err)
+			return 0, err
+		}
+		for _, object := range page.Contents {
+			total += aws.ToInt64(object.Size)
+		}
+	}
+
+	t.usage, t.usageLoaded = total, true
+	return total, nil
+}
+
+// reserve claims n bytes of quota for an upload in progress, failing if the
+// stored and reserved bytes would exceed it.
+func (t *TenantStorage) reserve(n int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.usage+t.reserved+n > t.tenant.QuotaBytes {
+		return false
+	}
+	t.reserved += n
+	return true
+}
+
+// settle releases an upload's reservation, counting it as usage if the object
+// was stored and rolling it back otherwise.
+func (t *TenantStorage) settle(n int64, stored bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.reserved -= n
+	if stored {
+		t.usage += n
+	}
+}
+
+func (t *TenantStorage) Shutdown(ctx context.Context) error {
+	return t.svc.Shutdown(ctx)
+}
+
+// quotaReader reserves quota for each chunk it reads and fails the upload
+// stream as soon as the tenant's quota would be exceeded.
+type quotaReader struct {
+	r        io.Reader
+	tenant   *TenantStorage
+	read     int64
+	exceeded bool
+}
+
+func (q *quotaReader) Read(p []byte) (int, error) {
+	n, err := q.r.Read(p)
+	if n > 0 {
+		if !q.tenant.reserve(int64(n)) {
+			q.exceeded = true
+			return n, ErrQuotaExceeded
+		}
+		q.read += int64(n)
+	}
+
+	return n, err
+}
@@ -0,0 +1,95 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+func newFakeTenant(t *testing.T, fake *fakeS3, tenant Tenant, opts ...Option) *TenantStorage {
+	t.Helper()
+
+	opts = append([]Option{
+		WithEndpoint(fake.URL),
+		WithCredentials(credentials.NewStaticCredentialsProvider("test", "test", "")),
+	}, opts...)
+	storage, err := NewTenantStorage("us-east-1", tenant, opts...)
+	if err != nil {
+		t.Fatalf("NewTenantStorage: %v", err)
+	}
+
+	return storage
+}
+
+func TestTenantDeleteDryRun(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	tenant := newFakeTenant(t, fake, Tenant{ID: "acme", BucketName: "bucket", Prefix: "acme"})
+
+	_, err := tenant.UploadFile(UploadFileRequest{
+		Filename:    "a.txt",
+		ContentType: "text/plain",
+		Body:        io.NopCloser(strings.NewReader("data")),
+	})
+	if err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+
+	result, err := tenant.DeleteFile(DeleteFileRequest{Filename: []string{"a.txt"}, DryRun: true})
+	if err != nil {
+		t.Fatalf("DeleteFile: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Key != "a.txt" || result.Results[0].Status != WouldDelete {
+		t.Errorf("DeleteFile results = %+v, want a.txt %s", result.Results, WouldDelete)
+	}
+	if _, exists := fake.object("bucket", "acme/a.txt"); !exists {
+		t.Error("dry run deleted acme/a.txt")
+	}
+}
+
+func TestTenantQuotaCountsUploadsInProgress(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	tenant := newFakeTenant(t, fake, Tenant{ID: "acme", BucketName: "bucket", Prefix: "acme", QuotaBytes: 10})
+
+	body, writer := io.Pipe()
+	first := make(chan error, 1)
+	go func() {
+		_, err := tenant.UploadFile(UploadFileRequest{Filename: "a.txt", ContentType: "text/plain", Body: body})
+		first <- err
+	}()
+	if _, err := writer.Write([]byte("123456")); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(time.Millisecond) {
+		tenant.mu.Lock()
+		reserved := tenant.reserved
+		tenant.mu.Unlock()
+		if reserved == 6 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("reserved %d bytes, want 6", reserved)
+		}
+	}
+
+	_, err := tenant.UploadFile(UploadFileRequest{
+		Filename:    "b.txt",
+		ContentType: "text/plain",
+		Body:        io.NopCloser(strings.NewReader("123456")),
+	})
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("second upload = %v, want %v", err, ErrQuotaExceeded)
+	}
+
+	writer.Close()
+	if err := <-first; err != nil {
+		t.Fatalf("first upload: %v", err)
+	}
+	if used, err := tenant.Usage(context.Background()); err != nil || used != 6 {
+		t.Errorf("Usage = %d, %v, want 6", used, err)
+	}
+}
@@ -0,0 +1,149 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+const (
+	defaultMetadataTimeout = 10 * time.Second
+	defaultTransferTimeout = time.Minute
+	defaultOtherTimeout    = 30 * time.Second
+	defaultMinThroughput   = 1 << 20
+)
+
+// Timeouts bound S3 calls whose context has no deadline, so a hung connection
+// cannot stall a worker forever. Zero fields use the defaults.
+type Timeouts struct {
+	// Metadata covers small calls such as HEAD, DELETE, tagging and listing.
+	Metadata time.Duration
+	// Transfer is the base timeout of calls moving object data. Uploads of a
+	// known size (including every multipart part) get size/MinThroughput on
+	// top of it. For GetObject it covers the wait for the response headers,
+	// and then again reading the body, plus ContentLength/MinThroughput.
+	Transfer      time.Duration
+	MinThroughput int64
+	// Other covers every remaining operation.
+	Other time.Duration
+}
+
+var metadataOperations = map[string]bool{
+	"HeadObject":                       true,
+	"HeadBucket":                       true,
+	"DeleteObject":                     true,
+	"DeleteObjects":                    true,
+	"GetObjectTagging":                 true,
+	"PutObjectTagging":                 true,
+	"ListObjectsV2":                    true,
+	"ListObjectVersions":               true,
+	"ListMultipartUploads":             true,
+	"AbortMultipartUpload":             true,
+	"GetBucketAccelerateConfiguration": true,
+}
+
+var transferOperations = map[string]bool{
+	"PutObject":      true,
+	"UploadPart":     true,
+	"GetObject":      true,
+	"CopyObject":     true,
+	"UploadPartCopy": true,
+}
+
+func (t Timeouts) withDefaults() Timeouts {
+	if t.Metadata <= 0 {
+		t.Metadata = defaultMetadataTimeout
+	}
+
+	if t.Transfer <= 0 {
+		t.Transfer = defaultTransferTimeout
+	}
+
+	if t.MinThroughput <= 0 {
+		t.MinThroughput = defaultMinThroughput
+	}
+
+	if t.Other <= 0 {
+		t.Other = defaultOtherTimeout
+	}
+
+	return t
+}
+
+func (t Timeouts) forOperation(operation string, params any) time.Duration {
+	switch {
+	case metadataOperations[operation]:
+		return t.Metadata
+	case transferOperations[operation]:
+		var size int64
+		switch input := params.(type) {
+		case *s3.PutObjectInput:
+			size = aws.ToInt64(input.ContentLength)
+		case *s3.UploadPartInput:
+			size = aws.ToInt64(input.ContentLength)
+		}
+		return t.forSize(size)
+	default:
+		return t.Other
+	}
+}
+
+func (t Timeouts) forSize(size int64) time.Duration {
+	return t.Transfer + time.Duration(size/t.MinThroughput)*time.Second
+}
+
+func (s *s3Service) timeoutMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("FileUploaderTimeout", func(
+		ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler,
+	) (middleware.InitializeOutput, middleware.Metadata, error) {
+		operation := middleware.GetOperationName(ctx)
+		if _, ok := ctx.Deadline(); ok || operation == "SelectObjectContent" {
+			return next.HandleInitialize(ctx, in)
+		}
+
+		if operation == "GetObject" {
+			return s.getObjectTimeout(ctx, in, next)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, s.timeouts.forOperation(operation, in.Parameters))
+		defer cancel()
+		return next.HandleInitialize(ctx, in)
+	}), middleware.After)
+}
+
+// getObjectTimeout bounds the wait for the response headers, then gives the
+// body, which is read after the call returns, a deadline sized from its
+// ContentLength that is released when the body is closed.
+func (s *s3Service) getObjectTimeout(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	timer := time.AfterFunc(s.timeouts.Transfer, cancel)
+	stop := func() {
+		timer.Stop()
+		cancel()
+	}
+
+	out, metadata, err := next.HandleInitialize(ctx, in)
+	output, ok := out.Result.(*s3.GetObjectOutput)
+	if err != nil || !ok {
+		stop()
+		return out, metadata, err
+	}
+
+	timer.Reset(s.timeouts.forSize(aws.ToInt64(output.ContentLength)))
+	output.Body = &cancelOnClose{ReadCloser: output.Body, cancel: stop}
+	return out, metadata, err
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
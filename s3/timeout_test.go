@@ -0,0 +1,88 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestTimeoutsForOperation(t *testing.T) {
+	timeouts := Timeouts{Metadata: time.Second, Transfer: 10 * time.Second, MinThroughput: 1 << 20, Other: 5 * time.Second}
+
+	tests := []struct {
+		operation string
+		params    any
+		want      time.Duration
+	}{
+		{operation: "HeadObject", want: time.Second},
+		{operation: "PutObject", params: &s3.PutObjectInput{ContentLength: aws.Int64(30 << 20)}, want: 40 * time.Second},
+		{operation: "UploadPart", params: &s3.UploadPartInput{ContentLength: aws.Int64(5 << 20)}, want: 15 * time.Second},
+		{operation: "GetObject", params: &s3.GetObjectInput{}, want: 10 * time.Second},
+		{operation: "CreateBucket", want: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operation, func(t *testing.T) {
+			if got := timeouts.forOperation(tt.operation, tt.params); got != tt.want {
+				t.Errorf("forOperation(%s) = %v, want %v", tt.operation, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetObjectTimeout(t *testing.T) {
+	const chunk, chunks = 1000, 3
+
+	tests := []struct {
+		name string
+		// delay before the headers, and between body chunks
+		headerDelay, chunkDelay time.Duration
+		wantErr                 bool
+	}{
+		// The body takes longer than Transfer but stays within its size budget.
+		{name: "slow body", chunkDelay: 150 * time.Millisecond},
+		{name: "headers never arrive", headerDelay: 2 * time.Second, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				select {
+				case <-time.After(tt.headerDelay):
+				case <-r.Context().Done():
+					return true
+				}
+				w.Header().Set("Content-Length", strconv.Itoa(chunk*chunks))
+				w.WriteHeader(http.StatusOK)
+				for range chunks {
+					w.Write([]byte(strings.Repeat("x", chunk)))
+					w.(http.Flusher).Flush()
+					time.Sleep(tt.chunkDelay)
+				}
+				return true
+			}
+			svc := fake.service(WithTimeouts(Timeouts{Transfer: 200 * time.Millisecond, MinThroughput: 1000})).(*s3Service)
+
+			body, err := func() ([]byte, error) {
+				output, err := svc.s3Cli.GetObject(context.Background(), &s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("a")})
+				if err != nil {
+					return nil, err
+				}
+				defer output.Body.Close()
+				return io.ReadAll(output.Body)
+			}()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetObject error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(body) != chunk*chunks {
+				t.Errorf("read %d bytes, want %d", len(body), chunk*chunks)
+			}
+		})
+	}
+}
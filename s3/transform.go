@@ -0,0 +1,101 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"slices"
+)
+
+// Transformer is one stage of the upload or download pipeline, e.g.
+// compression, encryption or format conversion. It returns the transformed
+// stream and its content type, which may differ from the input's.
+type Transformer interface {
+	Transform(ctx context.Context, contentType string, r io.Reader) (io.Reader, string, error)
+}
+
+type TransformerFunc func(ctx context.Context, contentType string, r io.Reader) (io.Reader, string, error)
+
+func (f TransformerFunc) Transform(ctx context.Context, contentType string, r io.Reader) (io.Reader, string, error) {
+	return f(ctx, contentType, r)
+}
+
+// applyTransformers chains the stages over r. The returned func closes every
+// stage output that is an io.Closer, which stops stages such as Gzip whose
+// goroutines would otherwise block forever once nothing reads their output;
+// it must be called once the result is no longer read.
+func applyTransformers(ctx context.Context, contentType string, r io.Reader, stages ...[]Transformer) (io.Reader, string, func(), error) {
+	var outputs []io.Closer
+	closeOutputs := func() {
+		for _, output := range slices.Backward(outputs) {
+			output.Close()
+		}
+	}
+
+	for _, transformers := range stages {
+		for _, transformer := range transformers {
+			var err error
+			r, contentType, err = transformer.Transform(ctx, contentType, r)
+			if err != nil {
+				closeOutputs()
+				return nil, "", nil, err
+			}
+			if output, ok := r.(io.Closer); ok {
+				outputs = append(outputs, output)
+			}
+		}
+	}
+
+	return r, contentType, closeOutputs, nil
+}
+
+// transformDownload runs the service's download transformers, then the
+// request's; uploads run them the other way round, so per-request stages sit
+// inside service-wide ones such as encryption.
+func (s *s3Service) transformDownload(ctx context.Context, data DownloadFileRequest, body []byte) ([]byte, error) {
+	if len(s.downloadTransformers) == 0 && len(data.Transformers) == 0 {
+		return body, nil
+	}
+
+	r, _, closeTransformers, err := applyTransformers(ctx, "", bytes.NewReader(body), s.downloadTransformers, data.Transformers)
+	if err != nil {
+		return nil, err
+	}
+	defer closeTransformers()
+
+	return io.ReadAll(r)
+}
+
+// Gzip compresses the stream and keeps the content type, so it is meant to be
+// paired with Gunzip on download.
+func Gzip(level int) Transformer {
+	return TransformerFunc(func(_ context.Context, contentType string, r io.Reader) (io.Reader, string, error) {
+		pr, pw := io.Pipe()
+		zw, err := gzip.NewWriterLevel(pw, level)
+		if err != nil {
+			return nil, "", err
+		}
+
+		go func() {
+			_, err := io.Copy(zw, r)
+			if closeErr := zw.Close(); err == nil {
+				err = closeErr
+			}
+			pw.CloseWithError(err)
+		}()
+
+		return pr, contentType, nil
+	})
+}
+
+func Gunzip() Transformer {
+	return TransformerFunc(func(_ context.Context, contentType string, r io.Reader) (io.Reader, string, error) {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, "", err
+		}
+
+		return zr, contentType, nil
+	})
+}
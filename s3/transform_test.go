@@ -0,0 +1,181 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// pipeTransformer copies its input through a pipe in a goroutine, like Gzip,
+// and reports when that goroutine has returned.
+type pipeTransformer struct {
+	started atomic.Int32
+	done    chan struct{}
+}
+
+func newPipeTransformer() *pipeTransformer {
+	return &pipeTransformer{done: make(chan struct{})}
+}
+
+func (p *pipeTransformer) Transform(_ context.Context, contentType string, r io.Reader) (io.Reader, string, error) {
+	p.started.Add(1)
+	pr, pw := io.Pipe()
+	go func() {
+		defer close(p.done)
+		_, err := io.Copy(pw, r)
+		pw.CloseWithError(err)
+	}()
+	return pr, contentType, nil
+}
+
+func TestUploadTransformersStopOnFailure(t *testing.T) {
+	// Larger than a part, so the upload fails while the stage still has
+	// bytes left to write.
+	const size = 11 << 20
+
+	tests := []struct {
+		name        string
+		fail        func(r *http.Request) bool
+		wantStarted bool
+	}{
+		{
+			name:        "bucket cannot be created",
+			fail:        func(r *http.Request) bool { return strings.TrimSuffix(r.URL.Path, "/") == "/missing" },
+			wantStarted: false,
+		},
+		{
+			name:        "upload rejected",
+			fail:        func(r *http.Request) bool { return r.Method == http.MethodPost || r.Method == http.MethodPut },
+			wantStarted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if !tt.fail(r) {
+					return false
+				}
+				fakeError(w, http.StatusForbidden, "AccessDenied")
+				return true
+			}
+			stage := newPipeTransformer()
+			svc := fake.service(WithUploadTransformers(stage))
+
+			bucketName := "bucket"
+			if !tt.wantStarted {
+				bucketName = "missing"
+			}
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  bucketName,
+				Filename:    "a.bin",
+				ContentType: "application/octet-stream",
+				Body:        io.NopCloser(io.LimitReader(zeros{}, size)),
+			})
+			if err == nil {
+				t.Fatal("UploadFile succeeded, want error")
+			}
+
+			if started := stage.started.Load() > 0; started != tt.wantStarted {
+				t.Fatalf("transformer started = %v, want %v", started, tt.wantStarted)
+			}
+			if !tt.wantStarted {
+				return
+			}
+			select {
+			case <-stage.done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("transformer goroutine still blocked after UploadFile returned")
+			}
+		})
+	}
+}
+
+func TestGzipRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "empty"},
+		{name: "text", body: "hello world"},
+		{name: "repetitive", body: strings.Repeat("abc", 100_000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compressed, _, closeUpload, err := applyTransformers(context.Background(), "text/plain", strings.NewReader(tt.body), []Transformer{Gzip(6)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeUpload()
+			data, err := io.ReadAll(compressed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			svc := &s3Service{downloadTransformers: []Transformer{Gunzip()}}
+			got, err := svc.transformDownload(context.Background(), DownloadFileRequest{}, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, []byte(tt.body)) {
+				t.Errorf("round trip returned %d bytes, want %d", len(got), len(tt.body))
+			}
+		})
+	}
+}
+
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// recordingEnricher keeps the content type and body it was given.
+type recordingEnricher struct {
+	contentType string
+	body        []byte
+}
+
+func (e *recordingEnricher) Enrich(contentType string, r io.Reader) (Enrichment, error) {
+	body, err := io.ReadAll(r)
+	e.contentType, e.body = contentType, body
+	return Enrichment{Text: string(body)}, err
+}
+
+func TestUploadInspectsBodyBeforeTransformers(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	enricher := &recordingEnricher{}
+	indexer := newFakeIndexer()
+	upper := TransformerFunc(func(_ context.Context, _ string, r io.Reader) (io.Reader, string, error) {
+		body, err := io.ReadAll(r)
+		return bytes.NewReader(bytes.ToUpper(body)), "application/x-upper", err
+	})
+	svc := fake.service(WithEnrichment(enricher, false), WithIndexer(indexer), WithUploadTransformers(upper))
+
+	_, err := svc.UploadFile(UploadFileRequest{
+		BucketName:  "bucket",
+		Filename:    "a.txt",
+		ContentType: "text/plain",
+		Body:        io.NopCloser(strings.NewReader("hello")),
+	})
+	if err != nil {
+		t.Fatalf("UploadFile: %v", err)
+	}
+
+	if enricher.contentType != "text/plain" || string(enricher.body) != "hello" {
+		t.Errorf("enricher saw %q %q, want the untransformed upload", enricher.contentType, enricher.body)
+	}
+	o, ok := fake.object("bucket", "a.txt")
+	if !ok || o.contentType != "application/x-upper" || string(o.body) != "HELLO" {
+		t.Fatalf("stored object = %+v, want the transformed body", o)
+	}
+	if doc, _ := indexer.doc("bucket", "a.txt"); doc.ContentType != "application/x-upper" || doc.Text != "hello" {
+		t.Errorf("indexed %q %q, want the stored content type and the enriched text", doc.ContentType, doc.Text)
+	}
+}
@@ -0,0 +1,52 @@
+package s3
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// TransportOptions tune the HTTP transport under the S3 client. The SDK
+// defaults cap idle connections per host low enough to throttle highly
+// concurrent batch uploads. Zero fields keep the SDK defaults.
+type TransportOptions struct {
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	TLSConfig           *tls.Config
+	Proxy               func(*http.Request) (*url.URL, error)
+	DisableHTTP2        bool
+}
+
+// NewHTTPClient builds an HTTP client from opts. Pass the same client to several
+// services with WithHTTPClient to have them share one connection pool.
+func NewHTTPClient(opts TransportOptions) *awshttp.BuildableClient {
+	return awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		if opts.MaxIdleConns > 0 {
+			tr.MaxIdleConns = opts.MaxIdleConns
+		}
+		if opts.MaxIdleConnsPerHost > 0 {
+			tr.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+		}
+		if opts.MaxConnsPerHost > 0 {
+			tr.MaxConnsPerHost = opts.MaxConnsPerHost
+		}
+		if opts.IdleConnTimeout > 0 {
+			tr.IdleConnTimeout = opts.IdleConnTimeout
+		}
+		if opts.TLSConfig != nil {
+			tr.TLSClientConfig = opts.TLSConfig
+		}
+		if opts.Proxy != nil {
+			tr.Proxy = opts.Proxy
+		}
+		if opts.DisableHTTP2 {
+			tr.ForceAttemptHTTP2 = false
+			tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		}
+	})
+}
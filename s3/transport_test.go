@@ -0,0 +1,82 @@
+package s3
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	defaults := awshttp.NewBuildableClient().GetTransport()
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS13}
+	tr := NewHTTPClient(TransportOptions{
+		MaxIdleConns:        500,
+		MaxIdleConnsPerHost: 100,
+		MaxConnsPerHost:     200,
+		IdleConnTimeout:     time.Minute,
+		TLSConfig:           tlsConfig,
+		DisableHTTP2:        true,
+	}).GetTransport()
+
+	if tr.MaxIdleConns != 500 || tr.MaxIdleConnsPerHost != 100 || tr.MaxConnsPerHost != 200 || tr.IdleConnTimeout != time.Minute {
+		t.Errorf("pool limits = %d, %d, %d, %v, want those configured", tr.MaxIdleConns, tr.MaxIdleConnsPerHost, tr.MaxConnsPerHost, tr.IdleConnTimeout)
+	}
+	if tr.TLSClientConfig == nil || tr.TLSClientConfig.MinVersion != tls.VersionTLS13 {
+		t.Error("TLS config not applied")
+	}
+	if tr.ForceAttemptHTTP2 || tr.TLSNextProto == nil || len(tr.TLSNextProto) != 0 {
+		t.Error("HTTP/2 still enabled")
+	}
+
+	unchanged := NewHTTPClient(TransportOptions{}).GetTransport()
+	if unchanged.MaxIdleConns != defaults.MaxIdleConns || unchanged.MaxIdleConnsPerHost != defaults.MaxIdleConnsPerHost || unchanged.IdleConnTimeout != defaults.IdleConnTimeout || !unchanged.ForceAttemptHTTP2 {
+		t.Error("zero options changed the SDK defaults")
+	}
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	errProxy := errors.New("no proxy")
+	tests := []struct {
+		name    string
+		proxy   func(*http.Request) (*url.URL, error)
+		wantErr bool
+	}{
+		{name: "direct", proxy: func(*http.Request) (*url.URL, error) { return nil, nil }},
+		{name: "proxy fails", proxy: func(*http.Request) (*url.URL, error) { return nil, errProxy }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var proxied atomic.Int32
+			client := NewHTTPClient(TransportOptions{Proxy: func(r *http.Request) (*url.URL, error) {
+				proxied.Add(1)
+				return tt.proxy(r)
+			}})
+			fake := newFakeS3(t, "bucket")
+			svc := fake.service(WithHTTPClient(client))
+
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.txt",
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader("a")),
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UploadFile error = %v, want error: %v", err, tt.wantErr)
+			}
+			if proxied.Load() == 0 {
+				t.Error("requests did not go through the configured transport")
+			}
+			if _, stored := fake.object("bucket", "a.txt"); stored == tt.wantErr {
+				t.Errorf("stored = %v, want %v", stored, !tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+const maxDeleteObjects = 1000
+
+// moveToTrash moves keys into the trash prefix and returns the keys it moved
+// and those already trashed, which the caller deletes permanently.
+func (s *s3Service) moveToTrash(ctx context.Context, bucketName string, keys []string, result *BatchResult) (trashed, permanent []string) {
+	for _, key := range keys {
+		if strings.HasPrefix(key, s.trashPrefix) {
+			permanent = append(permanent, key)
+			continue
+		}
+
+		if err := s.moveObject(ctx, bucketName, key, s.trashPrefix+key); err != nil {
+			result.Results = append(result.Results, keyFailure(key, err))
+			continue
+		}
+
+		trashed = append(trashed, key)
+		result.Results = append(result.Results, KeyResult{Key: key, Status: Trashed})
+	}
+
+	return trashed, permanent
+}
+
+// RestoreFromTrash undoes DeleteFile for the given keys, either by moving them
+// back out of the trash prefix or by removing their latest delete marker.
+func (s *s3Service) RestoreFromTrash(ctx context.Context, data DeleteFileRequest) (BatchResult, error) {
+	if err := s.acquire(); err != nil {
+		return BatchResult{}, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if s.trashPrefix == "" && !s.versionedTrash {
+		return BatchResult{}, ErrTrashNotConfigured
+	}
+
+	if err := s.validateDeleteFile(data); err != nil {
+		return BatchResult{}, err
+	}
+
+	// Restoring writes the key back, so it needs the permission an upload does.
+	for _, key := range data.Filename {
+		action := authz.Action{Permission: authz.PermissionUpload, BucketName: data.BucketName, Filename: key}
+		if err := s.authorize(ctx, data.Principal, action); err != nil {
+			return BatchResult{}, err
+		}
+	}
+
+	result := BatchResult{Results: make([]KeyResult, 0, len(data.Filename))}
+	for _, key := range data.Filename {
+		if s.trashPrefix != "" {
+			result.Results = append(result.Results, s.restoreTrashed(ctx, data.BucketName, key))
+		} else {
+			result.Results = append(result.Results, s.removeDeleteMarker(ctx, data.BucketName, key))
+		}
+	}
+
+	return result, result.Err()
+}
+
+func (s *s3Service) restoreTrashed(ctx context.Context, bucketName, key string) KeyResult {
+	isExist, err := s.isFileExist(ctx, bucketName, s.trashPrefix+key)
+	if err != nil {
+		return keyFailure(key, err)
+	}
+
+	if !isExist {
+		return KeyResult{Key: key, Status: DeleteNotFound, Err: ErrFileNotFound}
+	}
+
+	isExist, err = s.isFileExist(ctx, bucketName, key)
+	if err != nil {
+		return keyFailure(key, err)
+	}
+
+	if isExist {
+		return KeyResult{Key: key, Status: DeleteFailed, Err: fmt.Errorf("%s: file already exist", key)}
+	}
+
+	if err := s.moveObject(ctx, bucketName, s.trashPrefix+key, key); err != nil {
+		return keyFailure(key, err)
+	}
+
+	return KeyResult{Key: key, Status: Restored}
+}
+
+// removeDeleteMarker deletes the latest delete marker of key. The prefix also
+// matches longer keys and a key may have many versions, so the listing is paged
+// until it moves past key.
+func (s *s3Service) removeDeleteMarker(ctx context.Context, bucketName, key string) KeyResult {
+	paginator := s3.NewListObjectVersionsPaginator(s.s3Cli, &s3.ListObjectVersionsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(key),
+	})
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return keyFailure(key, err)
+		}
+
+		for _, marker := range output.DeleteMarkers {
+			if aws.ToString(marker.Key) != key || !aws.ToBool(marker.IsLatest) {
+				continue
+			}
+
+			_, err := s.s3Cli.DeleteObject(ctx, &s3.DeleteObjectInput{
+				Bucket:    aws.String(bucketName),
+				Key:       aws.String(key),
+				VersionId: marker.VersionId,
+			})
+			if err != nil {
+				return keyFailure(key, err)
+			}
+
+			return KeyResult{Key: key, Status: Restored}
+		}
+
+		// Versions are listed in key order, so later pages only hold longer keys.
+		if aws.ToString(output.NextKeyMarker) > key {
+			break
+		}
+	}
+
+	return KeyResult{Key: key, Status: DeleteNotFound, Err: ErrFileNotFound}
+}
+
+// EmptyTrash permanently deletes objects trashed more than olderThan ago and
+// returns how many objects or versions were removed.
+func (s *s3Service) EmptyTrash(ctx context.Context, bucketName string, olderThan time.Duration) (int, error) {
+	if err := s.acquire(); err != nil {
+		return 0, err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if s.trashPrefix == "" && !s.versionedTrash {
+		return 0, ErrTrashNotConfigured
+	}
+
+	if bucketName == "" {
+		return 0, errors.New("bucket name is required")
+	}
+
+	// The trash prefix ends in "/", so a grant covering it covers every trashed key.
+	if err := s.authorizeKeys(ctx, authz.PermissionDelete, bucketName, s.trashPrefix); err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	if s.trashPrefix != "" {
+		return s.emptyTrashPrefix(ctx, bucketName, cutoff)
+	}
+
+	return s.emptyDeleteMarkers(ctx, bucketName, cutoff)
+}
+
+func (s *s3Service) emptyTrashPrefix(ctx context.Context, bucketName string, cutoff time.Time) (int, error) {
+	objects := []types.ObjectIdentifier{}
+	// A copy gets a fresh LastModified, so it records when the file was trashed.
+	files := s.ListFiles(ctx, ListFilesRequest{BucketName: bucketName, Prefix: s.trashPrefix})
+	for file := range files.All() {
+		if file.LastModified.Before(cutoff) {
+			objects = append(objects, types.ObjectIdentifier{Key: aws.String(file.Key)})
+		}
+	}
+	if err := files.Err(); err != nil {
+		return 0, err
+	}
+
+	return s.purge(ctx, bucketName, objects)
+}
+
+// emptyDeleteMarkers removes every version of keys whose latest version is a
+// delete marker older than cutoff.
+func (s *s3Service) emptyDeleteMarkers(ctx context.Context, bucketName string, cutoff time.Time) (int, error) {
+	versions := map[string][]types.ObjectIdentifier{}
+	expired := []string{}
+
+	it := s.ListFileVersions(ctx, ListFilesRequest{BucketName: bucketName})
+	for version := range it.All() {
+		versions[version.Key] = append(versions[version.Key], types.ObjectIdentifier{
+			Key:       aws.String(version.Key),
+			VersionId: aws.String(version.VersionID),
+		})
+
+		if version.DeleteMarker && version.IsLatest && version.LastModified.Before(cutoff) {
+			expired = append(expired, version.Key)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return 0, err
+	}
+
+	objects := []types.ObjectIdentifier{}
+	for _, key := range expired {
+		objects = append(objects, versions[key]...)
+	}
+
+	return s.purge(ctx, bucketName, objects)
+}
+
+// purge permanently deletes objects through the bulk delete path and returns
+// how many were removed.
+func (s *s3Service) purge(ctx context.Context, bucketName string, objects []types.ObjectIdentifier) (int, error) {
+	result := BatchResult{}
+	deleted := s.deleteObjects(ctx, bucketName, objects, &result)
+	return len(deleted), result.Err()
+}
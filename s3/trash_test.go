@@ -0,0 +1,148 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeleteFileTrash(t *testing.T) {
+	tests := []struct {
+		name     string
+		key      string
+		wantKeys []string
+	}{
+		{name: "moved to trash", key: "a.txt", wantKeys: []string{"trash/a.txt"}},
+		{name: "already trashed", key: "trash/a.txt", wantKeys: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			indexer, catalog := newFakeIndexer(), newFakeCatalog()
+			svc := fake.service(WithTrash("trash/"), WithIndexer(indexer), WithCatalog(catalog))
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    tt.key,
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader("hello")),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := svc.DeleteFile(DeleteFileRequest{BucketName: "bucket", Filename: []string{tt.key}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := fake.keys("bucket"); !slices.Equal(got, tt.wantKeys) {
+				t.Errorf("bucket holds %v, want %v", got, tt.wantKeys)
+			}
+			if _, ok := indexer.doc("bucket", tt.key); ok {
+				t.Errorf("%s is still indexed", tt.key)
+			}
+			if _, ok := catalog.entry("bucket", tt.key); ok {
+				t.Errorf("%s is still cataloged", tt.key)
+			}
+		})
+	}
+}
+
+func TestDeleteFileTrashMissingKey(t *testing.T) {
+	fake := newFakeS3(t, "bucket")
+	svc := fake.service(WithTrash("trash/"))
+
+	result, err := svc.DeleteFile(DeleteFileRequest{BucketName: "bucket", Filename: []string{"missing.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Status != DeleteNotFound {
+		t.Errorf("DeleteFile results = %+v, want missing.txt %s", result.Results, DeleteNotFound)
+	}
+}
+
+func TestEmptyTrashRetriesThrottled(t *testing.T) {
+	tests := []struct {
+		name      string
+		throttled int32
+	}{
+		{name: "not throttled"},
+		{name: "throttled once", throttled: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			fake.put("bucket", "trash/a.txt", "text/plain", []byte("a"), nil)
+			fake.put("bucket", "trash/b.txt", "text/plain", []byte("b"), nil)
+			fake.put("bucket", "kept.txt", "text/plain", []byte("c"), nil)
+
+			var calls atomic.Int32
+			fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if r.Method != http.MethodPost || !r.URL.Query().Has("delete") || calls.Add(1) > tt.throttled {
+					return false
+				}
+				fakeError(w, http.StatusServiceUnavailable, "SlowDown")
+				return true
+			}
+			svc := fake.service(WithTrash("trash/"), WithBulkDelete(BulkDeleteOptions{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+
+			removed, err := svc.EmptyTrash(context.Background(), "bucket", -time.Hour)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if removed != 2 {
+				t.Errorf("EmptyTrash removed %d, want 2", removed)
+			}
+			if got := fake.keys("bucket"); !slices.Equal(got, []string{"kept.txt"}) {
+				t.Errorf("bucket holds %v, want [kept.txt]", got)
+			}
+		})
+	}
+}
+
+func TestRestoreFromVersionedTrashPagesVersions(t *testing.T) {
+	pages := map[string]string{
+		"": `<ListVersionsResult><Name>bucket</Name><Prefix>a.txt</Prefix><IsTruncated>true</IsTruncated>` +
+			`<NextKeyMarker>a.txt</NextKeyMarker><NextVersionIdMarker>v2</NextVersionIdMarker>` +
+			`<Version><Key>a.txt</Key><VersionId>v3</VersionId><IsLatest>false</IsLatest></Version>` +
+			`<Version><Key>a.txt</Key><VersionId>v2</VersionId><IsLatest>false</IsLatest></Version>` +
+			`</ListVersionsResult>`,
+		"a.txt": `<ListVersionsResult><Name>bucket</Name><Prefix>a.txt</Prefix><IsTruncated>false</IsTruncated>` +
+			`<DeleteMarker><Key>a.txt.bak</Key><VersionId>m1</VersionId><IsLatest>true</IsLatest></DeleteMarker>` +
+			`<DeleteMarker><Key>a.txt</Key><VersionId>m4</VersionId><IsLatest>true</IsLatest></DeleteMarker>` +
+			`</ListVersionsResult>`,
+	}
+
+	fake := newFakeS3(t, "bucket")
+	var deleted []string
+	fake.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		query := r.URL.Query()
+		switch {
+		case r.Method == http.MethodGet && query.Has("versions"):
+			w.Header().Set("Content-Type", "application/xml")
+			io.WriteString(w, pages[query.Get("key-marker")])
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, strings.TrimPrefix(r.URL.Path, "/bucket/")+"@"+query.Get("versionId"))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			return false
+		}
+		return true
+	}
+	svc := fake.service(WithVersionedTrash())
+
+	result, err := svc.RestoreFromTrash(context.Background(), DeleteFileRequest{BucketName: "bucket", Filename: []string{"a.txt"}})
+	if err != nil {
+		t.Fatalf("RestoreFromTrash: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].Status != Restored {
+		t.Errorf("results = %+v, want a.txt %s", result.Results, Restored)
+	}
+	if want := []string{"a.txt@m4"}; !slices.Equal(deleted, want) {
+		t.Errorf("deleted %v, want %v", deleted, want)
+	}
+}
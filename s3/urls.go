@@ -0,0 +1,76 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// URLBuilder renders the public URL of an object. It is used for upload
+// results and listings when configured with WithURLBuilder.
+type URLBuilder func(ctx context.Context, bucketName, key string) (string, error)
+
+// VirtualHostedURL and PathStyleURL address access points and Multi-Region
+// Access Points through their own hosts.
+func VirtualHostedURL(region string) URLBuilder {
+	return func(_ context.Context, bucketName, key string) (string, error) {
+		if host := accessPointHost(bucketName); host != "" {
+			return fmt.Sprintf("https://%s/%s", host, escapeKey(key)), nil
+		}
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, region, escapeKey(key)), nil
+	}
+}
+
+func PathStyleURL(region string) URLBuilder {
+	return func(_ context.Context, bucketName, key string) (string, error) {
+		if host := accessPointHost(bucketName); host != "" {
+			return fmt.Sprintf("https://%s/%s", host, escapeKey(key)), nil
+		}
+		return fmt.Sprintf("https://s3.%s.amazonaws.com/%s/%s", region, bucketName, escapeKey(key)), nil
+	}
+}
+
+// CustomDomainURL serves objects from a CDN or custom domain that maps the
+// bucket root onto baseURL, e.g. "https://cdn.example.com/assets".
+func CustomDomainURL(baseURL string) URLBuilder {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	return func(_ context.Context, _, key string) (string, error) {
+		return baseURL + "/" + escapeKey(key), nil
+	}
+}
+
+func (s *s3Service) presignedURL(expires time.Duration) URLBuilder {
+	return func(ctx context.Context, bucketName, key string) (string, error) {
+		request, err := s3.NewPresignClient(s.s3Cli).PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+		}, s3.WithPresignExpires(expires))
+		if err != nil {
+			return "", err
+		}
+
+		return request.URL, nil
+	}
+}
+
+func (s *s3Service) objectURL(ctx context.Context, bucketName, key, fallback string) (string, error) {
+	if s.urlBuilder == nil {
+		return fallback, nil
+	}
+
+	return s.urlBuilder(ctx, s.bucketNames.Name(bucketName), key)
+}
+
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,84 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestURLBuilders(t *testing.T) {
+	accessPoint := "arn:aws:s3:eu-west-1:123456789012:accesspoint/photos"
+	tests := []struct {
+		name    string
+		builder URLBuilder
+		bucket  string
+		key     string
+		want    string
+	}{
+		{name: "virtual hosted", builder: VirtualHostedURL("eu-west-1"), bucket: "bucket", key: "a b/c?.txt", want: "https://bucket.s3.eu-west-1.amazonaws.com/a%20b/c%3F.txt"},
+		{name: "path style", builder: PathStyleURL("eu-west-1"), bucket: "bucket", key: "a/b.txt", want: "https://s3.eu-west-1.amazonaws.com/bucket/a/b.txt"},
+		{name: "access point", builder: VirtualHostedURL("us-east-1"), bucket: accessPoint, key: "a.txt", want: "https://photos-123456789012.s3-accesspoint.eu-west-1.amazonaws.com/a.txt"},
+		{name: "path style access point", builder: PathStyleURL("us-east-1"), bucket: accessPoint, key: "a.txt", want: "https://photos-123456789012.s3-accesspoint.eu-west-1.amazonaws.com/a.txt"},
+		{name: "multi-region access point", builder: VirtualHostedURL("us-east-1"), bucket: "alias.mrap", key: "a.txt", want: "https://alias.mrap.accesspoint.s3-global.amazonaws.com/a.txt"},
+		{name: "custom domain", builder: CustomDomainURL("https://cdn.example.com/assets/"), bucket: "bucket", key: "a/b c.txt", want: "https://cdn.example.com/assets/a/b%20c.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.builder(context.Background(), tt.bucket, tt.key)
+			if err != nil || got != tt.want {
+				t.Errorf("URL = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUploadURL(t *testing.T) {
+	errBuild := errors.New("no url")
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantPrefix  string
+		wantExpires string
+		wantErr     bool
+	}{
+		{name: "location from S3", wantPrefix: "http://"},
+		{name: "custom domain", opts: []Option{WithURLBuilder(CustomDomainURL("https://cdn.example.com"))}, wantPrefix: "https://cdn.example.com/a.txt"},
+		{name: "presigned", opts: []Option{WithPresignedURLs(time.Minute)}, wantPrefix: "http://", wantExpires: "60"},
+		{name: "builder fails", opts: []Option{WithUploadGuarantee(), WithURLBuilder(func(context.Context, string, string) (string, error) { return "", errBuild })}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			svc := fake.service(tt.opts...)
+
+			result, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.txt",
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader("a")),
+			})
+			if tt.wantErr {
+				if !errors.Is(err, errBuild) {
+					t.Errorf("UploadFile error = %v, want %v", err, errBuild)
+				}
+				if _, ok := fake.object("bucket", "a.txt"); ok {
+					t.Error("upload kept although its URL could not be built")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UploadFile: %v", err)
+			}
+			if !strings.HasPrefix(result.Location, tt.wantPrefix) {
+				t.Errorf("Location = %q, want it to start with %q", result.Location, tt.wantPrefix)
+			}
+			if location, err := url.Parse(result.Location); err != nil || location.Query().Get("X-Amz-Expires") != tt.wantExpires {
+				t.Errorf("Location = %q, want it presigned for %q seconds", result.Location, tt.wantExpires)
+			}
+		})
+	}
+}
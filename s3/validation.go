@@ -1,63 +1,317 @@
 package s3
 
 import (
-	"errors"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
 )
 
-func (s *s3Service) validateUploadFile(data UploadFileRequest) error {
-	if data.Filename == "" {
-		return errors.New("filename is required")
+// Arguments of validators whose entry points take loose parameters.
+type (
+	httpUpload struct {
+		request *http.Request
+		opts    RequestUploadOptions
 	}
 
-	if data.Base64Encoding == "" {
-		return errors.New("base64Encoding is required")
+	remoteUpload struct {
+		sourceURL, bucketName, key string
+		opts                       URLUploadOptions
 	}
 
-	if data.BucketName == "" {
-		return errors.New("bucket name is required")
+	ownerListing struct {
+		bucketName, ownerID string
 	}
 
-	_, err := mime.ExtensionsByType(data.ContentType)
-	if err != nil {
+	exportTarget struct {
+		bucketName, localDir string
+	}
+
+	rename struct {
+		bucketName, oldKey, newKey string
+		opts                       RenameOptions
+	}
+
+	objectKey struct {
+		bucketName, key string
+	}
+)
+
+var (
+	uploadFileRules = Rules[UploadFileRequest]{
+		Required("Filename", "filename", func(d UploadFileRequest) string { return d.Filename }),
+		KeyFormat("Filename", func(d UploadFileRequest) string { return d.Filename }),
+		Check("Body", "base64Encoding, base64Body or body is required", func(d UploadFileRequest) bool {
+			return d.Base64Encoding != "" || d.Body != nil || d.Base64Body != nil
+		}),
+		Required("BucketName", "bucket name", func(d UploadFileRequest) string { return d.BucketName }),
+		ValidContentType("ContentType", func(d UploadFileRequest) string { return d.ContentType }),
+	}
+
+	deleteFileRules = Rules[DeleteFileRequest]{
+		Check("Filename", "filename is required", func(d DeleteFileRequest) bool { return len(d.Filename) > 0 }),
+		Required("BucketName", "bucket name", func(d DeleteFileRequest) string { return d.BucketName }),
+	}
+
+	downloadFileRules = Rules[DownloadFileRequest]{
+		Required("BucketName", "bucket name", func(d DownloadFileRequest) string { return d.BucketName }),
+		Required("Filename", "filename", func(d DownloadFileRequest) string { return d.Filename }),
+	}
+
+	abortStaleUploadsRules = Rules[AbortStaleUploadsRequest]{
+		Required("BucketName", "bucket name", func(d AbortStaleUploadsRequest) string { return d.BucketName }),
+		Check("OlderThan", "older than must be greater than zero", func(d AbortStaleUploadsRequest) bool { return d.OlderThan > 0 }),
+	}
+
+	postPolicyRules = Rules[PostPolicyRequest]{
+		Required("BucketName", "bucket name", func(d PostPolicyRequest) string { return d.BucketName }),
+		Check("Filename", "filename or key prefix is required", func(d PostPolicyRequest) bool {
+			return d.Filename != "" || d.KeyPrefix != ""
+		}),
+		KeyFormat("Filename", func(d PostPolicyRequest) string { return d.Filename }),
+		Check("MaxSize", "invalid size range", func(d PostPolicyRequest) bool {
+			return d.MaxSize >= 0 && d.MinSize >= 0 && (d.MaxSize == 0 || d.MinSize <= d.MaxSize)
+		}),
+		Check("Expires", "expires must be between 0 and 7 days", func(d PostPolicyRequest) bool {
+			return d.Expires >= 0 && d.Expires <= 7*24*time.Hour
+		}),
+	}
+
+	collectOrphansRules = Rules[OrphanGCRequest]{
+		Required("BucketName", "bucket name", func(d OrphanGCRequest) string { return d.BucketName }),
+		Check("MinAge", "min age must not be negative", func(d OrphanGCRequest) bool { return d.MinAge >= 0 }),
+	}
+
+	presignedMultipartRules = Rules[PresignedMultipartRequest]{
+		Required("BucketName", "bucket name", func(d PresignedMultipartRequest) string { return d.BucketName }),
+		Required("Filename", "filename", func(d PresignedMultipartRequest) string { return d.Filename }),
+		KeyFormat("Filename", func(d PresignedMultipartRequest) string { return d.Filename }),
+		Check("Size", "size must be greater than zero", func(d PresignedMultipartRequest) bool { return d.Size > 0 }),
+		Check("PartSize", "part size must be between 5 MiB and 5 GiB", func(d PresignedMultipartRequest) bool {
+			return d.PartSize == 0 || (d.PartSize >= manager.MinUploadPartSize && d.PartSize <= maxCopyObjectSize)
+		}),
+		Check("Expires", "expires must be between 0 and 7 days", func(d PresignedMultipartRequest) bool {
+			return d.Expires >= 0 && d.Expires <= 7*24*time.Hour
+		}),
+	}
+
+	completePresignedMultipartRules = Rules[CompletePresignedMultipartRequest]{
+		Required("BucketName", "bucket name", func(d CompletePresignedMultipartRequest) string { return d.BucketName }),
+		Required("Filename", "filename", func(d CompletePresignedMultipartRequest) string { return d.Filename }),
+		Required("UploadID", "upload id", func(d CompletePresignedMultipartRequest) string { return d.UploadID }),
+		Check("Size", "size must be greater than zero", func(d CompletePresignedMultipartRequest) bool { return d.Size > 0 }),
+		Check("Parts", "parts are required", func(d CompletePresignedMultipartRequest) bool { return len(d.Parts) > 0 }),
+		func(d CompletePresignedMultipartRequest) error {
+			seen := map[int32]bool{}
+			for _, part := range d.Parts {
+				if part.Number < 1 || part.Number > manager.MaxUploadParts || part.ETag == "" {
+					return Violationf("Parts", "part %d needs a number between 1 and %d and an etag", part.Number, manager.MaxUploadParts)
+				}
+				if seen[part.Number] {
+					return Violationf("Parts", "part %d is reported twice", part.Number)
+				}
+				seen[part.Number] = true
+			}
+			return nil
+		},
+	}
+
+	confirmUploadRules = Rules[ConfirmUploadRequest]{
+		Required("BucketName", "bucket name", func(d ConfirmUploadRequest) string { return d.BucketName }),
+		Required("Filename", "filename", func(d ConfirmUploadRequest) string { return d.Filename }),
+		Check("Size", "sizes must not be negative", func(d ConfirmUploadRequest) bool { return d.Size >= 0 && d.MaxSize >= 0 }),
+		Check("SHA256", "sha256 must be a hex digest", func(d ConfirmUploadRequest) bool {
+			sum, err := hex.DecodeString(d.SHA256)
+			return d.SHA256 == "" || (err == nil && len(sum) == sha256.Size)
+		}),
+	}
+
+	chunkOptionsRules = Rules[ChunkOptions]{
+		Check("AvgSize", "chunk sizes must satisfy min <= avg <= max", func(o ChunkOptions) bool {
+			return o.MinSize <= o.AvgSize && o.AvgSize <= o.MaxSize
+		}),
+		Check("MaxSize", "max chunk size must not exceed 5 GiB", func(o ChunkOptions) bool { return o.MaxSize <= maxCopyObjectSize }),
+	}
+
+	restoreFileRules = Rules[RestoreFileRequest]{
+		Required("BucketName", "bucket name", func(d RestoreFileRequest) string { return d.BucketName }),
+		Required("Filename", "filename", func(d RestoreFileRequest) string { return d.Filename }),
+		Check("Days", "days must be at least 1", func(d RestoreFileRequest) bool { return d.Days >= 1 }),
+	}
+
+	queryObjectRules = Rules[QueryObjectRequest]{
+		Required("BucketName", "bucket name", func(d QueryObjectRequest) string { return d.BucketName }),
+		Required("Filename", "filename", func(d QueryObjectRequest) string { return d.Filename }),
+		Required("Expression", "expression", func(d QueryObjectRequest) string { return d.Expression }),
+		func(d QueryObjectRequest) error {
+			if d.OutputFormat != "" && d.OutputFormat != QueryFormatCSV && d.OutputFormat != QueryFormatJSON {
+				return Violationf("OutputFormat", "unsupported output format %q", d.OutputFormat)
+			}
+			return nil
+		},
+	}
+
+	batchJobRules = Rules[BatchJobRequest]{
+		Required("AccountID", "account id", func(d BatchJobRequest) string { return d.AccountID }),
+		Required("RoleArn", "role arn", func(d BatchJobRequest) string { return d.RoleArn }),
+		Required("BucketName", "bucket name", func(d BatchJobRequest) string { return d.BucketName }),
+		Check("Filenames", "filenames are required", func(d BatchJobRequest) bool { return len(d.Filenames) > 0 }),
+		Required("ManifestKey", "manifest key", func(d BatchJobRequest) string { return d.ManifestKey }),
+		Check("DestinationBucket", "destination bucket is required", func(d BatchJobRequest) bool {
+			return d.Operation != BatchCopy || d.DestinationBucket != ""
+		}),
+		Check("Tags", "tags are required", func(d BatchJobRequest) bool {
+			return d.Operation != BatchTag || len(d.Tags) > 0
+		}),
+		Check("RestoreDays", "restore days must be at least 1", func(d BatchJobRequest) bool {
+			return d.Operation != BatchRestore || d.RestoreDays >= 1
+		}),
+		Check("LambdaArn", "lambda arn is required", func(d BatchJobRequest) bool {
+			return d.Operation != BatchInvoke || d.LambdaArn != ""
+		}),
+	}
+
+	migrateRules = Rules[MigrateRequest]{
+		Required("SourceBucket", "source bucket", func(d MigrateRequest) string { return d.SourceBucket }),
+		Required("DestinationBucket", "destination bucket", func(d MigrateRequest) string { return d.DestinationBucket }),
+		Check("DestinationBucket", "source and destination must differ", func(d MigrateRequest) bool {
+			return d.Destination != nil || d.SourceBucket != d.DestinationBucket || d.Prefix != d.DestinationPrefix
+		}),
+	}
+
+	requestUploadRules = Rules[RequestUploadOptions]{
+		Required("BucketName", "bucket name", func(d RequestUploadOptions) string { return d.BucketName }),
+		Check("MaxSize", "max size must not be negative", func(d RequestUploadOptions) bool { return d.MaxSize >= 0 }),
+		Check("MaxFiles", "max files must not be negative", func(d RequestUploadOptions) bool { return d.MaxFiles >= 0 }),
+	}
+
+	playlistRules = Rules[PlaylistRequest]{
+		Required("BucketName", "bucket name", func(d PlaylistRequest) string { return d.BucketName }),
+		Required("Filename", "filename", func(d PlaylistRequest) string { return d.Filename }),
+		Check("Duration", "duration must be greater than zero", func(d PlaylistRequest) bool { return d.Duration > 0 }),
+		Check("SegmentSize", "segment size must not be negative", func(d PlaylistRequest) bool { return d.SegmentSize >= 0 }),
+	}
+
+	urlUploadRules = Rules[URLUploadOptions]{
+		Check("MaxSize", "max size must not be negative", func(d URLUploadOptions) bool { return d.MaxSize >= 0 }),
+		Check("MaxRedirects", "max redirects must not be negative", func(d URLUploadOptions) bool { return d.MaxRedirects >= 0 }),
+		Check("SHA256", "sha256 must be a hex encoded digest", func(d URLUploadOptions) bool {
+			if d.SHA256 == "" {
+				return true
+			}
+			sum, err := hex.DecodeString(d.SHA256)
+			return err == nil && len(sum) == sha256.Size
+		}),
+	}
+
+	httpUploadRules = slices.Concat(
+		Rules[httpUpload]{
+			Check("Body", "request body is required", func(d httpUpload) bool { return d.request != nil && d.request.Body != nil }),
+		},
+		nested(func(d httpUpload) RequestUploadOptions { return d.opts }, requestUploadRules),
+	)
+
+	remoteUploadRules = slices.Concat(
+		Rules[remoteUpload]{
+			func(d remoteUpload) error {
+				parsed, err := url.Parse(d.sourceURL)
+				if err != nil {
+					return Violationf("SourceURL", "invalid source url: %v", err)
+				}
+				if parsed.Scheme != "http" && parsed.Scheme != "https" {
+					return Violationf("SourceURL", "source url must use http or https")
+				}
+				return nil
+			},
+			Required("BucketName", "bucket name", func(d remoteUpload) string { return d.bucketName }),
+			Required("Key", "key", func(d remoteUpload) string { return d.key }),
+		},
+		nested(func(d remoteUpload) URLUploadOptions { return d.opts }, urlUploadRules),
+	)
+
+	listFilesRules = Rules[string]{
+		Required("BucketName", "bucket name", func(bucketName string) string { return bucketName }),
+	}
+
+	ownerListingRules = Rules[ownerListing]{
+		Required("BucketName", "bucket name", func(d ownerListing) string { return d.bucketName }),
+		Required("OwnerID", "owner id", func(d ownerListing) string { return d.ownerID }),
+	}
+
+	exportRules = Rules[exportTarget]{
+		Required("BucketName", "bucket name", func(d exportTarget) string { return d.bucketName }),
+		Required("LocalDir", "local directory", func(d exportTarget) string { return d.localDir }),
+	}
+
+	renameRules = Rules[rename]{
+		Required("BucketName", "bucket name", func(d rename) string { return d.bucketName }),
+		func(d rename) error {
+			switch {
+			case d.oldKey == "" || d.newKey == "":
+				return Violationf("Key", "old and new key are required")
+			case d.oldKey == d.newKey:
+				return Violationf("Key", "old and new key must differ")
+			}
+			return nil
+		},
+		func(d rename) error {
+			switch d.opts.Overwrite {
+			case "", OverwriteFail, OverwriteReplace, OverwriteSkip, OverwriteRenameWithSuffix:
+				return nil
+			}
+			return Violationf("Overwrite", "unsupported overwrite policy %q", d.opts.Overwrite)
+		},
+	}
+
+	statFileRules = Rules[objectKey]{
+		Required("BucketName", "bucket name", func(d objectKey) string { return d.bucketName }),
+		Required("Key", "key", func(d objectKey) string { return d.key }),
+	}
+)
+
+func (s *s3Service) validateUploadFile(ctx context.Context, data UploadFileRequest) error {
+	if err := slices.Concat(uploadFileRules, s.uploadRules).Validate(data); err != nil {
 		return err
 	}
 
-	fileExist, err := s.isFileExist(data.BucketName, data.Filename)
+	fileExist, err := s.isFileExist(ctx, data.BucketName, data.Filename)
 	if err != nil {
 		return err
 	}
 
 	if fileExist {
-		return fmt.Errorf("file %s already exist on bucket %s", data.Filename, data.BucketName)
+		return fmt.Errorf("%w: %s on bucket %s", ErrFileExists, data.Filename, data.BucketName)
 	}
 
 	return nil
 }
 
-func (s *s3Service) validateDeleteFile(data DeleteFileRequest) error {
-	if len(data.Filename) == 0 {
-		return errors.New("filename is required")
-	}
-
-	if data.BucketName == "" {
-		return errors.New("bucket name is required")
+// validateUploadChunked allows replacing an existing manifest, unlike
+// validateUploadFile.
+func (s *s3Service) validateUploadChunked(data UploadFileRequest, opts ChunkOptions) error {
+	if err := slices.Concat(uploadFileRules, s.uploadRules).Validate(data); err != nil {
+		return err
 	}
 
-	return nil
+	return chunkOptionsRules.Validate(opts)
 }
 
-func (s *s3Service) validateDownloadFile(data DownloadFileRequest) error {
-	if data.BucketName == "" {
-		return errors.New("bucket name is required")
-	}
+func (s *s3Service) validateDeleteFile(data DeleteFileRequest) error {
+	return slices.Concat(deleteFileRules, s.deleteRules).Validate(data)
+}
 
-	if data.Filename == "" {
-		return errors.New("filename is required")
+func (s *s3Service) validateDownloadFile(ctx context.Context, data DownloadFileRequest) error {
+	if err := slices.Concat(downloadFileRules, s.downloadRules).Validate(data); err != nil {
+		return err
 	}
 
-	isExist, err := s.isExistBucket(data.BucketName)
+	isExist, err := s.isExistBucket(ctx, data.BucketName)
 	if err != nil {
 		return err
 	}
@@ -66,7 +320,7 @@ func (s *s3Service) validateDownloadFile(data DownloadFileRequest) error {
 		return ErrBucketNotFound
 	}
 
-	isExist, err = s.isFileExist(data.BucketName, data.Filename)
+	isExist, err = s.isFileExist(ctx, data.BucketName, data.Filename)
 	if err != nil {
 		return err
 	}
@@ -77,3 +331,88 @@ func (s *s3Service) validateDownloadFile(data DownloadFileRequest) error {
 
 	return nil
 }
+
+func (s *s3Service) validateAbortStaleUploads(data AbortStaleUploadsRequest) error {
+	return abortStaleUploadsRules.Validate(data)
+}
+
+func (s *s3Service) validatePostPolicy(data PostPolicyRequest) error {
+	return postPolicyRules.Validate(data)
+}
+
+func (s *s3Service) validateCollectOrphans(data OrphanGCRequest) error {
+	return collectOrphansRules.Validate(data)
+}
+
+func (s *s3Service) validatePresignedMultipart(ctx context.Context, data PresignedMultipartRequest) error {
+	if err := presignedMultipartRules.Validate(data); err != nil {
+		return err
+	}
+
+	fileExist, err := s.isFileExist(ctx, data.BucketName, data.Filename)
+	if err != nil {
+		return err
+	}
+
+	if fileExist {
+		return fmt.Errorf("%w: %s on bucket %s", ErrFileExists, data.Filename, data.BucketName)
+	}
+
+	return nil
+}
+
+func (s *s3Service) validateCompletePresignedMultipart(data CompletePresignedMultipartRequest) error {
+	return completePresignedMultipartRules.Validate(data)
+}
+
+func (s *s3Service) validateConfirmUpload(data ConfirmUploadRequest) error {
+	return confirmUploadRules.Validate(data)
+}
+
+func (s *s3Service) validateRestoreFile(data RestoreFileRequest) error {
+	return restoreFileRules.Validate(data)
+}
+
+func (s *s3Service) validateQueryObject(data QueryObjectRequest) error {
+	return queryObjectRules.Validate(data)
+}
+
+func (s *s3Service) validateBatchJob(data BatchJobRequest) error {
+	return batchJobRules.Validate(data)
+}
+
+func (s *s3Service) validateMigrate(data MigrateRequest) error {
+	return migrateRules.Validate(data)
+}
+
+func (s *s3Service) validatePlaylist(data PlaylistRequest) error {
+	return playlistRules.Validate(data)
+}
+
+func (s *s3Service) validateRequestUpload(r *http.Request, opts RequestUploadOptions) error {
+	return httpUploadRules.Validate(httpUpload{request: r, opts: opts})
+}
+
+func (s *s3Service) validateUploadFromURL(sourceURL, bucketName, key string, opts URLUploadOptions) error {
+	return remoteUploadRules.Validate(remoteUpload{sourceURL: sourceURL, bucketName: bucketName, key: key, opts: opts})
+}
+
+func (s *s3Service) validateListFiles(bucketName string) error {
+	return listFilesRules.Validate(bucketName)
+}
+
+func (s *s3Service) validateListFilesByOwner(bucketName, ownerID string) error {
+	return ownerListingRules.Validate(ownerListing{bucketName: bucketName, ownerID: ownerID})
+}
+
+func (s *s3Service) validateExport(bucketName, localDir string) error {
+	return exportRules.Validate(exportTarget{bucketName: bucketName, localDir: localDir})
+}
+
+func (s *s3Service) validateRenameFile(bucketName, oldKey, newKey string, opts RenameOptions) error {
+	return renameRules.Validate(rename{bucketName: bucketName, oldKey: oldKey, newKey: newKey, opts: opts})
+}
+
+func (s *s3Service) validateStatFile(bucketName, key string) error {
+	return statFileRules.Validate(objectKey{bucketName: bucketName, key: key})
+}
@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidators(t *testing.T) {
+	var svc s3Service
+	tests := []struct {
+		name       string
+		validate   func() error
+		wantFields []string
+	}{
+		{name: "list files", validate: func() error { return svc.validateListFiles("") }, wantFields: []string{"BucketName"}},
+		{name: "list files by owner", validate: func() error { return svc.validateListFilesByOwner("", "") }, wantFields: []string{"BucketName", "OwnerID"}},
+		{name: "export", validate: func() error { return svc.validateExport("bucket", "") }, wantFields: []string{"LocalDir"}},
+		{name: "stat file", validate: func() error { return svc.validateStatFile("", "") }, wantFields: []string{"BucketName", "Key"}},
+		{name: "rename to itself", validate: func() error {
+			return svc.validateRenameFile("bucket", "a", "a", RenameOptions{Overwrite: "merge"})
+		}, wantFields: []string{"Key", "Overwrite"}},
+		{name: "upload from url", validate: func() error {
+			return svc.validateUploadFromURL("ftp://host/a", "", "", URLUploadOptions{MaxSize: -1})
+		}, wantFields: []string{"SourceURL", "BucketName", "Key", "MaxSize"}},
+		{name: "request upload without body", validate: func() error {
+			return svc.validateRequestUpload(nil, RequestUploadOptions{MaxFiles: -1})
+		}, wantFields: []string{"Body", "BucketName", "MaxFiles"}},
+		{name: "valid rename", validate: func() error { return svc.validateRenameFile("bucket", "a", "b", RenameOptions{}) }},
+		{name: "valid request upload", validate: func() error {
+			r, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(""))
+			return svc.validateRequestUpload(r, RequestUploadOptions{BucketName: "bucket"})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.validate()
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("validate error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("validate error = %v, want a *ValidationError", err)
+			}
+			var fields []string
+			for _, violation := range validationErr.Violations {
+				fields = append(fields, violation.Field)
+			}
+			if !slices.Equal(fields, tt.wantFields) {
+				t.Errorf("violated fields = %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestUploadFileExists(t *testing.T) {
+	tests := []struct {
+		name    string
+		exists  bool
+		wantErr error
+	}{
+		{name: "new file"},
+		{name: "existing file", exists: true, wantErr: ErrFileExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFakeS3(t, "bucket")
+			if tt.exists {
+				fake.put("bucket", "a.txt", "text/plain", []byte("a"), nil)
+			}
+			svc := fake.service()
+
+			_, err := svc.UploadFile(UploadFileRequest{
+				BucketName:  "bucket",
+				Filename:    "a.txt",
+				ContentType: "text/plain",
+				Body:        io.NopCloser(strings.NewReader("b")),
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UploadFile error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
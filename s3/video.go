@@ -0,0 +1,174 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/spill"
+)
+
+const (
+	thumbnailSuffix     = ".thumbnail.jpg"
+	defaultPosterOffset = time.Second
+)
+
+// MediaRunner inspects video files on local disk. NewFFmpegRunner shells out
+// to ffprobe and ffmpeg; tests and other toolchains can supply their own.
+type MediaRunner interface {
+	Probe(ctx context.Context, path string) (VideoMetadata, error)
+	// Thumbnail returns a JPEG of the frame at offset.
+	Thumbnail(ctx context.Context, path string, offset time.Duration) ([]byte, error)
+}
+
+type ffmpegRunner struct {
+	ffmpeg  string
+	ffprobe string
+}
+
+// NewFFmpegRunner uses the given binaries, looked up on PATH when empty.
+func NewFFmpegRunner(ffmpegPath, ffprobePath string) MediaRunner {
+	if ffmpegPath == "" {
+		ffmpegPath = "ffmpeg"
+	}
+
+	if ffprobePath == "" {
+		ffprobePath = "ffprobe"
+	}
+
+	return &ffmpegRunner{ffmpeg: ffmpegPath, ffprobe: ffprobePath}
+}
+
+func (f *ffmpegRunner) Probe(ctx context.Context, path string) (VideoMetadata, error) {
+	out, err := run(ctx, f.ffprobe, "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	if err != nil {
+		return VideoMetadata{}, err
+	}
+
+	var probe struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return VideoMetadata{}, fmt.Errorf("failed to decode ffprobe output: %w", err)
+	}
+
+	metadata := VideoMetadata{}
+	if seconds, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		metadata.Duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	for _, stream := range probe.Streams {
+		if stream.CodecType == "video" {
+			metadata.Width, metadata.Height, metadata.Codec = stream.Width, stream.Height, stream.CodecName
+			break
+		}
+	}
+
+	return metadata, nil
+}
+
+func (f *ffmpegRunner) Thumbnail(ctx context.Context, path string, offset time.Duration) ([]byte, error) {
+	return run(ctx, f.ffmpeg, "-v", "error", "-ss", strconv.FormatFloat(offset.Seconds(), 'f', 3, 64),
+		"-i", path, "-frames:v", "1", "-f", "image2", "-c:v", "mjpeg", "pipe:1")
+}
+
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func isVideo(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return strings.HasPrefix(mediaType, "video/")
+}
+
+// spoolVideo tees the upload body into a spill file, since ffmpeg needs a
+// seekable input; the upload itself still streams. It fails with
+// spill.ErrNoPath when the store cannot give ffmpeg a file name.
+func (s *s3Service) spoolVideo(body io.Reader) (io.Reader, spill.File, error) {
+	file, err := s.spillStore().Create()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Checked before the body is copied, so an upload is not spooled for
+	// nothing. A memory file moves to disk here, as it would for ffmpeg later.
+	if _, err := file.Path(); err != nil {
+		removeSpool(file)
+		return nil, nil, err
+	}
+
+	return io.TeeReader(body, file), file, nil
+}
+
+func removeSpool(file spill.File) {
+	if err := file.Close(); err != nil {
+		log.Printf("failed to remove spill file: %v", err)
+	}
+}
+
+// processVideo probes the uploaded video and stores a poster frame next to it.
+func (s *s3Service) processVideo(ctx context.Context, data UploadFileRequest, path string) (*VideoMetadata, error) {
+	metadata, err := s.mediaRunner.Probe(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := defaultPosterOffset
+	if metadata.Duration > 0 && metadata.Duration <= offset {
+		offset = metadata.Duration / 2
+	}
+
+	thumbnail, err := s.mediaRunner.Thumbnail(ctx, path, offset)
+	if err != nil {
+		return &metadata, err
+	}
+
+	key := data.Filename + thumbnailSuffix
+	_, err = s.s3Cli.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(data.BucketName),
+		Key:         aws.String(key),
+		ContentType: aws.String("image/jpeg"),
+		Body:        bytes.NewReader(thumbnail),
+		Metadata:    map[string]string{SourceKeyMetadata: data.Filename},
+	})
+	if err != nil {
+		return &metadata, fmt.Errorf("failed to upload thumbnail: %w", err)
+	}
+
+	metadata.ThumbnailKey = key
+	return &metadata, nil
+}
+
+func (s *s3Service) spillStore() spill.Store {
+	if s.spill == nil {
+		return spill.NewDiskStore(spill.DiskOptions{})
+	}
+	return s.spill
+}
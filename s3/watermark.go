@@ -0,0 +1,240 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	pdfTypes "github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+type WatermarkPosition string
+
+const (
+	WatermarkTopLeft     WatermarkPosition = "tl"
+	WatermarkTopRight    WatermarkPosition = "tr"
+	WatermarkCenter      WatermarkPosition = "c"
+	WatermarkBottomLeft  WatermarkPosition = "bl"
+	WatermarkBottomRight WatermarkPosition = "br"
+)
+
+const (
+	defaultWatermarkOpacity = 0.5
+	defaultWatermarkScale   = 0.25
+	watermarkMarginRatio    = 0.02
+	jpegWatermarkQuality    = 90
+	// defaultWatermarkMaxPixels bounds the decoded image to about 200 MB as RGBA.
+	defaultWatermarkMaxPixels = 50_000_000
+)
+
+type WatermarkOptions struct {
+	// Text or Image (PNG or JPEG bytes) is stamped; Image wins if both are set.
+	Text  string
+	Image []byte
+	// Position defaults to the bottom-right corner.
+	Position WatermarkPosition
+	// Opacity is between 0 and 1 and defaults to 0.5.
+	Opacity float64
+	// Scale is the watermark width relative to the target width, default 0.25.
+	Scale float64
+	// MaxPixels rejects larger images before they are decoded, default 50
+	// megapixels.
+	MaxPixels int64
+}
+
+type watermarker struct {
+	opts    WatermarkOptions
+	overlay image.Image
+}
+
+// NewWatermarker returns a transformer stamping PNG, JPEG and PDF uploads.
+// Other content types pass through unchanged. Images and PDFs are decoded in
+// memory, so it should be combined with an upload size limit.
+func NewWatermarker(opts WatermarkOptions) (Transformer, error) {
+	if opts.Text == "" && len(opts.Image) == 0 {
+		return nil, errors.New("watermark text or image is required")
+	}
+
+	if opts.Position == "" {
+		opts.Position = WatermarkBottomRight
+	}
+
+	switch opts.Position {
+	case WatermarkTopLeft, WatermarkTopRight, WatermarkCenter, WatermarkBottomLeft, WatermarkBottomRight:
+	default:
+		return nil, fmt.Errorf("unsupported watermark position %q", opts.Position)
+	}
+
+	if opts.Opacity == 0 {
+		opts.Opacity = defaultWatermarkOpacity
+	}
+
+	if opts.Scale == 0 {
+		opts.Scale = defaultWatermarkScale
+	}
+
+	if opts.MaxPixels == 0 {
+		opts.MaxPixels = defaultWatermarkMaxPixels
+	}
+
+	if opts.MaxPixels < 0 {
+		return nil, errors.New("watermark max pixels must not be negative")
+	}
+
+	if opts.Opacity < 0 || opts.Opacity > 1 || opts.Scale < 0 || opts.Scale > 1 {
+		return nil, errors.New("watermark opacity and scale must be between 0 and 1")
+	}
+
+	w := &watermarker{opts: opts}
+	if len(opts.Image) > 0 {
+		overlay, _, err := image.Decode(bytes.NewReader(opts.Image))
+		if err != nil {
+			return nil, fmt.Errorf("invalid watermark image: %w", err)
+		}
+		w.overlay = overlay
+	} else {
+		w.overlay = renderText(opts.Text)
+	}
+
+	return w, nil
+}
+
+func (w *watermarker) Transform(_ context.Context, contentType string, r io.Reader) (io.Reader, string, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "image/png", "image/jpeg":
+		out, err := w.stampImage(mediaType, r)
+		return out, contentType, err
+	case "application/pdf":
+		out, err := w.stampPDF(r)
+		return out, contentType, err
+	default:
+		return r, contentType, nil
+	}
+}
+
+func (w *watermarker) stampImage(mediaType string, r io.Reader) (io.Reader, error) {
+	// The header states the dimensions, so oversized images are refused before
+	// any pixel memory is allocated.
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if pixels := int64(config.Width) * int64(config.Height); pixels > w.opts.MaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d exceeds %d pixels", ErrImageTooLarge, config.Width, config.Height, w.opts.MaxPixels)
+	}
+
+	src, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewRGBA(bounds)
+	draw.Draw(dst, bounds, src, bounds.Min, draw.Src)
+
+	overlayBounds := w.overlay.Bounds()
+	width := max(1, int(float64(bounds.Dx())*w.opts.Scale))
+	height := max(1, width*overlayBounds.Dy()/overlayBounds.Dx())
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), w.overlay, overlayBounds, draw.Over, nil)
+
+	at := w.anchor(bounds, scaled.Bounds().Size())
+	mask := image.NewUniform(color.Alpha{A: uint8(w.opts.Opacity * 255)})
+	draw.DrawMask(dst, image.Rectangle{Min: at, Max: at.Add(scaled.Bounds().Size())}, scaled, image.Point{}, mask, image.Point{}, draw.Over)
+
+	var out bytes.Buffer
+	if mediaType == "image/png" {
+		err = png.Encode(&out, dst)
+	} else {
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: jpegWatermarkQuality})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return &out, nil
+}
+
+func (w *watermarker) anchor(bounds image.Rectangle, size image.Point) image.Point {
+	margin := int(float64(min(bounds.Dx(), bounds.Dy())) * watermarkMarginRatio)
+	left, top := bounds.Min.X+margin, bounds.Min.Y+margin
+	right, bottom := bounds.Max.X-margin-size.X, bounds.Max.Y-margin-size.Y
+
+	switch w.opts.Position {
+	case WatermarkTopLeft:
+		return image.Pt(left, top)
+	case WatermarkTopRight:
+		return image.Pt(right, top)
+	case WatermarkBottomLeft:
+		return image.Pt(left, bottom)
+	case WatermarkCenter:
+		return image.Pt(bounds.Min.X+(bounds.Dx()-size.X)/2, bounds.Min.Y+(bounds.Dy()-size.Y)/2)
+	default:
+		return image.Pt(right, bottom)
+	}
+}
+
+func (w *watermarker) stampPDF(r io.Reader) (io.Reader, error) {
+	src, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := fmt.Sprintf("position:%s, opacity:%.2f, scalefactor:%.2f rel, rotation:0", w.opts.Position, w.opts.Opacity, w.opts.Scale)
+
+	var wm *model.Watermark
+	if len(w.opts.Image) > 0 {
+		wm, err = api.ImageWatermarkForReader(bytes.NewReader(w.opts.Image), desc, true, false, pdfTypes.POINTS)
+	} else {
+		wm, err = api.TextWatermark(w.opts.Text, desc, true, false, pdfTypes.POINTS)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := api.AddWatermarks(bytes.NewReader(src), &out, nil, wm, nil); err != nil {
+		return nil, fmt.Errorf("failed to watermark pdf: %w", err)
+	}
+
+	return &out, nil
+}
+
+// renderText draws text in the built-in bitmap font with a dark outline so it
+// stays legible on light and dark images; it is scaled up when stamped.
+func renderText(text string) image.Image {
+	face := basicfont.Face7x13
+	text = strings.ReplaceAll(text, "\n", " ")
+	width := font.MeasureString(face, text).Ceil() + 2
+	height := face.Metrics().Height.Ceil() + 2
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	baseline := face.Metrics().Ascent.Ceil() + 1
+
+	outline := &font.Drawer{Dst: img, Src: image.NewUniform(color.RGBA{A: 0xff}), Face: face}
+	for _, offset := range []image.Point{{0, 1}, {2, 1}, {1, 0}, {1, 2}} {
+		outline.Dot = fixed.P(offset.X, baseline+offset.Y-1)
+		outline.DrawString(text)
+	}
+
+	fill := &font.Drawer{Dst: img, Src: image.White, Face: face, Dot: fixed.P(1, baseline)}
+	fill.DrawString(text)
+
+	return img
+}
@@ -0,0 +1,67 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+// pngOfSize encodes a small PNG and rewrites its header to claim width x
+// height, the way a decompression bomb does.
+func pngOfSize(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 10, 10))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// IHDR follows the 8-byte signature: length, type, width, height, ..., CRC.
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func TestWatermarkImageSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxPixels int64
+		image     func(t *testing.T) []byte
+		wantErr   error
+	}{
+		{name: "within limit", image: func(t *testing.T) []byte { return pngOfSize(t, 10, 10) }},
+		{name: "above configured limit", maxPixels: 50, image: func(t *testing.T) []byte { return pngOfSize(t, 10, 10) }, wantErr: ErrImageTooLarge},
+		{name: "gigapixel header", image: func(t *testing.T) []byte { return pngOfSize(t, 100_000, 100_000) }, wantErr: ErrImageTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := NewWatermarker(WatermarkOptions{Text: "mark", MaxPixels: tt.maxPixels})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, _, err := w.Transform(context.Background(), "image/png", bytes.NewReader(tt.image(t)))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Transform error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			config, err := png.DecodeConfig(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.Width != 10 || config.Height != 10 {
+				t.Errorf("stamped image is %dx%d, want 10x10", config.Width, config.Height)
+			}
+		})
+	}
+}
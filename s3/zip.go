@@ -0,0 +1,108 @@
+package s3
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/KurniawanHendiW/file-uploader/authz"
+)
+
+// StreamZip writes the objects under keys into a single zip archive on w, one
+// object at a time, so nothing is staged on disk or held in memory beyond the
+// copy buffer. Already compressed content types are stored rather than deflated.
+// Once writing has started a failure leaves w holding a truncated archive.
+func (s *s3Service) StreamZip(ctx context.Context, bucketName string, keys []string, w io.Writer) error {
+	if err := s.acquire(); err != nil {
+		return err
+	}
+	defer s.release()
+	ctx, cancel := s.operation(ctx)
+	defer cancel()
+
+	if bucketName == "" {
+		return errors.New("bucket name is required")
+	}
+
+	if len(keys) == 0 {
+		return errors.New("at least one key is required")
+	}
+
+	if err := s.authorizeKeys(ctx, authz.PermissionDownload, bucketName, keys...); err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	for _, key := range keys {
+		if err := s.zipObject(ctx, archive, bucketName, key); err != nil {
+			log.Printf("failed to add %s to zip: %v", key, err)
+			return fmt.Errorf("failed to add %s to zip: %w", key, err)
+		}
+	}
+
+	return archive.Close()
+}
+
+func (s *s3Service) zipObject(ctx context.Context, archive *zip.Writer, bucketName, key string) error {
+	object, err := s.s3Cli.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return ErrFileNotFound
+		}
+		return err
+	}
+	defer object.Body.Close()
+
+	header := &zip.FileHeader{
+		Name:     zipEntryName(key),
+		Method:   zip.Deflate,
+		Modified: aws.ToTime(object.LastModified),
+	}
+	if isCompressed(aws.ToString(object.ContentType)) {
+		header.Method = zip.Store
+	}
+
+	entry, err := archive.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(entry, object.Body)
+	return err
+}
+
+// zipEntryName keeps entries relative and free of ".." segments, so extracting
+// the archive cannot write outside the target directory.
+func zipEntryName(key string) string {
+	name := path.Clean("/" + key)
+	return strings.TrimPrefix(name, "/")
+}
+
+func isCompressed(contentType string) bool {
+	mediaType := requestContentType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "image/") && mediaType != "image/svg+xml" && mediaType != "image/bmp":
+		return true
+	case strings.HasPrefix(mediaType, "video/"), strings.HasPrefix(mediaType, "audio/"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip", "application/x-7z-compressed",
+		"application/x-bzip2", "application/x-xz", "application/zstd", "application/pdf":
+		return true
+	}
+
+	return false
+}
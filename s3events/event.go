@@ -0,0 +1,205 @@
+package s3events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type EventType string
+
+const (
+	ObjectCreated       EventType = "ObjectCreated"
+	ObjectRemoved       EventType = "ObjectRemoved"
+	ObjectRestore       EventType = "ObjectRestore"
+	ObjectTagging       EventType = "ObjectTagging"
+	ObjectAcl           EventType = "ObjectAcl"
+	LifecycleExpiration EventType = "LifecycleExpiration"
+	LifecycleTransition EventType = "LifecycleTransition"
+	IntelligentTiering  EventType = "IntelligentTiering"
+	Replication         EventType = "Replication"
+)
+
+// Event is a bucket change, normalised from S3 event notifications (delivered
+// directly, via SNS or via SQS) and from EventBridge.
+type Event struct {
+	Type EventType
+	// Name is the provider's full event name, e.g. "ObjectCreated:Put" or, for
+	// EventBridge, the detail type followed by the reason.
+	Name      string
+	Region    string
+	Time      time.Time
+	Bucket    string
+	Key       string
+	Size      int64
+	ETag      string
+	VersionID string
+	Sequencer string
+}
+
+var errNotS3Event = errors.New("message is not an S3 event")
+
+type (
+	notification struct {
+		Records []notificationRecord `json:"Records"`
+		Event   string               `json:"Event"`
+	}
+
+	notificationRecord struct {
+		EventSource string    `json:"eventSource"`
+		AWSRegion   string    `json:"awsRegion"`
+		EventTime   time.Time `json:"eventTime"`
+		EventName   string    `json:"eventName"`
+		S3          struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key       string `json:"key"`
+				Size      int64  `json:"size"`
+				ETag      string `json:"eTag"`
+				VersionID string `json:"versionId"`
+				Sequencer string `json:"sequencer"`
+			} `json:"object"`
+		} `json:"s3"`
+	}
+
+	eventBridgeEvent struct {
+		Source     string    `json:"source"`
+		DetailType string    `json:"detail-type"`
+		Time       time.Time `json:"time"`
+		Region     string    `json:"region"`
+		Detail     struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key       string `json:"key"`
+				Size      int64  `json:"size"`
+				ETag      string `json:"etag"`
+				VersionID string `json:"version-id"`
+				Sequencer string `json:"sequencer"`
+			} `json:"object"`
+			Reason string `json:"reason"`
+		} `json:"detail"`
+	}
+
+	snsEnvelope struct {
+		Type    string `json:"Type"`
+		Message string `json:"Message"`
+	}
+)
+
+var eventBridgeTypes = map[string]EventType{
+	"Object Created":               ObjectCreated,
+	"Object Deleted":               ObjectRemoved,
+	"Object Restore Initiated":     ObjectRestore,
+	"Object Restore Completed":     ObjectRestore,
+	"Object Restore Expired":       ObjectRestore,
+	"Object Tags Added":            ObjectTagging,
+	"Object Tags Deleted":          ObjectTagging,
+	"Object ACL Updated":           ObjectAcl,
+	"Object Storage Class Changed": LifecycleTransition,
+	"Object Access Tier Changed":   IntelligentTiering,
+}
+
+// Parse decodes a message body into events. It accepts raw S3 notifications,
+// SNS envelopes wrapping them, and EventBridge events. The s3:TestEvent sent
+// when notifications are configured yields no events.
+func Parse(body []byte) ([]Event, error) {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return nil, fmt.Errorf("failed to decode event: %w", err)
+	}
+
+	switch {
+	case probe["Records"] != nil || probe["Event"] != nil:
+		return parseNotification(body)
+	case probe["detail-type"] != nil:
+		return parseEventBridge(body)
+	case probe["Type"] != nil && probe["Message"] != nil:
+		var envelope snsEnvelope
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			return nil, fmt.Errorf("failed to decode sns message: %w", err)
+		}
+		if envelope.Type != "Notification" {
+			return nil, nil
+		}
+		return Parse([]byte(envelope.Message))
+	default:
+		return nil, errNotS3Event
+	}
+}
+
+func parseNotification(body []byte) ([]Event, error) {
+	var n notification
+	if err := json.Unmarshal(body, &n); err != nil {
+		return nil, fmt.Errorf("failed to decode s3 notification: %w", err)
+	}
+
+	events := make([]Event, 0, len(n.Records))
+	for _, record := range n.Records {
+		if record.EventSource != "aws:s3" {
+			continue
+		}
+
+		// Keys in S3 notifications are form encoded.
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object key %q: %w", record.S3.Object.Key, err)
+		}
+
+		eventType, _, _ := strings.Cut(record.EventName, ":")
+		events = append(events, Event{
+			Type:      EventType(eventType),
+			Name:      record.EventName,
+			Region:    record.AWSRegion,
+			Time:      record.EventTime,
+			Bucket:    record.S3.Bucket.Name,
+			Key:       key,
+			Size:      record.S3.Object.Size,
+			ETag:      record.S3.Object.ETag,
+			VersionID: record.S3.Object.VersionID,
+			Sequencer: record.S3.Object.Sequencer,
+		})
+	}
+
+	return events, nil
+}
+
+func parseEventBridge(body []byte) ([]Event, error) {
+	var e eventBridgeEvent
+	if err := json.Unmarshal(body, &e); err != nil {
+		return nil, fmt.Errorf("failed to decode eventbridge event: %w", err)
+	}
+
+	if e.Source != "aws.s3" {
+		return nil, errNotS3Event
+	}
+
+	eventType, ok := eventBridgeTypes[e.DetailType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported eventbridge detail type %q", e.DetailType)
+	}
+
+	name := e.DetailType
+	if e.Detail.Reason != "" {
+		name += ":" + e.Detail.Reason
+	}
+
+	return []Event{{
+		Type:      eventType,
+		Name:      name,
+		Region:    e.Region,
+		Time:      e.Time,
+		Bucket:    e.Detail.Bucket.Name,
+		Key:       e.Detail.Object.Key,
+		Size:      e.Detail.Object.Size,
+		ETag:      e.Detail.Object.ETag,
+		VersionID: e.Detail.Object.VersionID,
+		Sequencer: e.Detail.Object.Sequencer,
+	}}, nil
+}
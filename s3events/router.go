@@ -0,0 +1,59 @@
+package s3events
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+type Handler func(ctx context.Context, event Event) error
+
+// Router dispatches events to the handlers registered for their type.
+type Router struct {
+	mu       sync.RWMutex
+	handlers map[EventType][]Handler
+	all      []Handler
+}
+
+func NewRouter() *Router {
+	return &Router{handlers: map[EventType][]Handler{}}
+}
+
+func (r *Router) Handle(eventType EventType, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.handlers[eventType] = append(r.handlers[eventType], handler)
+}
+
+// HandleAll registers handler for every event type.
+func (r *Router) HandleAll(handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.all = append(r.all, handler)
+}
+
+// Dispatch parses body and runs the matching handlers for each event. Every
+// handler runs even when an earlier one fails; the errors are joined.
+func (r *Router) Dispatch(ctx context.Context, body []byte) error {
+	events, err := Parse(body)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, event := range events {
+		r.mu.RLock()
+		handlers := append(append([]Handler{}, r.handlers[event.Type]...), r.all...)
+		r.mu.RUnlock()
+
+		for _, handler := range handlers {
+			if err := handler(ctx, event); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
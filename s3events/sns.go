@@ -0,0 +1,297 @@
+package s3events
+
+import (
+	"crypto"
+	"crypto/rsa"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxSNSMessageSize    = 256 << 10
+	defaultSNSMessageAge = time.Hour
+	snsClockSkew         = 5 * time.Minute
+)
+
+var ErrNoTopics = errors.New("at least one sns topic arn is required")
+
+var snsCertHost = regexp.MustCompile(`^sns\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+type SNSOptions struct {
+	// TopicARNs are the topics messages are accepted from; at least one is
+	// required, since any SNS topic can sign a message to the endpoint.
+	TopicARNs []string
+	// MaxAge rejects messages whose Timestamp is older, so a captured message
+	// cannot be replayed later; zero means an hour.
+	MaxAge time.Duration
+	// AutoConfirm visits the SubscribeURL of subscription confirmations.
+	AutoConfirm bool
+	HTTPClient  *http.Client
+}
+
+type snsMessage struct {
+	Type             string
+	MessageId        string
+	Token            string
+	TopicArn         string
+	Subject          string
+	Message          string
+	Timestamp        string
+	SignatureVersion string
+	Signature        string
+	SigningCertURL   string
+	SubscribeURL     string
+}
+
+type snsHandler struct {
+	router *Router
+	opts   SNSOptions
+
+	mu    sync.Mutex
+	certs map[string]*x509.Certificate
+}
+
+// NewSNSHandler is an HTTP(S) endpoint for an SNS subscription. Every message's
+// topic, age and signature, checked against the signing certificate published
+// by SNS, are verified before it is confirmed or dispatched.
+func NewSNSHandler(router *Router, opts SNSOptions) (http.Handler, error) {
+	if len(opts.TopicARNs) == 0 {
+		return nil, ErrNoTopics
+	}
+	if opts.MaxAge <= 0 {
+		opts.MaxAge = defaultSNSMessageAge
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+
+	return &snsHandler{router: router, opts: opts, certs: map[string]*x509.Certificate{}}, nil
+}
+
+func (h *snsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSNSMessageSize))
+	if err != nil {
+		http.Error(w, "failed to read message", http.StatusBadRequest)
+		return
+	}
+
+	var message snsMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		http.Error(w, "invalid message", http.StatusBadRequest)
+		return
+	}
+
+	if !slices.Contains(h.opts.TopicARNs, message.TopicArn) {
+		http.Error(w, "unexpected topic", http.StatusForbidden)
+		return
+	}
+
+	if err := h.checkTimestamp(message.Timestamp); err != nil {
+		log.Printf("rejected sns message %s: %v", message.MessageId, err)
+		http.Error(w, "stale message", http.StatusForbidden)
+		return
+	}
+
+	if err := h.verify(message); err != nil {
+		log.Printf("rejected sns message %s: %v", message.MessageId, err)
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	switch message.Type {
+	case "SubscriptionConfirmation":
+		if h.opts.AutoConfirm {
+			if err := h.confirm(message); err != nil {
+				log.Printf("failed to confirm subscription to %s: %v", message.TopicArn, err)
+				http.Error(w, "failed to confirm subscription", http.StatusBadGateway)
+				return
+			}
+		}
+	case "Notification":
+		if err := h.router.Dispatch(r.Context(), []byte(message.Message)); err != nil && !errors.Is(err, errNotS3Event) {
+			log.Printf("failed to handle sns message %s: %v", message.MessageId, err)
+			http.Error(w, "failed to handle message", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *snsHandler) confirm(message snsMessage) error {
+	if err := checkSNSURL(message.SubscribeURL); err != nil {
+		return err
+	}
+
+	resp, err := h.opts.HTTPClient.Get(message.SubscribeURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
+
+// checkTimestamp rejects messages sent more than MaxAge ago, or dated further
+// ahead than clocks plausibly drift.
+func (h *snsHandler) checkTimestamp(timestamp string) error {
+	sent, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+
+	now := time.Now()
+	if sent.Before(now.Add(-h.opts.MaxAge)) || sent.After(now.Add(snsClockSkew)) {
+		return fmt.Errorf("timestamp %s outside the accepted window", timestamp)
+	}
+
+	return nil
+}
+
+func (h *snsHandler) verify(message snsMessage) error {
+	var hash crypto.Hash
+	switch message.SignatureVersion {
+	case "1":
+		hash = crypto.SHA1
+	case "2":
+		hash = crypto.SHA256
+	default:
+		return fmt.Errorf("unsupported signature version %q", message.SignatureVersion)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(message.Signature)
+	if err != nil {
+		return fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	cert, err := h.certificate(message.SigningCertURL)
+	if err != nil {
+		return err
+	}
+
+	publicKey, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return errors.New("signing certificate does not hold an RSA key")
+	}
+
+	digest := hash.New()
+	digest.Write([]byte(stringToSign(message)))
+
+	return rsa.VerifyPKCS1v15(publicKey, hash, digest.Sum(nil), signature)
+}
+
+func (h *snsHandler) certificate(certURL string) (*x509.Certificate, error) {
+	if err := checkSNSURL(certURL); err != nil {
+		return nil, err
+	}
+
+	h.mu.Lock()
+	cert, ok := h.certs[certURL]
+	h.mu.Unlock()
+	if ok {
+		return cert, nil
+	}
+
+	resp, err := h.opts.HTTPClient.Get(certURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing certificate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch signing certificate: unexpected status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSNSMessageSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing certificate: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("signing certificate is not PEM encoded")
+	}
+
+	cert, err = x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing certificate: %w", err)
+	}
+
+	h.mu.Lock()
+	h.certs[certURL] = cert
+	h.mu.Unlock()
+
+	return cert, nil
+}
+
+// checkSNSURL only allows HTTPS URLs on SNS hosts, so a forged message cannot
+// point verification at a certificate the sender controls.
+func checkSNSURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid sns url: %w", err)
+	}
+
+	if parsed.Scheme != "https" || !snsCertHost.MatchString(parsed.Hostname()) {
+		return fmt.Errorf("untrusted sns url %q", rawURL)
+	}
+
+	return nil
+}
+
+func stringToSign(message snsMessage) string {
+	fields := [][2]string{
+		{"Message", message.Message},
+		{"MessageId", message.MessageId},
+	}
+
+	if message.Type == "Notification" {
+		if message.Subject != "" {
+			fields = append(fields, [2]string{"Subject", message.Subject})
+		}
+		fields = append(fields,
+			[2]string{"Timestamp", message.Timestamp},
+			[2]string{"TopicArn", message.TopicArn},
+			[2]string{"Type", message.Type},
+		)
+	} else {
+		fields = append(fields,
+			[2]string{"SubscribeURL", message.SubscribeURL},
+			[2]string{"Timestamp", message.Timestamp},
+			[2]string{"Token", message.Token},
+			[2]string{"TopicArn", message.TopicArn},
+			[2]string{"Type", message.Type},
+		)
+	}
+
+	var b strings.Builder
+	for _, field := range fields {
+		b.WriteString(field[0] + "\n" + field[1] + "\n")
+	}
+
+	return b.String()
+}
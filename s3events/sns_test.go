@@ -0,0 +1,186 @@
+package s3events
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	testTopicARN = "arn:aws:sns:us-east-1:123456789012:uploads"
+	testCertURL  = "https://sns.us-east-1.amazonaws.com/cert.pem"
+)
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newSNSSigner returns a key and an HTTP client serving its self-signed
+// certificate at testCertURL.
+func newSNSSigner(t *testing.T) (*rsa.PrivateKey, *http.Client) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sns.amazonaws.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.String() != testCertURL {
+			return &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found", Body: http.NoBody}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Body: io.NopCloser(bytes.NewReader(certPEM))}, nil
+	})}
+
+	return key, client
+}
+
+func signSNS(t *testing.T, key *rsa.PrivateKey, message snsMessage) snsMessage {
+	t.Helper()
+
+	message.SignatureVersion = "2"
+	digest := sha256.Sum256([]byte(stringToSign(message)))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	message.Signature = base64.StdEncoding.EncodeToString(signature)
+
+	return message
+}
+
+func TestNewSNSHandlerRequiresTopics(t *testing.T) {
+	if _, err := NewSNSHandler(NewRouter(), SNSOptions{}); !errors.Is(err, ErrNoTopics) {
+		t.Errorf("NewSNSHandler without topics = %v, want %v", err, ErrNoTopics)
+	}
+}
+
+func TestSNSHandlerVerifiesMessages(t *testing.T) {
+	key, client := newSNSSigner(t)
+	otherKey, _ := newSNSSigner(t)
+	event := `{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"a.txt"}}}]}`
+
+	notification := func(timestamp time.Time) snsMessage {
+		return snsMessage{
+			Type:           "Notification",
+			MessageId:      "message",
+			TopicArn:       testTopicARN,
+			Message:        event,
+			Timestamp:      timestamp.UTC().Format(time.RFC3339),
+			SigningCertURL: testCertURL,
+		}
+	}
+
+	tests := []struct {
+		name         string
+		message      snsMessage
+		wantStatus   int
+		wantDispatch bool
+	}{
+		{
+			name:         "valid",
+			message:      signSNS(t, key, notification(time.Now())),
+			wantStatus:   http.StatusOK,
+			wantDispatch: true,
+		},
+		{
+			name: "tampered message",
+			message: func() snsMessage {
+				m := signSNS(t, key, notification(time.Now()))
+				m.Message = strings.Replace(m.Message, "a.txt", "b.txt", 1)
+				return m
+			}(),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "signed by another key",
+			message:    signSNS(t, otherKey, notification(time.Now())),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "untrusted certificate url",
+			message: func() snsMessage {
+				m := notification(time.Now())
+				m.SigningCertURL = "https://example.com/cert.pem"
+				return signSNS(t, key, m)
+			}(),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "other topic",
+			message: func() snsMessage {
+				m := notification(time.Now())
+				m.TopicArn = "arn:aws:sns:us-east-1:999999999999:other"
+				return signSNS(t, key, m)
+			}(),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "replayed",
+			message:    signSNS(t, key, notification(time.Now().Add(-2*time.Hour))),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "dated in the future",
+			message:    signSNS(t, key, notification(time.Now().Add(time.Hour))),
+			wantStatus: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter()
+			dispatched := false
+			router.HandleAll(func(context.Context, Event) error {
+				dispatched = true
+				return nil
+			})
+			handler, err := NewSNSHandler(router, SNSOptions{TopicARNs: []string{testTopicARN}, HTTPClient: client})
+			if err != nil {
+				t.Fatalf("NewSNSHandler: %v", err)
+			}
+
+			body, err := json.Marshal(tt.message)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if dispatched != tt.wantDispatch {
+				t.Errorf("dispatched = %v, want %v", dispatched, tt.wantDispatch)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package s3events
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+const (
+	defaultWaitTime        = 20
+	defaultMaxMessages     = 10
+	defaultMaxReceiveCount = 5
+	receiveBackoff         = 5 * time.Second
+)
+
+// ErrListenerClosed is returned by Run once Shutdown has been called.
+var ErrListenerClosed = errors.New("s3events: listener closed")
+
+type SQSClient interface {
+	ReceiveMessage(ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, params *sqs.DeleteMessageInput, optFns ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+}
+
+type SQSOptions struct {
+	// MaxReceiveCount is how many deliveries a failing message gets before it
+	// is given up on; zero means 5 and a negative count retries forever.
+	MaxReceiveCount int
+	// DeadLetter receives each message given up on before it is deleted. A
+	// message DeadLetter fails to take is kept and redelivered.
+	DeadLetter func(ctx context.Context, body string, err error) error
+}
+
+// SQSListener long-polls a queue fed by S3 notifications, directly or through
+// an SNS subscription.
+type SQSListener struct {
+	client   SQSClient
+	queueURL string
+	router   *Router
+	opts     SQSOptions
+
+	mu      sync.Mutex
+	closed  bool
+	running sync.WaitGroup
+	// stopped ends receiving when Shutdown is called; aborted ends the
+	// handlers of received messages once Shutdown gives up waiting for them.
+	stopped context.Context
+	stop    context.CancelFunc
+	aborted context.Context
+	abort   context.CancelFunc
+}
+
+func NewSQSListener(client SQSClient, queueURL string, router *Router, opts SQSOptions) *SQSListener {
+	if opts.MaxReceiveCount == 0 {
+		opts.MaxReceiveCount = defaultMaxReceiveCount
+	}
+
+	l := &SQSListener{client: client, queueURL: queueURL, router: router, opts: opts}
+	l.stopped, l.stop = context.WithCancel(context.Background())
+	l.aborted, l.abort = context.WithCancel(context.Background())
+
+	return l
+}
+
+// Run receives messages until ctx is done or Shutdown is called, in which case
+// it returns ErrListenerClosed. A message is deleted once all its handlers
+// succeed; otherwise it becomes visible again after the queue's visibility
+// timeout and is redelivered, until it has been received MaxReceiveCount times.
+func (l *SQSListener) Run(ctx context.Context) error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return ErrListenerClosed
+	}
+	l.running.Add(1)
+	l.mu.Unlock()
+	defer l.running.Done()
+
+	receiveCtx, cancelReceive := context.WithCancel(ctx)
+	defer cancelReceive()
+	defer context.AfterFunc(l.stopped, cancelReceive)()
+
+	// Received messages are handled to the end after Shutdown is called, so
+	// they are not redelivered to another consumer halfway through.
+	handleCtx, cancelHandle := context.WithCancel(ctx)
+	defer cancelHandle()
+	defer context.AfterFunc(l.aborted, cancelHandle)()
+
+	for {
+		output, err := l.client.ReceiveMessage(receiveCtx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(l.queueURL),
+			MaxNumberOfMessages: defaultMaxMessages,
+			WaitTimeSeconds:     defaultWaitTime,
+			MessageSystemAttributeNames: []types.MessageSystemAttributeName{
+				types.MessageSystemAttributeNameApproximateReceiveCount,
+			},
+		})
+		if err != nil {
+			if err := l.done(ctx); err != nil {
+				return err
+			}
+
+			log.Printf("failed to receive messages from %s: %v", l.queueURL, err)
+			select {
+			case <-receiveCtx.Done():
+				return l.done(ctx)
+			case <-time.After(receiveBackoff):
+			}
+			continue
+		}
+
+		for _, message := range output.Messages {
+			l.handle(handleCtx, message)
+		}
+		if err := l.done(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// done returns why Run should stop receiving, if it should.
+func (l *SQSListener) done(ctx context.Context) error {
+	if l.stopped.Err() != nil {
+		return ErrListenerClosed
+	}
+
+	return ctx.Err()
+}
+
+// Shutdown stops receiving and waits for the messages already received to be
+// handled. If ctx expires first, the handlers' context is cancelled and
+// Shutdown returns once Run has.
+func (l *SQSListener) Shutdown(ctx context.Context) error {
+	l.mu.Lock()
+	l.closed = true
+	l.mu.Unlock()
+	l.stop()
+
+	done := make(chan struct{})
+	go func() {
+		l.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		log.Printf("shutdown deadline reached, cancelling message handlers of %s: %v", l.queueURL, ctx.Err())
+		l.abort()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (l *SQSListener) handle(ctx context.Context, message types.Message) {
+	body := aws.ToString(message.Body)
+	if err := l.router.Dispatch(ctx, []byte(body)); err != nil && !errors.Is(err, errNotS3Event) {
+		log.Printf("failed to handle message %s from %s: %v", aws.ToString(message.MessageId), l.queueURL, err)
+		if !l.giveUp(ctx, message, err) {
+			return
+		}
+	}
+
+	_, err := l.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+		QueueUrl:      aws.String(l.queueURL),
+		ReceiptHandle: message.ReceiptHandle,
+	})
+	if err != nil {
+		log.Printf("failed to delete message from %s: %v", l.queueURL, err)
+	}
+}
+
+// giveUp reports whether a failing message has been received MaxReceiveCount
+// times and was handed to DeadLetter, so it can be deleted.
+func (l *SQSListener) giveUp(ctx context.Context, message types.Message, err error) bool {
+	count, _ := strconv.Atoi(message.Attributes[string(types.MessageSystemAttributeNameApproximateReceiveCount)])
+	if l.opts.MaxReceiveCount < 0 || count < l.opts.MaxReceiveCount {
+		return false
+	}
+
+	if l.opts.DeadLetter != nil {
+		if err := l.opts.DeadLetter(ctx, aws.ToString(message.Body), err); err != nil {
+			log.Printf("failed to dead-letter message %s from %s: %v", aws.ToString(message.MessageId), l.queueURL, err)
+			return false
+		}
+	}
+
+	log.Printf("giving up on message %s from %s after %d receives", aws.ToString(message.MessageId), l.queueURL, count)
+	return true
+}
@@ -0,0 +1,146 @@
+package s3events
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// fakeSQS delivers messages on the first receive, after which receiving
+// blocks until the context is done.
+type fakeSQS struct {
+	messages []types.Message
+	deleted  int
+}
+
+func (c *fakeSQS) ReceiveMessage(ctx context.Context, _ *sqs.ReceiveMessageInput, _ ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	if messages := c.messages; len(messages) > 0 {
+		c.messages = nil
+		return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+	}
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (c *fakeSQS) DeleteMessage(context.Context, *sqs.DeleteMessageInput, ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	c.deleted++
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func TestSQSListenerPoisonMessage(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         SQSOptions
+		receives     int
+		deadLetter   error
+		wantDeleted  bool
+		wantLettered bool
+	}{
+		{name: "redelivered below the limit", receives: 4},
+		{name: "dropped at the default limit", receives: 5, wantDeleted: true},
+		{name: "dead-lettered at the limit", opts: SQSOptions{MaxReceiveCount: 2}, receives: 2, wantDeleted: true, wantLettered: true},
+		{name: "kept when dead-lettering fails", opts: SQSOptions{MaxReceiveCount: 2}, receives: 2, deadLetter: errors.New("unavailable"), wantLettered: true},
+		{name: "retried forever", opts: SQSOptions{MaxReceiveCount: -1}, receives: 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSQS{}
+			lettered := false
+			if tt.wantLettered {
+				tt.opts.DeadLetter = func(_ context.Context, body string, err error) error {
+					lettered = body == "{" && err != nil
+					return tt.deadLetter
+				}
+			}
+			l := NewSQSListener(client, "queue", NewRouter(), tt.opts)
+
+			l.handle(context.Background(), types.Message{
+				MessageId:     aws.String("message"),
+				Body:          aws.String("{"),
+				ReceiptHandle: aws.String("receipt"),
+				Attributes: map[string]string{
+					string(types.MessageSystemAttributeNameApproximateReceiveCount): strconv.Itoa(tt.receives),
+				},
+			})
+
+			if deleted := client.deleted > 0; deleted != tt.wantDeleted {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if lettered != tt.wantLettered {
+				t.Errorf("dead-lettered = %v, want %v", lettered, tt.wantLettered)
+			}
+		})
+	}
+}
+
+func TestSQSListenerShutdown(t *testing.T) {
+	event := `{"Records":[{"eventSource":"aws:s3","eventName":"ObjectCreated:Put","s3":{"bucket":{"name":"bucket"},"object":{"key":"a.txt"}}}]}`
+	tests := []struct {
+		name           string
+		timeout        time.Duration
+		wantErr        error
+		wantHandlerErr error
+		wantDeleted    bool
+	}{
+		{name: "waits for handlers", timeout: time.Second, wantDeleted: true},
+		{name: "cancels handlers past the deadline", timeout: 20 * time.Millisecond, wantErr: context.DeadlineExceeded, wantHandlerErr: context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &fakeSQS{messages: []types.Message{{MessageId: aws.String("message"), Body: aws.String(event)}}}
+			started, release := make(chan struct{}), make(chan struct{})
+			var handlerErr error
+			router := NewRouter()
+			router.HandleAll(func(ctx context.Context, _ Event) error {
+				close(started)
+				select {
+				case <-release:
+				case <-ctx.Done():
+				}
+				handlerErr = ctx.Err()
+				return handlerErr
+			})
+			l := NewSQSListener(client, "queue", router, SQSOptions{})
+
+			ran := make(chan error, 1)
+			go func() { ran <- l.Run(context.Background()) }()
+			<-started
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			shutdown := make(chan error, 1)
+			go func() { shutdown <- l.Shutdown(ctx) }()
+			select {
+			case err := <-shutdown:
+				t.Fatalf("Shutdown returned %v while a message was being handled", err)
+			case <-time.After(10 * time.Millisecond):
+			}
+			if tt.wantErr == nil {
+				close(release)
+			}
+
+			if err := <-shutdown; !errors.Is(err, tt.wantErr) {
+				t.Errorf("Shutdown = %v, want %v", err, tt.wantErr)
+			}
+			if err := <-ran; !errors.Is(err, ErrListenerClosed) {
+				t.Errorf("Run = %v, want %v", err, ErrListenerClosed)
+			}
+			if !errors.Is(handlerErr, tt.wantHandlerErr) {
+				t.Errorf("handler context error = %v, want %v", handlerErr, tt.wantHandlerErr)
+			}
+			if deleted := client.deleted > 0; deleted != tt.wantDeleted {
+				t.Errorf("deleted = %v, want %v", deleted, tt.wantDeleted)
+			}
+			if err := l.Run(context.Background()); !errors.Is(err, ErrListenerClosed) {
+				t.Errorf("Run after Shutdown = %v, want %v", err, ErrListenerClosed)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package s3events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/KurniawanHendiW/file-uploader/signing"
+)
+
+type WebhookOptions struct {
+	// Keys signs every delivery. Receivers check it with
+	// signing.Keyring.VerifyRequest, using a keyring from signing.NewVerifier.
+	Keys *signing.Keyring
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Webhook returns a Handler posting each event as JSON to url. The key ID and
+// algorithm travel in the signature headers, so the sender can rotate keys
+// while receivers still hold the previous ones. A delivery answered with
+// anything but 2xx fails the handler, which has SQS redeliver the message.
+func Webhook(url string, opts WebhookOptions) (Handler, error) {
+	if opts.Keys == nil {
+		return nil, errors.New("webhook requires a signing keyring")
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context, event Event) error {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if err := opts.Keys.SignRequest(req, body); err != nil {
+			return fmt.Errorf("failed to sign webhook: %w", err)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to deliver %s event for %s to webhook: %w", event.Name, event.Key, err)
+		}
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("webhook rejected %s event for %s: %s", event.Name, event.Key, resp.Status)
+		}
+		return nil
+	}, nil
+}
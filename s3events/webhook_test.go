@@ -0,0 +1,77 @@
+package s3events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/KurniawanHendiW/file-uploader/signing"
+)
+
+func TestWebhookSignsDeliveries(t *testing.T) {
+	current := signing.Key{ID: "current", Secret: bytes.Repeat([]byte("c"), 32)}
+	previous := signing.Key{ID: "previous", Secret: bytes.Repeat([]byte("p"), 32)}
+
+	tests := []struct {
+		name         string
+		senderKey    signing.Key
+		receiverKeys []signing.Key
+		wantErr      bool
+	}{
+		{name: "current key", senderKey: current, receiverKeys: []signing.Key{current}},
+		{name: "receiver still trusting the previous key", senderKey: previous, receiverKeys: []signing.Key{current, previous}},
+		{name: "retired key", senderKey: previous, receiverKeys: []signing.Key{current}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := signing.NewVerifier(tt.receiverKeys...)
+			if err != nil {
+				t.Fatalf("NewVerifier: %v", err)
+			}
+			var received Event
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				if err := verifier.VerifyRequest(r, body, 0); err != nil {
+					http.Error(w, err.Error(), http.StatusForbidden)
+					return
+				}
+				json.Unmarshal(body, &received)
+			}))
+			defer server.Close()
+
+			keys, err := signing.NewKeyring(tt.senderKey)
+			if err != nil {
+				t.Fatalf("NewKeyring: %v", err)
+			}
+			handler, err := Webhook(server.URL, WebhookOptions{Keys: keys})
+			if err != nil {
+				t.Fatalf("Webhook: %v", err)
+			}
+
+			event := Event{Type: ObjectCreated, Name: "ObjectCreated:Put", Bucket: "bucket", Key: "a.txt", Size: 4}
+			err = handler(context.Background(), event)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("delivery signed by a retired key was accepted")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("handler: %v", err)
+			}
+			if received != event {
+				t.Errorf("received %+v, want %+v", received, event)
+			}
+		})
+	}
+}
+
+func TestWebhookRequiresKeys(t *testing.T) {
+	if _, err := Webhook("http://example.com", WebhookOptions{}); err == nil {
+		t.Error("Webhook without keys succeeded")
+	}
+}
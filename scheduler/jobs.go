@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/appendlog"
+	"github.com/KurniawanHendiW/file-uploader/s3"
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/transfer"
+)
+
+// PurgePrefix deletes objects under prefix last modified more than olderThan ago.
+func PurgePrefix(store storage.Storage, prefix string, olderThan time.Duration) Job {
+	return func(ctx context.Context) error {
+		cutoff := time.Now().Add(-olderThan)
+
+		keys := []string{}
+		err := store.List(ctx, prefix, func(info storage.ObjectInfo) error {
+			if info.LastModified.Before(cutoff) {
+				keys = append(keys, info.Key)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, key := range keys {
+			if err := store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+
+		if len(keys) > 0 {
+			log.Printf("purged %d objects under %s", len(keys)-len(errs), prefix)
+		}
+
+		return errors.Join(errs...)
+	}
+}
+
+// UploadJanitor aborts stale multipart uploads, like StartUploadJanitor but on
+// a cron schedule.
+func UploadJanitor(svc s3.S3Service, data s3.AbortStaleUploadsRequest) Job {
+	return func(ctx context.Context) error {
+		_, err := svc.AbortStaleUploads(ctx, data)
+		return err
+	}
+}
+
+// Sync copies src into dst with transfer.Copy. Setting SkipExisting in opts
+// makes repeated runs only copy new objects.
+func Sync(src, dst storage.Storage, opts transfer.Options) Job {
+	return func(ctx context.Context) error {
+		result, err := transfer.Copy(ctx, src, dst, opts)
+		if err != nil {
+			return err
+		}
+
+		if len(result.Failed) > 0 {
+			return fmt.Errorf("sync finished with %d failed objects", len(result.Failed))
+		}
+
+		return nil
+	}
+}
+
+// TagPolicy runs the service's tag policy actions over prefix.
+func TagPolicy(svc s3.S3Service, bucketName, prefix string) Job {
+	return func(ctx context.Context) error {
+		_, err := svc.EnforceTagPolicy(ctx, bucketName, prefix)
+		return err
+	}
+}
+
+// CompactLog folds the segments of an append-only log into its base object.
+func CompactLog(l *appendlog.Log) Job {
+	return func(ctx context.Context) error {
+		return l.Compact(ctx)
+	}
+}
+
+// OrphanGC removes derived objects whose original has been deleted.
+func OrphanGC(svc s3.S3Service, data s3.OrphanGCRequest) Job {
+	return func(ctx context.Context) error {
+		_, err := svc.CollectOrphans(ctx, data)
+		return err
+	}
+}
@@ -0,0 +1,209 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+	"github.com/KurniawanHendiW/file-uploader/s3"
+)
+
+// Priority orders queued jobs; higher priorities are started first.
+type Priority int
+
+const (
+	Bulk Priority = iota
+	Normal
+	Interactive
+)
+
+var priorities = []Priority{Interactive, Normal, Bulk}
+
+func (p Priority) String() string {
+	switch p {
+	case Bulk:
+		return "bulk"
+	case Normal:
+		return "normal"
+	case Interactive:
+		return "interactive"
+	default:
+		return fmt.Sprintf("priority(%d)", int(p))
+	}
+}
+
+var ErrQueueClosed = errors.New("queue is stopped")
+
+type QueueOptions struct {
+	// Workers bounds the jobs running at once, defaults to 8.
+	Workers int
+	// Reserved keeps workers free for a priority: a job only starts while the
+	// workers left over would still cover the reservations of every higher
+	// priority. E.g. {Interactive: 2} means bulk and normal jobs never occupy
+	// the last two workers, so user uploads are not starved by migrations.
+	Reserved map[Priority]int
+	// IDs generates the correlation IDs of runs, idgen.Default when nil.
+	IDs idgen.Generator
+}
+
+// Queue runs submitted jobs on a shared, bounded set of workers.
+type Queue struct {
+	workers   int
+	limits    map[Priority]int
+	ids       idgen.Generator
+	observers []Observer
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.Mutex
+	pending map[Priority][]queuedJob
+	running int
+	closed  bool
+	idle    chan struct{}
+}
+
+type queuedJob struct {
+	name     string
+	job      Job
+	queued   time.Time
+	priority Priority
+}
+
+func NewQueue(opts QueueOptions, observers ...Observer) (*Queue, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+
+	// limits[p] is how many jobs may run while one of priority p starts.
+	limits := map[Priority]int{}
+	reserved := 0
+	for _, p := range priorities {
+		limits[p] = workers - reserved
+		if limits[p] < 1 {
+			return nil, fmt.Errorf("reservations leave no worker for %s jobs", p)
+		}
+		reserved += max(opts.Reserved[p], 0)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ids := opts.IDs
+	if ids == nil {
+		ids = idgen.Default
+	}
+
+	return &Queue{
+		workers:   workers,
+		ids:       ids,
+		limits:    limits,
+		observers: observers,
+		ctx:       ctx,
+		cancel:    cancel,
+		pending:   map[Priority][]queuedJob{},
+	}, nil
+}
+
+func (q *Queue) Submit(name string, priority Priority, job Job) error {
+	if job == nil {
+		return errors.New("job is required")
+	}
+	if _, ok := q.limits[priority]; !ok {
+		return fmt.Errorf("unknown priority %d", int(priority))
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	q.pending[priority] = append(q.pending[priority], queuedJob{name: name, job: job, queued: time.Now(), priority: priority})
+	q.dispatch()
+	return nil
+}
+
+// Pending returns the number of queued jobs per priority.
+func (q *Queue) Pending() map[Priority]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	counts := map[Priority]int{}
+	for p, jobs := range q.pending {
+		counts[p] = len(jobs)
+	}
+	return counts
+}
+
+// dispatch starts queued jobs, highest priority first, while the limits
+// allow. q.mu must be held.
+func (q *Queue) dispatch() {
+	for _, p := range priorities {
+		for len(q.pending[p]) > 0 && q.running < q.limits[p] {
+			job := q.pending[p][0]
+			q.pending[p] = q.pending[p][1:]
+			q.running++
+			go q.run(job)
+		}
+	}
+}
+
+func (q *Queue) run(job queuedJob) {
+	event := Event{Name: job.name, Started: time.Now(), CorrelationID: q.ids.NewID(), Priority: job.priority}
+	event.Wait = event.Started.Sub(job.queued)
+	event.Err = call(s3.WithCorrelationID(q.ctx, event.CorrelationID), job.job)
+	event.Duration = time.Since(event.Started)
+
+	if event.Err != nil {
+		log.Printf("[%s] %s job %s failed after %vs: %v", event.CorrelationID, job.priority, job.name, event.Duration.Seconds(), event.Err)
+	}
+
+	for _, observe := range q.observers {
+		observe(event)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.running--
+	q.dispatch()
+	if q.idle != nil && q.running == 0 {
+		close(q.idle)
+		q.idle = nil
+	}
+}
+
+// Stop stops accepting jobs and waits for queued and running ones. If ctx
+// expires first, queued jobs are dropped and running ones have their context
+// cancelled.
+func (q *Queue) Stop(ctx context.Context) error {
+	q.mu.Lock()
+	q.closed = true
+	if q.running == 0 {
+		q.mu.Unlock()
+		q.cancel()
+		return nil
+	}
+	if q.idle == nil {
+		q.idle = make(chan struct{})
+	}
+	idle := q.idle
+	q.mu.Unlock()
+
+	select {
+	case <-idle:
+		q.cancel()
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		clear(q.pending)
+		q.mu.Unlock()
+		q.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+	"github.com/KurniawanHendiW/file-uploader/s3"
+)
+
+type (
+	Job func(ctx context.Context) error
+
+	// Event describes one finished run and is passed to every Observer, which
+	// is where metrics and audit sinks attach.
+	Event struct {
+		Name     string
+		Started  time.Time
+		Duration time.Duration
+		Err      error
+		// Skipped is set when a run came due while the previous one was still
+		// in progress. The job was not started, so Duration is zero.
+		Skipped bool
+		// CorrelationID is attached to the run's context, so S3 calls made by
+		// the job can be matched to the event.
+		CorrelationID string
+		// Priority and Wait, the time spent queued, are only set for Queue jobs.
+		Priority Priority
+		Wait     time.Duration
+	}
+
+	Observer func(Event)
+)
+
+type Scheduler struct {
+	cron   *cron.Cron
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu        sync.Mutex
+	names     map[string]cron.EntryID
+	observers []Observer
+}
+
+// New creates a scheduler using standard five-field cron expressions and
+// descriptors such as "@hourly". A job is skipped when its previous run is
+// still in progress; observers receive an Event with Skipped set.
+func New(observers ...Observer) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		cron:      cron.New(cron.WithChain(cron.Recover(cron.DefaultLogger))),
+		ctx:       ctx,
+		cancel:    cancel,
+		names:     map[string]cron.EntryID{},
+		observers: observers,
+	}
+}
+
+func (s *Scheduler) Register(name, spec string, job Job) error {
+	if name == "" {
+		return errors.New("job name is required")
+	}
+
+	if job == nil {
+		return errors.New("job is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.names[name]; ok {
+		return fmt.Errorf("job %s is already registered", name)
+	}
+
+	var running sync.Mutex
+	id, err := s.cron.AddFunc(spec, func() {
+		if !running.TryLock() {
+			s.notify(Event{Name: name, Started: time.Now(), Skipped: true})
+			return
+		}
+		defer running.Unlock()
+
+		s.run(name, job)
+	})
+	if err != nil {
+		return fmt.Errorf("invalid schedule for job %s: %w", name, err)
+	}
+
+	s.names[name] = id
+	return nil
+}
+
+func (s *Scheduler) Unregister(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id, ok := s.names[name]; ok {
+		s.cron.Remove(id)
+		delete(s.names, name)
+	}
+}
+
+// Next returns the next scheduled run of the named job.
+func (s *Scheduler) Next(name string) (time.Time, bool) {
+	s.mu.Lock()
+	id, ok := s.names[name]
+	s.mu.Unlock()
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return s.cron.Entry(id).Next, true
+}
+
+func (s *Scheduler) Start() {
+	s.cron.Start()
+}
+
+// Stop stops scheduling new runs and waits for running jobs. If ctx expires
+// first, running jobs have their context cancelled.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	done := s.cron.Stop()
+
+	select {
+	case <-done.Done():
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
+
+func (s *Scheduler) run(name string, job Job) {
+	event := Event{Name: name, Started: time.Now(), CorrelationID: idgen.Default.NewID()}
+	event.Err = call(s3.WithCorrelationID(s.ctx, event.CorrelationID), job)
+	event.Duration = time.Since(event.Started)
+
+	if event.Err != nil {
+		log.Printf("[%s] scheduled job %s failed after %vs: %v", event.CorrelationID, name, event.Duration.Seconds(), event.Err)
+	}
+
+	s.notify(event)
+}
+
+func (s *Scheduler) notify(event Event) {
+	s.mu.Lock()
+	observers := s.observers
+	s.mu.Unlock()
+
+	for _, observe := range observers {
+		observe(event)
+	}
+}
+
+// call runs job, turning a panic into its error so the run is still observed
+// and a Queue worker is still released.
+func call(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("job panicked: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	return job(ctx)
+}
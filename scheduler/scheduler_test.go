@@ -0,0 +1,176 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) observe(event Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) all() []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Event(nil), r.events...)
+}
+
+// trigger runs the named job as cron would, through the scheduler's chain.
+func trigger(t *testing.T, s *Scheduler, name string) {
+	t.Helper()
+
+	s.mu.Lock()
+	id, ok := s.names[name]
+	s.mu.Unlock()
+	if !ok {
+		t.Fatalf("job %s is not registered", name)
+	}
+	s.cron.Entry(id).WrappedJob.Run()
+}
+
+func TestSchedulerReportsSkippedRuns(t *testing.T) {
+	events := &recorder{}
+	s := New(events.observe)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	err := s.Register("slow", "@hourly", func(context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		trigger(t, s, "slow")
+	}()
+	<-started
+	trigger(t, s, "slow")
+	close(release)
+	<-done
+
+	got := events.all()
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	if !got[0].Skipped || got[0].CorrelationID != "" {
+		t.Errorf("first event = %+v, want a skipped run", got[0])
+	}
+	if got[1].Skipped || got[1].Err != nil || got[1].CorrelationID == "" {
+		t.Errorf("second event = %+v, want a completed run", got[1])
+	}
+}
+
+func TestSchedulerRecoversPanics(t *testing.T) {
+	events := &recorder{}
+	s := New(events.observe)
+
+	if err := s.Register("broken", "@hourly", func(context.Context) error { panic("boom") }); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	trigger(t, s, "broken")
+	trigger(t, s, "broken")
+
+	got := events.all()
+	if len(got) != 2 {
+		t.Fatalf("got %d events, want 2", len(got))
+	}
+	for _, event := range got {
+		if event.Skipped || event.Err == nil {
+			t.Errorf("event = %+v, want a failed run", event)
+		}
+	}
+}
+
+func TestQueueReleasesWorkerAfterPanic(t *testing.T) {
+	events := &recorder{}
+	q, err := NewQueue(QueueOptions{Workers: 1}, events.observe)
+	if err != nil {
+		t.Fatalf("NewQueue: %v", err)
+	}
+
+	if err := q.Submit("broken", Normal, func(context.Context) error { panic("boom") }); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	ran := make(chan struct{})
+	if err := q.Submit("next", Normal, func(context.Context) error {
+		close(ran)
+		return nil
+	}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("job queued behind a panicking one never ran")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if got := events.all(); len(got) != 2 || got[0].Err == nil || got[1].Err != nil {
+		t.Errorf("events = %+v, want the panic reported then a clean run", got)
+	}
+}
+
+func TestQueuePriorities(t *testing.T) {
+	q, err := NewQueue(QueueOptions{Workers: 2, Reserved: map[Priority]int{Interactive: 1}})
+	if err != nil {
+		t.Fatalf("NewQueue: %v", err)
+	}
+
+	release := make(chan struct{})
+	block := func(context.Context) error {
+		<-release
+		return nil
+	}
+	for _, name := range []string{"bulk-1", "bulk-2"} {
+		if err := q.Submit(name, Bulk, block); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	if got := q.Pending()[Bulk]; got != 1 {
+		t.Errorf("pending bulk jobs = %d, want 1 held back by the reservation", got)
+	}
+
+	ran := make(chan struct{})
+	if err := q.Submit("upload", Interactive, func(context.Context) error {
+		close(ran)
+		return nil
+	}); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("interactive job did not use the reserved worker")
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := q.Submit("late", Normal, block); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Submit after Stop = %v, want %v", err, ErrQueueClosed)
+	}
+}
@@ -0,0 +1,301 @@
+// Package signing signs outgoing payloads, such as webhook bodies and share
+// tokens, so receivers can check where they came from. Every signature names
+// the key that made it, which lets keys be rotated without downtime: a new key
+// signs while the previous ones keep verifying until they are retired.
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Algorithm string
+
+const (
+	HMACSHA256 Algorithm = "hmac-sha256"
+	Ed25519    Algorithm = "ed25519"
+)
+
+// Headers carrying a signature on HTTP requests.
+const (
+	HeaderKeyID     = "X-Signature-Key-Id"
+	HeaderAlgorithm = "X-Signature-Algorithm"
+	HeaderTimestamp = "X-Signature-Timestamp"
+	HeaderSignature = "X-Signature"
+)
+
+const DefaultMaxSkew = 5 * time.Minute
+
+var (
+	ErrUnknownKey       = errors.New("signing key is unknown or retired")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpired          = errors.New("signature timestamp outside allowed skew")
+)
+
+// Key is an HMAC secret or an Ed25519 key pair. Verifying with Ed25519 only
+// needs PublicKey, so receivers never hold the private key.
+type Key struct {
+	ID         string
+	Algorithm  Algorithm
+	Secret     []byte
+	PrivateKey ed25519.PrivateKey
+	PublicKey  ed25519.PublicKey
+}
+
+func (k Key) validate(signing bool) error {
+	if k.ID == "" || strings.ContainsAny(k.ID, ". ") {
+		return fmt.Errorf("key id %q must be non-empty without dots or spaces", k.ID)
+	}
+
+	switch k.Algorithm {
+	case HMACSHA256, "":
+		if len(k.Secret) < sha256.Size {
+			return fmt.Errorf("key %s: hmac secret must be at least %d bytes", k.ID, sha256.Size)
+		}
+	case Ed25519:
+		if signing && len(k.PrivateKey) != ed25519.PrivateKeySize {
+			return fmt.Errorf("key %s: ed25519 private key is required to sign", k.ID)
+		}
+		if len(k.PrivateKey) != ed25519.PrivateKeySize && len(k.PublicKey) != ed25519.PublicKeySize {
+			return fmt.Errorf("key %s: ed25519 public key is required", k.ID)
+		}
+	default:
+		return fmt.Errorf("key %s: unsupported algorithm %q", k.ID, k.Algorithm)
+	}
+
+	return nil
+}
+
+func (k Key) algorithm() Algorithm {
+	if k.Algorithm == "" {
+		return HMACSHA256
+	}
+	return k.Algorithm
+}
+
+func (k Key) sign(message []byte) []byte {
+	if k.algorithm() == Ed25519 {
+		return ed25519.Sign(k.PrivateKey, message)
+	}
+
+	mac := hmac.New(sha256.New, k.Secret)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+func (k Key) verify(message, signature []byte) bool {
+	if k.algorithm() == Ed25519 {
+		public := k.PublicKey
+		if public == nil {
+			public = k.PrivateKey.Public().(ed25519.PublicKey)
+		}
+		return ed25519.Verify(public, message, signature)
+	}
+
+	return hmac.Equal(k.sign(message), signature)
+}
+
+// Keyring signs with its active key and verifies with any key it holds.
+type Keyring struct {
+	mu     sync.RWMutex
+	active string
+	keys   map[string]Key
+}
+
+// NewKeyring signs with active and keeps verifying signatures of previous. A
+// keyring without an active key, for receivers, is made with NewVerifier.
+func NewKeyring(active Key, previous ...Key) (*Keyring, error) {
+	if err := active.validate(true); err != nil {
+		return nil, err
+	}
+
+	k, err := NewVerifier(previous...)
+	if err != nil {
+		return nil, err
+	}
+
+	k.keys[active.ID] = active
+	k.active = active.ID
+	return k, nil
+}
+
+// NewVerifier only verifies, with any of keys.
+func NewVerifier(keys ...Key) (*Keyring, error) {
+	k := &Keyring{keys: map[string]Key{}}
+	for _, key := range keys {
+		if err := key.validate(false); err != nil {
+			return nil, err
+		}
+		k.keys[key.ID] = key
+	}
+
+	return k, nil
+}
+
+// Rotate makes key the signing key. The previous active key stays available
+// for verification until Retire removes it.
+func (k *Keyring) Rotate(key Key) error {
+	if err := key.validate(true); err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[key.ID] = key
+	k.active = key.ID
+	return nil
+}
+
+// Retire stops accepting signatures of keyID. The active key cannot be retired.
+func (k *Keyring) Retire(keyID string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if keyID == k.active {
+		return fmt.Errorf("key %s is the active signing key", keyID)
+	}
+	delete(k.keys, keyID)
+	return nil
+}
+
+// Key returns the key with keyID, e.g. to hand its public half to receivers.
+func (k *Keyring) Key(keyID string) (Key, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[keyID]
+	return key, ok
+}
+
+type Signature struct {
+	KeyID     string
+	Algorithm Algorithm
+	Value     []byte
+}
+
+func (k *Keyring) Sign(message []byte) (Signature, error) {
+	key, err := k.signingKey()
+	if err != nil {
+		return Signature{}, err
+	}
+
+	return Signature{KeyID: key.ID, Algorithm: key.algorithm(), Value: key.sign(message)}, nil
+}
+
+func (k *Keyring) signingKey() (Key, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.keys[k.active]
+	if !ok {
+		return Key{}, errors.New("keyring has no signing key")
+	}
+	return key, nil
+}
+
+func (k *Keyring) Verify(message []byte, signature Signature) error {
+	key, ok := k.Key(signature.KeyID)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownKey, signature.KeyID)
+	}
+
+	if signature.Algorithm != "" && signature.Algorithm != key.algorithm() {
+		return fmt.Errorf("%w: key %s does not use %s", ErrInvalidSignature, key.ID, signature.Algorithm)
+	}
+
+	if !key.verify(message, signature.Value) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// SignRequest signs body, the payload of req, and sets the signature headers.
+// The timestamp is signed along with the body so captured requests cannot be
+// replayed outside the receiver's allowed skew.
+func (k *Keyring) SignRequest(req *http.Request, body []byte) error {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, err := k.Sign(timestampedMessage(timestamp, body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set(HeaderKeyID, signature.KeyID)
+	req.Header.Set(HeaderAlgorithm, string(signature.Algorithm))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(signature.Value))
+	return nil
+}
+
+// VerifyRequest checks the signature headers of r against body. maxSkew
+// defaults to DefaultMaxSkew.
+func (k *Keyring) VerifyRequest(r *http.Request, body []byte, maxSkew time.Duration) error {
+	if maxSkew <= 0 {
+		maxSkew = DefaultMaxSkew
+	}
+
+	timestamp := r.Header.Get(HeaderTimestamp)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing or malformed timestamp", ErrInvalidSignature)
+	}
+	if skew := time.Since(time.Unix(seconds, 0)); skew > maxSkew || skew < -maxSkew {
+		return ErrExpired
+	}
+
+	value, err := base64.StdEncoding.DecodeString(r.Header.Get(HeaderSignature))
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+
+	return k.Verify(timestampedMessage(timestamp, body), Signature{
+		KeyID:     r.Header.Get(HeaderKeyID),
+		Algorithm: Algorithm(r.Header.Get(HeaderAlgorithm)),
+		Value:     value,
+	})
+}
+
+func timestampedMessage(timestamp string, body []byte) []byte {
+	return append([]byte(timestamp+"."), body...)
+}
+
+// SignToken encodes payload as a URL-safe token "<key id>.<payload>.<signature>",
+// e.g. for share links.
+func (k *Keyring) SignToken(payload []byte) (string, error) {
+	key, err := k.signingKey()
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	signature := key.sign([]byte(key.ID + "." + encoded))
+	return key.ID + "." + encoded + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyToken checks a token made by SignToken and returns its payload.
+func (k *Keyring) VerifyToken(token string) ([]byte, error) {
+	keyID, rest, ok := strings.Cut(token, ".")
+	encoded, encodedSignature, ok2 := strings.Cut(rest, ".")
+	if !ok || !ok2 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidSignature)
+	}
+
+	value, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidSignature)
+	}
+
+	if err := k.Verify([]byte(keyID+"."+encoded), Signature{KeyID: keyID, Value: value}); err != nil {
+		return nil, err
+	}
+
+	return base64.RawURLEncoding.DecodeString(encoded)
+}
@@ -0,0 +1,210 @@
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestKeys(t *testing.T) (Key, Key) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Key{ID: "hmac", Secret: bytes.Repeat([]byte("k"), 32)},
+		Key{ID: "ed", Algorithm: Ed25519, PrivateKey: private}
+}
+
+func TestSignVerify(t *testing.T) {
+	hmacKey, edKey := newTestKeys(t)
+	message := []byte(`{"key":"a.txt"}`)
+
+	for _, key := range []Key{hmacKey, edKey} {
+		t.Run(string(key.algorithm()), func(t *testing.T) {
+			keys, err := NewKeyring(key)
+			if err != nil {
+				t.Fatalf("NewKeyring: %v", err)
+			}
+			signature, err := keys.Sign(message)
+			if err != nil {
+				t.Fatalf("Sign: %v", err)
+			}
+			if signature.KeyID != key.ID || signature.Algorithm != key.algorithm() {
+				t.Errorf("signature names %s/%s, want %s/%s", signature.KeyID, signature.Algorithm, key.ID, key.algorithm())
+			}
+
+			verifier := keys
+			if key.Algorithm == Ed25519 {
+				// Receivers only hold the public half.
+				verifier, err = NewVerifier(Key{ID: key.ID, Algorithm: Ed25519, PublicKey: key.PrivateKey.Public().(ed25519.PublicKey)})
+				if err != nil {
+					t.Fatalf("NewVerifier: %v", err)
+				}
+			}
+
+			if err := verifier.Verify(message, signature); err != nil {
+				t.Errorf("Verify: %v", err)
+			}
+			if err := verifier.Verify([]byte(`{"key":"b.txt"}`), signature); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify tampered message = %v, want %v", err, ErrInvalidSignature)
+			}
+			tampered := signature
+			tampered.Value = append([]byte(nil), signature.Value...)
+			tampered.Value[0] ^= 1
+			if err := verifier.Verify(message, tampered); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Verify tampered signature = %v, want %v", err, ErrInvalidSignature)
+			}
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	hmacKey, edKey := newTestKeys(t)
+	message := []byte("payload")
+
+	keys, err := NewKeyring(hmacKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	old, err := keys.Sign(message)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	if err := keys.Rotate(edKey); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	current, err := keys.Sign(message)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if current.KeyID != edKey.ID {
+		t.Errorf("signed with %s after rotation, want %s", current.KeyID, edKey.ID)
+	}
+	if err := keys.Verify(message, old); err != nil {
+		t.Errorf("Verify with the previous key before Retire: %v", err)
+	}
+
+	if err := keys.Retire(edKey.ID); err == nil {
+		t.Error("Retire of the active key succeeded")
+	}
+	if err := keys.Retire(hmacKey.ID); err != nil {
+		t.Fatalf("Retire: %v", err)
+	}
+	if err := keys.Verify(message, old); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("Verify with a retired key = %v, want %v", err, ErrUnknownKey)
+	}
+	if err := keys.Verify(message, current); err != nil {
+		t.Errorf("Verify with the active key: %v", err)
+	}
+
+	mismatched := current
+	mismatched.Algorithm = HMACSHA256
+	if err := keys.Verify(message, mismatched); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify with the wrong algorithm = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	hmacKey, _ := newTestKeys(t)
+	keys, err := NewKeyring(hmacKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+	body := []byte(`{"key":"a.txt"}`)
+
+	signed := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if err := keys.SignRequest(r, body); err != nil {
+			t.Fatalf("SignRequest: %v", err)
+		}
+		return r
+	}
+	// resign signs body as if sent at timestamp.
+	resign := func(r *http.Request, timestamp time.Time) *http.Request {
+		value := strconv.FormatInt(timestamp.Unix(), 10)
+		signature, err := keys.Sign(timestampedMessage(value, body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set(HeaderTimestamp, value)
+		r.Header.Set(HeaderSignature, base64.StdEncoding.EncodeToString(signature.Value))
+		return r
+	}
+
+	tests := []struct {
+		name    string
+		request *http.Request
+		body    []byte
+		wantErr error
+	}{
+		{name: "valid", request: signed(), body: body},
+		{name: "tampered body", request: signed(), body: []byte(`{"key":"b.txt"}`), wantErr: ErrInvalidSignature},
+		{
+			name: "tampered timestamp",
+			request: func() *http.Request {
+				r := signed()
+				r.Header.Set(HeaderTimestamp, strconv.FormatInt(time.Now().Unix()+1, 10))
+				return r
+			}(),
+			body:    body,
+			wantErr: ErrInvalidSignature,
+		},
+		{name: "expired", request: resign(signed(), time.Now().Add(-time.Hour)), body: body, wantErr: ErrExpired},
+		{name: "dated in the future", request: resign(signed(), time.Now().Add(time.Hour)), body: body, wantErr: ErrExpired},
+		{name: "unsigned", request: httptest.NewRequest(http.MethodPost, "/", nil), body: body, wantErr: ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := keys.VerifyRequest(tt.request, tt.body, 0)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("VerifyRequest = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	hmacKey, edKey := newTestKeys(t)
+	keys, err := NewKeyring(hmacKey)
+	if err != nil {
+		t.Fatalf("NewKeyring: %v", err)
+	}
+
+	token, err := keys.SignToken([]byte("share"))
+	if err != nil {
+		t.Fatalf("SignToken: %v", err)
+	}
+	payload, err := keys.VerifyToken(token)
+	if err != nil || string(payload) != "share" {
+		t.Fatalf("VerifyToken = %q, %v", payload, err)
+	}
+
+	if err := keys.Rotate(edKey); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if _, err := keys.VerifyToken(token); err != nil {
+		t.Errorf("VerifyToken signed by the previous key: %v", err)
+	}
+
+	keyID, rest, _ := strings.Cut(token, ".")
+	_, signature, _ := strings.Cut(rest, ".")
+	for name, tampered := range map[string]string{
+		"payload":   keyID + "." + "c2hhcmYi" + "." + signature,
+		"key id":    edKey.ID + "." + rest,
+		"malformed": "token",
+	} {
+		if _, err := keys.VerifyToken(tampered); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("VerifyToken with tampered %s = %v, want %v", name, err, ErrInvalidSignature)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+// Package spill holds data that has to be buffered, e.g. an upload a tool needs
+// to read twice, in memory or on disk under configurable limits instead of
+// unconditionally in os.TempDir.
+package spill
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+)
+
+var (
+	ErrTooLarge = errors.New("spill file exceeds size limit")
+	// ErrNoPath is returned by File.Path for files that have no plain file on
+	// disk to hand to external tools, e.g. encrypted ones.
+	ErrNoPath = errors.New("spill file has no plain path")
+	ErrClosed = errors.New("spill file is closed")
+)
+
+type (
+	// Store creates spill files. Implementations must be safe for concurrent
+	// use.
+	Store interface {
+		Create() (File, error)
+	}
+
+	// File is written once and then read, possibly concurrently with ReadAt.
+	// Close discards the data.
+	File interface {
+		io.Writer
+		io.ReaderAt
+		io.Closer
+		Size() int64
+		// Path returns a file name external tools can read.
+		Path() (string, error)
+	}
+
+	DiskOptions struct {
+		// Dir defaults to os.TempDir.
+		Dir string
+		// MaxSize limits every file; zero is unlimited.
+		MaxSize int64
+		// Encrypt stores files with AES-256-CTR under a random key that only
+		// lives in memory, so spilled uploads are unreadable once the process
+		// is gone. Encrypted files have no Path.
+		Encrypt bool
+		// IDs names the files, idgen.Default when nil.
+		IDs idgen.Generator
+	}
+)
+
+// Reader returns a reader over the whole of f.
+func Reader(f File) *io.SectionReader {
+	return io.NewSectionReader(f, 0, f.Size())
+}
+
+type diskStore struct {
+	opts DiskOptions
+}
+
+func NewDiskStore(opts DiskOptions) Store {
+	return &diskStore{opts: opts}
+}
+
+func (d *diskStore) Create() (File, error) {
+	file, err := idgen.CreateTemp(d.opts.IDs, d.opts.Dir, "file-uploader-*")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := file.Chmod(0o600); err != nil {
+		removeFile(file)
+		return nil, err
+	}
+
+	spilled := &diskFile{file: file, maxSize: d.opts.MaxSize}
+	if d.opts.Encrypt {
+		if spilled.block, spilled.iv, err = newCipher(); err != nil {
+			removeFile(file)
+			return nil, err
+		}
+	}
+
+	return spilled, nil
+}
+
+type diskFile struct {
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	maxSize int64
+	block   cipher.Block
+	iv      []byte
+}
+
+func (f *diskFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, ErrClosed
+	}
+	if f.maxSize > 0 && f.size+int64(len(p)) > f.maxSize {
+		return 0, ErrTooLarge
+	}
+
+	data := p
+	if f.block != nil {
+		data = make([]byte, len(p))
+		f.xor(data, p, f.size)
+	}
+
+	n, err := f.file.WriteAt(data, f.size)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *diskFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	file, size := f.file, f.size
+	f.mu.Unlock()
+
+	if file == nil {
+		return 0, ErrClosed
+	}
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	limit := p
+	if remaining := size - off; int64(len(p)) > remaining {
+		limit = p[:remaining]
+	}
+
+	n, err := file.ReadAt(limit, off)
+	if f.block != nil {
+		f.xor(limit[:n], limit[:n], off)
+	}
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (f *diskFile) Size() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.size
+}
+
+func (f *diskFile) Path() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case f.file == nil:
+		return "", ErrClosed
+	case f.block != nil:
+		return "", ErrNoPath
+	}
+	return f.file.Name(), nil
+}
+
+func (f *diskFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file != nil {
+		removeFile(f.file)
+		f.file = nil
+	}
+	return nil
+}
+
+// xor encrypts or decrypts src at offset into dst. CTR mode lets any offset be
+// processed without the bytes before it.
+func (f *diskFile) xor(dst, src []byte, offset int64) {
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, f.iv)
+	counter := binary.BigEndian.Uint64(iv[8:]) + uint64(offset/aes.BlockSize)
+	binary.BigEndian.PutUint64(iv[8:], counter)
+
+	stream := cipher.NewCTR(f.block, iv)
+	if skip := int(offset % aes.BlockSize); skip > 0 {
+		discard := make([]byte, skip)
+		stream.XORKeyStream(discard, discard)
+	}
+	stream.XORKeyStream(dst, src)
+}
+
+func newCipher() (cipher.Block, []byte, error) {
+	key := make([]byte, 32)
+	iv := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, err
+	}
+	if _, err := rand.Read(iv); err != nil {
+		return nil, nil, err
+	}
+	// The low half of the IV is the block counter; starting it at zero keeps
+	// it from wrapping into the nonce half.
+	clear(iv[8:])
+
+	block, err := aes.NewCipher(key)
+	return block, iv, err
+}
+
+func removeFile(file *os.File) {
+	file.Close()
+	if err := os.Remove(file.Name()); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove spill file %s: %v", file.Name(), err)
+	}
+}
+
+type memoryStore struct {
+	limit    int64
+	overflow Store
+}
+
+// NewMemoryStore keeps files of up to limit bytes in memory. Larger files move
+// to overflow, e.g. a disk store, or fail with ErrTooLarge when it is nil. A
+// file also moves to overflow when its Path is needed.
+func NewMemoryStore(limit int64, overflow Store) Store {
+	return &memoryStore{limit: limit, overflow: overflow}
+}
+
+func (m *memoryStore) Create() (File, error) {
+	return &memoryFile{store: m}, nil
+}
+
+type memoryFile struct {
+	store *memoryStore
+
+	mu       sync.RWMutex
+	data     []byte
+	spilled  File
+	isClosed bool
+}
+
+func (f *memoryFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.isClosed {
+		return 0, ErrClosed
+	}
+	if f.spilled == nil && int64(len(f.data)+len(p)) > f.store.limit {
+		if err := f.spill(); err != nil {
+			return 0, err
+		}
+	}
+	if f.spilled != nil {
+		return f.spilled.Write(p)
+	}
+
+	f.data = append(f.data, p...)
+	return len(p), nil
+}
+
+// spill moves the buffered data into a file of the overflow store.
+func (f *memoryFile) spill() error {
+	if f.store.overflow == nil {
+		return fmt.Errorf("%w: memory limit is %d bytes", ErrTooLarge, f.store.limit)
+	}
+
+	file, err := f.store.overflow.Create()
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(f.data); err != nil {
+		file.Close()
+		return err
+	}
+
+	f.spilled, f.data = file, nil
+	return nil
+}
+
+func (f *memoryFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	switch {
+	case f.isClosed:
+		return 0, ErrClosed
+	case f.spilled != nil:
+		return f.spilled.ReadAt(p, off)
+	case off >= int64(len(f.data)):
+		return 0, io.EOF
+	}
+
+	n := copy(p, f.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memoryFile) Size() int64 {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	if f.spilled != nil {
+		return f.spilled.Size()
+	}
+	return int64(len(f.data))
+}
+
+func (f *memoryFile) Path() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.isClosed {
+		return "", ErrClosed
+	}
+	if f.spilled == nil {
+		if f.store.overflow == nil {
+			return "", ErrNoPath
+		}
+		if err := f.spill(); err != nil {
+			return "", err
+		}
+	}
+	return f.spilled.Path()
+}
+
+func (f *memoryFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.isClosed, f.data = true, nil
+	if f.spilled != nil {
+		return f.spilled.Close()
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package spill
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// content is long enough to span several AES blocks and not a multiple of one.
+var content = bytes.Repeat([]byte("0123456789abcdef-"), 20)
+
+// write fills f with content in uneven chunks, so encryption has to continue
+// mid-block.
+func write(t *testing.T, f File) {
+	t.Helper()
+
+	for rest := content; len(rest) > 0; {
+		n := min(len(rest), 7)
+		if _, err := f.Write(rest[:n]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		rest = rest[n:]
+	}
+}
+
+func checkReads(t *testing.T, f File) {
+	t.Helper()
+
+	if f.Size() != int64(len(content)) {
+		t.Fatalf("Size = %d, want %d", f.Size(), len(content))
+	}
+	if data, err := io.ReadAll(Reader(f)); err != nil || !bytes.Equal(data, content) {
+		t.Fatalf("ReadAll = %q, %v, want the written content", data, err)
+	}
+
+	for _, off := range []int64{0, 5, 16, 33, int64(len(content)) - 3} {
+		p := make([]byte, 20)
+		n, err := f.ReadAt(p, off)
+		want := content[off:min(off+20, int64(len(content)))]
+		if !bytes.Equal(p[:n], want) {
+			t.Errorf("ReadAt(%d) = %q, want %q", off, p[:n], want)
+		}
+		if n < len(p) && err != io.EOF {
+			t.Errorf("short ReadAt(%d) error = %v, want io.EOF", off, err)
+		}
+	}
+}
+
+func TestDiskStore(t *testing.T) {
+	tests := []struct {
+		name    string
+		encrypt bool
+	}{
+		{name: "plain"},
+		{name: "encrypted", encrypt: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f, err := NewDiskStore(DiskOptions{Dir: dir, Encrypt: tt.encrypt}).Create()
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			write(t, f)
+			checkReads(t, f)
+
+			files, err := filepath.Glob(filepath.Join(dir, "*"))
+			if err != nil || len(files) != 1 {
+				t.Fatalf("spill dir holds %v, %v, want one file", files, err)
+			}
+			info, err := os.Stat(files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if perm := info.Mode().Perm(); perm != 0o600 {
+				t.Errorf("file mode = %v, want 0600", perm)
+			}
+			onDisk, err := os.ReadFile(files[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plain := bytes.Equal(onDisk, content); plain == tt.encrypt {
+				t.Errorf("plain content on disk = %v, want %v", plain, !tt.encrypt)
+			}
+
+			path, err := f.Path()
+			switch {
+			case tt.encrypt && !errors.Is(err, ErrNoPath):
+				t.Errorf("Path of an encrypted file = %q, %v, want %v", path, err, ErrNoPath)
+			case !tt.encrypt && path != files[0]:
+				t.Errorf("Path = %q, %v, want %s", path, err, files[0])
+			}
+
+			if err := f.Close(); err != nil {
+				t.Fatalf("Close: %v", err)
+			}
+			if _, err := os.Stat(files[0]); !os.IsNotExist(err) {
+				t.Errorf("spill file left behind after Close: %v", err)
+			}
+			if _, err := f.ReadAt(make([]byte, 1), 0); !errors.Is(err, ErrClosed) {
+				t.Errorf("ReadAt after Close = %v, want %v", err, ErrClosed)
+			}
+		})
+	}
+}
+
+func TestEncryptedFilesUseDistinctKeys(t *testing.T) {
+	dir := t.TempDir()
+	store := NewDiskStore(DiskOptions{Dir: dir, Encrypt: true})
+	for range 2 {
+		f, err := store.Create()
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		defer f.Close()
+		write(t, f)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil || len(files) != 2 {
+		t.Fatalf("spill dir holds %v, %v, want two files", files, err)
+	}
+	first, _ := os.ReadFile(files[0])
+	second, _ := os.ReadFile(files[1])
+	if bytes.Equal(first, second) {
+		t.Error("two encrypted files of the same content are identical on disk")
+	}
+}
+
+func TestDiskStoreMaxSize(t *testing.T) {
+	f, err := NewDiskStore(DiskOptions{Dir: t.TempDir(), MaxSize: 10}).Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(make([]byte, 10)); err != nil {
+		t.Fatalf("Write up to the limit: %v", err)
+	}
+	if _, err := f.Write(make([]byte, 1)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Write past the limit = %v, want %v", err, ErrTooLarge)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      int64
+		overflow   bool
+		wantOnDisk bool
+		wantErr    error
+	}{
+		{name: "within limit", limit: int64(len(content)), overflow: true},
+		{name: "spills to overflow", limit: 10, overflow: true, wantOnDisk: true},
+		{name: "too large without overflow", limit: 10, wantErr: ErrTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var overflow Store
+			if tt.overflow {
+				overflow = NewDiskStore(DiskOptions{Dir: dir})
+			}
+			f, err := NewMemoryStore(tt.limit, overflow).Create()
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			defer f.Close()
+
+			if tt.wantErr != nil {
+				_, err := f.Write(content)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Write = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			write(t, f)
+			checkReads(t, f)
+			if files, _ := filepath.Glob(filepath.Join(dir, "*")); (len(files) > 0) != tt.wantOnDisk {
+				t.Errorf("overflow dir holds %v, want a file: %v", files, tt.wantOnDisk)
+			}
+		})
+	}
+}
+
+func TestMemoryFilePathMovesToDisk(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewMemoryStore(1<<20, NewDiskStore(DiskOptions{Dir: dir})).Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	defer f.Close()
+	write(t, f)
+
+	path, err := f.Path()
+	if err != nil {
+		t.Fatalf("Path: %v", err)
+	}
+	if onDisk, err := os.ReadFile(path); err != nil || !bytes.Equal(onDisk, content) {
+		t.Errorf("file at Path = %q, %v, want the written content", onDisk, err)
+	}
+	checkReads(t, f)
+
+	noOverflow, err := NewMemoryStore(1<<20, nil).Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := noOverflow.Path(); !errors.Is(err, ErrNoPath) {
+		t.Errorf("Path without overflow = %v, want %v", err, ErrNoPath)
+	}
+}
@@ -0,0 +1,178 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+type containerStorage struct {
+	client *container.Client
+}
+
+// NewStorage exposes an Azure Blob Storage container as a storage.Storage.
+func NewStorage(client *container.Client) storage.Storage {
+	return &containerStorage{client: client}
+}
+
+func (c *containerStorage) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	props, err := c.client.NewBlobClient(key).GetProperties(ctx, nil)
+	if err != nil {
+		return storage.ObjectInfo{}, mapError(err)
+	}
+
+	info := storage.ObjectInfo{
+		Key:         key,
+		ContentType: value(props.ContentType),
+		ETag:        etag(props.ETag),
+		MD5:         props.ContentMD5,
+		Metadata:    metadata(props.Metadata),
+	}
+	if props.ContentLength != nil {
+		info.Size = *props.ContentLength
+	}
+	if props.LastModified != nil {
+		info.LastModified = *props.LastModified
+	}
+
+	return info, nil
+}
+
+func (c *containerStorage) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	resp, err := c.client.NewBlobClient(key).DownloadStream(ctx, nil)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, mapError(err)
+	}
+
+	info := storage.ObjectInfo{
+		Key:         key,
+		ContentType: value(resp.ContentType),
+		ETag:        etag(resp.ETag),
+		MD5:         resp.ContentMD5,
+		Metadata:    metadata(resp.Metadata),
+	}
+	if resp.ContentLength != nil {
+		info.Size = *resp.ContentLength
+	}
+	if resp.LastModified != nil {
+		info.LastModified = *resp.LastModified
+	}
+
+	return resp.Body, info, nil
+}
+
+func (c *containerStorage) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (storage.ObjectInfo, error) {
+	meta := make(map[string]*string, len(opts.Metadata))
+	for k, v := range opts.Metadata {
+		meta[k] = to.Ptr(v)
+	}
+
+	uploadOptions := &blockblob.UploadStreamOptions{Metadata: meta}
+	if opts.ContentType != "" {
+		uploadOptions.HTTPHeaders = &blob.HTTPHeaders{BlobContentType: to.Ptr(opts.ContentType)}
+	}
+
+	resp, err := c.client.NewBlockBlobClient(key).UploadStream(ctx, r, uploadOptions)
+	if err != nil {
+		return storage.ObjectInfo{}, mapError(err)
+	}
+
+	return storage.ObjectInfo{
+		Key:         key,
+		Size:        opts.Size,
+		ContentType: opts.ContentType,
+		ETag:        etag(resp.ETag),
+		Metadata:    opts.Metadata,
+	}, nil
+}
+
+func (c *containerStorage) Delete(ctx context.Context, key string) error {
+	_, err := c.client.NewBlobClient(key).Delete(ctx, nil)
+	if err := mapError(err); err != nil && !errors.Is(err, storage.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (c *containerStorage) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	pager := c.client.NewListBlobsFlatPager(&container.ListBlobsFlatOptions{
+		Prefix:  to.Ptr(prefix),
+		Include: container.ListBlobsInclude{Metadata: true},
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range page.Segment.BlobItems {
+			info := storage.ObjectInfo{
+				Key:      value(item.Name),
+				Metadata: metadata(item.Metadata),
+			}
+			if props := item.Properties; props != nil {
+				info.ContentType = value(props.ContentType)
+				info.ETag = etag(props.ETag)
+				info.MD5 = props.ContentMD5
+				if props.ContentLength != nil {
+					info.Size = *props.ContentLength
+				}
+				if props.LastModified != nil {
+					info.LastModified = *props.LastModified
+				}
+			}
+
+			if err := fn(info); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func mapError(err error) error {
+	if bloberror.HasCode(err, bloberror.BlobNotFound, bloberror.ContainerNotFound) {
+		return storage.ErrNotExist
+	}
+
+	return err
+}
+
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+
+	return *s
+}
+
+func etag[T ~string](e *T) string {
+	if e == nil {
+		return ""
+	}
+
+	return string(*e)
+}
+
+func metadata(meta map[string]*string) map[string]string {
+	if len(meta) == 0 {
+		return nil
+	}
+
+	out := make(map[string]string, len(meta))
+	for k, v := range meta {
+		out[k] = value(v)
+	}
+
+	return out
+}
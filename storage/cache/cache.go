@@ -0,0 +1,373 @@
+// Package cache serves Get from a size-bounded local disk cache in front of
+// another storage.Storage.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+const (
+	defaultMaxSize = 1 << 30
+	fileSuffix     = ".cache"
+)
+
+type Options struct {
+	// Dir holds a private directory per Store for the cached objects, removed
+	// by Close. Defaults to os.TempDir.
+	Dir string
+	// MaxSize bounds the cache in bytes, defaults to 1 GiB. Objects larger
+	// than MaxObjectSize, MaxSize/4 by default, are never cached.
+	MaxSize       int64
+	MaxObjectSize int64
+	// Revalidate serves entries checked within this window without asking the
+	// backend whether the ETag changed. Zero checks on every Get.
+	Revalidate time.Duration
+	// IDs names the cache files, idgen.Default when nil.
+	IDs idgen.Generator
+}
+
+// Cache is the storage.Storage returned by New. It also implements
+// storage.RangeReader and storage.Presigner when the backend does; ranges of
+// cached objects are read from disk, presigned URLs always go to the backend.
+type Cache interface {
+	storage.Storage
+	Stats() Stats
+	// Close removes the cached files. The Cache must not be used afterwards.
+	Close() error
+}
+
+type Stats struct {
+	Hits      int64
+	Misses    int64
+	Evictions int64
+	Size      int64
+	Entries   int
+}
+
+// Store is an LRU disk cache for Get. Objects are cached by ETag: a cached
+// copy is only served while the backend reports the same ETag, so objects the
+// backend returns without one are always read from it. Put and Delete through
+// the Store invalidate the key; writes made around it are caught by the ETag
+// check once Revalidate has passed.
+type Store struct {
+	next storage.Storage
+	opts Options
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	size    int64
+
+	hits, misses, evictions atomic.Int64
+}
+
+type entry struct {
+	key       string
+	path      string
+	info      storage.ObjectInfo
+	validated time.Time
+}
+
+func New(next storage.Storage, opts Options) (Cache, error) {
+	if opts.MaxSize <= 0 {
+		opts.MaxSize = defaultMaxSize
+	}
+	if opts.MaxObjectSize <= 0 || opts.MaxObjectSize > opts.MaxSize {
+		opts.MaxObjectSize = opts.MaxSize / 4
+	}
+
+	if opts.Dir != "" {
+		if err := os.MkdirAll(opts.Dir, 0o700); err != nil {
+			return nil, err
+		}
+	}
+	// The index only lives in memory, so every Store starts with a directory
+	// of its own rather than clearing one that others may be using.
+	dir, err := os.MkdirTemp(opts.Dir, "file-uploader-cache-*")
+	if err != nil {
+		return nil, err
+	}
+	opts.Dir = dir
+
+	s := &Store{next: next, opts: opts, entries: map[string]*list.Element{}, lru: list.New()}
+
+	_, ranged := next.(storage.RangeReader)
+	_, presigned := next.(storage.Presigner)
+	switch {
+	case ranged && presigned:
+		return rangePresignStore{s}, nil
+	case ranged:
+		return rangeStore{s}, nil
+	case presigned:
+		return presignStore{s}, nil
+	default:
+		return s, nil
+	}
+}
+
+type (
+	rangeStore        struct{ *Store }
+	presignStore      struct{ *Store }
+	rangePresignStore struct{ *Store }
+)
+
+func (s rangeStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return s.getRange(ctx, key, offset, length)
+}
+
+func (s presignStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.next.(storage.Presigner).PresignGet(ctx, key, expires)
+}
+
+func (s rangePresignStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return s.getRange(ctx, key, offset, length)
+}
+
+func (s rangePresignStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.next.(storage.Presigner).PresignGet(ctx, key, expires)
+}
+
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = map[string]*list.Element{}
+	s.lru.Init()
+	s.size = 0
+	return os.RemoveAll(s.opts.Dir)
+}
+
+func (s *Store) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Stats{
+		Hits:      s.hits.Load(),
+		Misses:    s.misses.Load(),
+		Evictions: s.evictions.Load(),
+		Size:      s.size,
+		Entries:   len(s.entries),
+	}
+}
+
+func (s *Store) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	return s.next.Stat(ctx, key)
+}
+
+func (s *Store) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	if body, info, ok, err := s.cached(ctx, key); ok || err != nil {
+		return body, info, err
+	}
+	s.misses.Add(1)
+
+	body, info, err := s.next.Get(ctx, key)
+	if err != nil || info.ETag == "" || info.Size < 0 || info.Size > s.opts.MaxObjectSize {
+		return body, info, err
+	}
+
+	file, err := idgen.CreateTemp(s.opts.IDs, s.opts.Dir, "*"+fileSuffix)
+	if err != nil {
+		log.Printf("failed to create cache file for %s: %v", key, err)
+		return body, info, nil
+	}
+
+	return &fillReader{body: body, file: file, store: s, entry: entry{key: key, path: file.Name(), info: info}}, info, nil
+}
+
+// cached returns the cached copy of key when its ETag is still current.
+func (s *Store) cached(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, bool, error) {
+	s.mu.Lock()
+	element, ok := s.entries[key]
+	var e entry
+	if ok {
+		e = *element.Value.(*entry)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return nil, storage.ObjectInfo{}, false, nil
+	}
+
+	if time.Since(e.validated) >= s.opts.Revalidate {
+		info, err := s.next.Stat(ctx, key)
+		if errors.Is(err, storage.ErrNotExist) {
+			s.invalidate(key)
+			return nil, storage.ObjectInfo{}, false, err
+		}
+		if err != nil {
+			return nil, storage.ObjectInfo{}, false, err
+		}
+		if info.ETag != e.info.ETag {
+			s.invalidate(key)
+			return nil, storage.ObjectInfo{}, false, nil
+		}
+		s.touch(key, element, time.Now())
+	} else {
+		s.touch(key, element, time.Time{})
+	}
+
+	file, err := os.Open(e.path)
+	if err != nil {
+		// Evicted between the lookup and the open.
+		return nil, storage.ObjectInfo{}, false, nil
+	}
+
+	s.hits.Add(1)
+	return file, e.info, true, nil
+}
+
+// getRange reads a range of a cached object from disk and leaves every other
+// range read to the backend, without caching it.
+func (s *Store) getRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	body, _, ok, err := s.cached(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return s.next.(storage.RangeReader).GetRange(ctx, key, offset, length)
+	}
+
+	file := body.(*os.File)
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if length < 0 {
+		return file, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+// touch marks element as recently used, and as validated at validated unless
+// it is zero. The element is left alone if it was replaced meanwhile.
+func (s *Store) touch(key string, element *list.Element, validated time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.entries[key] != element {
+		return
+	}
+	s.lru.MoveToFront(element)
+	if !validated.IsZero() {
+		element.Value.(*entry).validated = validated
+	}
+}
+
+func (s *Store) add(e entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[e.key]; ok {
+		s.remove(element)
+	}
+	e.validated = time.Now()
+	s.entries[e.key] = s.lru.PushFront(&e)
+	s.size += e.info.Size
+
+	for s.size > s.opts.MaxSize {
+		s.remove(s.lru.Back())
+		s.evictions.Add(1)
+	}
+}
+
+// remove drops element; s.mu must be held. Readers that opened the file
+// before keep reading it.
+func (s *Store) remove(element *list.Element) {
+	e := element.Value.(*entry)
+	s.lru.Remove(element)
+	delete(s.entries, e.key)
+	s.size -= e.info.Size
+	removeFile(e.path)
+}
+
+func (s *Store) invalidate(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.entries[key]; ok {
+		s.remove(element)
+	}
+}
+
+func (s *Store) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (storage.ObjectInfo, error) {
+	s.invalidate(key)
+	return s.next.Put(ctx, key, r, opts)
+}
+
+func (s *Store) Delete(ctx context.Context, key string) error {
+	s.invalidate(key)
+	return s.next.Delete(ctx, key)
+}
+
+func (s *Store) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	return s.next.List(ctx, prefix, fn)
+}
+
+// fillReader copies the backend body into the cache file as the caller reads
+// it. The entry is only added once the whole object was read.
+type fillReader struct {
+	body    io.ReadCloser
+	file    *os.File
+	store   *Store
+	entry   entry
+	written int64
+	failed  bool
+	done    bool
+}
+
+func (r *fillReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 && !r.failed {
+		if _, writeErr := r.file.Write(p[:n]); writeErr != nil {
+			log.Printf("failed to write cache file for %s: %v", r.entry.key, writeErr)
+			r.failed = true
+		}
+		r.written += int64(n)
+	}
+	if err == io.EOF {
+		r.finish()
+	}
+	return n, err
+}
+
+func (r *fillReader) finish() {
+	if r.done {
+		return
+	}
+	r.done = true
+
+	closeErr := r.file.Close()
+	if r.failed || closeErr != nil || r.written != r.entry.info.Size {
+		removeFile(r.entry.path)
+		return
+	}
+	r.store.add(r.entry)
+}
+
+func (r *fillReader) Close() error {
+	if !r.done {
+		// Abandoned before EOF: the partial copy is useless.
+		r.failed = true
+		r.finish()
+	}
+	return r.body.Close()
+}
+
+func removeFile(path string) {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		log.Printf("failed to remove cache file %s: %v", path, err)
+	}
+}
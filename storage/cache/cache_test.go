@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/storage/memory"
+)
+
+type plainStore struct {
+	storage.Storage
+}
+
+type presigningStore struct {
+	storage.Storage
+}
+
+func (presigningStore) PresignGet(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://example.com/" + key, nil
+}
+
+type rangePresigningStore struct {
+	presigningStore
+	storage.RangeReader
+}
+
+func read(t *testing.T, body io.ReadCloser) string {
+	t.Helper()
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestNewKeepsSharedDir(t *testing.T) {
+	dir := t.TempDir()
+	other := filepath.Join(dir, "other.cache")
+	if err := os.WriteFile(other, []byte("not ours"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	next := memory.NewStorage()
+	if _, err := next.Put(context.Background(), "f", strings.NewReader("data"), storage.PutOptions{Size: -1}); err != nil {
+		t.Fatal(err)
+	}
+	first, err := New(next, Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _, err := first.Get(context.Background(), "f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	read(t, body)
+
+	second, err := New(next, Options{Dir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("file of another owner removed: %v", err)
+	}
+	body, _, err = first.Get(context.Background(), "f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	read(t, body)
+	if stats := first.Stats(); stats.Hits != 1 {
+		t.Errorf("first cache hits = %d after a second cache started, want 1", stats.Hits)
+	}
+
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("dir holds %d entries after Close, want the other file and the second cache", len(entries))
+	}
+}
+
+func TestNewForwardsOptionalInterfaces(t *testing.T) {
+	next := memory.NewStorage()
+	tests := []struct {
+		name          string
+		next          storage.Storage
+		wantRange     bool
+		wantPresigner bool
+	}{
+		{name: "plain", next: plainStore{next}},
+		{name: "ranged", next: next, wantRange: true},
+		{name: "presigner", next: presigningStore{plainStore{next}}, wantPresigner: true},
+		{name: "both", next: rangePresigningStore{presigningStore{next}, next.(storage.RangeReader)}, wantRange: true, wantPresigner: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := New(tt.next, Options{Dir: t.TempDir()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+
+			if _, ok := store.(storage.RangeReader); ok != tt.wantRange {
+				t.Errorf("RangeReader = %v, want %v", ok, tt.wantRange)
+			}
+			if _, ok := store.(storage.Presigner); ok != tt.wantPresigner {
+				t.Errorf("Presigner = %v, want %v", ok, tt.wantPresigner)
+			}
+		})
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	tests := []struct {
+		name           string
+		cached         bool
+		offset, length int64
+		want           string
+	}{
+		{name: "backend", offset: 2, length: 4, want: "2345"},
+		{name: "cached", cached: true, offset: 2, length: 4, want: "2345"},
+		{name: "cached to the end", cached: true, offset: 7, length: -1, want: "789"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := memory.NewStorage()
+			if _, err := next.Put(context.Background(), "f", strings.NewReader("0123456789"), storage.PutOptions{Size: -1}); err != nil {
+				t.Fatal(err)
+			}
+			store, err := New(next, Options{Dir: t.TempDir(), Revalidate: time.Hour})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer store.Close()
+			if tt.cached {
+				body, _, err := store.Get(context.Background(), "f")
+				if err != nil {
+					t.Fatal(err)
+				}
+				read(t, body)
+			}
+
+			body, err := store.(storage.RangeReader).GetRange(context.Background(), "f", tt.offset, tt.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := read(t, body); got != tt.want {
+				t.Errorf("GetRange = %q, want %q", got, tt.want)
+			}
+			if hits := store.Stats().Hits; (hits > 0) != tt.cached {
+				t.Errorf("hits = %d, want cached %v", hits, tt.cached)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+type (
+	Op    string
+	Fault string
+)
+
+const (
+	OpStat   Op = "stat"
+	OpGet    Op = "get"
+	OpPut    Op = "put"
+	OpDelete Op = "delete"
+	OpList   Op = "list"
+
+	// Latency delays the call by Rule.Latency before running it.
+	Latency Fault = "latency"
+	// Throttle fails the call with ErrThrottled without running it.
+	Throttle Fault = "throttle"
+	// PartialWrite stores only the first half of a Put body and fails.
+	PartialWrite Fault = "partial_write"
+	// ConnectionReset fails calls with ErrConnectionReset; Get bodies are cut
+	// off half way through instead.
+	ConnectionReset Fault = "connection_reset"
+)
+
+var (
+	ErrThrottled       = errors.New("chaos: request throttled, slow down")
+	ErrConnectionReset = fmt.Errorf("chaos: %w", syscall.ECONNRESET)
+	ErrPartialWrite    = errors.New("chaos: partial write")
+)
+
+// Rule injects Fault into matching calls. A rule is active between Start and
+// Start+Duration after the store is created (always when both are zero) and
+// fires on every Every-th matching call, or with Probability otherwise.
+type Rule struct {
+	Ops         []Op
+	Fault       Fault
+	Probability float64
+	Every       int
+	Latency     time.Duration
+	Start       time.Duration
+	Duration    time.Duration
+}
+
+type store struct {
+	next    storage.Storage
+	rules   []Rule
+	created time.Time
+
+	mu     sync.Mutex
+	rand   *rand.Rand
+	counts []int
+}
+
+// New wraps next with fault injection. seed makes probabilistic faults
+// reproducible across runs. The result implements storage.RangeReader and
+// storage.Presigner when next does; ranged reads get the faults of OpGet.
+func New(next storage.Storage, seed uint64, rules ...Rule) storage.Storage {
+	s := &store{
+		next:    next,
+		rules:   rules,
+		created: time.Now(),
+		rand:    rand.New(rand.NewPCG(seed, seed)),
+		counts:  make([]int, len(rules)),
+	}
+
+	_, ranged := next.(storage.RangeReader)
+	_, presigned := next.(storage.Presigner)
+	switch {
+	case ranged && presigned:
+		return rangePresignStore{s}
+	case ranged:
+		return rangeStore{s}
+	case presigned:
+		return presignStore{s}
+	default:
+		return s
+	}
+}
+
+type (
+	rangeStore        struct{ *store }
+	presignStore      struct{ *store }
+	rangePresignStore struct{ *store }
+)
+
+func (s rangeStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return s.getRange(ctx, key, offset, length)
+}
+
+func (s presignStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.next.(storage.Presigner).PresignGet(ctx, key, expires)
+}
+
+func (s rangePresignStore) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	return s.getRange(ctx, key, offset, length)
+}
+
+func (s rangePresignStore) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.next.(storage.Presigner).PresignGet(ctx, key, expires)
+}
+
+// faults returns the faults firing for this call of op.
+func (s *store) faults(op Op) []Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Since(s.created)
+	fired := []Rule{}
+	for i, rule := range s.rules {
+		if len(rule.Ops) > 0 && !slices.Contains(rule.Ops, op) {
+			continue
+		}
+
+		if elapsed < rule.Start || (rule.Duration > 0 && elapsed >= rule.Start+rule.Duration) {
+			continue
+		}
+
+		s.counts[i]++
+		if rule.Every > 0 {
+			if s.counts[i]%rule.Every == 0 {
+				fired = append(fired, rule)
+			}
+		} else if s.rand.Float64() < rule.Probability {
+			fired = append(fired, rule)
+		}
+	}
+
+	return fired
+}
+
+// inject applies latency and failing faults, returning the faults the call
+// itself has to simulate.
+func (s *store) inject(ctx context.Context, op Op) (map[Fault]bool, error) {
+	active := map[Fault]bool{}
+	for _, rule := range s.faults(op) {
+		switch rule.Fault {
+		case Latency:
+			select {
+			case <-time.After(rule.Latency):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		case Throttle:
+			return nil, ErrThrottled
+		case ConnectionReset:
+			if op != OpGet {
+				return nil, ErrConnectionReset
+			}
+			active[ConnectionReset] = true
+		case PartialWrite:
+			active[PartialWrite] = true
+		}
+	}
+
+	return active, nil
+}
+
+func (s *store) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	if _, err := s.inject(ctx, OpStat); err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	return s.next.Stat(ctx, key)
+}
+
+func (s *store) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	active, err := s.inject(ctx, OpGet)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+
+	body, info, err := s.next.Get(ctx, key)
+	if err != nil || !active[ConnectionReset] {
+		return body, info, err
+	}
+
+	return &resetReader{ReadCloser: body, remaining: info.Size / 2}, info, nil
+}
+
+func (s *store) getRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	active, err := s.inject(ctx, OpGet)
+	if err != nil {
+		return nil, err
+	}
+
+	if active[ConnectionReset] && length < 0 {
+		info, err := s.next.Stat(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		length = max(info.Size-offset, 0)
+	}
+
+	body, err := s.next.(storage.RangeReader).GetRange(ctx, key, offset, length)
+	if err != nil || !active[ConnectionReset] {
+		return body, err
+	}
+
+	return &resetReader{ReadCloser: body, remaining: length / 2}, nil
+}
+
+func (s *store) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (storage.ObjectInfo, error) {
+	active, err := s.inject(ctx, OpPut)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	if !active[PartialWrite] {
+		return s.next.Put(ctx, key, r, opts)
+	}
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	opts.Size = int64(len(body) / 2)
+	if _, err := s.next.Put(ctx, key, bytes.NewReader(body[:opts.Size]), opts); err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	return storage.ObjectInfo{}, ErrPartialWrite
+}
+
+func (s *store) Delete(ctx context.Context, key string) error {
+	if _, err := s.inject(ctx, OpDelete); err != nil {
+		return err
+	}
+
+	return s.next.Delete(ctx, key)
+}
+
+func (s *store) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	if _, err := s.inject(ctx, OpList); err != nil {
+		return err
+	}
+
+	return s.next.List(ctx, prefix, fn)
+}
+
+type resetReader struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (r *resetReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		return 0, ErrConnectionReset
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,95 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/storage/memory"
+)
+
+// plainStore hides every optional interface of the store it wraps.
+type plainStore struct {
+	storage.Storage
+}
+
+type presigningStore struct {
+	storage.Storage
+}
+
+func (presigningStore) PresignGet(_ context.Context, key string, _ time.Duration) (string, error) {
+	return "https://example.com/" + key, nil
+}
+
+type rangePresigningStore struct {
+	presigningStore
+	storage.RangeReader
+}
+
+func TestNewForwardsOptionalInterfaces(t *testing.T) {
+	next := memory.NewStorage()
+	tests := []struct {
+		name          string
+		next          storage.Storage
+		wantRange     bool
+		wantPresigner bool
+	}{
+		{name: "plain", next: plainStore{memory.NewStorage()}},
+		{name: "ranged", next: memory.NewStorage(), wantRange: true},
+		{name: "presigner", next: presigningStore{plainStore{memory.NewStorage()}}, wantPresigner: true},
+		{name: "both", next: rangePresigningStore{presigningStore{next}, next.(storage.RangeReader)}, wantRange: true, wantPresigner: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := New(tt.next, 1)
+			if _, ok := store.(storage.RangeReader); ok != tt.wantRange {
+				t.Errorf("RangeReader = %v, want %v", ok, tt.wantRange)
+			}
+			if _, ok := store.(storage.Presigner); ok != tt.wantPresigner {
+				t.Errorf("Presigner = %v, want %v", ok, tt.wantPresigner)
+			}
+		})
+	}
+}
+
+func TestGetRangeFaults(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		length  int64
+		want    string
+		wantErr error
+	}{
+		{name: "no fault", length: 4, want: "2345"},
+		{name: "throttled", rule: Rule{Ops: []Op{OpGet}, Fault: Throttle, Every: 1}, length: 4, wantErr: ErrThrottled},
+		{name: "reset", rule: Rule{Ops: []Op{OpGet}, Fault: ConnectionReset, Every: 1}, length: 4, want: "23", wantErr: ErrConnectionReset},
+		{name: "reset to the end", rule: Rule{Ops: []Op{OpGet}, Fault: ConnectionReset, Every: 1}, length: -1, want: "2345", wantErr: ErrConnectionReset},
+		{name: "put faults ignored", rule: Rule{Ops: []Op{OpPut}, Fault: Throttle, Every: 1}, length: -1, want: "23456789"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := memory.NewStorage()
+			if _, err := next.Put(context.Background(), "f", strings.NewReader("0123456789"), storage.PutOptions{Size: -1}); err != nil {
+				t.Fatal(err)
+			}
+			store := New(next, 1, tt.rule).(storage.RangeReader)
+
+			body, err := store.GetRange(context.Background(), "f", 2, tt.length)
+			var got []byte
+			if err == nil {
+				got, err = io.ReadAll(body)
+				body.Close()
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetRange error = %v, want %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("GetRange = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// DirEntry is an immediate child of a directory. Storage backends have no real
+// directories, so directories are implied by "/"-separated key prefixes.
+type DirEntry struct {
+	Name  string
+	IsDir bool
+	Info  ObjectInfo
+}
+
+var errStopList = errors.New("stop listing")
+
+// ReadDir lists the immediate children of dir ("" for the root).
+func ReadDir(ctx context.Context, store Storage, dir string) ([]DirEntry, error) {
+	prefix := dirPrefix(dir)
+	entries := []DirEntry{}
+	seen := map[string]bool{}
+
+	err := store.List(ctx, prefix, func(info ObjectInfo) error {
+		rest := strings.TrimPrefix(info.Key, prefix)
+		if rest == "" {
+			return nil
+		}
+
+		name, _, isDir := strings.Cut(rest, "/")
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+
+		entry := DirEntry{Name: name, IsDir: isDir}
+		if !isDir {
+			entry.Info = info
+		}
+		entries = append(entries, entry)
+		return nil
+	})
+
+	return entries, err
+}
+
+// IsDir reports whether any object exists below dir.
+func IsDir(ctx context.Context, store Storage, dir string) (bool, error) {
+	found := false
+	err := store.List(ctx, dirPrefix(dir), func(ObjectInfo) error {
+		found = true
+		return errStopList
+	})
+	if err != nil && !errors.Is(err, errStopList) {
+		return false, err
+	}
+
+	return found, nil
+}
+
+func dirPrefix(dir string) string {
+	dir = strings.Trim(dir, "/")
+	if dir == "" {
+		return ""
+	}
+
+	return dir + "/"
+}
+
+// SeekReader gives random access to an object by re-opening it when reading
+// backwards, since Storage only offers sequential reads. Backends implementing
+// RangeReader are re-opened at the target offset instead.
+type SeekReader struct {
+	ctx    context.Context
+	store  Storage
+	info   ObjectInfo
+	body   io.ReadCloser
+	pos    int64
+	offset int64
+}
+
+func NewSeekReader(ctx context.Context, store Storage, info ObjectInfo) *SeekReader {
+	return &SeekReader{ctx: ctx, store: store, info: info}
+}
+
+func (r *SeekReader) Read(p []byte) (int, error) {
+	if r.offset >= r.info.Size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil || r.offset < r.pos {
+		if err := r.reopen(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.offset > r.pos {
+		skipped, err := io.CopyN(io.Discard, r.body, r.offset-r.pos)
+		r.pos += skipped
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.body.Read(p)
+	r.pos += int64(n)
+	r.offset = r.pos
+	return n, err
+}
+
+func (r *SeekReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.info.Size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	r.offset = offset
+	return offset, nil
+}
+
+func (r *SeekReader) reopen() error {
+	if r.body != nil {
+		r.body.Close()
+	}
+
+	if ranged, ok := r.store.(RangeReader); ok && r.offset > 0 {
+		body, err := ranged.GetRange(r.ctx, r.info.Key, r.offset, -1)
+		if err != nil {
+			r.body = nil
+			return err
+		}
+
+		r.body, r.pos = body, r.offset
+		return nil
+	}
+
+	body, _, err := r.store.Get(r.ctx, r.info.Key)
+	if err != nil {
+		r.body = nil
+		return err
+	}
+
+	r.body, r.pos = body, 0
+	return nil
+}
+
+func (r *SeekReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
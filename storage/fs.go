@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// WritableFS extends fs.FS with the two write operations Storage supports.
+type WritableFS interface {
+	fs.StatFS
+	fs.ReadDirFS
+	Create(name string) (io.WriteCloser, error)
+	Remove(name string) error
+}
+
+type storageFS struct {
+	ctx   context.Context
+	store Storage
+}
+
+// NewFS exposes store as an fs.FS, so fs.FS consumers such as html/template,
+// http.FS and fs.WalkDir can read objects directly. Files are read lazily and
+// implement io.Seeker and io.ReaderAt; every call is bound to ctx.
+func NewFS(ctx context.Context, store Storage) WritableFS {
+	return &storageFS{ctx: ctx, store: store}
+}
+
+func (f *storageFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if info.IsDir() {
+		return &fsDir{fs: f, name: name, info: info}, nil
+	}
+
+	object := info.Sys().(ObjectInfo)
+	return &fsFile{SeekReader: NewSeekReader(f.ctx, f.store, object), info: info}, nil
+}
+
+func (f *storageFS) Stat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrInvalid}
+	}
+
+	info, err := f.stat(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	return info, nil
+}
+
+func (f *storageFS) stat(name string) (fs.FileInfo, error) {
+	if name == "." {
+		return fsInfo{name: ".", dir: true}, nil
+	}
+
+	object, err := f.store.Stat(f.ctx, name)
+	if err == nil {
+		return fsInfo{name: path.Base(name), object: object}, nil
+	}
+	if !errors.Is(err, ErrNotExist) {
+		return nil, err
+	}
+
+	isDir, err := IsDir(f.ctx, f.store, name)
+	if err != nil {
+		return nil, err
+	}
+	if !isDir {
+		return nil, fs.ErrNotExist
+	}
+
+	return fsInfo{name: path.Base(name), dir: true}, nil
+}
+
+func (f *storageFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+
+	entries, err := f.readDir(name)
+	if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+
+	return entries, nil
+}
+
+func (f *storageFS) readDir(name string) ([]fs.DirEntry, error) {
+	dir := name
+	if dir == "." {
+		dir = ""
+	}
+
+	children, err := ReadDir(f.ctx, f.store, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(children) == 0 && dir != "" {
+		return nil, fs.ErrNotExist
+	}
+
+	entries := make([]fs.DirEntry, 0, len(children))
+	for _, child := range children {
+		entries = append(entries, fs.FileInfoToDirEntry(fsInfo{name: child.Name, dir: child.IsDir, object: child.Info}))
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// Create returns a writer that uploads to name; the object becomes visible once
+// Close returns without error.
+func (f *storageFS) Create(name string) (io.WriteCloser, error) {
+	if !fs.ValidPath(name) || name == "." {
+		return nil, &fs.PathError{Op: "create", Path: name, Err: fs.ErrInvalid}
+	}
+
+	pr, pw := io.Pipe()
+	w := &fsWriter{pw: pw, done: make(chan error, 1)}
+
+	go func() {
+		_, err := f.store.Put(f.ctx, name, pr, PutOptions{Size: -1})
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+
+	return w, nil
+}
+
+func (f *storageFS) Remove(name string) error {
+	if !fs.ValidPath(name) || name == "." {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if err := f.store.Delete(f.ctx, name); err != nil {
+		if errors.Is(err, ErrNotExist) {
+			err = fs.ErrNotExist
+		}
+		return &fs.PathError{Op: "remove", Path: name, Err: err}
+	}
+
+	return nil
+}
+
+type fsInfo struct {
+	name   string
+	dir    bool
+	object ObjectInfo
+}
+
+func (i fsInfo) Name() string { return i.name }
+func (i fsInfo) Size() int64  { return i.object.Size }
+func (i fsInfo) IsDir() bool  { return i.dir }
+
+func (i fsInfo) Mode() fs.FileMode {
+	if i.dir {
+		return fs.ModeDir | 0o555
+	}
+
+	return 0o444
+}
+
+func (i fsInfo) ModTime() time.Time { return i.object.LastModified }
+
+func (i fsInfo) Sys() any {
+	if i.dir {
+		return nil
+	}
+
+	return i.object
+}
+
+type fsFile struct {
+	*SeekReader
+	info fs.FileInfo
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// ReadAt fills p from off and returns io.EOF when the object ends first. Each
+// call reads the range on its own, so calls may run in parallel with each other
+// and with Read and Seek, whose offset is left alone.
+func (f *fsFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, &fs.PathError{Op: "readat", Path: f.info.Name(), Err: errors.New("negative offset")}
+	}
+
+	size := f.info.Size()
+	if off >= size {
+		return 0, io.EOF
+	}
+
+	length := min(int64(len(p)), size-off)
+	body, err := f.openRange(off, length)
+	if err != nil {
+		return 0, &fs.PathError{Op: "readat", Path: f.info.Name(), Err: err}
+	}
+	defer body.Close()
+
+	n, err := io.ReadFull(body, p[:length])
+	if errors.Is(err, io.ErrUnexpectedEOF) || (err == nil && length < int64(len(p))) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// openRange opens length bytes of the object from off, skipping to off on
+// backends that only read sequentially.
+func (f *fsFile) openRange(off, length int64) (io.ReadCloser, error) {
+	r := f.SeekReader
+	if ranged, ok := r.store.(RangeReader); ok {
+		return ranged.GetRange(r.ctx, r.info.Key, off, length)
+	}
+
+	body, _, err := r.store.Get(r.ctx, r.info.Key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, body, off); err != nil {
+		body.Close()
+		return nil, err
+	}
+
+	return body, nil
+}
+
+type fsDir struct {
+	fs      *storageFS
+	name    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	read    bool
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: fs.ErrInvalid}
+}
+
+func (d *fsDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	if !d.read {
+		entries, err := d.fs.readDir(d.name)
+		if err != nil {
+			return nil, &fs.PathError{Op: "readdir", Path: d.name, Err: err}
+		}
+		d.entries, d.read = entries, true
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n := min(count, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+type fsWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func (w *fsWriter) Write(p []byte) (int, error) { return w.pw.Write(p) }
+
+func (w *fsWriter) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
@@ -0,0 +1,123 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/storage/memory"
+)
+
+// sequentialStore hides GetRange and returns bodies that read one byte at a
+// time, the shortest reads a backend may give.
+type sequentialStore struct {
+	storage.Storage
+}
+
+func (s sequentialStore) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	body, info, err := s.Storage.Get(ctx, key)
+	if err != nil {
+		return nil, info, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{iotest.OneByteReader(body), body}, info, nil
+}
+
+func TestFileReadAt(t *testing.T) {
+	const content = "0123456789"
+	tests := []struct {
+		name    string
+		off     int64
+		size    int
+		want    string
+		wantErr error
+	}{
+		{name: "start", off: 0, size: 4, want: "0123"},
+		{name: "middle", off: 3, size: 4, want: "3456"},
+		{name: "whole", off: 0, size: 10, want: content},
+		{name: "past end", off: 8, size: 4, want: "89", wantErr: io.EOF},
+		{name: "at end", off: 10, size: 4, want: "", wantErr: io.EOF},
+	}
+	stores := map[string]storage.Storage{
+		"ranged":     memory.NewStorage(),
+		"sequential": sequentialStore{memory.NewStorage()},
+	}
+	for storeName, store := range stores {
+		if _, err := store.Put(context.Background(), "f", strings.NewReader(content), storage.PutOptions{Size: -1}); err != nil {
+			t.Fatal(err)
+		}
+		fsys := storage.NewFS(context.Background(), store)
+
+		for _, tt := range tests {
+			t.Run(storeName+"/"+tt.name, func(t *testing.T) {
+				f, err := fsys.Open("f")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+
+				// ReadAt must not move the offset sequential reads use.
+				head := make([]byte, 2)
+				if _, err := io.ReadFull(f, head); err != nil {
+					t.Fatal(err)
+				}
+
+				p := make([]byte, tt.size)
+				n, err := f.(io.ReaderAt).ReadAt(p, tt.off)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("ReadAt error = %v, want %v", err, tt.wantErr)
+				}
+				if got := string(p[:n]); got != tt.want {
+					t.Errorf("ReadAt = %q, want %q", got, tt.want)
+				}
+
+				rest, err := io.ReadAll(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := string(head) + string(rest); got != content {
+					t.Errorf("Read after ReadAt = %q, want %q", got, content)
+				}
+			})
+		}
+	}
+}
+
+func TestFileReadAtConcurrent(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	store := memory.NewStorage()
+	if _, err := store.Put(context.Background(), "f", strings.NewReader(content), storage.PutOptions{Size: -1}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := storage.NewFS(context.Background(), store).Open("f")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var wg sync.WaitGroup
+	for off := 0; off < len(content); off += 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := make([]byte, 10)
+			if _, err := f.(io.ReaderAt).ReadAt(p, int64(off)); err != nil {
+				t.Errorf("ReadAt(%d): %v", off, err)
+			} else if got := string(p); got != content[off:off+10] {
+				t.Errorf("ReadAt(%d) = %q, want %q", off, got, content[off:off+10])
+			}
+		}()
+	}
+	// Sequential reads share the file with the ReadAt calls.
+	if got, err := io.ReadAll(f); err != nil || string(got) != content {
+		t.Errorf("ReadAll = %d bytes, %v", len(got), err)
+	}
+	wg.Wait()
+}
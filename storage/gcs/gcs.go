@@ -0,0 +1,114 @@
+package gcs
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	gcs "cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+type bucketStorage struct {
+	bucket *gcs.BucketHandle
+}
+
+// NewStorage exposes a Google Cloud Storage bucket as a storage.Storage.
+func NewStorage(client *gcs.Client, bucketName string) storage.Storage {
+	return &bucketStorage{bucket: client.Bucket(bucketName)}
+}
+
+func (b *bucketStorage) Stat(ctx context.Context, key string) (storage.ObjectInfo, error) {
+	attrs, err := b.bucket.Object(key).Attrs(ctx)
+	if err != nil {
+		return storage.ObjectInfo{}, mapError(err)
+	}
+
+	return objectInfo(attrs), nil
+}
+
+// Get reads the object without an extra metadata call; ETag and user metadata are
+// therefore not populated, use Stat when they are needed.
+func (b *bucketStorage) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	reader, err := b.bucket.Object(key).NewReader(ctx)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, mapError(err)
+	}
+
+	return reader, storage.ObjectInfo{
+		Key:          key,
+		Size:         reader.Attrs.Size,
+		ContentType:  reader.Attrs.ContentType,
+		LastModified: reader.Attrs.LastModified,
+	}, nil
+}
+
+func (b *bucketStorage) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (storage.ObjectInfo, error) {
+	// Cancelling the writer's context is how an upload is aborted; the object
+	// is then never created.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	writer := b.bucket.Object(key).NewWriter(ctx)
+	writer.ContentType = opts.ContentType
+	writer.Metadata = opts.Metadata
+
+	if _, err := io.Copy(writer, r); err != nil {
+		cancel()
+		writer.Close()
+		return storage.ObjectInfo{}, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	return objectInfo(writer.Attrs()), nil
+}
+
+func (b *bucketStorage) Delete(ctx context.Context, key string) error {
+	if err := mapError(b.bucket.Object(key).Delete(ctx)); err != nil && !errors.Is(err, storage.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (b *bucketStorage) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	it := b.bucket.Objects(ctx, &gcs.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(objectInfo(attrs)); err != nil {
+			return err
+		}
+	}
+}
+
+func objectInfo(attrs *gcs.ObjectAttrs) storage.ObjectInfo {
+	return storage.ObjectInfo{
+		Key:          attrs.Name,
+		Size:         attrs.Size,
+		ContentType:  attrs.ContentType,
+		ETag:         attrs.Etag,
+		MD5:          attrs.MD5,
+		LastModified: attrs.Updated,
+		Metadata:     attrs.Metadata,
+	}
+}
+
+func mapError(err error) error {
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return storage.ErrNotExist
+	}
+
+	return err
+}
@@ -0,0 +1,138 @@
+// Package memory is a storage.Storage kept in process memory, for tests and
+// other short-lived data.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+type memoryStorage struct {
+	mu      sync.RWMutex
+	objects map[string]object
+}
+
+type object struct {
+	data []byte
+	info storage.ObjectInfo
+}
+
+// NewStorage returns an empty store. It also implements storage.RangeReader.
+func NewStorage() storage.Storage {
+	return &memoryStorage{objects: map[string]object{}}
+}
+
+func (m *memoryStorage) Stat(_ context.Context, key string) (storage.ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	o, ok := m.objects[key]
+	if !ok {
+		return storage.ObjectInfo{}, storage.ErrNotExist
+	}
+
+	return o.info, nil
+}
+
+func (m *memoryStorage) Get(_ context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	o, ok := m.objects[key]
+	if !ok {
+		return nil, storage.ObjectInfo{}, storage.ErrNotExist
+	}
+
+	return io.NopCloser(bytes.NewReader(o.data)), o.info, nil
+}
+
+func (m *memoryStorage) GetRange(_ context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	o, ok := m.objects[key]
+	if !ok {
+		return nil, storage.ErrNotExist
+	}
+
+	data := o.data[min(offset, int64(len(o.data))):]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memoryStorage) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (storage.ObjectInfo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	sum := md5.Sum(data)
+	info := storage.ObjectInfo{
+		Key:          key,
+		Size:         int64(len(data)),
+		ContentType:  opts.ContentType,
+		ETag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		MD5:          sum[:],
+		LastModified: time.Now(),
+		Metadata:     maps.Clone(opts.Metadata),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.objects[key] = object{data: data, info: info}
+	return info, nil
+}
+
+func (m *memoryStorage) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memoryStorage) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	m.mu.RLock()
+	keys := []string{}
+	for key := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	m.mu.RUnlock()
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		info, err := m.Stat(ctx, key)
+		if err != nil {
+			// Deleted while listing.
+			continue
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
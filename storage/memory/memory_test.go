@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	store := NewStorage()
+	for _, key := range []string{"b/2", "a", "b/1", "c"} {
+		if _, err := store.Put(ctx, key, strings.NewReader("data-"+key), storage.PutOptions{ContentType: "text/plain", Size: -1}); err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		prefix string
+		want   []string
+	}{
+		{name: "all", prefix: "", want: []string{"a", "b/1", "b/2", "c"}},
+		{name: "prefix", prefix: "b/", want: []string{"b/1", "b/2"}},
+		{name: "none", prefix: "x", want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			err := store.List(ctx, tt.prefix, func(info storage.ObjectInfo) error {
+				got = append(got, info.Key)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("List(%q) = %v, want %v", tt.prefix, got, tt.want)
+			}
+		})
+	}
+
+	body, info, err := store.Get(ctx, "b/1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	if string(data) != "data-b/1" || info.Size != int64(len(data)) || info.ETag == "" {
+		t.Errorf("Get = %q, %+v", data, info)
+	}
+
+	ranged, err := store.(storage.RangeReader).GetRange(ctx, "b/1", 5, 2)
+	if err != nil {
+		t.Fatalf("GetRange: %v", err)
+	}
+	if data, _ := io.ReadAll(ranged); string(data) != "b/" {
+		t.Errorf("GetRange = %q, want %q", data, "b/")
+	}
+
+	if err := store.Delete(ctx, "b/1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Stat(ctx, "b/1"); !errors.Is(err, storage.ErrNotExist) {
+		t.Errorf("Stat after Delete = %v, want ErrNotExist", err)
+	}
+}
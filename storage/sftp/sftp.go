@@ -0,0 +1,194 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/sftp"
+
+	"github.com/KurniawanHendiW/file-uploader/idgen"
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+type serverStorage struct {
+	client *sftp.Client
+	root   string
+}
+
+// NewStorage exposes the directory tree under root on an SFTP server as a
+// storage.Storage. Object metadata is not supported by SFTP and is dropped on Put;
+// content types are derived from file extensions.
+func NewStorage(client *sftp.Client, root string) storage.Storage {
+	return &serverStorage{client: client, root: path.Clean("/" + root)}
+}
+
+// remotePath maps key under root, rejecting keys that would escape it.
+func (s *serverStorage) remotePath(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return "", fmt.Errorf("invalid key %q", key)
+	}
+
+	for _, segment := range strings.Split(key, "/") {
+		if segment == ".." || segment == "." || segment == "" {
+			return "", fmt.Errorf("invalid key %q", key)
+		}
+	}
+
+	return path.Join(s.root, key), nil
+}
+
+func (s *serverStorage) Stat(_ context.Context, key string) (storage.ObjectInfo, error) {
+	p, err := s.remotePath(key)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	info, err := s.client.Stat(p)
+	if err != nil {
+		return storage.ObjectInfo{}, mapError(err)
+	}
+
+	if info.IsDir() {
+		return storage.ObjectInfo{}, storage.ErrNotExist
+	}
+
+	return objectInfo(key, info), nil
+}
+
+func (s *serverStorage) Get(ctx context.Context, key string) (io.ReadCloser, storage.ObjectInfo, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, err
+	}
+
+	p, _ := s.remotePath(key)
+	file, err := s.client.Open(p)
+	if err != nil {
+		return nil, storage.ObjectInfo{}, mapError(err)
+	}
+
+	return file, info, nil
+}
+
+// Put writes to a temporary file next to the target and renames it into place, so
+// readers never observe a partially written file. Each Put has its own temporary
+// file, so concurrent writers to one key cannot mix their contents.
+func (s *serverStorage) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (storage.ObjectInfo, error) {
+	p, err := s.remotePath(key)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	if err := s.client.MkdirAll(path.Dir(p)); err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	tmp := p + "." + idgen.Default.NewID() + ".partial"
+	file, err := s.client.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+
+	if _, err := file.ReadFrom(r); err != nil {
+		file.Close()
+		s.client.Remove(tmp)
+		return storage.ObjectInfo{}, err
+	}
+
+	if err := file.Close(); err != nil {
+		s.client.Remove(tmp)
+		return storage.ObjectInfo{}, err
+	}
+
+	if err := s.client.PosixRename(tmp, p); err != nil {
+		s.client.Remove(tmp)
+		return storage.ObjectInfo{}, err
+	}
+
+	return s.Stat(ctx, key)
+}
+
+func (s *serverStorage) Delete(_ context.Context, key string) error {
+	p, err := s.remotePath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := mapError(s.client.Remove(p)); err != nil && !errors.Is(err, storage.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *serverStorage) List(ctx context.Context, prefix string, fn func(storage.ObjectInfo) error) error {
+	// Walk from the deepest directory fully contained in prefix.
+	dir := s.root
+	if i := strings.LastIndex(prefix, "/"); i >= 0 {
+		p, err := s.remotePath(prefix[:i])
+		if err != nil {
+			return err
+		}
+		dir = p
+	}
+
+	infos := []storage.ObjectInfo{}
+	walker := s.client.Walk(dir)
+	for walker.Step() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := walker.Err(); err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		stat := walker.Stat()
+		if stat.IsDir() || strings.HasSuffix(walker.Path(), ".partial") {
+			continue
+		}
+
+		key := strings.TrimPrefix(strings.TrimPrefix(walker.Path(), s.root), "/")
+		if strings.HasPrefix(key, prefix) {
+			infos = append(infos, objectInfo(key, stat))
+		}
+	}
+
+	// SFTP directory listings are unordered, while Storage promises lexical order.
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func objectInfo(key string, info os.FileInfo) storage.ObjectInfo {
+	return storage.ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  mime.TypeByExtension(path.Ext(key)),
+		LastModified: info.ModTime(),
+	}
+}
+
+func mapError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return storage.ErrNotExist
+	}
+
+	return err
+}
@@ -0,0 +1,116 @@
+package sftp
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+func newTestStorage(t *testing.T) storage.Storage {
+	t.Helper()
+
+	serverConn, clientConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, sftp.InMemHandler())
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	return NewStorage(client, "/data")
+}
+
+// pausedReader returns its first half, then waits for resume before the rest.
+type pausedReader struct {
+	first, rest io.Reader
+	paused      chan struct{}
+	resume      chan struct{}
+}
+
+func newPausedReader(body string) *pausedReader {
+	half := len(body) / 2
+	return &pausedReader{
+		first:  strings.NewReader(body[:half]),
+		rest:   strings.NewReader(body[half:]),
+		paused: make(chan struct{}),
+		resume: make(chan struct{}),
+	}
+}
+
+func (r *pausedReader) Read(p []byte) (int, error) {
+	if n, _ := r.first.Read(p); n > 0 {
+		return n, nil
+	}
+	if r.paused != nil {
+		close(r.paused)
+		r.paused = nil
+		<-r.resume
+	}
+	return r.rest.Read(p)
+}
+
+func TestPutConcurrentWriters(t *testing.T) {
+	tests := []struct {
+		name          string
+		first, second string
+	}{
+		{name: "same length", first: strings.Repeat("a", 64), second: strings.Repeat("b", 64)},
+		{name: "shorter second", first: strings.Repeat("a", 64), second: "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newTestStorage(t)
+
+			slow := newPausedReader(tt.first)
+			paused := slow.paused
+			done := make(chan error, 1)
+			go func() {
+				_, err := s.Put(ctx, "dir/f.txt", slow, storage.PutOptions{Size: -1})
+				done <- err
+			}()
+
+			<-paused
+			if _, err := s.Put(ctx, "dir/f.txt", strings.NewReader(tt.second), storage.PutOptions{Size: -1}); err != nil {
+				t.Fatal(err)
+			}
+			close(slow.resume)
+			if err := <-done; err != nil {
+				t.Fatalf("first Put failed: %v", err)
+			}
+
+			body, _, err := s.Get(ctx, "dir/f.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer body.Close()
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.first {
+				t.Errorf("f.txt = %q, want the last completed write %q", got, tt.first)
+			}
+
+			var keys []string
+			s.List(ctx, "", func(info storage.ObjectInfo) error {
+				keys = append(keys, info.Key)
+				return nil
+			})
+			if len(keys) != 1 {
+				t.Errorf("List = %v, want only dir/f.txt", keys)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotExist = errors.New("object does not exist")
+
+type (
+	ObjectInfo struct {
+		Key          string
+		Size         int64
+		ContentType  string
+		ETag         string
+		MD5          []byte
+		LastModified time.Time
+		Metadata     map[string]string
+	}
+
+	PutOptions struct {
+		ContentType string
+		// Size is the body length, or -1 when unknown.
+		Size     int64
+		Metadata map[string]string
+	}
+
+	// Storage is the provider-neutral object store implemented by every backend
+	// (S3, GCS, Azure Blob, ...). Keys are "/"-separated and relative to the
+	// backend's bucket or container.
+	Storage interface {
+		Stat(ctx context.Context, key string) (ObjectInfo, error)
+		Get(ctx context.Context, key string) (io.ReadCloser, ObjectInfo, error)
+		Put(ctx context.Context, key string, r io.Reader, opts PutOptions) (ObjectInfo, error)
+		Delete(ctx context.Context, key string) error
+		// List calls fn for every object under prefix in lexical key order,
+		// stopping at the first error fn returns.
+		List(ctx context.Context, prefix string, fn func(ObjectInfo) error) error
+	}
+
+	// RangeReader is implemented by backends that can read from an offset
+	// without streaming the bytes before it. A negative length reads to the end.
+	RangeReader interface {
+		GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	}
+
+	// Presigner is implemented by backends that can hand out time-limited
+	// download URLs.
+	Presigner interface {
+		PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+	}
+)
@@ -0,0 +1,241 @@
+package testharness
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	awsS3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/localstack"
+	"github.com/testcontainers/testcontainers-go/modules/minio"
+
+	"github.com/KurniawanHendiW/file-uploader/s3"
+)
+
+type Backend string
+
+const (
+	MinIO      Backend = "minio"
+	LocalStack Backend = "localstack"
+)
+
+const (
+	defaultRegion          = "us-east-1"
+	defaultMinIOImage      = "minio/minio:RELEASE.2024-01-16T16-07-38Z"
+	defaultLocalStackImage = "localstack/localstack:3.8"
+	localStackCredential   = "test"
+)
+
+type Options struct {
+	// Backend defaults to MinIO.
+	Backend Backend
+	// Image overrides the container image of the backend.
+	Image   string
+	Buckets []string
+}
+
+// Harness is a disposable S3-compatible server for integration tests. Its
+// helpers use a plain SDK client, independent of the package under test.
+type Harness struct {
+	Endpoint  string
+	Region    string
+	AccessKey string
+	SecretKey string
+
+	container testcontainers.Container
+	client    *awsS3.Client
+}
+
+// New starts a harness for t and terminates it when the test ends. It skips the
+// test in -short mode and fails it when the container cannot start.
+func New(t testing.TB, opts Options) *Harness {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	h, err := Start(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("failed to start test harness: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := h.Terminate(context.Background()); err != nil {
+			t.Logf("failed to terminate test harness: %v", err)
+		}
+	})
+
+	return h
+}
+
+func Start(ctx context.Context, opts Options) (*Harness, error) {
+	h := &Harness{Region: defaultRegion}
+
+	switch opts.Backend {
+	case "", MinIO:
+		image := opts.Image
+		if image == "" {
+			image = defaultMinIOImage
+		}
+
+		container, err := minio.Run(ctx, image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start minio: %w", err)
+		}
+		h.container, h.AccessKey, h.SecretKey = container, container.Username, container.Password
+
+		address, err := container.ConnectionString(ctx)
+		if err != nil {
+			return nil, errors.Join(err, h.Terminate(ctx))
+		}
+		h.Endpoint = "http://" + address
+	case LocalStack:
+		image := opts.Image
+		if image == "" {
+			image = defaultLocalStackImage
+		}
+
+		container, err := localstack.Run(ctx, image)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start localstack: %w", err)
+		}
+		h.container, h.AccessKey, h.SecretKey = container, localStackCredential, localStackCredential
+
+		h.Endpoint, err = container.PortEndpoint(ctx, "4566/tcp", "http")
+		if err != nil {
+			return nil, errors.Join(err, h.Terminate(ctx))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported backend %q", opts.Backend)
+	}
+
+	h.client = awsS3.New(awsS3.Options{
+		Region:       h.Region,
+		BaseEndpoint: aws.String(h.Endpoint),
+		UsePathStyle: true,
+		Credentials:  h.credentials(),
+	})
+
+	for _, bucket := range opts.Buckets {
+		if err := h.CreateBucket(ctx, bucket); err != nil {
+			return nil, errors.Join(err, h.Terminate(ctx))
+		}
+	}
+
+	return h, nil
+}
+
+func (h *Harness) credentials() aws.CredentialsProvider {
+	return credentials.NewStaticCredentialsProvider(h.AccessKey, h.SecretKey, "")
+}
+
+// Service returns an S3Service talking to the harness.
+func (h *Harness) Service(opts ...s3.Option) s3.S3Service {
+	opts = append([]s3.Option{s3.WithEndpoint(h.Endpoint), s3.WithCredentials(h.credentials())}, opts...)
+	return s3.NewS3Service(h.Region, opts...)
+}
+
+func (h *Harness) Client() *awsS3.Client {
+	return h.client
+}
+
+func (h *Harness) CreateBucket(ctx context.Context, bucketName string) error {
+	_, err := h.client.CreateBucket(ctx, &awsS3.CreateBucketInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		return fmt.Errorf("failed to create bucket %s: %w", bucketName, err)
+	}
+
+	return nil
+}
+
+func (h *Harness) AssertObjectExists(t testing.TB, bucketName, key string) {
+	t.Helper()
+
+	if _, err := h.head(bucketName, key); err != nil {
+		t.Errorf("expected %s/%s to exist: %v", bucketName, key, err)
+	}
+}
+
+func (h *Harness) AssertObjectMissing(t testing.TB, bucketName, key string) {
+	t.Helper()
+
+	if _, err := h.head(bucketName, key); err == nil {
+		t.Errorf("expected %s/%s not to exist", bucketName, key)
+	}
+}
+
+func (h *Harness) ReadObject(t testing.TB, bucketName, key string) []byte {
+	t.Helper()
+
+	output, err := h.client.GetObject(context.Background(), &awsS3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		t.Fatalf("failed to read %s/%s: %v", bucketName, key, err)
+	}
+	defer output.Body.Close()
+
+	body, err := io.ReadAll(output.Body)
+	if err != nil {
+		t.Fatalf("failed to read %s/%s: %v", bucketName, key, err)
+	}
+
+	return body
+}
+
+// ErrEmptyObject is returned by CorruptObject, since an empty object has no
+// byte to flip without changing its size.
+var ErrEmptyObject = errors.New("cannot corrupt an empty object")
+
+// CorruptObject flips the first byte of an object while keeping its size,
+// content type and metadata, to exercise checksum verification paths.
+func (h *Harness) CorruptObject(t testing.TB, bucketName, key string) error {
+	t.Helper()
+
+	head, err := h.head(bucketName, key)
+	if err != nil {
+		t.Fatalf("failed to stat %s/%s: %v", bucketName, key, err)
+	}
+
+	body := h.ReadObject(t, bucketName, key)
+	if len(body) == 0 {
+		return fmt.Errorf("%w: %s/%s", ErrEmptyObject, bucketName, key)
+	}
+	body[0] ^= 0xff
+
+	_, err = h.client.PutObject(context.Background(), &awsS3.PutObjectInput{
+		Bucket:      aws.String(bucketName),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: head.ContentType,
+		Metadata:    head.Metadata,
+	})
+	if err != nil {
+		t.Fatalf("failed to corrupt %s/%s: %v", bucketName, key, err)
+	}
+
+	return nil
+}
+
+func (h *Harness) head(bucketName, key string) (*awsS3.HeadObjectOutput, error) {
+	return h.client.HeadObject(context.Background(), &awsS3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+}
+
+func (h *Harness) Terminate(ctx context.Context) error {
+	if h.container == nil {
+		return nil
+	}
+
+	return h.container.Terminate(ctx)
+}
@@ -0,0 +1,328 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+)
+
+const defaultConcurrency = 8
+
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+type (
+	Options struct {
+		Prefix            string
+		DestinationPrefix string
+		// Concurrency bounds the number of objects in flight, and with it memory use,
+		// since every object is streamed from source to destination.
+		Concurrency int
+		// Verify compares the MD5 of the streamed bytes with the source and the
+		// destination, re-reading the destination when it reports no digest.
+		Verify bool
+		// SkipExisting skips objects already present in the destination with the
+		// same size.
+		SkipExisting bool
+		// CheckpointPath records completed keys so an interrupted transfer resumes
+		// without copying them again.
+		CheckpointPath string
+		OnProgress     func(Progress)
+	}
+
+	Progress struct {
+		Key         string
+		Transferred int64
+		Skipped     int64
+		Failed      int64
+		Bytes       int64
+	}
+
+	Failure struct {
+		Key string
+		Err error
+	}
+
+	Result struct {
+		Transferred int64
+		Skipped     int64
+		Bytes       int64
+		Failed      []Failure
+	}
+)
+
+// Copy streams every object under opts.Prefix from src to dst. The backends may
+// belong to different providers.
+func Copy(ctx context.Context, src, dst storage.Storage, opts Options) (Result, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	checkpoint, err := openCheckpoint(opts.CheckpointPath)
+	if err != nil {
+		return Result{}, err
+	}
+	defer checkpoint.Close()
+
+	var (
+		mu     sync.Mutex
+		result Result
+		wg     sync.WaitGroup
+		sem    = make(chan struct{}, concurrency)
+	)
+
+	record := func(key string, size int64, skipped bool, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case err != nil:
+			result.Failed = append(result.Failed, Failure{Key: key, Err: err})
+		case skipped:
+			result.Skipped++
+		default:
+			result.Transferred++
+			result.Bytes += size
+		}
+
+		if err == nil {
+			if cpErr := checkpoint.Done(key); cpErr != nil {
+				log.Printf("failed to checkpoint %s: %v", key, cpErr)
+			}
+		}
+
+		if opts.OnProgress != nil {
+			opts.OnProgress(Progress{
+				Key:         key,
+				Transferred: result.Transferred,
+				Skipped:     result.Skipped,
+				Failed:      int64(len(result.Failed)),
+				Bytes:       result.Bytes,
+			})
+		}
+	}
+
+	listErr := src.List(ctx, opts.Prefix, func(info storage.ObjectInfo) error {
+		if checkpoint.Completed(info.Key) {
+			record(info.Key, 0, true, nil)
+			return nil
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			dstKey := opts.DestinationPrefix + strings.TrimPrefix(info.Key, opts.Prefix)
+			skipped, err := copyObject(ctx, src, dst, info, dstKey, opts)
+			if err != nil {
+				log.Printf("failed to transfer %s: %v", info.Key, err)
+			}
+			record(info.Key, info.Size, skipped, err)
+		}()
+
+		return nil
+	})
+	wg.Wait()
+
+	if listErr != nil {
+		return result, fmt.Errorf("failed to list source: %w", listErr)
+	}
+
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("failed to transfer %d objects", len(result.Failed))
+	}
+
+	checkpoint.Remove()
+	return result, nil
+}
+
+func copyObject(ctx context.Context, src, dst storage.Storage, info storage.ObjectInfo, dstKey string, opts Options) (bool, error) {
+	if opts.SkipExisting {
+		existing, err := dst.Stat(ctx, dstKey)
+		if err == nil && existing.Size == info.Size {
+			return true, nil
+		}
+		if err != nil && !errors.Is(err, storage.ErrNotExist) {
+			return false, err
+		}
+	}
+
+	body, srcInfo, err := src.Get(ctx, info.Key)
+	if err != nil {
+		return false, err
+	}
+	defer body.Close()
+
+	hash := md5.New()
+	reader := io.Reader(body)
+	if opts.Verify {
+		reader = io.TeeReader(body, hash)
+	}
+
+	putInfo, err := dst.Put(ctx, dstKey, reader, storage.PutOptions{
+		ContentType: srcInfo.ContentType,
+		Size:        srcInfo.Size,
+		Metadata:    srcInfo.Metadata,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if !opts.Verify {
+		return false, nil
+	}
+
+	sum := hash.Sum(nil)
+	sourceSum := srcInfo.MD5
+	if sourceSum == nil {
+		sourceSum = info.MD5
+	}
+	if sourceSum != nil && !bytes.Equal(sourceSum, sum) {
+		return false, mismatch(ctx, dst, dstKey, "source")
+	}
+
+	destinationSum, err := destinationMD5(ctx, dst, dstKey, putInfo)
+	if err != nil {
+		return false, err
+	}
+	if !bytes.Equal(destinationSum, sum) {
+		return false, mismatch(ctx, dst, dstKey, "destination")
+	}
+
+	return false, nil
+}
+
+func destinationMD5(ctx context.Context, dst storage.Storage, key string, putInfo storage.ObjectInfo) ([]byte, error) {
+	if putInfo.MD5 != nil {
+		return putInfo.MD5, nil
+	}
+
+	if stat, err := dst.Stat(ctx, key); err == nil && stat.MD5 != nil {
+		return stat.MD5, nil
+	}
+
+	body, _, err := dst.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, body); err != nil {
+		return nil, err
+	}
+
+	return hash.Sum(nil), nil
+}
+
+// mismatch removes the corrupt copy so it is never mistaken for a good one.
+func mismatch(ctx context.Context, dst storage.Storage, key, side string) error {
+	if err := dst.Delete(ctx, key); err != nil {
+		log.Printf("failed to remove corrupt copy %s: %v", key, err)
+	}
+
+	return fmt.Errorf("%w: %s digest differs for %s", ErrChecksumMismatch, side, key)
+}
+
+// checkpoint is an append-only file with one JSON-encoded completed key per line.
+type checkpoint struct {
+	path string
+	file *os.File
+	done map[string]bool
+	mu   sync.Mutex
+}
+
+func openCheckpoint(path string) (*checkpoint, error) {
+	cp := &checkpoint{path: path, done: map[string]bool{}}
+	if path == "" {
+		return cp, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var key string
+		if err := json.Unmarshal(scanner.Bytes(), &key); err == nil {
+			cp.done[key] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	cp.file = file
+	return cp, nil
+}
+
+func (c *checkpoint) Completed(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.done[key]
+}
+
+func (c *checkpoint) Done(key string) error {
+	if c.file == nil {
+		return nil
+	}
+
+	line, err := json.Marshal(key)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done[key] {
+		return nil
+	}
+
+	c.done[key] = true
+	_, err = c.file.Write(append(line, '\n'))
+	return err
+}
+
+func (c *checkpoint) Close() {
+	if c.file != nil {
+		c.file.Close()
+	}
+}
+
+func (c *checkpoint) Remove() {
+	if c.file == nil {
+		return
+	}
+
+	c.file.Close()
+	c.file = nil
+	if err := os.Remove(c.path); err != nil {
+		log.Printf("failed to remove checkpoint %s: %v", c.path, err)
+	}
+}
@@ -0,0 +1,186 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/KurniawanHendiW/file-uploader/storage"
+	"github.com/KurniawanHendiW/file-uploader/storage/memory"
+)
+
+// corruptingStore flips the first byte of every object it stores.
+type corruptingStore struct {
+	storage.Storage
+}
+
+func (c corruptingStore) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (storage.ObjectInfo, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return storage.ObjectInfo{}, err
+	}
+	if len(data) > 0 {
+		data[0] ^= 1
+	}
+
+	info, err := c.Storage.Put(ctx, key, bytes.NewReader(data), opts)
+	info.MD5 = nil
+	return info, err
+}
+
+// failingStore rejects writes of one key.
+type failingStore struct {
+	storage.Storage
+	key string
+}
+
+func (f failingStore) Put(ctx context.Context, key string, r io.Reader, opts storage.PutOptions) (storage.ObjectInfo, error) {
+	if key == f.key {
+		return storage.ObjectInfo{}, errors.New("write rejected")
+	}
+	return f.Storage.Put(ctx, key, r, opts)
+}
+
+func put(t *testing.T, store storage.Storage, key, content string) {
+	t.Helper()
+
+	opts := storage.PutOptions{ContentType: "text/plain", Size: int64(len(content)), Metadata: map[string]string{"owner": "alice"}}
+	if _, err := store.Put(context.Background(), key, strings.NewReader(content), opts); err != nil {
+		t.Fatalf("Put %s: %v", key, err)
+	}
+}
+
+func keys(t *testing.T, store storage.Storage) []string {
+	t.Helper()
+
+	got := []string{}
+	err := store.List(context.Background(), "", func(info storage.ObjectInfo) error {
+		got = append(got, info.Key)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	return got
+}
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	src, dst := memory.NewStorage(), memory.NewStorage()
+	put(t, src, "in/a.txt", "alpha")
+	put(t, src, "in/sub/b.txt", "bravo")
+	put(t, src, "other/c.txt", "charlie")
+
+	var progress []Progress
+	result, err := Copy(ctx, src, dst, Options{
+		Prefix:            "in/",
+		DestinationPrefix: "out/",
+		Verify:            true,
+		Concurrency:       1,
+		OnProgress:        func(p Progress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if result.Transferred != 2 || result.Bytes != 10 || result.Skipped != 0 || len(result.Failed) != 0 {
+		t.Errorf("result = %+v, want 2 objects and 10 bytes transferred", result)
+	}
+	if len(progress) != 2 || progress[1].Transferred != 2 {
+		t.Errorf("progress = %+v, want one update per object", progress)
+	}
+
+	if got, want := keys(t, dst), []string{"out/a.txt", "out/sub/b.txt"}; !slices.Equal(got, want) {
+		t.Errorf("destination holds %v, want %v", got, want)
+	}
+	body, info, err := dst.Get(ctx, "out/sub/b.txt")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	data, _ := io.ReadAll(body)
+	if string(data) != "bravo" || info.ContentType != "text/plain" || info.Metadata["owner"] != "alice" {
+		t.Errorf("copied %q with %+v, want the source body, content type and metadata", data, info)
+	}
+}
+
+func TestCopySkipExisting(t *testing.T) {
+	src, dst := memory.NewStorage(), memory.NewStorage()
+	put(t, src, "a.txt", "alpha")
+	put(t, src, "b.txt", "bravo")
+	put(t, dst, "a.txt", "ALPHA")
+	put(t, dst, "b.txt", "BRAVO!")
+
+	result, err := Copy(context.Background(), src, dst, Options{SkipExisting: true})
+	if err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if result.Skipped != 1 || result.Transferred != 1 {
+		t.Errorf("result = %+v, want a.txt skipped and b.txt replaced", result)
+	}
+}
+
+func TestCopyVerifyRemovesCorruptCopies(t *testing.T) {
+	src, dst := memory.NewStorage(), memory.NewStorage()
+	put(t, src, "a.txt", "alpha")
+
+	result, err := Copy(context.Background(), src, corruptingStore{dst}, Options{Verify: true})
+	if err == nil {
+		t.Fatal("Copy of a corrupted object succeeded")
+	}
+	if len(result.Failed) != 1 || !errors.Is(result.Failed[0].Err, ErrChecksumMismatch) {
+		t.Errorf("failures = %+v, want a checksum mismatch", result.Failed)
+	}
+	if got := keys(t, dst); len(got) != 0 {
+		t.Errorf("destination holds %v, want the corrupt copy removed", got)
+	}
+}
+
+func TestCopyResumesFromCheckpoint(t *testing.T) {
+	src, dst := memory.NewStorage(), memory.NewStorage()
+	put(t, src, "a.txt", "alpha")
+	put(t, src, "b.txt", "bravo")
+
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	if err := os.WriteFile(path, []byte("\"a.txt\"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Copy(context.Background(), src, dst, Options{CheckpointPath: path})
+	if err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	if result.Skipped != 1 || result.Transferred != 1 {
+		t.Errorf("result = %+v, want a.txt skipped from the checkpoint", result)
+	}
+	if got, want := keys(t, dst), []string{"b.txt"}; !slices.Equal(got, want) {
+		t.Errorf("destination holds %v, want %v", got, want)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("checkpoint left behind after a complete transfer: %v", err)
+	}
+}
+
+func TestCopyKeepsCheckpointOnFailure(t *testing.T) {
+	src, dst := memory.NewStorage(), memory.NewStorage()
+	put(t, src, "a.txt", "alpha")
+	put(t, src, "b.txt", "bravo")
+
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	_, err := Copy(context.Background(), src, failingStore{dst, "b.txt"}, Options{CheckpointPath: path, Concurrency: 1})
+	if err == nil {
+		t.Fatal("Copy with a failing object succeeded")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	if string(data) != "\"a.txt\"\n" {
+		t.Errorf("checkpoint = %q, want only a.txt recorded", data)
+	}
+}